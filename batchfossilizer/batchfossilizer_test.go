@@ -28,6 +28,8 @@ import (
 	"github.com/stratumn/go-indigocore/batchfossilizer/evidences"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
 )
 
 func TestGetInfo(t *testing.T) {
@@ -308,9 +310,12 @@ func TestBatchProof(t *testing.T) {
 	t.Run("TestVerify()", func(t *testing.T) {
 		for _, r := range results {
 			e := r.Evidence.Proof.(*evidences.BatchProof)
-			if e.Verify(nil) != true {
+			if e.Verify(types.NewBytes32FromBytes(r.Data)) != true {
 				t.Errorf("got evidence.Verify() == false")
 			}
+			if e.Verify(testutil.RandomHash()) != false {
+				t.Errorf("got evidence.Verify() == true for another link hash")
+			}
 		}
 	})
 }
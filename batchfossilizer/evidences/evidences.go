@@ -49,10 +49,32 @@ func (p *BatchProof) FullProof() []byte {
 	return bytes
 }
 
-// Verify returns true if the proof of a given linkHash is correct
+// Verify returns true if the proof of a given linkHash is correct.
+// The link hash must be a leaf of the Merkle path, and the path must
+// resolve to the Merkle root.
 func (p *BatchProof) Verify(linkHash interface{}) bool {
-	err := p.Path.Validate()
-	return err == nil
+	lh, ok := linkHash.(*types.Bytes32)
+	if !ok || lh == nil || p.Root == nil {
+		return false
+	}
+
+	// If the tree contains a single element, it's valid only if it's the
+	// root.
+	if len(p.Path) == 0 {
+		return lh.Equals(p.Root)
+	}
+
+	if err := p.Path.Validate(); err != nil {
+		return false
+	}
+
+	// The path should start at the given link hash...
+	if !lh.EqualsBytes(p.Path[0].Left) && !lh.EqualsBytes(p.Path[0].Right) {
+		return false
+	}
+
+	// ...and end at the Merkle root.
+	return p.Root.EqualsBytes(p.Path[len(p.Path)-1].Parent)
 }
 
 func init() {
@@ -0,0 +1,84 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evidences_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stratumn/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func createProofs(t *testing.T, leavesCount int) ([]*types.Bytes32, []*evidences.BatchProof) {
+	linkHashes := make([]*types.Bytes32, leavesCount)
+	leaves := make([][]byte, leavesCount)
+	for i := range leaves {
+		linkHashes[i] = testutil.RandomHash()
+		leaves[i] = linkHashes[i][:]
+	}
+
+	tree, err := merkle.NewStaticTree(leaves)
+	if err != nil {
+		t.Fatalf("merkle.NewStaticTree(): err: %s", err)
+	}
+
+	proofs := make([]*evidences.BatchProof, leavesCount)
+	for i := range proofs {
+		proofs[i] = &evidences.BatchProof{
+			Timestamp: time.Now().Unix(),
+			Root:      types.NewBytes32FromBytes(tree.Root()),
+			Path:      tree.Path(i),
+		}
+	}
+
+	return linkHashes, proofs
+}
+
+func TestBatchProof_Verify(t *testing.T) {
+	t.Run("Valid proofs", func(t *testing.T) {
+		linkHashes, proofs := createProofs(t, 5)
+		for i, p := range proofs {
+			assert.True(t, p.Verify(linkHashes[i]), "p.Verify()")
+		}
+	})
+
+	t.Run("Single leaf", func(t *testing.T) {
+		linkHashes, proofs := createProofs(t, 1)
+		assert.True(t, proofs[0].Verify(linkHashes[0]), "p.Verify()")
+		assert.False(t, proofs[0].Verify(testutil.RandomHash()), "p.Verify()")
+	})
+
+	t.Run("Proof of another link", func(t *testing.T) {
+		linkHashes, proofs := createProofs(t, 4)
+		assert.False(t, proofs[0].Verify(linkHashes[3]), "p.Verify()")
+		assert.False(t, proofs[0].Verify(testutil.RandomHash()), "p.Verify()")
+	})
+
+	t.Run("Wrong root", func(t *testing.T) {
+		linkHashes, proofs := createProofs(t, 4)
+		proofs[0].Root = testutil.RandomHash()
+		assert.False(t, proofs[0].Verify(linkHashes[0]), "p.Verify()")
+	})
+
+	t.Run("Invalid argument", func(t *testing.T) {
+		linkHashes, proofs := createProofs(t, 4)
+		assert.False(t, proofs[0].Verify(nil), "p.Verify()")
+		assert.False(t, proofs[0].Verify(linkHashes[0].String()), "p.Verify()")
+	})
+}
@@ -19,12 +19,17 @@ package bcbatchfossilizer
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stratumn/go-indigocore/batchfossilizer"
 	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
 	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
 	"github.com/stratumn/go-indigocore/blockchain"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/fossilizer"
 	"github.com/stratumn/go-indigocore/types"
@@ -36,11 +41,67 @@ const (
 
 	// Description is the description set in the fossilizer's information.
 	Description = "Indigo's Blockchain Batch Fossilizer"
+
+	// DefaultConfirmations is the default number of confirmations after
+	// which a transaction is considered final.
+	DefaultConfirmations = 6
+
+	// DefaultTrackInterval is the default interval between two checks of
+	// the confirmations of pending transactions.
+	DefaultTrackInterval = 10 * time.Minute
+
+	// DefaultDropTimeout is the default duration after which a transaction
+	// that cannot be found is considered dropped.
+	DefaultDropTimeout = time.Hour
 )
 
 // Config contains configuration options for the fossilizer.
 type Config struct {
 	HashTimestamper blockchain.HashTimestamper
+
+	// An optional finder used to track the confirmations of transactions.
+	// If it is nil, transactions are not tracked. If the batch fossilizer
+	// has a path, pending transactions are saved there at every check so
+	// that they are still tracked after a restart. Otherwise they are only
+	// kept in memory.
+	ConfirmationFinder btc.ConfirmationFinder
+
+	// The number of confirmations after which a transaction is final.
+	Confirmations int64
+
+	// The interval between two checks of pending transactions.
+	TrackInterval time.Duration
+
+	// The duration after which a transaction that cannot be found is
+	// broadcast again with a higher fee.
+	DropTimeout time.Duration
+}
+
+// GetConfirmations returns the configuration's number of confirmations or
+// DefaultConfirmations.
+func (c *Config) GetConfirmations() int64 {
+	if c.Confirmations > 0 {
+		return c.Confirmations
+	}
+	return DefaultConfirmations
+}
+
+// GetTrackInterval returns the configuration's track interval or
+// DefaultTrackInterval.
+func (c *Config) GetTrackInterval() time.Duration {
+	if c.TrackInterval > 0 {
+		return c.TrackInterval
+	}
+	return DefaultTrackInterval
+}
+
+// GetDropTimeout returns the configuration's drop timeout or
+// DefaultDropTimeout.
+func (c *Config) GetDropTimeout() time.Duration {
+	if c.DropTimeout > 0 {
+		return c.DropTimeout
+	}
+	return DefaultDropTimeout
 }
 
 // Info is the info returned by GetInfo.
@@ -59,6 +120,16 @@ type Fossilizer struct {
 	config            *Config
 	lastRoot          *types.Bytes32
 	lastTransactionID types.TransactionID
+
+	eventMutex sync.RWMutex
+	eventChans []chan *fossilizer.Event
+
+	trackMutex     sync.Mutex
+	path           string
+	pending        map[string]*pendingTransaction
+	pendingChanged bool
+
+	lowBalanceRoot *types.Bytes32
 }
 
 // New creates an instance of a Fossilizer.
@@ -75,6 +146,14 @@ func New(config *Config, batchConfig *batchfossilizer.Config) (*Fossilizer, erro
 	f := Fossilizer{
 		Adapter: b,
 		config:  config,
+		path:    batchConfig.Path,
+		pending: make(map[string]*pendingTransaction),
+	}
+
+	if config.ConfirmationFinder != nil {
+		if err := f.loadPending(); err != nil {
+			return nil, err
+		}
 	}
 
 	f.SetTransformer(f.transform)
@@ -105,6 +184,38 @@ func (a *Fossilizer) GetInfo(ctx context.Context) (interface{}, error) {
 	}, nil
 }
 
+// AddFossilizerEventChan implements
+// github.com/stratumn/go-indigocore/fossilizer.Adapter.AddFossilizerEventChan.
+func (a *Fossilizer) AddFossilizerEventChan(fossilizerEventChan chan *fossilizer.Event) {
+	a.Adapter.AddFossilizerEventChan(fossilizerEventChan)
+
+	a.eventMutex.Lock()
+	defer a.eventMutex.Unlock()
+	a.eventChans = append(a.eventChans, fossilizerEventChan)
+}
+
+// Start implements github.com/stratumn/go-indigocore/batchfossilizer.Adapter.Start.
+// If a ConfirmationFinder is configured, it also tracks the confirmations of
+// the transactions.
+func (a *Fossilizer) Start(ctx context.Context) error {
+	if a.config.ConfirmationFinder == nil {
+		return a.Adapter.Start(ctx)
+	}
+
+	trackCtx, cancel := context.WithCancel(ctx)
+	trackDone := make(chan struct{})
+	go func() {
+		a.track(trackCtx)
+		close(trackDone)
+	}()
+
+	err := a.Adapter.Start(ctx)
+	cancel()
+	<-trackDone
+
+	return err
+}
+
 func (a *Fossilizer) transform(evidence *cs.Evidence, data, meta []byte) (*fossilizer.Result, error) {
 	var (
 		root = evidence.Proof.(*batchevidences.BatchProof).Root
@@ -115,6 +226,9 @@ func (a *Fossilizer) transform(evidence *cs.Evidence, data, meta []byte) (*fossi
 	if a.lastRoot == nil || *root != *a.lastRoot {
 		txid, err = a.config.HashTimestamper.TimestampHash(root)
 		if err != nil {
+			if e, ok := errors.Cause(err).(*blockchain.LowBalanceError); ok {
+				a.lowBalance(root, e)
+			}
 			return nil, err
 		}
 		log.WithFields(log.Fields{
@@ -126,8 +240,13 @@ func (a *Fossilizer) transform(evidence *cs.Evidence, data, meta []byte) (*fossi
 		a.lastTransactionID = txid
 	}
 
-	evidence.Provider = a.config.HashTimestamper.GetInfo().Network.String()
+	network := a.config.HashTimestamper.GetInfo().Network
+	evidence.Provider = network.String()
 	evidence.Backend = Name
+	if _, ok := network.(eth.Network); ok {
+		// Ethereum proofs are verified differently.
+		evidence.Backend = evidences.EthBatchFossilizerName
+	}
 	evidence.Proof = &evidences.BcBatchProof{
 		Batch:         *evidence.Proof.(*batchevidences.BatchProof),
 		TransactionID: a.lastTransactionID,
@@ -139,5 +258,42 @@ func (a *Fossilizer) transform(evidence *cs.Evidence, data, meta []byte) (*fossi
 		Meta:     meta,
 	}
 
+	if a.config.ConfirmationFinder != nil {
+		a.addPending(root, a.lastTransactionID, &r)
+	}
+
 	return &r, nil
 }
+
+// lowBalance sends a LowBalance event once per batch.
+func (a *Fossilizer) lowBalance(root *types.Bytes32, err *blockchain.LowBalanceError) {
+	if a.lowBalanceRoot != nil && *a.lowBalanceRoot == *root {
+		return
+	}
+	a.lowBalanceRoot = root
+
+	log.WithFields(log.Fields{
+		"address":   err.Address,
+		"balance":   err.Balance,
+		"threshold": err.Threshold,
+	}).Error("Refused to broadcast transaction because the balance is too low")
+
+	event := &fossilizer.Event{
+		EventType: fossilizer.LowBalance,
+		Data:      err,
+	}
+
+	a.sendEvent(event)
+}
+
+// sendEvent sends an event to all the event channels. The channels are
+// copied so that the lock isn't held while a slow receiver blocks.
+func (a *Fossilizer) sendEvent(event *fossilizer.Event) {
+	a.eventMutex.RLock()
+	eventChans := append([]chan *fossilizer.Event(nil), a.eventChans...)
+	a.eventMutex.RUnlock()
+
+	for _, c := range eventChans {
+		c <- event
+	}
+}
@@ -22,8 +22,15 @@ import (
 	"time"
 
 	"github.com/stratumn/go-indigocore/batchfossilizer"
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
 	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain"
 	"github.com/stratumn/go-indigocore/blockchain/dummytimestamper"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
 )
 
 func TestGetInfo(t *testing.T) {
@@ -106,10 +113,96 @@ func TestBcBatchProof(t *testing.T) {
 
 	t.Run("TestVerify()", func(t *testing.T) {
 		for _, r := range results {
+			// The dummy timestamper doesn't create transactions, so
+			// only the Merkle path can be verified.
 			e := r.Evidence.Proof.(*evidences.BcBatchProof)
-			if e.Verify(nil) != true {
-				t.Errorf("got evidence.Verify() == false")
+			if err := e.VerifyWithFinder(types.NewBytes32FromBytes(r.Data), nil); err != evidences.ErrNoTransactionFinder {
+				t.Errorf("evidence.VerifyWithFinder() = %v, want %v", err, evidences.ErrNoTransactionFinder)
+			}
+			if err := e.VerifyWithFinder(testutil.RandomHash(), nil); err != evidences.ErrInvalidProof {
+				t.Errorf("evidence.VerifyWithFinder() = %v, want %v for another link hash", err, evidences.ErrInvalidProof)
 			}
 		}
 	})
 }
+
+// lowBalanceTimestamper is a dummy timestamper without funds.
+type lowBalanceTimestamper struct {
+	dummytimestamper.Timestamper
+}
+
+func (lowBalanceTimestamper) TimestampHash(*types.Bytes32) (types.TransactionID, error) {
+	return nil, &blockchain.LowBalanceError{Balance: 10, Threshold: 100}
+}
+
+func TestLowBalance(t *testing.T) {
+	a, err := New(&Config{
+		HashTimestamper: lowBalanceTimestamper{},
+	}, &batchfossilizer.Config{})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	ec := make(chan *fossilizer.Event, 10)
+	a.AddFossilizerEventChan(ec)
+
+	for _, root := range []*types.Bytes32{testutil.RandomHash(), testutil.RandomHash()} {
+		// Only one event is sent per batch.
+		for i := 0; i < 2; i++ {
+			evidence := &cs.Evidence{Proof: &batchevidences.BatchProof{Root: root}}
+			if _, err := a.transform(evidence, root[:], nil); err == nil {
+				t.Error("a.transform(): err = nil want Error")
+			}
+		}
+	}
+
+	if got, want := len(ec), 2; got != want {
+		t.Fatalf("len(ec) = %d want %d", got, want)
+	}
+
+	e := <-ec
+	if got, want := e.EventType, fossilizer.LowBalance; got != want {
+		t.Errorf("e.EventType = %s want %s", got, want)
+	}
+	if got, want := e.Data.(*blockchain.LowBalanceError).Balance, int64(10); got != want {
+		t.Errorf("e.Data.Balance = %d want %d", got, want)
+	}
+}
+
+// ethTimestamper is a dummy timestamper on an Ethereum network.
+type ethTimestamper struct {
+	dummytimestamper.Timestamper
+}
+
+func (ethTimestamper) GetInfo() *blockchain.Info {
+	return &blockchain.Info{Network: eth.NetworkRopsten, Description: "Ethereum"}
+}
+
+func TestBackend(t *testing.T) {
+	tests := []struct {
+		name        string
+		timestamper blockchain.HashTimestamper
+		want        string
+	}{
+		{"bitcoin", dummytimestamper.Timestamper{}, Name},
+		{"ethereum", ethTimestamper{}, evidences.EthBatchFossilizerName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(&Config{HashTimestamper: tt.timestamper}, &batchfossilizer.Config{})
+			if err != nil {
+				t.Fatalf("New(): err: %s", err)
+			}
+
+			root := testutil.RandomHash()
+			r, err := a.transform(&cs.Evidence{Proof: &batchevidences.BatchProof{Root: root}}, root[:], nil)
+			if err != nil {
+				t.Fatalf("a.transform(): err: %s", err)
+			}
+			if got := r.Evidence.Backend; got != tt.want {
+				t.Errorf("r.Evidence.Backend = %s want %s", got, tt.want)
+			}
+		})
+	}
+}
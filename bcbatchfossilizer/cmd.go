@@ -21,6 +21,7 @@ import (
 
 	"github.com/stratumn/go-indigocore/batchfossilizer"
 	"github.com/stratumn/go-indigocore/blockchain"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -32,6 +33,10 @@ var (
 	archive   bool
 	exitBatch bool
 	fsync     bool
+
+	confirmations int64
+	trackInterval time.Duration
+	dropTimeout   time.Duration
 )
 
 // RegisterFlags registers the flags used by RunWithFlags.
@@ -42,15 +47,24 @@ func RegisterFlags() {
 	flag.BoolVar(&archive, "archive", batchfossilizer.DefaultArchive, "whether to archive completed batches (requires path)")
 	flag.BoolVar(&exitBatch, "exitbatch", batchfossilizer.DefaultStopBatch, "whether to do a batch on exit")
 	flag.BoolVar(&fsync, "fsync", batchfossilizer.DefaultFSync, "whether to fsync after saving a pending hash (requires path)")
+	flag.Int64Var(&confirmations, "confirmations", DefaultConfirmations, "number of confirmations after which a transaction is final")
+	flag.DurationVar(&trackInterval, "trackinterval", DefaultTrackInterval, "interval between two checks of pending transactions")
+	flag.DurationVar(&dropTimeout, "droptimeout", DefaultDropTimeout, "duration after which a transaction that cannot be found is broadcast again")
 }
 
 // RunWithFlags should be called after RegisterFlags and flag.Parse to initialize
 // a bcbatchfossilizer using flag values.
-func RunWithFlags(ctx context.Context, version, commit string, hashTS blockchain.HashTimestamper) *Fossilizer {
+// The confirmation finder is optional, if it is nil transactions are not
+// tracked.
+func RunWithFlags(ctx context.Context, version, commit string, hashTS blockchain.HashTimestamper, finder btc.ConfirmationFinder) *Fossilizer {
 	log.Infof("%s v%s@%s", Description, version, commit[:7])
 
 	a, err := New(&Config{
-		HashTimestamper: hashTS,
+		HashTimestamper:    hashTS,
+		ConfirmationFinder: finder,
+		Confirmations:      confirmations,
+		TrackInterval:      trackInterval,
+		DropTimeout:        dropTimeout,
 	}, &batchfossilizer.Config{
 		Version:   version,
 		Commit:    commit,
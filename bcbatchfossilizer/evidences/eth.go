@@ -0,0 +1,89 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evidences
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+var (
+	// EthBatchFossilizerName is the name used as the backend of the
+	// BcBatchProof of batches fossilized on Ethereum.
+	EthBatchFossilizerName = "ethbatch"
+)
+
+// EthVerifier verifies the proofs of batches fossilized on Ethereum using a
+// transaction finder.
+// It implements github.com/stratumn/go-indigocore/store.ProofVerifier.
+type EthVerifier struct {
+	finder eth.TransactionFinder
+}
+
+// NewEthVerifier creates a verifier that finds Ethereum transactions with
+// the given finder.
+func NewEthVerifier(finder eth.TransactionFinder) *EthVerifier {
+	return &EthVerifier{finder: finder}
+}
+
+// VerifyProof checks that a proof of a given linkHash is correct.
+// The Merkle path is verified and the Ethereum transaction is checked to
+// carry the Merkle root, either as its data or as the argument of a call to
+// the anchor method of a contract.
+func (v *EthVerifier) VerifyProof(proof cs.Proof, linkHash *types.Bytes32) error {
+	p, ok := proof.(*BcBatchProof)
+	if !ok {
+		return errors.Errorf("unexpected proof type %T", proof)
+	}
+
+	// Block proofs are Bitcoin block headers.
+	if !p.Batch.Verify(linkHash) || p.Block != nil {
+		return ErrInvalidProof
+	}
+	if p.Batch.Root == nil {
+		return ErrRootNotFound
+	}
+
+	tx, err := v.finder.FindTransaction(p.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	// Do not trust the finder, make sure it returned the right transaction.
+	if !bytes.Equal(tx.Hash(), p.TransactionID) {
+		return ErrTransactionMismatch
+	}
+
+	if bytes.Equal(p.Batch.Root[:], tx.Data) || bytes.Equal(eth.AnchorCallData(p.Batch.Root), tx.Data) {
+		return nil
+	}
+
+	return ErrRootNotFound
+}
+
+func init() {
+	cs.DeserializeMethods[EthBatchFossilizerName] = func(rawProof json.RawMessage) (cs.Proof, error) {
+		p := BcBatchProof{}
+		if err := json.Unmarshal(rawProof, &p); err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evidences_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/blockchain/eth/ethtesting"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// createEthTx creates a signed Ethereum transaction with the given data.
+func createEthTx(data []byte) *eth.SignedTransaction {
+	return &eth.SignedTransaction{
+		Transaction: eth.Transaction{
+			Nonce:    9,
+			GasPrice: big.NewInt(20000000000),
+			GasLimit: 60000,
+			To:       &types.Bytes20{0x42},
+			Value:    new(big.Int),
+			Data:     data,
+		},
+		V: big.NewInt(37),
+		R: new(big.Int).SetBytes(testutil.RandomHash()[:]),
+		S: new(big.Int).SetBytes(testutil.RandomHash()[:]),
+	}
+}
+
+// createEthFinder creates a finder that returns the given transaction.
+func createEthFinder(tx *eth.SignedTransaction) *ethtesting.Mock {
+	mock := &ethtesting.Mock{}
+	mock.MockFindTransaction.Fn = func(types.TransactionID) (*eth.SignedTransaction, error) { return tx, nil }
+	return mock
+}
+
+func TestEthVerifier(t *testing.T) {
+	t.Run("Root in data", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		tx := createEthTx(p.Batch.Root[:])
+		p.TransactionID = tx.Hash()
+		mock := createEthFinder(tx)
+
+		assert.NoError(t, evidences.NewEthVerifier(mock).VerifyProof(p, linkHash))
+		assert.Equal(t, p.TransactionID, mock.MockFindTransaction.LastCalledWith)
+	})
+
+	t.Run("Root in anchor call", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		tx := createEthTx(eth.AnchorCallData(p.Batch.Root))
+		p.TransactionID = tx.Hash()
+
+		assert.NoError(t, evidences.NewEthVerifier(createEthFinder(tx)).VerifyProof(p, linkHash))
+	})
+
+	t.Run("Invalid link hash", func(t *testing.T) {
+		_, p, _ := createProof(t)
+		tx := createEthTx(p.Batch.Root[:])
+		p.TransactionID = tx.Hash()
+		mock := createEthFinder(tx)
+
+		err := evidences.NewEthVerifier(mock).VerifyProof(p, testutil.RandomHash())
+		assert.EqualError(t, err, evidences.ErrInvalidProof.Error())
+		assert.Equal(t, 0, mock.MockFindTransaction.CalledCount)
+	})
+
+	t.Run("Transaction without the root", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		tx := createEthTx(testutil.RandomHash()[:])
+		p.TransactionID = tx.Hash()
+
+		err := evidences.NewEthVerifier(createEthFinder(tx)).VerifyProof(p, linkHash)
+		assert.EqualError(t, err, evidences.ErrRootNotFound.Error())
+	})
+
+	t.Run("Finder returns another transaction", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		p.TransactionID = createEthTx(p.Batch.Root[:]).Hash()
+
+		err := evidences.NewEthVerifier(createEthFinder(createEthTx(p.Batch.Root[:]))).VerifyProof(p, linkHash)
+		assert.EqualError(t, err, evidences.ErrTransactionMismatch.Error())
+	})
+}
@@ -16,9 +16,12 @@
 package evidences
 
 import (
+	"bytes"
 	"encoding/json"
 
+	"github.com/pkg/errors"
 	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/types"
 )
@@ -32,6 +35,41 @@ var (
 type BcBatchProof struct {
 	Batch         batchevidences.BatchProof `json:"batch"`
 	TransactionID types.TransactionID       `json:"txid"`
+
+	// Block is set once the transaction has enough confirmations.
+	Block *BlockProof `json:"block,omitempty"`
+}
+
+// BlockProof proves that a transaction is included in a block.
+type BlockProof struct {
+	Hash   types.Bytes32   `json:"hash"`
+	Height int64           `json:"height"`
+	Header []byte          `json:"header"`
+	Index  int             `json:"index"`
+	Branch []types.Bytes32 `json:"branch"`
+}
+
+// NewBlockProof creates a block proof from a transaction confirmation.
+func NewBlockProof(c *btc.Confirmation) *BlockProof {
+	return &BlockProof{
+		Hash:   c.BlockHash,
+		Height: c.BlockHeight,
+		Header: c.Header,
+		Index:  c.Index,
+		Branch: c.Branch,
+	}
+}
+
+// Verify checks that the transaction with the given ID is included in the
+// block.
+func (b *BlockProof) Verify(txID types.TransactionID) error {
+	return btc.VerifyConfirmation(txID, &btc.Confirmation{
+		BlockHash:   b.Hash,
+		BlockHeight: b.Height,
+		Header:      b.Header,
+		Index:       b.Index,
+		Branch:      b.Branch,
+	})
 }
 
 // Time returns the timestamp from the block header
@@ -48,10 +86,109 @@ func (p *BcBatchProof) FullProof() []byte {
 	return bytes
 }
 
-// Verify returns true if the proof of a given linkHash is correct
+var (
+	// ErrTransactionMismatch is returned when the transaction returned by
+	// the transaction finder does not have the ID of the proof.
+	ErrTransactionMismatch = errors.New("transaction ID mismatch")
+
+	// ErrRootNotFound is returned when the transaction does not carry
+	// the Merkle root in an OP_RETURN output.
+	ErrRootNotFound = errors.New("merkle root not found in transaction")
+
+	// ErrInvalidProof is returned when the Merkle path or the block of a
+	// proof is not valid.
+	ErrInvalidProof = errors.New("invalid batch proof")
+
+	// ErrNoTransactionFinder is returned when a proof is verified without
+	// a transaction finder.
+	ErrNoTransactionFinder = errors.New("a transaction finder is required to verify the transaction")
+)
+
+// Verify implements github.com/stratumn/go-indigocore/cs.Proof.Verify.
+// The transaction of the proof cannot be checked to carry the Merkle root
+// without a transaction finder, so it always returns false. Use
+// VerifyWithFinder or a Verifier instead.
 func (p *BcBatchProof) Verify(linkHash interface{}) bool {
-	err := p.Batch.Path.Validate()
-	return err == nil
+	return p.VerifyWithFinder(linkHash, nil) == nil
+}
+
+// VerifyWithFinder checks that the proof of a given linkHash is correct.
+// The Merkle path is verified and the transaction is checked to carry the
+// Merkle root. If the proof contains a block, the inclusion of the
+// transaction in the block is verified too.
+func (p *BcBatchProof) VerifyWithFinder(linkHash interface{}, finder btc.TransactionFinder) error {
+	if !p.Batch.Verify(linkHash) {
+		return ErrInvalidProof
+	}
+
+	if p.Block != nil {
+		if err := p.Block.Verify(p.TransactionID); err != nil {
+			return err
+		}
+	}
+
+	if finder == nil {
+		return ErrNoTransactionFinder
+	}
+
+	return p.VerifyTransaction(finder)
+}
+
+// VerifyTransaction checks that the transaction of the proof carries the
+// Merkle root in an OP_RETURN output.
+func (p *BcBatchProof) VerifyTransaction(finder btc.TransactionFinder) error {
+	if p.Batch.Root == nil {
+		return ErrRootNotFound
+	}
+
+	raw, err := finder.FindTransaction(p.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	// Do not trust the finder, make sure it returned the right transaction.
+	txID, err := btc.TransactionID(raw)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(txID, p.TransactionID) {
+		return ErrTransactionMismatch
+	}
+
+	data, err := btc.NullData(raw)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range data {
+		if p.Batch.Root.EqualsBytes(d) {
+			return nil
+		}
+	}
+
+	return ErrRootNotFound
+}
+
+// Verifier verifies bcbatch proofs using a transaction finder.
+// It implements github.com/stratumn/go-indigocore/store.ProofVerifier.
+type Verifier struct {
+	finder btc.TransactionFinder
+}
+
+// NewVerifier creates a verifier that finds transactions with the given
+// finder.
+func NewVerifier(finder btc.TransactionFinder) *Verifier {
+	return &Verifier{finder: finder}
+}
+
+// VerifyProof checks that a bcbatch proof of a given linkHash is correct.
+func (v *Verifier) VerifyProof(proof cs.Proof, linkHash *types.Bytes32) error {
+	p, ok := proof.(*BcBatchProof)
+	if !ok {
+		return errors.Errorf("unexpected proof type %T", proof)
+	}
+
+	return p.VerifyWithFinder(linkHash, v.finder)
 }
 
 func init() {
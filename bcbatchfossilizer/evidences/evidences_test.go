@@ -0,0 +1,204 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evidences_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/blockchain/btc/btctesting"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stratumn/merkle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTx creates a raw Bitcoin transaction with an OP_RETURN output
+// containing the given data.
+func createTx(t *testing.T, data []byte) (types.TransactionID, []byte) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), nil, nil))
+
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(data).Script()
+	require.NoError(t, err, "txscript.NewScriptBuilder()")
+	tx.AddTxOut(wire.NewTxOut(0, script))
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, tx.Serialize(buf), "tx.Serialize()")
+	raw := buf.Bytes()
+
+	txID, err := btc.TransactionID(raw)
+	require.NoError(t, err, "btc.TransactionID()")
+
+	return txID, raw
+}
+
+func createProof(t *testing.T) (*types.Bytes32, *evidences.BcBatchProof, []byte) {
+	linkHash := testutil.RandomHash()
+	tree, err := merkle.NewStaticTree([][]byte{linkHash[:], testutil.RandomHash()[:]})
+	require.NoError(t, err, "merkle.NewStaticTree()")
+
+	root := types.NewBytes32FromBytes(tree.Root())
+	txID, raw := createTx(t, root[:])
+
+	return linkHash, &evidences.BcBatchProof{
+		Batch: batchevidences.BatchProof{
+			Timestamp: time.Now().Unix(),
+			Root:      root,
+			Path:      tree.Path(0),
+		},
+		TransactionID: txID,
+	}, raw
+}
+
+func TestBcBatchProof_Verify(t *testing.T) {
+	t.Run("Without transaction finder", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		assert.False(t, p.Verify(linkHash), "p.Verify()")
+		assert.EqualError(t, p.VerifyWithFinder(linkHash, nil), evidences.ErrNoTransactionFinder.Error())
+	})
+
+	t.Run("With transaction finder", func(t *testing.T) {
+		linkHash, p, raw := createProof(t)
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+
+		assert.NoError(t, p.VerifyWithFinder(linkHash, mock), "p.VerifyWithFinder()")
+		assert.Equal(t, 1, mock.MockFindTransaction.CalledCount)
+		assert.Equal(t, p.TransactionID, mock.MockFindTransaction.LastCalledWith)
+
+		assert.NoError(t, evidences.NewVerifier(mock).VerifyProof(p, linkHash), "v.VerifyProof()")
+	})
+
+	t.Run("Invalid link hash", func(t *testing.T) {
+		_, p, raw := createProof(t)
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+
+		err := p.VerifyWithFinder(testutil.RandomHash(), mock)
+		assert.EqualError(t, err, evidences.ErrInvalidProof.Error())
+		assert.Equal(t, 0, mock.MockFindTransaction.CalledCount)
+	})
+
+	t.Run("Transaction not found", func(t *testing.T) {
+		linkHash, p, _ := createProof(t)
+		assert.Error(t, p.VerifyWithFinder(linkHash, &btctesting.Mock{}), "p.VerifyWithFinder()")
+	})
+}
+
+func TestBcBatchProof_VerifyTransaction(t *testing.T) {
+	t.Run("Valid transaction", func(t *testing.T) {
+		_, p, raw := createProof(t)
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+
+		assert.NoError(t, p.VerifyTransaction(mock))
+	})
+
+	t.Run("Transaction without the root", func(t *testing.T) {
+		_, p, _ := createProof(t)
+		txID, raw := createTx(t, testutil.RandomHash()[:])
+		p.TransactionID = txID
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+
+		assert.EqualError(t, p.VerifyTransaction(mock), evidences.ErrRootNotFound.Error())
+	})
+
+	t.Run("Finder returns another transaction", func(t *testing.T) {
+		_, p, _ := createProof(t)
+		_, other := createTx(t, testutil.RandomHash()[:])
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return other, nil }
+
+		assert.EqualError(t, p.VerifyTransaction(mock), evidences.ErrTransactionMismatch.Error())
+	})
+}
+
+// createBlockProof creates the proof of a block containing the given
+// transaction and another random transaction.
+func createBlockProof(t *testing.T, txID types.TransactionID) *evidences.BlockProof {
+	other, _ := createTx(t, testutil.RandomHash()[:])
+	txIDs := []types.TransactionID{other, txID}
+
+	var leaves [2]chainhash.Hash
+	for i, id := range txIDs {
+		for j, b := range id {
+			leaves[i][chainhash.HashSize-j-1] = b
+		}
+	}
+	root := chainhash.DoubleHashH(append(leaves[0][:], leaves[1][:]...))
+
+	header := wire.NewBlockHeader(1, &chainhash.Hash{}, &root, 0x1d00ffff, 0)
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, header.Serialize(buf), "header.Serialize()")
+
+	blockHash, err := btc.BlockHash(buf.Bytes())
+	require.NoError(t, err, "btc.BlockHash()")
+
+	branch, err := btc.MerkleBranch(txIDs, 1)
+	require.NoError(t, err, "btc.MerkleBranch()")
+
+	return evidences.NewBlockProof(&btc.Confirmation{
+		BlockHash:     *blockHash,
+		BlockHeight:   42,
+		Confirmations: 6,
+		Header:        buf.Bytes(),
+		Index:         1,
+		Branch:        branch,
+	})
+}
+
+func TestBcBatchProof_VerifyBlock(t *testing.T) {
+	finder := func(raw []byte) btc.TransactionFinder {
+		mock := &btctesting.Mock{}
+		mock.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+		return mock
+	}
+
+	t.Run("Valid block", func(t *testing.T) {
+		linkHash, p, raw := createProof(t)
+		p.Block = createBlockProof(t, p.TransactionID)
+
+		assert.NoError(t, p.Block.Verify(p.TransactionID))
+		assert.NoError(t, p.VerifyWithFinder(linkHash, finder(raw)), "p.VerifyWithFinder()")
+	})
+
+	t.Run("Block of another transaction", func(t *testing.T) {
+		linkHash, p, raw := createProof(t)
+		other, _ := createTx(t, testutil.RandomHash()[:])
+		p.Block = createBlockProof(t, other)
+
+		assert.EqualError(t, p.Block.Verify(p.TransactionID), btc.ErrBadMerkleBranch.Error())
+		assert.Error(t, p.VerifyWithFinder(linkHash, finder(raw)), "p.VerifyWithFinder()")
+	})
+
+	t.Run("Wrong block hash", func(t *testing.T) {
+		linkHash, p, raw := createProof(t)
+		p.Block = createBlockProof(t, p.TransactionID)
+		p.Block.Hash = *testutil.RandomHash()
+
+		assert.EqualError(t, p.Block.Verify(p.TransactionID), btc.ErrBadBlockHash.Error())
+		assert.Error(t, p.VerifyWithFinder(linkHash, finder(raw)), "p.VerifyWithFinder()")
+	})
+}
@@ -0,0 +1,260 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bcbatchfossilizer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/batchfossilizer"
+	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PendingFilename is the name of the file, in the path of the batch
+// fossilizer, where transactions that don't have enough confirmations yet
+// are saved.
+const PendingFilename = "confirmations.json"
+
+// pendingTransaction is a broadcast transaction that doesn't have enough
+// confirmations yet.
+type pendingTransaction struct {
+	Root        *types.Bytes32       `json:"root"`
+	TxID        types.TransactionID  `json:"txid"`
+	BroadcastAt time.Time            `json:"broadcastAt"`
+	Attempts    int                  `json:"attempts"`
+	Results     []*fossilizer.Result `json:"results"`
+}
+
+// addPending adds a result to the pending transaction that timestamped the
+// given root.
+func (a *Fossilizer) addPending(root *types.Bytes32, txID types.TransactionID, r *fossilizer.Result) {
+	a.trackMutex.Lock()
+	defer a.trackMutex.Unlock()
+
+	tx, ok := a.pending[txID.String()]
+	if !ok {
+		tx = &pendingTransaction{
+			Root:        root,
+			TxID:        txID,
+			BroadcastAt: time.Now(),
+		}
+		a.pending[txID.String()] = tx
+	}
+
+	tx.Results = append(tx.Results, r)
+
+	// Saving the results of a batch one at a time would be quadratic, so
+	// they are saved by the next check.
+	a.pendingChanged = true
+}
+
+// loadPending loads the pending transactions saved by a previous run, if
+// the fossilizer has a path.
+func (a *Fossilizer) loadPending() error {
+	if a.path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(a.path, PendingFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var txs []*pendingTransaction
+	if err := json.Unmarshal(data, &txs); err != nil {
+		return errors.Wrapf(err, "invalid %s", PendingFilename)
+	}
+
+	for _, tx := range txs {
+		a.pending[tx.TxID.String()] = tx
+	}
+
+	return nil
+}
+
+// savePending saves the pending transactions if the fossilizer has a path.
+// The track mutex must be held.
+func (a *Fossilizer) savePending() {
+	a.pendingChanged = false
+	if a.path == "" {
+		return
+	}
+
+	txs := make([]*pendingTransaction, 0, len(a.pending))
+	for _, tx := range a.pending {
+		txs = append(txs, tx)
+	}
+
+	if err := writePending(a.path, txs); err != nil {
+		log.WithField("error", err).Error("Failed to save pending transactions")
+	}
+}
+
+// writePending replaces the file of pending transactions.
+func writePending(path string, txs []*pendingTransaction) error {
+	data, err := json.Marshal(txs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	filename := filepath.Join(path, PendingFilename)
+	if err := ioutil.WriteFile(filename+".tmp", data, batchfossilizer.FilePerm); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(filename+".tmp", filename))
+}
+
+// track checks pending transactions at regular intervals until the context
+// is canceled.
+func (a *Fossilizer) track(ctx context.Context) {
+	ticker := time.NewTicker(a.config.GetTrackInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.checkPending()
+		case <-ctx.Done():
+			a.trackMutex.Lock()
+			if a.pendingChanged {
+				a.savePending()
+			}
+			a.trackMutex.Unlock()
+			return
+		}
+	}
+}
+
+// checkPending checks the confirmations of all pending transactions.
+func (a *Fossilizer) checkPending() {
+	a.trackMutex.Lock()
+	if a.pendingChanged {
+		a.savePending()
+	}
+	txs := make([]*pendingTransaction, 0, len(a.pending))
+	for _, tx := range a.pending {
+		txs = append(txs, tx)
+	}
+	a.trackMutex.Unlock()
+
+	for _, tx := range txs {
+		a.checkTransaction(tx)
+	}
+}
+
+func (a *Fossilizer) checkTransaction(tx *pendingTransaction) {
+	c, err := a.config.ConfirmationFinder.FindConfirmation(tx.TxID)
+
+	switch {
+	case errors.Cause(err) == btc.ErrTransactionNotFound:
+		if time.Since(tx.BroadcastAt) >= a.config.GetDropTimeout() {
+			a.rebroadcast(tx)
+		}
+	case err != nil:
+		log.WithFields(log.Fields{
+			"txid":  tx.TxID,
+			"error": err,
+		}).Warn("Failed to find transaction confirmation")
+	case c == nil || c.Confirmations < a.config.GetConfirmations():
+		// Not final yet, check again later.
+	default:
+		a.confirm(tx, c)
+	}
+}
+
+// rebroadcast timestamps the root of a dropped transaction again.
+func (a *Fossilizer) rebroadcast(tx *pendingTransaction) {
+	r, ok := a.config.HashTimestamper.(blockchain.Rebroadcaster)
+	if !ok {
+		log.WithField("txid", tx.TxID).Warn("Transaction was dropped but the timestamper cannot rebroadcast it")
+		return
+	}
+
+	txID, err := r.RetimestampHash(tx.Root, tx.Attempts+1)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"txid":  tx.TxID,
+			"error": err,
+		}).Error("Failed to rebroadcast transaction")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"txid":    txID,
+		"dropped": tx.TxID,
+		"root":    tx.Root,
+	}).Info("Rebroadcasted transaction")
+
+	a.trackMutex.Lock()
+	defer a.trackMutex.Unlock()
+
+	delete(a.pending, tx.TxID.String())
+	tx.TxID = txID
+	tx.BroadcastAt = time.Now()
+	tx.Attempts++
+	a.pending[txID.String()] = tx
+	a.savePending()
+}
+
+// confirm sends the upgraded evidences of a final transaction and stops
+// tracking it.
+func (a *Fossilizer) confirm(tx *pendingTransaction, c *btc.Confirmation) {
+	a.trackMutex.Lock()
+	delete(a.pending, tx.TxID.String())
+	a.savePending()
+	a.trackMutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"txid":          tx.TxID,
+		"block":         c.BlockHash.String(),
+		"height":        c.BlockHeight,
+		"confirmations": c.Confirmations,
+	}).Info("Confirmed transaction")
+
+	for _, r := range tx.Results {
+		proof := *r.Evidence.Proof.(*evidences.BcBatchProof)
+		proof.TransactionID = tx.TxID
+		proof.Block = evidences.NewBlockProof(c)
+
+		evidence := r.Evidence
+		evidence.Proof = &proof
+
+		event := &fossilizer.Event{
+			EventType: fossilizer.DidConfirmLink,
+			Data: &fossilizer.Result{
+				Evidence: evidence,
+				Data:     r.Data,
+				Meta:     r.Meta,
+			},
+		}
+
+		a.sendEvent(event)
+	}
+}
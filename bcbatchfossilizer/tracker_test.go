@@ -0,0 +1,407 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bcbatchfossilizer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/batchfossilizer"
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/blockchain/btc/btctesting"
+	"github.com/stratumn/go-indigocore/blockchain/dummytimestamper"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// rebroadcastTimestamper is a dummy timestamper that can rebroadcast.
+type rebroadcastTimestamper struct {
+	dummytimestamper.Timestamper
+	attempts []int
+}
+
+func (ts *rebroadcastTimestamper) RetimestampHash(hash *types.Bytes32, attempt int) (types.TransactionID, error) {
+	ts.attempts = append(ts.attempts, attempt)
+	return testutil.RandomHash()[:], nil
+}
+
+func createTracker(t *testing.T, config *Config) (*Fossilizer, chan *fossilizer.Event) {
+	a, err := New(config, &batchfossilizer.Config{})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	ec := make(chan *fossilizer.Event, 10)
+	a.AddFossilizerEventChan(ec)
+
+	return a, ec
+}
+
+// transformLeaves transforms the evidences of leaves sharing a root.
+func transformLeaves(t *testing.T, a *Fossilizer, n int) (*types.Bytes32, types.TransactionID) {
+	root := testutil.RandomHash()
+
+	for i := 0; i < n; i++ {
+		evidence := &cs.Evidence{
+			Proof: &batchevidences.BatchProof{
+				Timestamp: time.Now().Unix(),
+				Root:      root,
+			},
+		}
+		if _, err := a.transform(evidence, testutil.RandomHash()[:], []byte{byte(i)}); err != nil {
+			t.Fatalf("a.transform(): err: %s", err)
+		}
+	}
+
+	return root, a.lastTransactionID
+}
+
+func confirmation(confirmations int64) *btc.Confirmation {
+	return &btc.Confirmation{
+		BlockHash:     *testutil.RandomHash(),
+		BlockHeight:   42,
+		Confirmations: confirmations,
+	}
+}
+
+func TestTrack_Pending(t *testing.T) {
+	mock := &btctesting.Mock{}
+	a, ec := createTracker(t, &Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: mock,
+	})
+
+	_, txID := transformLeaves(t, a, 2)
+
+	a.checkPending()
+
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return confirmation(DefaultConfirmations - 1), nil
+	}
+	a.checkPending()
+
+	if got, want := mock.MockFindConfirmation.CalledCount, 2; got != want {
+		t.Errorf("mock.MockFindConfirmation.CalledCount = %d want %d", got, want)
+	}
+	if got, want := mock.MockFindConfirmation.LastCalledWith.String(), txID.String(); got != want {
+		t.Errorf("mock.MockFindConfirmation.LastCalledWith = %s want %s", got, want)
+	}
+	if got, want := len(a.pending), 1; got != want {
+		t.Errorf("len(a.pending) = %d want %d", got, want)
+	}
+	if got, want := len(ec), 0; got != want {
+		t.Errorf("len(ec) = %d want %d", got, want)
+	}
+}
+
+func TestTrack_Confirmed(t *testing.T) {
+	mock := &btctesting.Mock{}
+	a, ec := createTracker(t, &Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: mock,
+		Confirmations:      3,
+	})
+
+	root, txID := transformLeaves(t, a, 2)
+
+	c := confirmation(3)
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) { return c, nil }
+
+	a.checkPending()
+
+	if got, want := len(a.pending), 0; got != want {
+		t.Errorf("len(a.pending) = %d want %d", got, want)
+	}
+	if got, want := len(ec), 2; got != want {
+		t.Fatalf("len(ec) = %d want %d", got, want)
+	}
+
+	for i := 0; i < 2; i++ {
+		e := <-ec
+		if got, want := e.EventType, fossilizer.DidConfirmLink; got != want {
+			t.Errorf("e.EventType = %s want %s", got, want)
+		}
+
+		r := e.Data.(*fossilizer.Result)
+		if got, want := r.Meta, []byte{byte(i)}; string(got) != string(want) {
+			t.Errorf("r.Meta = %v want %v", got, want)
+		}
+
+		proof := r.Evidence.Proof.(*evidences.BcBatchProof)
+		if got, want := proof.TransactionID.String(), txID.String(); got != want {
+			t.Errorf("proof.TransactionID = %s want %s", got, want)
+		}
+		if got, want := proof.Batch.Root.String(), root.String(); got != want {
+			t.Errorf("proof.Batch.Root = %s want %s", got, want)
+		}
+		if proof.Block == nil {
+			t.Fatal("proof.Block = nil want *BlockProof")
+		}
+		if got, want := proof.Block.Height, c.BlockHeight; got != want {
+			t.Errorf("proof.Block.Height = %d want %d", got, want)
+		}
+		if got, want := proof.Block.Hash, c.BlockHash; got != want {
+			t.Errorf("proof.Block.Hash = %s want %s", got.String(), want.String())
+		}
+	}
+}
+
+func TestTrack_Dropped(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return nil, errors.WithStack(btc.ErrTransactionNotFound)
+	}
+
+	ts := &rebroadcastTimestamper{}
+	a, ec := createTracker(t, &Config{
+		HashTimestamper:    ts,
+		ConfirmationFinder: mock,
+		DropTimeout:        time.Nanosecond,
+	})
+
+	_, txID := transformLeaves(t, a, 1)
+
+	a.checkPending()
+	a.checkPending()
+
+	if got, want := ts.attempts, []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ts.attempts = %v want %v", got, want)
+	}
+	if got, want := len(a.pending), 1; got != want {
+		t.Fatalf("len(a.pending) = %d want %d", got, want)
+	}
+	if _, ok := a.pending[txID.String()]; ok {
+		t.Error("dropped transaction is still pending")
+	}
+
+	previousTxID := mock.MockFindConfirmation.LastCalledWith
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return confirmation(DefaultConfirmations), nil
+	}
+	a.checkPending()
+
+	if got, want := len(ec), 1; got != want {
+		t.Fatalf("len(ec) = %d want %d", got, want)
+	}
+
+	proof := (<-ec).Data.(*fossilizer.Result).Evidence.Proof.(*evidences.BcBatchProof)
+	if got, want := proof.TransactionID.String(), mock.MockFindConfirmation.LastCalledWith.String(); got != want {
+		t.Errorf("proof.TransactionID = %s want %s", got, want)
+	}
+	if proof.TransactionID.String() == previousTxID.String() {
+		t.Error("proof.TransactionID is the ID of a dropped transaction")
+	}
+}
+
+func TestTrack_DroppedTooSoon(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return nil, btc.ErrTransactionNotFound
+	}
+
+	ts := &rebroadcastTimestamper{}
+	a, _ := createTracker(t, &Config{
+		HashTimestamper:    ts,
+		ConfirmationFinder: mock,
+	})
+
+	transformLeaves(t, a, 1)
+	a.checkPending()
+
+	if got := len(ts.attempts); got != 0 {
+		t.Errorf("len(ts.attempts) = %d want 0", got)
+	}
+}
+
+func TestTrack_CannotRebroadcast(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return nil, btc.ErrTransactionNotFound
+	}
+
+	a, _ := createTracker(t, &Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: mock,
+		DropTimeout:        time.Nanosecond,
+	})
+
+	_, txID := transformLeaves(t, a, 1)
+	a.checkPending()
+
+	if _, ok := a.pending[txID.String()]; !ok {
+		t.Error("transaction is not pending anymore")
+	}
+}
+
+func TestTrack_Persisted(t *testing.T) {
+	path, err := ioutil.TempDir("", "bcbatchfossilizer")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): err: %s", err)
+	}
+	defer os.RemoveAll(path)
+
+	config := &Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: &btctesting.Mock{},
+	}
+	a, err := New(config, &batchfossilizer.Config{Path: path})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	root, txID := transformLeaves(t, a, 2)
+	a.checkPending()
+
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return confirmation(DefaultConfirmations), nil
+	}
+	config.ConfirmationFinder = mock
+	b, err := New(config, &batchfossilizer.Config{Path: path})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	ec := make(chan *fossilizer.Event, 2)
+	b.AddFossilizerEventChan(ec)
+	b.checkPending()
+
+	if got, want := mock.MockFindConfirmation.LastCalledWith.String(), txID.String(); got != want {
+		t.Errorf("mock.MockFindConfirmation.LastCalledWith = %s want %s", got, want)
+	}
+	if got, want := len(ec), 2; got != want {
+		t.Fatalf("len(ec) = %d want %d", got, want)
+	}
+
+	proof := (<-ec).Data.(*fossilizer.Result).Evidence.Proof.(*evidences.BcBatchProof)
+	if got, want := proof.Batch.Root.String(), root.String(); got != want {
+		t.Errorf("proof.Batch.Root = %s want %s", got, want)
+	}
+
+	c, err := New(config, &batchfossilizer.Config{Path: path})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+	if got, want := len(c.pending), 0; got != want {
+		t.Errorf("len(c.pending) = %d want %d", got, want)
+	}
+}
+
+func TestTrack_ConfirmDoesNotHoldLock(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return confirmation(DefaultConfirmations), nil
+	}
+
+	a, err := New(&Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: mock,
+	}, &batchfossilizer.Config{})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	ec := make(chan *fossilizer.Event)
+	a.AddFossilizerEventChan(ec)
+
+	transformLeaves(t, a, 1)
+
+	done := make(chan struct{})
+	go func() {
+		a.checkPending()
+		close(done)
+	}()
+
+	// Wait for the confirmation to be blocked on the unbuffered channel.
+	for {
+		a.trackMutex.Lock()
+		n := len(a.pending)
+		a.trackMutex.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	added := make(chan struct{})
+	go func() {
+		a.AddFossilizerEventChan(make(chan *fossilizer.Event, 1))
+		close(added)
+	}()
+
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("AddFossilizerEventChan() blocked while an event was being sent")
+	}
+
+	<-ec
+	<-done
+}
+
+func TestTrack_Start(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) {
+		return confirmation(DefaultConfirmations), nil
+	}
+
+	a, err := New(&Config{
+		HashTimestamper:    dummytimestamper.Timestamper{},
+		ConfirmationFinder: mock,
+		TrackInterval:      testInterval,
+	}, &batchfossilizer.Config{
+		Interval: testInterval,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	ec := make(chan *fossilizer.Event, 2)
+	a.AddFossilizerEventChan(ec)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := a.Start(ctx); err != nil && errors.Cause(err) != context.Canceled {
+			t.Errorf("a.Start(): err: %s", err)
+		}
+	}()
+
+	<-a.Started()
+
+	if err := a.Fossilize(context.Background(), atos(*testutil.RandomHash()), []byte("test")); err != nil {
+		t.Fatalf("a.Fossilize(): err: %s", err)
+	}
+
+	got := map[fossilizer.EventType]bool{}
+	for len(got) < 2 {
+		select {
+		case e := <-ec:
+			got[e.EventType] = true
+		case <-time.After(10 * testInterval):
+			t.Fatalf("events = %v want %s and %s", got, fossilizer.DidFossilizeLink, fossilizer.DidConfirmLink)
+		}
+	}
+}
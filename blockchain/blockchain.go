@@ -46,3 +46,26 @@ type HashTimestamper interface {
 	// TimestampHash timestamps a hash on a blockchain.
 	TimestampHash(hash *types.Bytes32) (types.TransactionID, error)
 }
+
+// Rebroadcaster is implemented by hash timestampers able to timestamp a hash
+// again after a transaction was dropped.
+type Rebroadcaster interface {
+	// RetimestampHash timestamps a hash again. Attempt is the number of
+	// previous transactions that were dropped, the fee should increase
+	// with it.
+	RetimestampHash(hash *types.Bytes32, attempt int) (types.TransactionID, error)
+}
+
+// LowBalanceError is returned by a timestamper that refuses to broadcast a
+// transaction because the balance of its wallet is below a threshold.
+type LowBalanceError struct {
+	Network   string `json:"network"`
+	Address   string `json:"address"`
+	Balance   int64  `json:"balance"`
+	Threshold int64  `json:"threshold"`
+}
+
+// Error implements error.
+func (e *LowBalanceError) Error() string {
+	return fmt.Sprintf("balance of %s on %s is %d, below threshold %d", e.Address, e.Network, e.Balance, e.Threshold)
+}
@@ -0,0 +1,357 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bitcoind defines a client for the JSON-RPC API of Bitcoin Core.
+//
+// To find unspent outputs, the address of the fossilizer must be imported in
+// the wallet of the node (for instance as a watch-only address). To find
+// transactions that are not in the wallet, the node must maintain a
+// transaction index (-txindex).
+package bitcoind
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// DefaultURL is the default URL of the JSON-RPC API.
+	DefaultURL = "http://localhost:8332"
+
+	// DefaultTimeout is the default timeout of requests.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultConfTarget is the default number of blocks within which a
+	// transaction should confirm when estimating fees.
+	DefaultConfTarget = 6
+
+	// maxConfirmations is the maximum number of confirmations of unspent
+	// outputs.
+	maxConfirmations = 9999999
+)
+
+const (
+	// ErrCodeInvalidAddressOrKey is the error code returned by the node
+	// when a transaction cannot be found.
+	ErrCodeInvalidAddressOrKey = -5
+)
+
+// Config contains configuration options for the client.
+type Config struct {
+	// Network is the Bitcoin network.
+	Network btc.Network
+
+	// URL is the URL of the JSON-RPC API of the node.
+	URL string
+
+	// User and Password are the credentials of the JSON-RPC API.
+	User     string
+	Password string
+
+	// Timeout is the timeout of requests.
+	Timeout time.Duration
+
+	// ConfTarget is the number of blocks within which a transaction should
+	// confirm when estimating fees.
+	ConfTarget int
+}
+
+// Error is an error returned by the node.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error.Error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("bitcoind error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Client is a JSON-RPC client for a Bitcoin Core node.
+//
+// It implements btc.UnspentFinder, btc.Broadcaster, btc.TransactionFinder,
+// btc.ConfirmationFinder and btc.FeeEstimator.
+type Client struct {
+	config *Config
+	http   *http.Client
+	id     uint64
+}
+
+// New creates a client for the JSON-RPC API of a Bitcoin Core node.
+func New(config *Config) *Client {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		config: config,
+		http:   &http.Client{Timeout: timeout},
+	}
+}
+
+// call calls a JSON-RPC method and decodes its result.
+func (c *Client) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	req := request{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Method:  method,
+		Params:  params,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.config.User != "" || c.config.Password != "" {
+		httpReq.SetBasicAuth(c.config.User, c.config.Password)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	// The node answers RPC errors with an error status and a JSON body.
+	var res response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("%s: node returned status %d", method, resp.StatusCode)
+		}
+		return errors.Wrap(err, method)
+	}
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s: node returned status %d", method, resp.StatusCode)
+	}
+	if res.ID != req.ID {
+		return errors.Errorf("%s: unexpected response ID %d", method, res.ID)
+	}
+
+	return errors.Wrap(json.Unmarshal(res.Result, result), method)
+}
+
+// unspent is an output returned by listunspent.
+type unspent struct {
+	TXID         string  `json:"txid"`
+	Vout         int     `json:"vout"`
+	ScriptPubKey string  `json:"scriptPubKey"`
+	Amount       float64 `json:"amount"`
+}
+
+// FindUnspent implements
+// github.com/stratumn/go-indigocore/blockchain/btc.UnspentFinder.FindUnspent.
+func (c *Client) FindUnspent(address *types.ReversedBytes20, amount int64) ([]btc.Output, int64, error) {
+	addr := base58.CheckEncode(address[:], c.config.Network.ID())
+
+	var unspents []unspent
+	if err := c.call("listunspent", &unspents, 0, maxConfirmations, []string{addr}); err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		outputs []btc.Output
+		total   int64
+	)
+
+	for _, u := range unspents {
+		value, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+
+		output := btc.Output{Index: u.Vout, Value: int64(value)}
+		if err := output.TXHash.Unstring(u.TXID); err != nil {
+			return nil, 0, err
+		}
+		if output.PKScript, err = hex.DecodeString(u.ScriptPubKey); err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+
+		outputs = append(outputs, output)
+		total += output.Value
+	}
+
+	if total < amount {
+		return nil, 0, fmt.Errorf("Not enough Bitcoins available on %s, expected at least %d satoshis got %d", addr, amount, total)
+	}
+
+	return outputs, total, nil
+}
+
+// Broadcast implements
+// github.com/stratumn/go-indigocore/blockchain/btc.Broadcaster.Broadcast.
+func (c *Client) Broadcast(raw []byte) error {
+	var txID string
+	return c.call("sendrawtransaction", &txID, hex.EncodeToString(raw))
+}
+
+// FindTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/btc.TransactionFinder.FindTransaction.
+func (c *Client) FindTransaction(txID types.TransactionID) ([]byte, error) {
+	var raw string
+	if err := c.call("getrawtransaction", &raw, txID.String()); err != nil {
+		return nil, notFound(err)
+	}
+
+	b, err := hex.DecodeString(raw)
+	return b, errors.WithStack(err)
+}
+
+// FindConfirmation implements
+// github.com/stratumn/go-indigocore/blockchain/btc.ConfirmationFinder.FindConfirmation.
+func (c *Client) FindConfirmation(txID types.TransactionID) (*btc.Confirmation, error) {
+	var tx struct {
+		BlockHash     string `json:"blockhash"`
+		Confirmations int64  `json:"confirmations"`
+	}
+	if err := c.call("getrawtransaction", &tx, txID.String(), true); err != nil {
+		return nil, notFound(err)
+	}
+
+	// Unconfirmed transactions have no block.
+	if tx.BlockHash == "" {
+		return nil, nil
+	}
+
+	var block struct {
+		Height int64    `json:"height"`
+		TX     []string `json:"tx"`
+	}
+	if err := c.call("getblock", &block, tx.BlockHash, 1); err != nil {
+		return nil, err
+	}
+
+	index := -1
+	txIDs := make([]types.TransactionID, len(block.TX))
+	for i, id := range block.TX {
+		b, err := hex.DecodeString(id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		txIDs[i] = b
+		if bytes.Equal(b, txID) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("Transaction %s not found in block %s", txID, tx.BlockHash)
+	}
+
+	branch, err := btc.MerkleBranch(txIDs, index)
+	if err != nil {
+		return nil, err
+	}
+
+	var header string
+	if err := c.call("getblockheader", &header, tx.BlockHash, false); err != nil {
+		return nil, err
+	}
+
+	confirmation := &btc.Confirmation{
+		BlockHeight:   block.Height,
+		Confirmations: tx.Confirmations,
+		Index:         index,
+		Branch:        branch,
+	}
+	if confirmation.Header, err = hex.DecodeString(header); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := confirmation.BlockHash.Unstring(tx.BlockHash); err != nil {
+		return nil, err
+	}
+
+	// Do not trust the node, make sure the data is consistent.
+	if err := btc.VerifyConfirmation(txID, confirmation); err != nil {
+		return nil, err
+	}
+
+	return confirmation, nil
+}
+
+// EstimateFeeRate implements
+// github.com/stratumn/go-indigocore/blockchain/btc.FeeEstimator.EstimateFeeRate.
+func (c *Client) EstimateFeeRate() (int64, error) {
+	target := c.config.ConfTarget
+	if target == 0 {
+		target = DefaultConfTarget
+	}
+
+	var estimate struct {
+		FeeRate *float64 `json:"feerate"`
+		Errors  []string `json:"errors"`
+	}
+	if err := c.call("estimatesmartfee", &estimate, target); err != nil {
+		return 0, err
+	}
+
+	if estimate.FeeRate == nil {
+		return 0, errors.Errorf("could not estimate fee rate: %v", estimate.Errors)
+	}
+
+	// The fee rate is in bitcoins per kilobyte.
+	rate, err := btcutil.NewAmount(*estimate.FeeRate)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return int64(rate), nil
+}
+
+// notFound converts errors about unknown transactions to
+// btc.ErrTransactionNotFound.
+func notFound(err error) error {
+	if e, ok := err.(*Error); ok && e.Code == ErrCodeInvalidAddressOrKey {
+		return btc.ErrTransactionNotFound
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitcoind
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUser     = "alice"
+	testPassword = "secret"
+)
+
+// Transactions of block 100000 of the main Bitcoin network.
+var block100000TxIDs = []string{
+	"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+	"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+	"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+}
+
+const (
+	block100000Hash   = "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506"
+	block100000Header = "0100000050120119172a610421a6c3011dd330d9df07b63616c2cc1f1cd00200000000006657a9252aacd5c0b2940996ecff952228c3067cc38d4885efb5a4ac4247e9f337221b4d4c86041b0f2b5710"
+)
+
+// createNode creates a fake node that answers the given results by method.
+func createNode(t *testing.T, results map[string]interface{}) (*httptest.Server, *[]request) {
+	var requests []request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != testUser || password != testPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req), "json.Decode()")
+		requests = append(requests, req)
+
+		res := map[string]interface{}{"id": req.ID, "result": nil, "error": nil}
+		switch result := results[req.Method].(type) {
+		case nil:
+			w.WriteHeader(http.StatusNotFound)
+			res["error"] = Error{Code: -32601, Message: "Method not found"}
+		case *Error:
+			w.WriteHeader(http.StatusInternalServerError)
+			res["error"] = result
+		default:
+			res["result"] = result
+		}
+
+		assert.NoError(t, json.NewEncoder(w).Encode(res), "json.Encode()")
+	}))
+
+	return server, &requests
+}
+
+func createClient(url string) *Client {
+	return New(&Config{
+		Network:  btc.NetworkTest3,
+		URL:      url,
+		User:     testUser,
+		Password: testPassword,
+	})
+}
+
+func TestFindUnspent(t *testing.T) {
+	address := types.ReversedBytes20{0x42}
+	server, requests := createNode(t, map[string]interface{}{
+		"listunspent": []unspent{{
+			TXID:         "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+			Vout:         1,
+			ScriptPubKey: "76a914" + hex.EncodeToString(address[:]) + "88ac",
+			Amount:       0.0624,
+		}, {
+			TXID:         "e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+			Vout:         0,
+			ScriptPubKey: "76a914" + hex.EncodeToString(address[:]) + "88ac",
+			Amount:       0.001,
+		}},
+	})
+	defer server.Close()
+
+	outputs, total, err := createClient(server.URL).FindUnspent(&address, 1000000)
+	require.NoError(t, err, "FindUnspent()")
+	assert.Equal(t, int64(6340000), total)
+	require.Len(t, outputs, 2)
+	assert.Equal(t, "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4", outputs[0].TXHash.String())
+	assert.Equal(t, 1, outputs[0].Index)
+	assert.Equal(t, int64(6240000), outputs[0].Value)
+	assert.Equal(t, byte(0x76), outputs[0].PKScript[0])
+	assert.Equal(t, int64(100000), outputs[1].Value)
+
+	require.Len(t, *requests, 1)
+	addr := base58.CheckEncode(address[:], btc.NetworkTest3.ID())
+	assert.Equal(t, []interface{}{0.0, float64(maxConfirmations), []interface{}{addr}}, (*requests)[0].Params)
+}
+
+func TestFindUnspent_notEnough(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"listunspent": []unspent{{
+			TXID:         "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+			ScriptPubKey: "76a914000000000000000000000000000000000000000088ac",
+			Amount:       0.001,
+		}},
+	})
+	defer server.Close()
+
+	address := types.ReversedBytes20{0x42}
+	_, _, err := createClient(server.URL).FindUnspent(&address, 1000000)
+	assert.Error(t, err, "FindUnspent()")
+}
+
+func TestBroadcast(t *testing.T) {
+	server, requests := createNode(t, map[string]interface{}{
+		"sendrawtransaction": "6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	})
+	defer server.Close()
+
+	err := createClient(server.URL).Broadcast([]byte{0x01, 0x00})
+	require.NoError(t, err, "Broadcast()")
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, []interface{}{"0100"}, (*requests)[0].Params)
+}
+
+func TestBroadcast_rejected(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"sendrawtransaction": &Error{Code: -26, Message: "insufficient fee"},
+	})
+	defer server.Close()
+
+	err := createClient(server.URL).Broadcast([]byte{0x01, 0x00})
+	require.Error(t, err, "Broadcast()")
+	assert.Equal(t, -26, err.(*Error).Code)
+}
+
+func TestCall_unauthorized(t *testing.T) {
+	server, _ := createNode(t, nil)
+	defer server.Close()
+
+	client := New(&Config{URL: server.URL, User: testUser, Password: "wrong"})
+	err := client.Broadcast([]byte{0x01, 0x00})
+	assert.EqualError(t, err, "sendrawtransaction: node returned status 401")
+}
+
+func TestFindTransaction(t *testing.T) {
+	server, requests := createNode(t, map[string]interface{}{
+		"getrawtransaction": "0100",
+	})
+	defer server.Close()
+
+	txID, _ := hex.DecodeString(block100000TxIDs[2])
+	raw, err := createClient(server.URL).FindTransaction(txID)
+	require.NoError(t, err, "FindTransaction()")
+	assert.Equal(t, []byte{0x01, 0x00}, raw)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, []interface{}{block100000TxIDs[2]}, (*requests)[0].Params)
+}
+
+func TestFindTransaction_notFound(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"getrawtransaction": &Error{Code: -5, Message: "No such mempool or blockchain transaction"},
+	})
+	defer server.Close()
+
+	txID, _ := hex.DecodeString(block100000TxIDs[2])
+	_, err := createClient(server.URL).FindTransaction(txID)
+	assert.Equal(t, btc.ErrTransactionNotFound, err)
+}
+
+func TestFindConfirmation(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"getrawtransaction": map[string]interface{}{
+			"blockhash":     block100000Hash,
+			"confirmations": 6,
+		},
+		"getblock": map[string]interface{}{
+			"height": 100000,
+			"tx":     block100000TxIDs,
+		},
+		"getblockheader": block100000Header,
+	})
+	defer server.Close()
+
+	txID, _ := hex.DecodeString(block100000TxIDs[2])
+	c, err := createClient(server.URL).FindConfirmation(txID)
+	require.NoError(t, err, "FindConfirmation()")
+	require.NotNil(t, c)
+	assert.Equal(t, block100000Hash, c.BlockHash.String())
+	assert.Equal(t, int64(100000), c.BlockHeight)
+	assert.Equal(t, int64(6), c.Confirmations)
+	assert.Equal(t, 2, c.Index)
+	assert.Len(t, c.Branch, 2)
+}
+
+func TestFindConfirmation_pending(t *testing.T) {
+	server, requests := createNode(t, map[string]interface{}{
+		"getrawtransaction": map[string]interface{}{},
+	})
+	defer server.Close()
+
+	txID, _ := hex.DecodeString(block100000TxIDs[2])
+	c, err := createClient(server.URL).FindConfirmation(txID)
+	require.NoError(t, err, "FindConfirmation()")
+	assert.Nil(t, c)
+	assert.Len(t, *requests, 1)
+}
+
+func TestFindConfirmation_badHeader(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"getrawtransaction": map[string]interface{}{
+			"blockhash":     block100000Hash,
+			"confirmations": 6,
+		},
+		"getblock": map[string]interface{}{
+			"height": 100000,
+			"tx":     block100000TxIDs[:3],
+		},
+		"getblockheader": block100000Header,
+	})
+	defer server.Close()
+
+	txID, _ := hex.DecodeString(block100000TxIDs[2])
+	_, err := createClient(server.URL).FindConfirmation(txID)
+	assert.Error(t, err, "FindConfirmation()")
+}
+
+func TestEstimateFeeRate(t *testing.T) {
+	server, requests := createNode(t, map[string]interface{}{
+		"estimatesmartfee": map[string]interface{}{"feerate": 0.0002, "blocks": 6},
+	})
+	defer server.Close()
+
+	rate, err := createClient(server.URL).EstimateFeeRate()
+	require.NoError(t, err, "EstimateFeeRate()")
+	assert.Equal(t, int64(20000), rate)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, []interface{}{float64(DefaultConfTarget)}, (*requests)[0].Params)
+}
+
+func TestEstimateFeeRate_insufficientData(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"estimatesmartfee": map[string]interface{}{"errors": []string{"Insufficient data or no feerate found"}, "blocks": 0},
+	})
+	defer server.Close()
+
+	_, err := createClient(server.URL).EstimateFeeRate()
+	assert.Error(t, err, "EstimateFeeRate()")
+}
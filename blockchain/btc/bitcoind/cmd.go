@@ -0,0 +1,70 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitcoind
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	url        string
+	user       string
+	password   string
+	timeout    time.Duration
+	confTarget int
+)
+
+// RegisterFlags registers the flags used by InitializeWithFlags.
+func RegisterFlags() {
+	flag.StringVar(&url, "bitcoind", os.Getenv("BITCOIND_URL"), "URL of the JSON-RPC API of a Bitcoin Core node (BlockCypher is used if empty)")
+	flag.StringVar(&user, "bitcoinduser", os.Getenv("BITCOIND_USER"), "user of the JSON-RPC API of the Bitcoin Core node")
+	flag.StringVar(&password, "bitcoindpassword", os.Getenv("BITCOIND_PASSWORD"), "password of the JSON-RPC API of the Bitcoin Core node")
+	flag.DurationVar(&timeout, "bitcoindtimeout", DefaultTimeout, "timeout of requests to the Bitcoin Core node")
+	flag.IntVar(&confTarget, "conftarget", DefaultConfTarget, "number of blocks within which a transaction should confirm when estimating fees")
+}
+
+// Enabled returns true if a node was configured using flags.
+func Enabled() bool {
+	return url != ""
+}
+
+// InitializeWithFlags should be called after RegisterFlags and flag.Parse to
+// initialize a bitcoind client using flag values.
+func InitializeWithFlags(key string) *Client {
+	if key == "" {
+		log.Fatal("A WIF encoded private key is required")
+	}
+
+	network, err := btc.GetNetworkFromWIF(key)
+	if err != nil {
+		log.WithField("error", err).Fatal()
+	}
+
+	return New(&Config{
+		Network:    network,
+		URL:        utils.OrStrings(url, DefaultURL),
+		User:       user,
+		Password:   password,
+		Timeout:    timeout,
+		ConfTarget: confTarget,
+	})
+}
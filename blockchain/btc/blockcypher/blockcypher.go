@@ -16,14 +16,18 @@
 package blockcypher
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/blockcypher/gobcy"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/stratumn/go-indigocore/blockchain/btc"
 	"github.com/stratumn/go-indigocore/types"
@@ -36,6 +40,10 @@ const (
 
 	// DefaultLimiterSize is the default BlockCypher API limiter size.
 	DefaultLimiterSize = 2
+
+	// blockTXsLimit is the maximum number of transaction IDs returned per
+	// block request.
+	blockTXsLimit = 500
 )
 
 // Config contains configuration options for the client.
@@ -98,14 +106,14 @@ func (c *Client) FindUnspent(address *types.ReversedBytes20, amount int64) ([]bt
 		return nil, 0, err
 	}
 
-	var (
-		outputs []btc.Output
-		total   int64
-	)
+	// Only the first outputs are listed, so the total is the confirmed
+	// balance of the address instead of the sum of the outputs.
+	var outputs []btc.Output
+	total := int64(addrInfo.Balance)
 
-TX_LOOP:
+	// Return all the outputs so that the caller can consolidate them.
 	for _, TXRef := range addrInfo.TXRefs {
-		output := btc.Output{Index: TXRef.TXOutputN}
+		output := btc.Output{Index: TXRef.TXOutputN, Value: int64(TXRef.Value)}
 		if err := output.TXHash.Unstring(TXRef.TXHash); err != nil {
 			return nil, 0, err
 		}
@@ -116,11 +124,6 @@ TX_LOOP:
 		}
 
 		outputs = append(outputs, output)
-
-		total += int64(TXRef.Value)
-		if total >= amount {
-			break TX_LOOP
-		}
 	}
 
 	if total < amount {
@@ -143,6 +146,182 @@ func (c *Client) Broadcast(raw []byte) error {
 	return err
 }
 
+// FindTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/btc.TransactionFinder.FindTransaction.
+func (c *Client) FindTransaction(txID types.TransactionID) ([]byte, error) {
+	for range c.limiter {
+		break
+	}
+	c.waitGroup.Add(1)
+	defer c.waitGroup.Done()
+
+	tx, err := c.api.GetTX(txID.String(), map[string]string{"includeHex": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(tx.Hex)
+}
+
+// EstimateFeeRate implements
+// github.com/stratumn/go-indigocore/blockchain/btc.FeeEstimator.EstimateFeeRate.
+func (c *Client) EstimateFeeRate() (int64, error) {
+	for range c.limiter {
+		break
+	}
+	c.waitGroup.Add(1)
+	defer c.waitGroup.Done()
+
+	chain, err := c.api.GetChain()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(chain.MediumFee), nil
+}
+
+// FindConfirmation implements
+// github.com/stratumn/go-indigocore/blockchain/btc.ConfirmationFinder.FindConfirmation.
+func (c *Client) FindConfirmation(txID types.TransactionID) (*btc.Confirmation, error) {
+	tx, err := c.getTX(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unconfirmed transactions have no block.
+	if tx.BlockHash == "" || tx.BlockHeight < 0 {
+		return nil, nil
+	}
+
+	block, txIDs, err := c.getBlock(tx.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	for i, id := range txIDs {
+		if bytes.Equal(id, txID) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("Transaction %s not found in block %s", txID, tx.BlockHash)
+	}
+
+	branch, err := btc.MerkleBranch(txIDs, index)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := blockHeader(&block)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := &btc.Confirmation{
+		BlockHeight:   int64(block.Height),
+		Confirmations: int64(tx.Confirmations),
+		Header:        header,
+		Index:         index,
+		Branch:        branch,
+	}
+	if err := confirmation.BlockHash.Unstring(block.Hash); err != nil {
+		return nil, err
+	}
+
+	// Do not trust the API, make sure the data is consistent.
+	if err := btc.VerifyConfirmation(txID, confirmation); err != nil {
+		return nil, err
+	}
+
+	return confirmation, nil
+}
+
+func (c *Client) getTX(txID types.TransactionID) (*gobcy.TX, error) {
+	for range c.limiter {
+		break
+	}
+	c.waitGroup.Add(1)
+	defer c.waitGroup.Done()
+
+	tx, err := c.api.GetTX(txID.String(), nil)
+	if err != nil {
+		// The API doesn't distinguish errors, so we have to look at the
+		// message.
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return nil, btc.ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// getBlock returns a block and the IDs of all its transactions, which may
+// require several requests.
+func (c *Client) getBlock(hash string) (gobcy.Block, []types.TransactionID, error) {
+	var (
+		block gobcy.Block
+		txIDs []types.TransactionID
+	)
+
+	for {
+		page, err := c.getBlockPage(hash, len(txIDs))
+		if err != nil {
+			return block, nil, err
+		}
+		block = page
+
+		for _, id := range page.TXids {
+			txID, err := hex.DecodeString(id)
+			if err != nil {
+				return block, nil, err
+			}
+			txIDs = append(txIDs, txID)
+		}
+
+		if len(page.TXids) == 0 || len(txIDs) >= page.NumTX {
+			return block, txIDs, nil
+		}
+	}
+}
+
+func (c *Client) getBlockPage(hash string, start int) (gobcy.Block, error) {
+	for range c.limiter {
+		break
+	}
+	c.waitGroup.Add(1)
+	defer c.waitGroup.Done()
+
+	return c.api.GetBlock(0, hash, map[string]string{
+		"txstart": strconv.Itoa(start),
+		"limit":   strconv.Itoa(blockTXsLimit),
+	})
+}
+
+// blockHeader serializes the header of a block.
+func blockHeader(block *gobcy.Block) ([]byte, error) {
+	prevBlock, err := chainhash.NewHashFromStr(block.PrevBlock)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, err := chainhash.NewHashFromStr(block.MerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	h := wire.NewBlockHeader(int32(block.Ver), prevBlock, merkleRoot, uint32(block.Bits), uint32(block.Nonce))
+	h.Timestamp = block.Time
+
+	buf := bytes.NewBuffer(nil)
+	if err := h.Serialize(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 // Start starts the client.
 func (c *Client) Start(ctx context.Context) {
 	size := c.config.LimiterSize
@@ -17,7 +17,9 @@ package blockcypher
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/blockcypher/gobcy"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/stratumn/go-indigocore/blockchain/btc"
@@ -81,3 +83,26 @@ func TestFindUnspent_notEnough(t *testing.T) {
 		t.Errorf("bcy.FindUnspent(): err = nil want Error")
 	}
 }
+
+func TestBlockHeader(t *testing.T) {
+	// Block 100000 of the main Bitcoin network.
+	header, err := blockHeader(&gobcy.Block{
+		Ver:        1,
+		PrevBlock:  "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+		MerkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		Time:       time.Unix(1293623863, 0),
+		Bits:       0x1b04864c,
+		Nonce:      274148111,
+	})
+	if err != nil {
+		t.Fatalf("blockHeader(): err: %s", err)
+	}
+
+	hash, err := btc.BlockHash(header)
+	if err != nil {
+		t.Fatalf("btc.BlockHash(): err: %s", err)
+	}
+	if got, want := hash.String(), "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506"; got != want {
+		t.Errorf("btc.BlockHash() = %s want %s", got, want)
+	}
+}
@@ -16,7 +16,11 @@
 package btc
 
 import (
+	"bytes"
+
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/pkg/errors"
 	"github.com/stratumn/go-indigocore/types"
@@ -39,6 +43,9 @@ var (
 
 	// ErrBadWIF is returned when the WIF encoded private key could not be decoded
 	ErrBadWIF = errors.New("Failed to decode WIF encoded private key")
+
+	// ErrTransactionNotFound is returned when a transaction could not be found.
+	ErrTransactionNotFound = errors.New("transaction not found")
 )
 
 // GetNetworkFromWIF returns the network ID associated to a bitcoin wallet.
@@ -81,13 +88,18 @@ type Output struct {
 	TXHash   types.ReversedBytes32
 	PKScript []byte
 	Index    int
+
+	// Value is the amount of the output in satoshis.
+	Value int64
 }
 
 // UnspentFinder is find unspent outputs.
 type UnspentFinder interface {
 	// FindUnspent find unspent outputs for the given address and the
 	// required amount. It returns the outputs and the total amount of the
-	// outputs.
+	// unspent outputs of the address, which is its balance even if not all
+	// the outputs are returned. It may return more outputs than needed so
+	// that the caller can select which ones to spend.
 	FindUnspent(address *types.ReversedBytes20, amount int64) (outputs []Output, total int64, err error)
 }
 
@@ -96,3 +108,63 @@ type Broadcaster interface {
 	// Broadcast broadcasts a raw transaction.
 	Broadcast(raw []byte) error
 }
+
+// TransactionFinder is able to find raw Bitcoin transactions.
+type TransactionFinder interface {
+	// FindTransaction returns the raw transaction with the given ID.
+	// It returns ErrTransactionNotFound if the transaction doesn't exist.
+	FindTransaction(txID types.TransactionID) ([]byte, error)
+}
+
+// TransactionID computes the ID of a raw transaction.
+// As is customary for Bitcoin, the bytes of the hash are reversed.
+func TransactionID(raw []byte) (types.TransactionID, error) {
+	tx, err := decodeTx(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return reverseHash(tx.TxHash()), nil
+}
+
+// NullData decodes a raw transaction and returns the data pushed by its
+// OP_RETURN outputs.
+func NullData(raw []byte) ([][]byte, error) {
+	tx, err := decodeTx(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var data [][]byte
+	for _, out := range tx.TxOut {
+		if txscript.GetScriptClass(out.PkScript) != txscript.NullDataTy {
+			continue
+		}
+
+		pushes, err := txscript.PushedData(out.PkScript)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		data = append(data, pushes...)
+	}
+
+	return data, nil
+}
+
+func decodeTx(raw []byte) (*wire.MsgTx, error) {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, errors.Wrap(err, "could not decode transaction")
+	}
+
+	return &tx, nil
+}
+
+func reverseHash(hash [32]byte) types.TransactionID {
+	txID := make(types.TransactionID, len(hash))
+	for i, b := range hash {
+		txID[len(hash)-i-1] = b
+	}
+
+	return txID
+}
@@ -20,7 +20,8 @@ import (
 	"github.com/stratumn/go-indigocore/types"
 )
 
-// Mock is used to mock a UnspentFinder and Broadcaster.
+// Mock is used to mock a UnspentFinder, Broadcaster, TransactionFinder and
+// ConfirmationFinder.
 //
 // It implements github.com/stratumn/go-indigocore/fossilizer.Adapter.
 type Mock struct {
@@ -29,6 +30,12 @@ type Mock struct {
 
 	// The mock for the Broadcast function.
 	MockBroadcast MockBroadcast
+
+	// The mock for the FindTransaction function.
+	MockFindTransaction MockFindTransaction
+
+	// The mock for the FindConfirmation function.
+	MockFindConfirmation MockFindConfirmation
 }
 
 // MockFindUnspent mocks the FindUnspent function.
@@ -67,6 +74,36 @@ type MockBroadcast struct {
 	Fn func([]byte) error
 }
 
+// MockFindTransaction mocks the FindTransaction function.
+type MockFindTransaction struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// The transaction ID that was passed to each call.
+	CalledWith []types.TransactionID
+
+	// The last transaction ID that was passed.
+	LastCalledWith types.TransactionID
+
+	// An optional implementation of the function.
+	Fn func(types.TransactionID) ([]byte, error)
+}
+
+// MockFindConfirmation mocks the FindConfirmation function.
+type MockFindConfirmation struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// The transaction ID that was passed to each call.
+	CalledWith []types.TransactionID
+
+	// The last transaction ID that was passed.
+	LastCalledWith types.TransactionID
+
+	// An optional implementation of the function.
+	Fn func(types.TransactionID) (*btc.Confirmation, error)
+}
+
 // FindUnspent implements
 // github.com/stratumn/go-indigocore/blockchain/btc.UnspentFinder.FindUnspent.
 func (a *Mock) FindUnspent(address *types.ReversedBytes20, amount int64) ([]btc.Output, int64, error) {
@@ -96,3 +133,32 @@ func (a *Mock) Broadcast(raw []byte) error {
 
 	return nil
 }
+
+// FindTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/btc.TransactionFinder.FindTransaction.
+func (a *Mock) FindTransaction(txID types.TransactionID) ([]byte, error) {
+	a.MockFindTransaction.CalledCount++
+	a.MockFindTransaction.CalledWith = append(a.MockFindTransaction.CalledWith, txID)
+	a.MockFindTransaction.LastCalledWith = txID
+
+	if a.MockFindTransaction.Fn != nil {
+		return a.MockFindTransaction.Fn(txID)
+	}
+
+	return nil, btc.ErrTransactionNotFound
+}
+
+// FindConfirmation implements
+// github.com/stratumn/go-indigocore/blockchain/btc.ConfirmationFinder.FindConfirmation.
+// By default the transaction is never confirmed.
+func (a *Mock) FindConfirmation(txID types.TransactionID) (*btc.Confirmation, error) {
+	a.MockFindConfirmation.CalledCount++
+	a.MockFindConfirmation.CalledWith = append(a.MockFindConfirmation.CalledWith, txID)
+	a.MockFindConfirmation.LastCalledWith = txID
+
+	if a.MockFindConfirmation.Fn != nil {
+		return a.MockFindConfirmation.Fn(txID)
+	}
+
+	return nil, nil
+}
@@ -85,3 +85,65 @@ func TestMockBroadcast(t *testing.T) {
 		t.Errorf(`a.MockBroadcast.LastCalledWith = %q want %q`, got, want)
 	}
 }
+
+func TestMockFindTransaction(t *testing.T) {
+	a := &Mock{}
+
+	txID1 := types.TransactionID(testutil.RandomHash()[:])
+	if _, err := a.FindTransaction(txID1); err != btc.ErrTransactionNotFound {
+		t.Errorf("a.FindTransaction(): err = %v want %v", err, btc.ErrTransactionNotFound)
+	}
+
+	raw := testutil.RandomHash()[:]
+	a.MockFindTransaction.Fn = func(types.TransactionID) ([]byte, error) { return raw, nil }
+
+	txID2 := types.TransactionID(testutil.RandomHash()[:])
+	got, err := a.FindTransaction(txID2)
+	if err != nil {
+		t.Errorf("a.FindTransaction(): err: %s", err)
+	}
+	if want := raw; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.FindTransaction() = %x want %x`, got, want)
+	}
+
+	if got, want := a.MockFindTransaction.CalledCount, 2; got != want {
+		t.Errorf(`a.MockFindTransaction.CalledCount = %d want %d`, got, want)
+	}
+	if got, want := a.MockFindTransaction.CalledWith, []types.TransactionID{txID1, txID2}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindTransaction.CalledWith = %q want %q`, got, want)
+	}
+	if got, want := a.MockFindTransaction.LastCalledWith, txID2; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindTransaction.LastCalledWith = %q want %q`, got, want)
+	}
+}
+
+func TestMockFindConfirmation(t *testing.T) {
+	a := &Mock{}
+
+	txID1 := types.TransactionID(testutil.RandomHash()[:])
+	if c, err := a.FindConfirmation(txID1); err != nil || c != nil {
+		t.Errorf("a.FindConfirmation(): c = %v, err = %v want nil, nil", c, err)
+	}
+
+	confirmation := &btc.Confirmation{BlockHeight: 42, Confirmations: 6}
+	a.MockFindConfirmation.Fn = func(types.TransactionID) (*btc.Confirmation, error) { return confirmation, nil }
+
+	txID2 := types.TransactionID(testutil.RandomHash()[:])
+	got, err := a.FindConfirmation(txID2)
+	if err != nil {
+		t.Errorf("a.FindConfirmation(): err: %s", err)
+	}
+	if want := confirmation; got != want {
+		t.Errorf(`a.FindConfirmation() = %v want %v`, got, want)
+	}
+
+	if got, want := a.MockFindConfirmation.CalledCount, 2; got != want {
+		t.Errorf(`a.MockFindConfirmation.CalledCount = %d want %d`, got, want)
+	}
+	if got, want := a.MockFindConfirmation.CalledWith, []types.TransactionID{txID1, txID2}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindConfirmation.CalledWith = %q want %q`, got, want)
+	}
+	if got, want := a.MockFindConfirmation.LastCalledWith, txID2; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindConfirmation.LastCalledWith = %q want %q`, got, want)
+	}
+}
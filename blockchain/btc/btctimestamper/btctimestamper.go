@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -38,6 +39,35 @@ const (
 
 	// Description describes this Timestamper
 	Description = "Bitcoin Timestamper"
+
+	// RebroadcastFeeIncrease is the percentage by which the fee increases
+	// each time a hash is timestamped again.
+	RebroadcastFeeIncrease = 50
+
+	// DefaultMaxFee is the default maximum fee when fees are estimated.
+	DefaultMaxFee = int64(100000)
+
+	// DefaultMaxInputs is the default maximum number of outputs spent by a
+	// transaction.
+	DefaultMaxInputs = 10
+
+	// DustLimit is the value under which the change of a transaction is
+	// added to the fee instead of creating an output.
+	DustLimit = int64(546)
+
+	// Sizes used to estimate the size of a transaction in bytes. Inputs
+	// spend outputs paying to an uncompressed public key hash.
+	txOverheadSize = 10
+	txInputSize    = 180
+	txOutputsSize  = 34 + 43
+
+	// rbfSequence is the sequence number of inputs that signals that the
+	// transaction can be replaced (BIP 125).
+	rbfSequence = wire.MaxTxInSequenceNum - 2
+
+	// maxReplaceable is the number of transactions remembered to be
+	// replaced when a hash is timestamped again.
+	maxReplaceable = 100
 )
 
 // Config contains configuration options for the timestamper.
@@ -51,8 +81,29 @@ type Config struct {
 	// A wallet import format key.
 	WIF string
 
-	// Transaction fee
+	// Transaction fee, used if there is no fee estimator.
 	Fee int64
+
+	// An optional fee estimator. If set, the fee depends on the size of the
+	// transaction.
+	FeeEstimator btc.FeeEstimator
+
+	// The maximum fee when fees are estimated, zero means no limit.
+	MaxFee int64
+
+	// The maximum number of outputs spent by a transaction, zero means no
+	// limit.
+	MaxInputs int
+
+	// The balance under which transactions are not broadcast, zero
+	// disables the check.
+	MinBalance int64
+}
+
+// spending contains the outputs spent by a transaction.
+type spending struct {
+	outputs []btc.Output
+	total   int64
 }
 
 // Timestamper is the type that implements
@@ -64,6 +115,10 @@ type Timestamper struct {
 	privKey   *btcec.PrivateKey
 	pubKey    *btcec.PublicKey
 	address   *btcutil.AddressPubKeyHash
+
+	mutex     sync.Mutex
+	spendings map[types.Bytes32]*spending
+	hashes    []types.Bytes32
 }
 
 // New creates an instance of a Timestamper.
@@ -74,9 +129,10 @@ func New(config *Config) (*Timestamper, error) {
 	}
 
 	ts := &Timestamper{
-		config:  config,
-		privKey: WIF.PrivKey,
-		pubKey:  WIF.PrivKey.PubKey(),
+		config:    config,
+		privKey:   WIF.PrivKey,
+		pubKey:    WIF.PrivKey.PubKey(),
+		spendings: make(map[types.Bytes32]*spending),
 	}
 
 	if WIF.IsForNet(&chaincfg.TestNet3Params) {
@@ -116,27 +172,61 @@ func (ts *Timestamper) GetInfo() *blockchain.Info {
 
 // TimestampHash implements
 // github.com/stratumn/go-indigocore/blockchain.HashTimestamper.
+//
+// It returns a *blockchain.LowBalanceError if the balance of the wallet is
+// below the configured threshold.
 func (ts *Timestamper) TimestampHash(hash *types.Bytes32) (types.TransactionID, error) {
-	var prevPKScripts [][]byte
+	return ts.timestampHash(hash, 0)
+}
 
-	addr := (*types.ReversedBytes20)(ts.address.Hash160())
-	outputs, total, err := ts.config.UnspentFinder.FindUnspent(addr, ts.config.Fee)
+// RetimestampHash implements
+// github.com/stratumn/go-indigocore/blockchain.Rebroadcaster.
+//
+// If the previous transaction of the hash is known, the new transaction
+// spends the same outputs so that it replaces it.
+func (ts *Timestamper) RetimestampHash(hash *types.Bytes32, attempt int) (types.TransactionID, error) {
+	return ts.timestampHash(hash, attempt)
+}
+
+func (ts *Timestamper) timestampHash(hash *types.Bytes32, attempt int) (types.TransactionID, error) {
+	fee, err := ts.feeFunc(attempt)
 	if err != nil {
 		return nil, err
 	}
 
+	var spent *spending
+	if attempt > 0 {
+		spent = ts.replaceable(hash)
+	}
+	if spent == nil {
+		if spent, err = ts.selectOutputs(fee); err != nil {
+			return nil, err
+		}
+	}
+
+	change := spent.total - fee(len(spent.outputs))
+	if change < 0 {
+		return nil, btc.ErrInsufficientFunds
+	}
+
+	var prevPKScripts [][]byte
+
 	tx := wire.NewMsgTx(wire.TxVersion)
-	for _, output := range outputs {
+	for _, output := range spent.outputs {
 		prevPKScripts = append(prevPKScripts, output.PKScript)
 		out := wire.NewOutPoint((*chainhash.Hash)(&output.TXHash), uint32(output.Index))
-		tx.AddTxIn(wire.NewTxIn(out, nil, nil))
+		in := wire.NewTxIn(out, nil, nil)
+		in.Sequence = rbfSequence
+		tx.AddTxIn(in)
 	}
 
-	payToAddrOut, err := ts.createPayToAddrTxOut(total - ts.config.Fee)
-	if err != nil {
-		return nil, err
+	if change >= DustLimit {
+		payToAddrOut, err := ts.createPayToAddrTxOut(change)
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(payToAddrOut)
 	}
-	tx.AddTxOut(payToAddrOut)
 
 	nullDataOut, err := ts.createNullDataTxOut(hash)
 	if err != nil {
@@ -164,6 +254,8 @@ func (ts *Timestamper) TimestampHash(hash *types.Bytes32) (types.TransactionID,
 		return nil, err
 	}
 
+	ts.setReplaceable(hash, spent)
+
 	// Reverse the bytes!
 	var txHash32 types.Bytes32
 	for i, b := range tx.TxHash() {
@@ -173,6 +265,85 @@ func (ts *Timestamper) TimestampHash(hash *types.Bytes32) (types.TransactionID,
 	return txHash32[:], nil
 }
 
+// feeFunc returns a function that computes the fee of a transaction given
+// its number of inputs.
+func (ts *Timestamper) feeFunc(attempt int) (func(inputs int) int64, error) {
+	increase := func(fee int64) int64 {
+		return fee + fee*int64(attempt)*RebroadcastFeeIncrease/100
+	}
+
+	if ts.config.FeeEstimator == nil {
+		fee := increase(ts.config.Fee)
+		return func(int) int64 { return fee }, nil
+	}
+
+	rate, err := ts.config.FeeEstimator.EstimateFeeRate()
+	if err != nil {
+		return nil, err
+	}
+	rate = increase(rate)
+
+	return func(inputs int) int64 {
+		size := txOverheadSize + txInputSize*inputs + txOutputsSize
+		fee := rate * int64(size) / 1000
+		if ts.config.MaxFee > 0 && fee > ts.config.MaxFee {
+			return ts.config.MaxFee
+		}
+		return fee
+	}, nil
+}
+
+// selectOutputs finds unspent outputs to pay the fee of a transaction.
+// All the outputs are requested so that the balance can be checked even
+// when it doesn't cover the fee.
+func (ts *Timestamper) selectOutputs(fee func(inputs int) int64) (*spending, error) {
+	addr := (*types.ReversedBytes20)(ts.address.Hash160())
+	outputs, balance, err := ts.config.UnspentFinder.FindUnspent(addr, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if ts.config.MinBalance > 0 && balance < ts.config.MinBalance {
+		return nil, &blockchain.LowBalanceError{
+			Network:   ts.net.String(),
+			Address:   ts.address.EncodeAddress(),
+			Balance:   balance,
+			Threshold: ts.config.MinBalance,
+		}
+	}
+
+	selected, total, _, err := btc.SelectOutputs(outputs, 0, ts.config.MaxInputs, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spending{outputs: selected, total: total}, nil
+}
+
+// replaceable returns the outputs spent by the last transaction of a hash.
+func (ts *Timestamper) replaceable(hash *types.Bytes32) *spending {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	return ts.spendings[*hash]
+}
+
+// setReplaceable remembers the outputs spent by the last transaction of a hash.
+func (ts *Timestamper) setReplaceable(hash *types.Bytes32, spent *spending) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if _, ok := ts.spendings[*hash]; !ok {
+		ts.hashes = append(ts.hashes, *hash)
+	}
+	ts.spendings[*hash] = spent
+
+	if len(ts.hashes) > maxReplaceable {
+		delete(ts.spendings, ts.hashes[0])
+		ts.hashes = ts.hashes[1:]
+	}
+}
+
 func (ts *Timestamper) createPayToAddrTxOut(amount int64) (*wire.TxOut, error) {
 	PKScript, err := txscript.PayToAddrScript(ts.address)
 	if err != nil {
@@ -193,7 +364,7 @@ func (ts *Timestamper) createNullDataTxOut(hash *types.Bytes32) (*wire.TxOut, er
 
 func (ts *Timestamper) signTx(tx *wire.MsgTx, prevPKScripts [][]byte) error {
 	for index, PKScript := range prevPKScripts {
-		sig, err := txscript.SignTxOutput(ts.netParams, tx, 0, PKScript,
+		sig, err := txscript.SignTxOutput(ts.netParams, tx, index, PKScript,
 			txscript.SigHashAll, txscript.KeyClosure(ts.lookupKey), nil, nil)
 		if err != nil {
 			return err
@@ -209,8 +380,8 @@ const validateTxEngineFlags = txscript.ScriptBip16 | txscript.ScriptVerifyDERSig
 	txscript.ScriptStrictMultiSig | txscript.ScriptDiscourageUpgradableNops
 
 func (ts *Timestamper) validateTx(tx *wire.MsgTx, prevPKScripts [][]byte) error {
-	for _, PKScript := range prevPKScripts {
-		vm, err := txscript.NewEngine(PKScript, tx, 0, validateTxEngineFlags, nil, nil, 0)
+	for index, PKScript := range prevPKScripts {
+		vm, err := txscript.NewEngine(PKScript, tx, index, validateTxEngineFlags, nil, nil, 0)
 		if err != nil {
 			return err
 		}
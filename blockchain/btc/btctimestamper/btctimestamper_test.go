@@ -15,9 +15,13 @@
 package btctimestamper
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"testing"
 
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stratumn/go-indigocore/blockchain"
 	"github.com/stratumn/go-indigocore/blockchain/btc"
 	"github.com/stratumn/go-indigocore/blockchain/btc/btctesting"
 	"github.com/stratumn/go-indigocore/testutil"
@@ -57,7 +61,7 @@ func TestTimestamperTimestampHash(t *testing.T) {
 	mock.MockFindUnspent.Fn = func(*types.ReversedBytes20, int64) ([]btc.Output, int64, error) {
 		PKScriptHex := "76a914fc56f7f9f80cfba26f300c77b893c39ed89351ff88ac"
 		PKScript, _ := hex.DecodeString(PKScriptHex)
-		output := btc.Output{Index: 0, PKScript: PKScript}
+		output := btc.Output{Index: 0, PKScript: PKScript, Value: 6241000}
 		if err := output.TXHash.Unstring("c805dd0fbf728e6b7e6c4e5d4ddfaba0089291145453aafb762bcff7a8afe2f5"); err != nil {
 			return nil, 0, err
 		}
@@ -82,3 +86,286 @@ func TestTimestamperTimestampHash(t *testing.T) {
 		t.Errorf("ts.TimestampHash(): Broadcast() called %d time(s) want 1 time", mock.MockBroadcast.CalledCount)
 	}
 }
+
+func TestTimestamperRetimestampHash(t *testing.T) {
+	mock := &btctesting.Mock{}
+	mock.MockFindUnspent.Fn = func(*types.ReversedBytes20, int64) ([]btc.Output, int64, error) {
+		PKScriptHex := "76a914fc56f7f9f80cfba26f300c77b893c39ed89351ff88ac"
+		PKScript, _ := hex.DecodeString(PKScriptHex)
+		output := btc.Output{Index: 0, PKScript: PKScript, Value: 6241000}
+		if err := output.TXHash.Unstring("c805dd0fbf728e6b7e6c4e5d4ddfaba0089291145453aafb762bcff7a8afe2f5"); err != nil {
+			return nil, 0, err
+		}
+		return []btc.Output{output}, 6241000, nil
+	}
+
+	ts, err := New(&Config{
+		WIF:           "924v2d7ryXJjnbwB6M9GsZDEjAkfE9aHeQAG1j8muA4UEjozeAJ",
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		Fee:           int64(10000),
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	if _, err := ts.RetimestampHash(testutil.RandomHash(), 2); err != nil {
+		t.Fatalf("ts.RetimestampHash(): err: %s", err)
+	}
+
+	if got := mock.MockBroadcast.CalledCount; got != 1 {
+		t.Fatalf("ts.RetimestampHash(): Broadcast() called %d time(s) want 1 time", got)
+	}
+
+	fee := int64(6241000)
+	for _, out := range decodeTx(t, mock.MockBroadcast.LastCalledWith).TxOut {
+		fee -= out.Value
+	}
+	if got, want := fee, int64(20000); got != want {
+		t.Errorf("ts.RetimestampHash(): fee = %d want %d", got, want)
+	}
+}
+
+const (
+	testWIF      = "924v2d7ryXJjnbwB6M9GsZDEjAkfE9aHeQAG1j8muA4UEjozeAJ"
+	testPKScript = "76a914fc56f7f9f80cfba26f300c77b893c39ed89351ff88ac"
+)
+
+// createOutputs creates outputs of the test address with the given values.
+func createOutputs(values ...int64) []btc.Output {
+	PKScript, _ := hex.DecodeString(testPKScript)
+
+	var outputs []btc.Output
+	for i, v := range values {
+		output := btc.Output{Index: i, PKScript: PKScript, Value: v}
+		copy(output.TXHash[:], testutil.RandomHash()[:])
+		outputs = append(outputs, output)
+	}
+
+	return outputs
+}
+
+func createMock(outputs []btc.Output) *btctesting.Mock {
+	mock := &btctesting.Mock{}
+	mock.MockFindUnspent.Fn = func(*types.ReversedBytes20, int64) ([]btc.Output, int64, error) {
+		var total int64
+		for _, o := range outputs {
+			total += o.Value
+		}
+		return outputs, total, nil
+	}
+	return mock
+}
+
+func decodeTx(t *testing.T, raw []byte) *wire.MsgTx {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		t.Fatalf("tx.Deserialize(): err: %s", err)
+	}
+	return &tx
+}
+
+func TestTimestamperTimestampHash_FeeEstimator(t *testing.T) {
+	mock := createMock(createOutputs(1000000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		FeeEstimator:  btc.StaticFeeRate(100000),
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+
+	tx := decodeTx(t, mock.MockBroadcast.LastCalledWith)
+	if got, want := len(tx.TxOut), 2; got != want {
+		t.Fatalf("len(tx.TxOut) = %d want %d", got, want)
+	}
+
+	// 100000 satoshis per kilobyte for 267 bytes.
+	if got, want := 1000000-tx.TxOut[0].Value, int64(26700); got != want {
+		t.Errorf("fee = %d want %d", got, want)
+	}
+	for _, in := range tx.TxIn {
+		if got, want := in.Sequence, uint32(rbfSequence); got != want {
+			t.Errorf("in.Sequence = %x want %x", got, want)
+		}
+	}
+}
+
+func TestTimestamperTimestampHash_MaxFee(t *testing.T) {
+	mock := createMock(createOutputs(1000000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		FeeEstimator:  btc.StaticFeeRate(100000),
+		MaxFee:        20000,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+
+	tx := decodeTx(t, mock.MockBroadcast.LastCalledWith)
+	if got, want := 1000000-tx.TxOut[0].Value, int64(20000); got != want {
+		t.Errorf("fee = %d want %d", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash_FeeEstimatorError(t *testing.T) {
+	mock := createMock(createOutputs(1000000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		FeeEstimator:  btc.FeeRateFunc(func() (int64, error) { return 0, errors.New("error") }),
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err == nil {
+		t.Error("ts.TimestampHash(): err = nil want Error")
+	}
+	if got := mock.MockBroadcast.CalledCount; got != 0 {
+		t.Errorf("ts.TimestampHash(): Broadcast() called %d time(s) want 0 time", got)
+	}
+}
+
+func TestTimestamperTimestampHash_Consolidate(t *testing.T) {
+	mock := createMock(createOutputs(5000000, 4000, 6000, 8000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		Fee:           10000,
+		MaxInputs:     DefaultMaxInputs,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+
+	tx := decodeTx(t, mock.MockBroadcast.LastCalledWith)
+	if got, want := len(tx.TxIn), 2; got != want {
+		t.Errorf("len(tx.TxIn) = %d want %d", got, want)
+	}
+
+	// The change is dust so it is added to the fee.
+	if got, want := len(tx.TxOut), 1; got != want {
+		t.Errorf("len(tx.TxOut) = %d want %d", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash_LowBalance(t *testing.T) {
+	mock := createMock(createOutputs(50000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		Fee:           10000,
+		MinBalance:    100000,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	_, err = ts.TimestampHash(testutil.RandomHash())
+	e, ok := err.(*blockchain.LowBalanceError)
+	if !ok {
+		t.Fatalf("ts.TimestampHash(): err = %v want *blockchain.LowBalanceError", err)
+	}
+	if got, want := e.Balance, int64(50000); got != want {
+		t.Errorf("e.Balance = %d want %d", got, want)
+	}
+	if got, want := e.Threshold, int64(100000); got != want {
+		t.Errorf("e.Threshold = %d want %d", got, want)
+	}
+	if got := mock.MockBroadcast.CalledCount; got != 0 {
+		t.Errorf("ts.TimestampHash(): Broadcast() called %d time(s) want 0 time", got)
+	}
+}
+
+func TestTimestamperTimestampHash_LowBalanceBelowFee(t *testing.T) {
+	outputs := createOutputs(5000)
+	mock := &btctesting.Mock{}
+	mock.MockFindUnspent.Fn = func(_ *types.ReversedBytes20, amount int64) ([]btc.Output, int64, error) {
+		// Like the real finders, fail when the outputs don't cover the amount.
+		if amount > 5000 {
+			return nil, 0, errors.New("not enough bitcoins")
+		}
+		return outputs, 5000, nil
+	}
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		Fee:           10000,
+		MinBalance:    100000,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	_, err = ts.TimestampHash(testutil.RandomHash())
+	e, ok := err.(*blockchain.LowBalanceError)
+	if !ok {
+		t.Fatalf("ts.TimestampHash(): err = %v want *blockchain.LowBalanceError", err)
+	}
+	if got, want := e.Balance, int64(5000); got != want {
+		t.Errorf("e.Balance = %d want %d", got, want)
+	}
+}
+
+func TestTimestamperRetimestampHash_Replace(t *testing.T) {
+	mock := createMock(createOutputs(1000000))
+
+	ts, err := New(&Config{
+		WIF:           testWIF,
+		UnspentFinder: mock,
+		Broadcaster:   mock,
+		Fee:           10000,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	hash := testutil.RandomHash()
+	if _, err := ts.TimestampHash(hash); err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+	if _, err := ts.RetimestampHash(hash, 1); err != nil {
+		t.Fatalf("ts.RetimestampHash(): err: %s", err)
+	}
+
+	if got, want := mock.MockFindUnspent.CalledCount, 1; got != want {
+		t.Errorf("FindUnspent() called %d time(s) want %d time(s)", got, want)
+	}
+
+	first := decodeTx(t, mock.MockBroadcast.CalledWith[0])
+	second := decodeTx(t, mock.MockBroadcast.CalledWith[1])
+
+	if got, want := second.TxIn[0].PreviousOutPoint, first.TxIn[0].PreviousOutPoint; got != want {
+		t.Errorf("second.TxIn[0].PreviousOutPoint = %v want %v", got, want)
+	}
+	if got, want := 1000000-second.TxOut[0].Value, int64(15000); got != want {
+		t.Errorf("fee = %d want %d", got, want)
+	}
+}
@@ -23,23 +23,40 @@ import (
 )
 
 var (
-	fee int64
+	fee        int64
+	dynamicFee bool
+	maxFee     int64
+	maxInputs  int
+	minBalance int64
 )
 
 // RegisterFlags registers the flags used by InitializeWithFlags.
 func RegisterFlags() {
 	flag.Int64Var(&fee, "fee", DefaultFee, "transaction fee (satoshis)")
+	flag.BoolVar(&dynamicFee, "dynamicfee", false, "whether to estimate the transaction fee using the network instead of a fixed fee")
+	flag.Int64Var(&maxFee, "maxfee", DefaultMaxFee, "maximum estimated transaction fee (satoshis)")
+	flag.IntVar(&maxInputs, "maxinputs", DefaultMaxInputs, "maximum number of outputs spent by a transaction")
+	flag.Int64Var(&minBalance, "minbalance", 0, "balance under which transactions are not broadcast (satoshis)")
 }
 
 // InitializeWithFlags should be called after RegisterFlags and flag.Parse to initialize
 // a bcbatchfossilizer using flag values.
-func InitializeWithFlags(version, commit string, key string, unspentFinder btc.UnspentFinder, broadcaster btc.Broadcaster) *Timestamper {
-	ts, err := New(&Config{
+// The fee estimator is only used if dynamic fees are enabled.
+func InitializeWithFlags(version, commit string, key string, unspentFinder btc.UnspentFinder, broadcaster btc.Broadcaster, estimator btc.FeeEstimator) *Timestamper {
+	config := &Config{
 		UnspentFinder: unspentFinder,
 		Broadcaster:   broadcaster,
 		WIF:           key,
 		Fee:           fee,
-	})
+		MaxFee:        maxFee,
+		MaxInputs:     maxInputs,
+		MinBalance:    minBalance,
+	}
+	if dynamicFee {
+		config.FeeEstimator = estimator
+	}
+
+	ts, err := New(config)
 	if err != nil {
 		log.WithField("error", err).Fatal("Failed to create Bitcoin timestamper")
 	}
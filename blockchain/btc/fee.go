@@ -0,0 +1,112 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInsufficientFunds is returned when unspent outputs cannot pay for a
+// transaction.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// FeeEstimator is able to estimate transaction fees.
+type FeeEstimator interface {
+	// EstimateFeeRate returns the fee rate in satoshis per kilobyte.
+	EstimateFeeRate() (int64, error)
+}
+
+// StaticFeeRate is a FeeEstimator that always returns the same fee rate in
+// satoshis per kilobyte.
+type StaticFeeRate int64
+
+// EstimateFeeRate implements
+// github.com/stratumn/go-indigocore/blockchain/btc.FeeEstimator.EstimateFeeRate.
+func (r StaticFeeRate) EstimateFeeRate() (int64, error) {
+	return int64(r), nil
+}
+
+// FeeRateFunc is an adapter to use a function as a FeeEstimator.
+type FeeRateFunc func() (int64, error)
+
+// EstimateFeeRate implements
+// github.com/stratumn/go-indigocore/blockchain/btc.FeeEstimator.EstimateFeeRate.
+func (f FeeRateFunc) EstimateFeeRate() (int64, error) {
+	return f()
+}
+
+// SelectOutputs selects unspent outputs to pay the given amount plus the
+// fee of the transaction.
+//
+// To consolidate the wallet, the smallest outputs are spent first. If more
+// than maxInputs outputs would be needed, the largest outputs are spent
+// instead. A maxInputs of zero means no limit. Outputs that are worth less
+// than the fee needed to spend them are ignored.
+//
+// The fee function returns the fee of a transaction with the given number of
+// inputs. SelectOutputs returns the selected outputs, their total value and
+// the fee.
+func SelectOutputs(outputs []Output, amount int64, maxInputs int, fee func(inputs int) int64) ([]Output, int64, int64, error) {
+	var candidates []Output
+	for _, o := range outputs {
+		if o.Value > fee(1)-fee(0) {
+			candidates = append(candidates, o)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Value < candidates[j].Value
+	})
+
+	if selected, total, ok := selectOutputs(candidates, amount, maxInputs, fee); ok {
+		return selected, total, fee(len(selected)), nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Value > candidates[j].Value
+	})
+
+	if selected, total, ok := selectOutputs(candidates, amount, maxInputs, fee); ok {
+		return selected, total, fee(len(selected)), nil
+	}
+
+	return nil, 0, 0, ErrInsufficientFunds
+}
+
+// selectOutputs adds outputs in order until they pay for the amount and the
+// fee, without exceeding maxInputs outputs.
+func selectOutputs(outputs []Output, amount int64, maxInputs int, fee func(inputs int) int64) ([]Output, int64, bool) {
+	var (
+		selected []Output
+		total    int64
+	)
+
+	for _, o := range outputs {
+		if maxInputs > 0 && len(selected) >= maxInputs {
+			break
+		}
+
+		selected = append(selected, o)
+		total += o.Value
+
+		if total >= amount+fee(len(selected)) {
+			return selected, total, true
+		}
+	}
+
+	return nil, 0, false
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFeeRate(t *testing.T) {
+	rate, err := btc.StaticFeeRate(20000).EstimateFeeRate()
+	require.NoError(t, err)
+	assert.Equal(t, int64(20000), rate)
+}
+
+func TestFeeRateFunc(t *testing.T) {
+	f := btc.FeeRateFunc(func() (int64, error) { return 0, errors.New("no estimate") })
+	_, err := f.EstimateFeeRate()
+	assert.EqualError(t, err, "no estimate")
+}
+
+func TestSelectOutputs(t *testing.T) {
+	// Each input costs 100 satoshis.
+	fee := func(inputs int) int64 { return 200 + 100*int64(inputs) }
+
+	outputs := func(values ...int64) []btc.Output {
+		var outs []btc.Output
+		for i, v := range values {
+			outs = append(outs, btc.Output{Index: i, Value: v})
+		}
+		return outs
+	}
+
+	values := func(outs []btc.Output) []int64 {
+		var vals []int64
+		for _, o := range outs {
+			vals = append(vals, o.Value)
+		}
+		return vals
+	}
+
+	type testCase struct {
+		name      string
+		outputs   []btc.Output
+		amount    int64
+		maxInputs int
+		selected  []int64
+		total     int64
+		fee       int64
+		err       error
+	}
+
+	tests := []testCase{{
+		name:     "single output",
+		outputs:  outputs(10000),
+		amount:   1000,
+		selected: []int64{10000},
+		total:    10000,
+		fee:      300,
+	}, {
+		name:     "consolidate small outputs",
+		outputs:  outputs(100000, 500, 600, 700),
+		amount:   1000,
+		selected: []int64{500, 600, 700},
+		total:    1800,
+		fee:      500,
+	}, {
+		name:     "ignore dust",
+		outputs:  outputs(100000, 50, 100, 600, 700),
+		amount:   500,
+		selected: []int64{600, 700},
+		total:    1300,
+		fee:      400,
+	}, {
+		name:      "too many small outputs",
+		outputs:   outputs(500, 500, 500, 500, 100000),
+		amount:    1300,
+		maxInputs: 3,
+		selected:  []int64{100000},
+		total:     100000,
+		fee:       300,
+	}, {
+		name:      "no limit",
+		outputs:   outputs(500, 500, 500, 500, 100000),
+		amount:    1300,
+		maxInputs: 0,
+		selected:  []int64{500, 500, 500, 500},
+		total:     2000,
+		fee:       600,
+	}, {
+		name:    "insufficient funds",
+		outputs: outputs(500, 600),
+		amount:  1000,
+		err:     btc.ErrInsufficientFunds,
+	}, {
+		name:   "no outputs",
+		amount: 1000,
+		err:    btc.ErrInsufficientFunds,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, total, f, err := btc.SelectOutputs(tt.outputs, tt.amount, tt.maxInputs, fee)
+			if tt.err != nil {
+				assert.EqualError(t, err, tt.err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.selected, values(selected))
+			assert.Equal(t, tt.total, total)
+			assert.Equal(t, tt.fee, f)
+		})
+	}
+}
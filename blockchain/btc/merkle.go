@@ -0,0 +1,180 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+var (
+	// ErrBadMerkleBranch is returned when a Merkle branch does not lead to
+	// the Merkle root of a block header.
+	ErrBadMerkleBranch = errors.New("merkle branch does not match block header")
+
+	// ErrBadBlockHash is returned when a block header does not have the
+	// expected hash.
+	ErrBadBlockHash = errors.New("block header does not match block hash")
+)
+
+// Confirmation describes the inclusion of a transaction in a block.
+type Confirmation struct {
+	// BlockHash is the hash of the block. As is customary for Bitcoin, the
+	// bytes of the hash are reversed.
+	BlockHash types.Bytes32
+
+	// BlockHeight is the height of the block.
+	BlockHeight int64
+
+	// Confirmations is the number of blocks from the block of the
+	// transaction to the tip of the chain, including both.
+	Confirmations int64
+
+	// Header is the serialized header of the block.
+	Header []byte
+
+	// Index is the position of the transaction in the block.
+	Index int
+
+	// Branch contains the hashes needed to compute the Merkle root of the
+	// block from the transaction ID.
+	Branch []types.Bytes32
+}
+
+// ConfirmationFinder is able to find the block that includes a transaction.
+type ConfirmationFinder interface {
+	// FindConfirmation returns the confirmation of the transaction with the
+	// given ID, or nil if the transaction is not in a block yet.
+	// It returns ErrTransactionNotFound if the transaction doesn't exist,
+	// for instance because it was dropped from the mempool.
+	FindConfirmation(txID types.TransactionID) (*Confirmation, error)
+}
+
+// BlockHash computes the hash of a serialized block header.
+// As is customary for Bitcoin, the bytes of the hash are reversed.
+func BlockHash(header []byte) (*types.Bytes32, error) {
+	h, err := decodeHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewBytes32FromBytes(reverseHash(h.BlockHash())), nil
+}
+
+// MerkleBranch computes the Merkle branch of the transaction at the given
+// index from the IDs of all the transactions of a block.
+func MerkleBranch(txIDs []types.TransactionID, index int) ([]types.Bytes32, error) {
+	if index < 0 || index >= len(txIDs) {
+		return nil, errors.Errorf("transaction index %d out of range", index)
+	}
+
+	level := make([]chainhash.Hash, len(txIDs))
+	for i, txID := range txIDs {
+		h, err := txHash(txID)
+		if err != nil {
+			return nil, err
+		}
+		level[i] = *h
+	}
+
+	var branch []types.Bytes32
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		branch = append(branch, types.Bytes32(level[index^1]))
+
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			next[i] = hashMerkleNodes(&level[2*i], &level[2*i+1])
+		}
+
+		level = next
+		index /= 2
+	}
+
+	return branch, nil
+}
+
+// VerifyConfirmation checks that the transaction with the given ID is
+// included in the block of a confirmation.
+func VerifyConfirmation(txID types.TransactionID, c *Confirmation) error {
+	h, err := decodeHeader(c.Header)
+	if err != nil {
+		return err
+	}
+
+	if !c.BlockHash.EqualsBytes(reverseHash(h.BlockHash())) {
+		return ErrBadBlockHash
+	}
+
+	hash, err := txHash(txID)
+	if err != nil {
+		return err
+	}
+
+	index := c.Index
+	for _, b := range c.Branch {
+		sibling := chainhash.Hash(b)
+		if index%2 == 0 {
+			*hash = hashMerkleNodes(hash, &sibling)
+		} else {
+			*hash = hashMerkleNodes(&sibling, hash)
+		}
+		index /= 2
+	}
+
+	if index != 0 || *hash != h.MerkleRoot {
+		return ErrBadMerkleBranch
+	}
+
+	return nil
+}
+
+// txHash returns the hash of a transaction in internal byte order.
+func txHash(txID types.TransactionID) (*chainhash.Hash, error) {
+	if len(txID) != chainhash.HashSize {
+		return nil, errors.Errorf("invalid transaction ID %s", txID)
+	}
+
+	var h chainhash.Hash
+	for i, b := range txID {
+		h[chainhash.HashSize-i-1] = b
+	}
+
+	return &h, nil
+}
+
+func hashMerkleNodes(left, right *chainhash.Hash) chainhash.Hash {
+	var buf [chainhash.HashSize * 2]byte
+	copy(buf[:chainhash.HashSize], left[:])
+	copy(buf[chainhash.HashSize:], right[:])
+
+	return chainhash.DoubleHashH(buf[:])
+}
+
+func decodeHeader(raw []byte) (*wire.BlockHeader, error) {
+	var h wire.BlockHeader
+	if err := h.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, errors.Wrap(err, "could not decode block header")
+	}
+
+	return &h, nil
+}
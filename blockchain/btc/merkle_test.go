@@ -0,0 +1,190 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Transactions of block 100000 of the main Bitcoin network.
+var block100000TxIDs = []string{
+	"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+	"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+	"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+}
+
+const block100000Hash = "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506"
+
+// txHash returns the hash of a transaction in internal byte order.
+func txHash(txID types.TransactionID) *chainhash.Hash {
+	var h chainhash.Hash
+	for i, b := range txID {
+		h[chainhash.HashSize-i-1] = b
+	}
+	return &h
+}
+
+func hashMerkleNodes(left, right *chainhash.Hash) chainhash.Hash {
+	return chainhash.DoubleHashH(append(left[:], right[:]...))
+}
+
+func block100000Header(t *testing.T) []byte {
+	prevBlock, _ := chainhash.NewHashFromStr("000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250")
+	merkleRoot, _ := chainhash.NewHashFromStr("f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766")
+
+	h := wire.NewBlockHeader(1, prevBlock, merkleRoot, 0x1b04864c, 274148111)
+	h.Timestamp = time.Unix(1293623863, 0)
+
+	buf := bytes.NewBuffer(nil)
+	if err := h.Serialize(buf); err != nil {
+		t.Fatalf("h.Serialize(): err: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func block100000Confirmation(t *testing.T, index int) *btc.Confirmation {
+	var txIDs []types.TransactionID
+	for _, s := range block100000TxIDs {
+		txID, _ := hex.DecodeString(s)
+		txIDs = append(txIDs, txID)
+	}
+
+	branch, err := btc.MerkleBranch(txIDs, index)
+	if err != nil {
+		t.Fatalf("btc.MerkleBranch(): err: %s", err)
+	}
+
+	blockHash, _ := types.NewBytes32FromString(block100000Hash)
+
+	return &btc.Confirmation{
+		BlockHash:     *blockHash,
+		BlockHeight:   100000,
+		Confirmations: 1,
+		Header:        block100000Header(t),
+		Index:         index,
+		Branch:        branch,
+	}
+}
+
+func TestBlockHash(t *testing.T) {
+	hash, err := btc.BlockHash(block100000Header(t))
+	if err != nil {
+		t.Fatalf("btc.BlockHash(): err: %s", err)
+	}
+
+	if got, want := hash.String(), block100000Hash; got != want {
+		t.Errorf("btc.BlockHash() = %s want %s", got, want)
+	}
+}
+
+func TestBlockHash_Invalid(t *testing.T) {
+	if _, err := btc.BlockHash([]byte{1, 2, 3}); err == nil {
+		t.Error("btc.BlockHash(): err = nil want Error")
+	}
+}
+
+func TestMerkleBranch_OutOfRange(t *testing.T) {
+	if _, err := btc.MerkleBranch(nil, 0); err == nil {
+		t.Error("btc.MerkleBranch(): err = nil want Error")
+	}
+}
+
+func TestVerifyConfirmation(t *testing.T) {
+	for i, s := range block100000TxIDs {
+		txID, _ := hex.DecodeString(s)
+		c := block100000Confirmation(t, i)
+
+		if got, want := len(c.Branch), 2; got != want {
+			t.Errorf("tx#%d: len(c.Branch) = %d want %d", i, got, want)
+		}
+		if err := btc.VerifyConfirmation(txID, c); err != nil {
+			t.Errorf("tx#%d: btc.VerifyConfirmation(): err: %s", i, err)
+		}
+	}
+}
+
+func TestMerkleBranch_OddTransactions(t *testing.T) {
+	var (
+		txIDs  []types.TransactionID
+		hashes []*chainhash.Hash
+	)
+	for _, s := range block100000TxIDs[:3] {
+		txID, _ := hex.DecodeString(s)
+		txIDs = append(txIDs, txID)
+		hashes = append(hashes, txHash(txID))
+	}
+
+	// The last transaction is paired with itself.
+	ab := hashMerkleNodes(hashes[0], hashes[1])
+	cc := hashMerkleNodes(hashes[2], hashes[2])
+
+	tests := [][]chainhash.Hash{
+		{*hashes[1], cc},
+		{*hashes[0], cc},
+		{*hashes[2], ab},
+	}
+
+	for i, want := range tests {
+		branch, err := btc.MerkleBranch(txIDs, i)
+		if err != nil {
+			t.Fatalf("btc.MerkleBranch(): err: %s", err)
+		}
+		if got := len(branch); got != len(want) {
+			t.Fatalf("tx#%d: len(branch) = %d want %d", i, got, len(want))
+		}
+		for j := range want {
+			if got := chainhash.Hash(branch[j]); got != want[j] {
+				t.Errorf("tx#%d: branch[%d] = %s want %s", i, j, got, want[j])
+			}
+		}
+	}
+}
+
+func TestVerifyConfirmation_Invalid(t *testing.T) {
+	txID, _ := hex.DecodeString(block100000TxIDs[1])
+
+	c := block100000Confirmation(t, 1)
+	c.Index = 2
+	if err := btc.VerifyConfirmation(txID, c); err != btc.ErrBadMerkleBranch {
+		t.Errorf("btc.VerifyConfirmation(): err = %v want %v", err, btc.ErrBadMerkleBranch)
+	}
+
+	c = block100000Confirmation(t, 1)
+	c.Branch[0][0] ^= 1
+	if err := btc.VerifyConfirmation(txID, c); err != btc.ErrBadMerkleBranch {
+		t.Errorf("btc.VerifyConfirmation(): err = %v want %v", err, btc.ErrBadMerkleBranch)
+	}
+
+	c = block100000Confirmation(t, 1)
+	c.BlockHash[0] ^= 1
+	if err := btc.VerifyConfirmation(txID, c); err != btc.ErrBadBlockHash {
+		t.Errorf("btc.VerifyConfirmation(): err = %v want %v", err, btc.ErrBadBlockHash)
+	}
+
+	c = block100000Confirmation(t, 1)
+	if err := btc.VerifyConfirmation(txID[1:], c); err == nil {
+		t.Error("btc.VerifyConfirmation(): err = nil want Error")
+	}
+}
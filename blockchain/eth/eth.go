@@ -0,0 +1,129 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eth defines primitives to work with Ethereum.
+package eth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Network represents an Ethereum network.
+type Network string
+
+const (
+	// NetworkMain is an identifier for the main Ethereum network.
+	NetworkMain Network = "ethereum:main"
+
+	// NetworkRopsten is an identifier for the Ropsten test network.
+	NetworkRopsten Network = "ethereum:ropsten"
+
+	// NetworkRinkeby is an identifier for the Rinkeby test network.
+	NetworkRinkeby Network = "ethereum:rinkeby"
+
+	// NetworkKovan is an identifier for the Kovan test network.
+	NetworkKovan Network = "ethereum:kovan"
+)
+
+// AnchorMethod is the signature of the method called on an anchoring
+// contract. Such a contract only needs to log the hash it receives, for
+// instance:
+//
+//	contract Anchor {
+//	    event Anchored(address indexed sender, bytes32 hash);
+//	    function anchor(bytes32 hash) public { emit Anchored(msg.sender, hash); }
+//	}
+const AnchorMethod = "anchor(bytes32)"
+
+var (
+	// ErrBadPrivateKey is returned when a private key could not be decoded.
+	ErrBadPrivateKey = errors.New("Failed to decode hex encoded private key")
+)
+
+// NetworkFromChainID returns the network of an EIP-155 chain ID.
+func NetworkFromChainID(chainID int64) Network {
+	switch chainID {
+	case 1:
+		return NetworkMain
+	case 3:
+		return NetworkRopsten
+	case 4:
+		return NetworkRinkeby
+	case 42:
+		return NetworkKovan
+	}
+
+	return Network(fmt.Sprintf("ethereum:%d", chainID))
+}
+
+// String implements fmt.Stringer.
+func (n Network) String() string {
+	return string(n)
+}
+
+// Client is able to send JSON-RPC requests to an Ethereum node.
+type Client interface {
+	// PendingNonce returns the number of transactions sent from an
+	// address, including pending transactions.
+	PendingNonce(address *types.Bytes20) (uint64, error)
+
+	// GasPrice returns the gas price suggested by the node, in wei.
+	GasPrice() (*big.Int, error)
+
+	// SendRawTransaction broadcasts a signed transaction and returns its
+	// hash.
+	SendRawTransaction(raw []byte) (types.TransactionID, error)
+}
+
+// TransactionFinder is able to find Ethereum transactions.
+type TransactionFinder interface {
+	// FindTransaction returns the transaction with the given hash.
+	FindTransaction(txID types.TransactionID) (*SignedTransaction, error)
+}
+
+// ParsePrivateKey decodes a hex encoded private key.
+func ParsePrivateKey(key string) (*btcec.PrivateKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil || len(b) != 32 {
+		return nil, ErrBadPrivateKey
+	}
+
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), b)
+	return privKey, nil
+}
+
+// PublicKeyAddress returns the address of a public key.
+func PublicKeyAddress(pubKey *btcec.PublicKey) *types.Bytes20 {
+	// The first byte of an uncompressed key is its prefix.
+	hash := Keccak256(pubKey.SerializeUncompressed()[1:])
+
+	var address types.Bytes20
+	copy(address[:], hash[12:])
+
+	return &address
+}
+
+// AnchorCallData returns the data of a transaction calling the anchor method
+// of a contract with the given hash.
+func AnchorCallData(hash *types.Bytes32) []byte {
+	selector := Keccak256([]byte(AnchorMethod))[:4]
+	return append(selector, hash[:]...)
+}
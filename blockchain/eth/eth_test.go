@@ -0,0 +1,120 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		data string
+		hash string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"hello world", "47173285a8d7341e5e972fc677286384f802f8ef42a5ec5f03bbfa254cb01fad"},
+		{strings.Repeat("a", 200), "96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(Keccak256([]byte(tt.data)))
+		assert.Equal(t, tt.hash, got, "Keccak256(%q)", tt.data)
+	}
+}
+
+func TestRLP(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded []byte
+		want    string
+	}{
+		{"empty string", rlpBytes(nil), "80"},
+		{"single byte", rlpBytes([]byte{0x0f}), "0f"},
+		{"short string", rlpBytes([]byte("dog")), "83646f67"},
+		{"long string", rlpBytes([]byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit")), "b8384c6f72656d20697073756d20646f6c6f722073697420616d65742c20636f6e7365637465747572206164697069736963696e6720656c6974"},
+		{"zero", rlpUint(0), "80"},
+		{"small integer", rlpUint(15), "0f"},
+		{"integer", rlpUint(1024), "820400"},
+		{"big integer", rlpBigInt(big.NewInt(0x0100000000)), "850100000000"},
+		{"empty list", rlpList(), "c0"},
+		{"list", rlpList(rlpBytes([]byte("cat")), rlpBytes([]byte("dog"))), "c88363617483646f67"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hex.EncodeToString(tt.encoded))
+		})
+	}
+}
+
+// The test vector of EIP-155.
+func TestTransaction_Sign(t *testing.T) {
+	key, err := ParsePrivateKey("0x4646464646464646464646464646464646464646464646464646464646464646")
+	require.NoError(t, err, "ParsePrivateKey()")
+
+	to, err := types.NewBytes20FromString("3535353535353535353535353535353535353535")
+	require.NoError(t, err, "types.NewBytes20FromString()")
+
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	tx := Transaction{
+		Nonce:    9,
+		GasPrice: big.NewInt(20000000000),
+		GasLimit: 21000,
+		To:       to,
+		Value:    value,
+	}
+
+	assert.Equal(t, "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53", hex.EncodeToString(tx.SigningHash(1)), "tx.SigningHash()")
+
+	raw, txID, err := tx.Sign(key, 1)
+	require.NoError(t, err, "tx.Sign()")
+	assert.Equal(t, "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83", hex.EncodeToString(raw), "raw")
+	assert.Equal(t, "33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788", txID.String(), "txID")
+}
+
+func TestPublicKeyAddress(t *testing.T) {
+	key, err := ParsePrivateKey("4646464646464646464646464646464646464646464646464646464646464646")
+	require.NoError(t, err, "ParsePrivateKey()")
+	assert.Equal(t, "9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f", PublicKeyAddress(key.PubKey()).String())
+}
+
+func TestParsePrivateKey_Invalid(t *testing.T) {
+	for _, key := range []string{"", "0xzz", "4646"} {
+		_, err := ParsePrivateKey(key)
+		assert.EqualError(t, err, ErrBadPrivateKey.Error(), "ParsePrivateKey(%q)", key)
+	}
+}
+
+func TestNetworkFromChainID(t *testing.T) {
+	assert.Equal(t, NetworkMain, NetworkFromChainID(1))
+	assert.Equal(t, NetworkRopsten, NetworkFromChainID(3))
+	assert.Equal(t, Network("ethereum:1337"), NetworkFromChainID(1337))
+}
+
+func TestAnchorCallData(t *testing.T) {
+	hash := types.Bytes32{0x42}
+	data := AnchorCallData(&hash)
+	require.Len(t, data, 36)
+	assert.Equal(t, Keccak256([]byte("anchor(bytes32)"))[:4], data[:4])
+	assert.Equal(t, hash[:], data[4:])
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethrpc
+
+import (
+	"flag"
+	"time"
+)
+
+var (
+	url     string
+	timeout time.Duration
+)
+
+// RegisterFlags registers the flags used by InitializeWithFlags.
+func RegisterFlags() {
+	flag.StringVar(&url, "ethrpc", DefaultURL, "URL of the JSON-RPC API of the Ethereum node")
+	flag.DurationVar(&timeout, "ethrpctimeout", DefaultTimeout, "timeout of requests to the Ethereum node")
+}
+
+// InitializeWithFlags should be called after RegisterFlags and flag.Parse to
+// initialize a JSON-RPC client using flag values.
+func InitializeWithFlags() *Client {
+	return New(&Config{
+		URL:     url,
+		Timeout: timeout,
+	})
+}
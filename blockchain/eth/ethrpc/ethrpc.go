@@ -0,0 +1,250 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethrpc defines a client for the JSON-RPC API of Ethereum nodes.
+package ethrpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// DefaultURL is the default URL of the JSON-RPC API.
+	DefaultURL = "http://localhost:8545"
+
+	// DefaultTimeout is the default timeout of requests.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Config contains configuration options for the client.
+type Config struct {
+	// URL is the URL of the JSON-RPC API of the node.
+	URL string
+
+	// Timeout is the timeout of requests.
+	Timeout time.Duration
+}
+
+// Error is an error returned by the node.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error.Error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Client is a JSON-RPC client for an Ethereum node.
+//
+// It implements github.com/stratumn/go-indigocore/blockchain/eth.Client and
+// github.com/stratumn/go-indigocore/blockchain/eth.TransactionFinder.
+type Client struct {
+	config *Config
+	http   *http.Client
+	id     uint64
+}
+
+// New creates a client for the JSON-RPC API of an Ethereum node.
+func New(config *Config) *Client {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		config: config,
+		http:   &http.Client{Timeout: timeout},
+	}
+}
+
+// call calls a JSON-RPC method and decodes its result.
+func (c *Client) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	req := request{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Method:  method,
+		Params:  params,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	resp, err := c.http.Post(c.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s: node returned status %d", method, resp.StatusCode)
+	}
+
+	var res response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return errors.Wrap(err, method)
+	}
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.ID != req.ID {
+		return errors.Errorf("%s: unexpected response ID %d", method, res.ID)
+	}
+
+	return errors.Wrap(json.Unmarshal(res.Result, result), method)
+}
+
+// PendingNonce implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.PendingNonce.
+func (c *Client) PendingNonce(address *types.Bytes20) (uint64, error) {
+	var count string
+	if err := c.call("eth_getTransactionCount", &count, "0x"+address.String(), "pending"); err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(strings.TrimPrefix(count, "0x"), 16, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid transaction count %q", count)
+	}
+
+	return n, nil
+}
+
+// GasPrice implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.GasPrice.
+func (c *Client) GasPrice() (*big.Int, error) {
+	var price string
+	if err := c.call("eth_gasPrice", &price); err != nil {
+		return nil, err
+	}
+
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(price, "0x"), 16)
+	if !ok {
+		return nil, errors.Errorf("invalid gas price %q", price)
+	}
+
+	return n, nil
+}
+
+// SendRawTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.SendRawTransaction.
+func (c *Client) SendRawTransaction(raw []byte) (types.TransactionID, error) {
+	var hash string
+	if err := c.call("eth_sendRawTransaction", &hash, "0x"+hex.EncodeToString(raw)); err != nil {
+		return nil, err
+	}
+
+	txID, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid transaction hash %q", hash)
+	}
+
+	return txID, nil
+}
+
+// transaction is a transaction returned by the node.
+type transaction struct {
+	Nonce    string  `json:"nonce"`
+	GasPrice string  `json:"gasPrice"`
+	Gas      string  `json:"gas"`
+	To       *string `json:"to"`
+	Value    string  `json:"value"`
+	Input    string  `json:"input"`
+	V        string  `json:"v"`
+	R        string  `json:"r"`
+	S        string  `json:"s"`
+}
+
+// FindTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/eth.TransactionFinder.FindTransaction.
+func (c *Client) FindTransaction(txID types.TransactionID) (*eth.SignedTransaction, error) {
+	var res *transaction
+	if err := c.call("eth_getTransactionByHash", &res, "0x"+txID.String()); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("transaction %s not found", txID)
+	}
+
+	tx := &eth.SignedTransaction{}
+	quantities := []struct {
+		value string
+		n     **big.Int
+	}{
+		{res.GasPrice, &tx.GasPrice},
+		{res.Value, &tx.Value},
+		{res.V, &tx.V},
+		{res.R, &tx.R},
+		{res.S, &tx.S},
+	}
+	for _, q := range quantities {
+		n, ok := new(big.Int).SetString(strings.TrimPrefix(q.value, "0x"), 16)
+		if !ok {
+			return nil, errors.Errorf("invalid quantity %q", q.value)
+		}
+		*q.n = n
+	}
+
+	var err error
+	if tx.Nonce, err = strconv.ParseUint(strings.TrimPrefix(res.Nonce, "0x"), 16, 64); err != nil {
+		return nil, errors.Wrapf(err, "invalid nonce %q", res.Nonce)
+	}
+	if tx.GasLimit, err = strconv.ParseUint(strings.TrimPrefix(res.Gas, "0x"), 16, 64); err != nil {
+		return nil, errors.Wrapf(err, "invalid gas %q", res.Gas)
+	}
+	if res.To != nil {
+		if tx.To, err = types.NewBytes20FromString(strings.TrimPrefix(*res.To, "0x")); err != nil {
+			return nil, errors.Wrapf(err, "invalid recipient %q", *res.To)
+		}
+	}
+	if tx.Data, err = hex.DecodeString(strings.TrimPrefix(res.Input, "0x")); err != nil {
+		return nil, errors.Wrapf(err, "invalid input %q", res.Input)
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethrpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createNode creates a fake node that answers the given results by method.
+func createNode(t *testing.T, results map[string]interface{}) (*httptest.Server, *[]request) {
+	var requests []request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req), "json.Decode()")
+		requests = append(requests, req)
+
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch result := results[req.Method].(type) {
+		case nil:
+			res["error"] = Error{Code: -32601, Message: "method not found"}
+		case *Error:
+			res["error"] = result
+		default:
+			res["result"] = result
+		}
+
+		assert.NoError(t, json.NewEncoder(w).Encode(res), "json.Encode()")
+	}))
+
+	return server, &requests
+}
+
+func TestPendingNonce(t *testing.T) {
+	server, requests := createNode(t, map[string]interface{}{
+		"eth_getTransactionCount": "0x2a",
+	})
+	defer server.Close()
+
+	address := types.Bytes20{0x42}
+	nonce, err := New(&Config{URL: server.URL}).PendingNonce(&address)
+	require.NoError(t, err, "PendingNonce()")
+	assert.Equal(t, uint64(42), nonce)
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "2.0", req.JSONRPC)
+	assert.Equal(t, []interface{}{"0x" + address.String(), "pending"}, req.Params)
+}
+
+func TestGasPrice(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"eth_gasPrice": "0x4a817c800",
+	})
+	defer server.Close()
+
+	price, err := New(&Config{URL: server.URL}).GasPrice()
+	require.NoError(t, err, "GasPrice()")
+	assert.Equal(t, big.NewInt(20000000000), price)
+}
+
+func TestSendRawTransaction(t *testing.T) {
+	hash := "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788"
+	server, requests := createNode(t, map[string]interface{}{
+		"eth_sendRawTransaction": hash,
+	})
+	defer server.Close()
+
+	txID, err := New(&Config{URL: server.URL}).SendRawTransaction([]byte{0xf8, 0x6c})
+	require.NoError(t, err, "SendRawTransaction()")
+	assert.Equal(t, hash[2:], txID.String())
+	assert.Equal(t, []interface{}{"0xf86c"}, (*requests)[0].Params)
+}
+
+func TestSendRawTransaction_Error(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"eth_sendRawTransaction": &Error{Code: -32000, Message: "nonce too low"},
+	})
+	defer server.Close()
+
+	_, err := New(&Config{URL: server.URL}).SendRawTransaction([]byte{0xf8})
+	assert.EqualError(t, err, "JSON-RPC error -32000: nonce too low")
+}
+
+func TestCall_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := New(&Config{URL: server.URL}).GasPrice()
+	assert.EqualError(t, err, "eth_gasPrice: node returned status 404")
+}
+
+func TestCall_InvalidResult(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"eth_gasPrice": "price",
+	})
+	defer server.Close()
+
+	_, err := New(&Config{URL: server.URL}).GasPrice()
+	assert.EqualError(t, err, `invalid gas price "price"`)
+}
+
+func TestFindTransaction(t *testing.T) {
+	// The signed transaction of the EIP-155 example.
+	hash := "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788"
+	server, requests := createNode(t, map[string]interface{}{
+		"eth_getTransactionByHash": map[string]interface{}{
+			"hash":     hash,
+			"nonce":    "0x9",
+			"gasPrice": "0x4a817c800",
+			"gas":      "0x5208",
+			"to":       "0x3535353535353535353535353535353535353535",
+			"value":    "0xde0b6b3a7640000",
+			"input":    "0x",
+			"v":        "0x25",
+			"r":        "0x28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276",
+			"s":        "0x67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+		},
+	})
+	defer server.Close()
+
+	txID, err := hex.DecodeString(hash[2:])
+	require.NoError(t, err)
+
+	tx, err := New(&Config{URL: server.URL}).FindTransaction(txID)
+	require.NoError(t, err, "FindTransaction()")
+	assert.Equal(t, uint64(9), tx.Nonce)
+	assert.Equal(t, "3535353535353535353535353535353535353535", tx.To.String())
+	assert.Empty(t, tx.Data)
+	assert.Equal(t, hash[2:], tx.Hash().String())
+	assert.Equal(t, []interface{}{hash}, (*requests)[0].Params)
+}
+
+func TestFindTransaction_NotFound(t *testing.T) {
+	server, _ := createNode(t, map[string]interface{}{
+		"eth_getTransactionByHash": json.RawMessage("null"),
+	})
+	defer server.Close()
+
+	_, err := New(&Config{URL: server.URL}).FindTransaction(types.TransactionID{0x42})
+	assert.EqualError(t, err, "transaction 42 not found")
+}
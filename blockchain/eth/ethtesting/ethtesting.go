@@ -0,0 +1,150 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethtesting defines helpers to test Ethereum.
+package ethtesting
+
+import (
+	"math/big"
+
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Mock is used to mock an Ethereum JSON-RPC client.
+//
+// It implements github.com/stratumn/go-indigocore/blockchain/eth.Client and
+// github.com/stratumn/go-indigocore/blockchain/eth.TransactionFinder.
+type Mock struct {
+	// The mock for the PendingNonce function.
+	MockPendingNonce MockPendingNonce
+
+	// The mock for the GasPrice function.
+	MockGasPrice MockGasPrice
+
+	// The mock for the SendRawTransaction function.
+	MockSendRawTransaction MockSendRawTransaction
+
+	// The mock for the FindTransaction function.
+	MockFindTransaction MockFindTransaction
+}
+
+// MockPendingNonce mocks the PendingNonce function.
+type MockPendingNonce struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// The address that was passed to each call.
+	CalledWith []*types.Bytes20
+
+	// The last address that was passed.
+	LastCalledWith *types.Bytes20
+
+	// An optional implementation of the function.
+	Fn func(*types.Bytes20) (uint64, error)
+}
+
+// MockGasPrice mocks the GasPrice function.
+type MockGasPrice struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// An optional implementation of the function.
+	Fn func() (*big.Int, error)
+}
+
+// MockSendRawTransaction mocks the SendRawTransaction function.
+type MockSendRawTransaction struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// The transaction that was passed to each call.
+	CalledWith [][]byte
+
+	// The last transaction that was passed.
+	LastCalledWith []byte
+
+	// An optional implementation of the function.
+	Fn func([]byte) (types.TransactionID, error)
+}
+
+// MockFindTransaction mocks the FindTransaction function.
+type MockFindTransaction struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// The transaction ID that was passed to each call.
+	CalledWith []types.TransactionID
+
+	// The last transaction ID that was passed.
+	LastCalledWith types.TransactionID
+
+	// An optional implementation of the function.
+	Fn func(types.TransactionID) (*eth.SignedTransaction, error)
+}
+
+// PendingNonce implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.PendingNonce.
+func (a *Mock) PendingNonce(address *types.Bytes20) (uint64, error) {
+	a.MockPendingNonce.CalledCount++
+	a.MockPendingNonce.CalledWith = append(a.MockPendingNonce.CalledWith, address)
+	a.MockPendingNonce.LastCalledWith = address
+
+	if a.MockPendingNonce.Fn != nil {
+		return a.MockPendingNonce.Fn(address)
+	}
+
+	return 0, nil
+}
+
+// GasPrice implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.GasPrice.
+func (a *Mock) GasPrice() (*big.Int, error) {
+	a.MockGasPrice.CalledCount++
+
+	if a.MockGasPrice.Fn != nil {
+		return a.MockGasPrice.Fn()
+	}
+
+	return big.NewInt(1), nil
+}
+
+// SendRawTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/eth.Client.SendRawTransaction.
+// By default it returns the hash of the transaction.
+func (a *Mock) SendRawTransaction(raw []byte) (types.TransactionID, error) {
+	a.MockSendRawTransaction.CalledCount++
+	a.MockSendRawTransaction.CalledWith = append(a.MockSendRawTransaction.CalledWith, raw)
+	a.MockSendRawTransaction.LastCalledWith = raw
+
+	if a.MockSendRawTransaction.Fn != nil {
+		return a.MockSendRawTransaction.Fn(raw)
+	}
+
+	return eth.Keccak256(raw), nil
+}
+
+// FindTransaction implements
+// github.com/stratumn/go-indigocore/blockchain/eth.TransactionFinder.FindTransaction.
+func (a *Mock) FindTransaction(txID types.TransactionID) (*eth.SignedTransaction, error) {
+	a.MockFindTransaction.CalledCount++
+	a.MockFindTransaction.CalledWith = append(a.MockFindTransaction.CalledWith, txID)
+	a.MockFindTransaction.LastCalledWith = txID
+
+	if a.MockFindTransaction.Fn != nil {
+		return a.MockFindTransaction.Fn(txID)
+	}
+
+	return nil, nil
+}
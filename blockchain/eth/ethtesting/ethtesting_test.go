@@ -0,0 +1,129 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtesting
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+func TestMockPendingNonce(t *testing.T) {
+	a := &Mock{}
+
+	var addr1 types.Bytes20
+	copy(addr1[:], testutil.RandomHash()[:])
+	if nonce, err := a.PendingNonce(&addr1); err != nil || nonce != 0 {
+		t.Fatalf("a.PendingNonce(): nonce = %d, err = %v want 0, nil", nonce, err)
+	}
+
+	a.MockPendingNonce.Fn = func(*types.Bytes20) (uint64, error) { return 42, nil }
+
+	var addr2 types.Bytes20
+	copy(addr2[:], testutil.RandomHash()[:])
+	if nonce, err := a.PendingNonce(&addr2); err != nil || nonce != 42 {
+		t.Errorf("a.PendingNonce(): nonce = %d, err = %v want 42, nil", nonce, err)
+	}
+
+	if got, want := a.MockPendingNonce.CalledCount, 2; got != want {
+		t.Errorf(`a.MockPendingNonce.CalledCount = %d want %d`, got, want)
+	}
+	if got, want := a.MockPendingNonce.CalledWith, []*types.Bytes20{&addr1, &addr2}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockPendingNonce.CalledWith = %q want %q`, got, want)
+	}
+	if got, want := a.MockPendingNonce.LastCalledWith, &addr2; got != want {
+		t.Errorf(`a.MockPendingNonce.LastCalledWith = %q want %q`, got, want)
+	}
+}
+
+func TestMockGasPrice(t *testing.T) {
+	a := &Mock{}
+
+	if price, err := a.GasPrice(); err != nil || price.Int64() != 1 {
+		t.Fatalf("a.GasPrice(): price = %v, err = %v want 1, nil", price, err)
+	}
+
+	a.MockGasPrice.Fn = func() (*big.Int, error) { return nil, errors.New("error") }
+
+	if _, err := a.GasPrice(); err == nil {
+		t.Error("a.GasPrice(): err = nil want Error")
+	}
+
+	if got, want := a.MockGasPrice.CalledCount, 2; got != want {
+		t.Errorf(`a.MockGasPrice.CalledCount = %d want %d`, got, want)
+	}
+}
+
+func TestMockSendRawTransaction(t *testing.T) {
+	a := &Mock{}
+
+	tx1 := testutil.RandomHash()[:]
+	txID, err := a.SendRawTransaction(tx1)
+	if err != nil {
+		t.Errorf("a.SendRawTransaction(): err: %s", err)
+	}
+	if got, want := txID.String(), types.TransactionID(eth.Keccak256(tx1)).String(); got != want {
+		t.Errorf("a.SendRawTransaction(): txID = %s want %s", got, want)
+	}
+
+	a.MockSendRawTransaction.Fn = func([]byte) (types.TransactionID, error) { return nil, errors.New("error") }
+
+	tx2 := testutil.RandomHash()[:]
+	if _, err := a.SendRawTransaction(tx2); err == nil {
+		t.Error("a.SendRawTransaction(): err = nil want Error")
+	}
+
+	if got, want := a.MockSendRawTransaction.CalledCount, 2; got != want {
+		t.Errorf(`a.MockSendRawTransaction.CalledCount = %d want %d`, got, want)
+	}
+	if got, want := a.MockSendRawTransaction.CalledWith, [][]byte{tx1, tx2}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockSendRawTransaction.CalledWith = %q want %q`, got, want)
+	}
+	if got, want := a.MockSendRawTransaction.LastCalledWith, tx2; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockSendRawTransaction.LastCalledWith = %q want %q`, got, want)
+	}
+}
+
+func TestMockFindTransaction(t *testing.T) {
+	a := &Mock{}
+
+	txID1 := types.TransactionID(testutil.RandomHash()[:])
+	if tx, err := a.FindTransaction(txID1); err != nil || tx != nil {
+		t.Fatalf("a.FindTransaction(): tx = %v, err = %v want nil, nil", tx, err)
+	}
+
+	tx := &eth.SignedTransaction{}
+	a.MockFindTransaction.Fn = func(types.TransactionID) (*eth.SignedTransaction, error) { return tx, nil }
+
+	txID2 := types.TransactionID(testutil.RandomHash()[:])
+	if got, err := a.FindTransaction(txID2); err != nil || got != tx {
+		t.Errorf("a.FindTransaction(): tx = %v, err = %v want %v, nil", got, err, tx)
+	}
+
+	if got, want := a.MockFindTransaction.CalledCount, 2; got != want {
+		t.Errorf(`a.MockFindTransaction.CalledCount = %d want %d`, got, want)
+	}
+	if got, want := a.MockFindTransaction.CalledWith, []types.TransactionID{txID1, txID2}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindTransaction.CalledWith = %q want %q`, got, want)
+	}
+	if got, want := a.MockFindTransaction.LastCalledWith, txID2; !reflect.DeepEqual(got, want) {
+		t.Errorf(`a.MockFindTransaction.LastCalledWith = %q want %q`, got, want)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtimestamper
+
+import (
+	"flag"
+	"math/big"
+	"strings"
+
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	chainID  int64
+	contract string
+	gasLimit uint64
+	gasPrice string
+)
+
+// RegisterFlags registers the flags used by InitializeWithFlags.
+func RegisterFlags() {
+	flag.Int64Var(&chainID, "chainid", DefaultChainID, "EIP-155 chain ID of the Ethereum network")
+	flag.StringVar(&contract, "contract", "", "address of an anchoring contract (hashes are sent to self if empty)")
+	flag.Uint64Var(&gasLimit, "gaslimit", DefaultGasLimit, "transaction gas limit")
+	flag.StringVar(&gasPrice, "gasprice", "", "transaction gas price in wei (suggested by the node if empty)")
+}
+
+// InitializeWithFlags should be called after RegisterFlags and flag.Parse to initialize
+// an Ethereum timestamper using flag values.
+func InitializeWithFlags(version, commit string, key string, client eth.Client) *Timestamper {
+	config := &Config{
+		Client:     client,
+		PrivateKey: key,
+		ChainID:    chainID,
+		GasLimit:   gasLimit,
+	}
+
+	if contract != "" {
+		addr, err := types.NewBytes20FromString(strings.TrimPrefix(contract, "0x"))
+		if err != nil {
+			log.WithField("error", err).Fatal("Failed to parse contract address")
+		}
+		config.Contract = addr
+	}
+
+	if gasPrice != "" {
+		price, ok := new(big.Int).SetString(gasPrice, 10)
+		if !ok {
+			log.WithField("gasprice", gasPrice).Fatal("Failed to parse gas price")
+		}
+		config.GasPrice = price
+	}
+
+	ts, err := New(config)
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to create Ethereum timestamper")
+	}
+	return ts
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethtimestamper implements a timestamper for the Ethereum
+// blockchain.
+//
+// The hash is either sent in the data of a transaction to the address of the
+// timestamper itself, or passed to the anchor method of a contract (see
+// eth.AnchorMethod).
+package ethtimestamper
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/blockchain"
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// DefaultChainID is the default EIP-155 chain ID (Ropsten).
+	DefaultChainID = int64(3)
+
+	// DefaultGasLimit is the default gas limit of a transaction.
+	DefaultGasLimit = uint64(60000)
+
+	// Description describes this Timestamper
+	Description = "Ethereum Timestamper"
+)
+
+// Config contains configuration options for the timestamper.
+type Config struct {
+	// A JSON-RPC client.
+	Client eth.Client
+
+	// A hex encoded private key.
+	PrivateKey string
+
+	// The EIP-155 chain ID.
+	ChainID int64
+
+	// An optional anchoring contract. If nil, hashes are sent in the data of
+	// a transaction to the address of the timestamper.
+	Contract *types.Bytes20
+
+	// The gas limit of a transaction. If zero, DefaultGasLimit is used.
+	GasLimit uint64
+
+	// An optional gas price in wei. If nil, the price suggested by the node
+	// is used.
+	GasPrice *big.Int
+}
+
+// Timestamper is the type that implements
+// github.com/stratumn/go-indigocore/blockchain.HashTimestamper.
+type Timestamper struct {
+	config   *Config
+	net      eth.Network
+	privKey  *btcec.PrivateKey
+	address  *types.Bytes20
+	gasLimit uint64
+
+	mutex sync.Mutex
+	nonce uint64
+}
+
+// New creates an instance of a Timestamper.
+func New(config *Config) (*Timestamper, error) {
+	privKey, err := eth.ParsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ChainID <= 0 {
+		return nil, errors.New("invalid chain ID")
+	}
+
+	gasLimit := config.GasLimit
+	if gasLimit == 0 {
+		gasLimit = DefaultGasLimit
+	}
+
+	return &Timestamper{
+		config:   config,
+		net:      eth.NetworkFromChainID(config.ChainID),
+		privKey:  privKey,
+		address:  eth.PublicKeyAddress(privKey.PubKey()),
+		gasLimit: gasLimit,
+	}, nil
+}
+
+// Address returns the address transactions are sent from.
+func (ts *Timestamper) Address() *types.Bytes20 {
+	return ts.address
+}
+
+// Network returns the Ethereum network of the timestamper.
+func (ts *Timestamper) Network() blockchain.Network {
+	return ts.net
+}
+
+// GetInfo implements
+// github.com/stratumn/go-indigocore/blockchain.HashTimestamper.
+func (ts *Timestamper) GetInfo() *blockchain.Info {
+	return &blockchain.Info{
+		Network:     ts.net,
+		Description: Description,
+	}
+}
+
+// TimestampHash implements
+// github.com/stratumn/go-indigocore/blockchain.HashTimestamper.
+func (ts *Timestamper) TimestampHash(hash *types.Bytes32) (types.TransactionID, error) {
+	gasPrice := ts.config.GasPrice
+	if gasPrice == nil {
+		var err error
+		if gasPrice, err = ts.config.Client.GasPrice(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx := &eth.Transaction{
+		GasPrice: gasPrice,
+		GasLimit: ts.gasLimit,
+		To:       ts.address,
+		Value:    new(big.Int),
+		Data:     hash[:],
+	}
+	if ts.config.Contract != nil {
+		tx.To = ts.config.Contract
+		tx.Data = eth.AnchorCallData(hash)
+	}
+
+	// The lock is held until the transaction is sent so that concurrent
+	// calls never reuse a nonce.
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	nonce, err := ts.config.Client.PendingNonce(ts.address)
+	if err != nil {
+		return nil, err
+	}
+
+	// The node may not know about transactions that were just sent.
+	if nonce < ts.nonce {
+		nonce = ts.nonce
+	}
+	tx.Nonce = nonce
+
+	raw, txID, err := tx.Sign(ts.privKey, ts.config.ChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ts.config.Client.SendRawTransaction(raw); err != nil {
+		// Let the node decide which nonce to use next time.
+		ts.nonce = 0
+		return nil, err
+	}
+
+	ts.nonce = nonce + 1
+
+	return txID, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtimestamper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/blockchain/eth"
+	"github.com/stratumn/go-indigocore/blockchain/eth/ethtesting"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Private key of the EIP-155 example.
+const testKey = "4646464646464646464646464646464646464646464646464646464646464646"
+
+func createTimestamper(t *testing.T, mock *ethtesting.Mock, contract *types.Bytes20) *Timestamper {
+	ts, err := New(&Config{
+		Client:     mock,
+		PrivateKey: testKey,
+		ChainID:    1,
+		Contract:   contract,
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+	return ts
+}
+
+func TestNew_InvalidKey(t *testing.T) {
+	if _, err := New(&Config{PrivateKey: "zz", ChainID: 1}); err != eth.ErrBadPrivateKey {
+		t.Errorf("New(): err = %v want %v", err, eth.ErrBadPrivateKey)
+	}
+}
+
+func TestNew_InvalidChainID(t *testing.T) {
+	if _, err := New(&Config{PrivateKey: testKey}); err == nil {
+		t.Error("New(): err = nil want Error")
+	}
+}
+
+func TestNew_GasLimit(t *testing.T) {
+	ts, err := New(&Config{PrivateKey: testKey, ChainID: 1, GasLimit: 100000})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+	if got, want := ts.gasLimit, uint64(100000); got != want {
+		t.Errorf("ts.gasLimit = %d want %d", got, want)
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	ts := createTimestamper(t, &ethtesting.Mock{}, nil)
+
+	if got, want := ts.Network(), eth.NetworkMain; got != want {
+		t.Errorf("ts.Network() = %q want %q", got, want)
+	}
+	if got, want := ts.GetInfo().Description, Description; got != want {
+		t.Errorf("ts.GetInfo().Description = %q want %q", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash(t *testing.T) {
+	mock := &ethtesting.Mock{}
+	mock.MockPendingNonce.Fn = func(*types.Bytes20) (uint64, error) { return 9, nil }
+	mock.MockGasPrice.Fn = func() (*big.Int, error) { return big.NewInt(20000000000), nil }
+
+	ts := createTimestamper(t, mock, nil)
+	hash := testutil.RandomHash()
+
+	txID, err := ts.TimestampHash(hash)
+	if err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+
+	if got, want := mock.MockPendingNonce.LastCalledWith.String(), ts.Address().String(); got != want {
+		t.Errorf("mock.MockPendingNonce.LastCalledWith = %s want %s", got, want)
+	}
+	if got, want := mock.MockSendRawTransaction.CalledCount, 1; got != want {
+		t.Fatalf("mock.MockSendRawTransaction.CalledCount = %d want %d", got, want)
+	}
+
+	tx := &eth.Transaction{
+		Nonce:    9,
+		GasPrice: big.NewInt(20000000000),
+		GasLimit: DefaultGasLimit,
+		To:       ts.Address(),
+		Value:    new(big.Int),
+		Data:     hash[:],
+	}
+	raw, wantID, _ := tx.Sign(ts.privKey, 1)
+
+	if got, want := mock.MockSendRawTransaction.LastCalledWith, raw; string(got) != string(want) {
+		t.Errorf("mock.MockSendRawTransaction.LastCalledWith = %x want %x", got, want)
+	}
+	if got, want := txID.String(), wantID.String(); got != want {
+		t.Errorf("ts.TimestampHash() = %s want %s", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash_Contract(t *testing.T) {
+	mock := &ethtesting.Mock{}
+
+	var contract types.Bytes20
+	copy(contract[:], testutil.RandomHash()[:])
+
+	ts, err := New(&Config{
+		Client:     mock,
+		PrivateKey: testKey,
+		ChainID:    3,
+		Contract:   &contract,
+		GasLimit:   DefaultGasLimit,
+		GasPrice:   big.NewInt(42),
+	})
+	if err != nil {
+		t.Fatalf("New(): err: %s", err)
+	}
+
+	hash := testutil.RandomHash()
+	if _, err := ts.TimestampHash(hash); err != nil {
+		t.Fatalf("ts.TimestampHash(): err: %s", err)
+	}
+
+	if got, want := mock.MockGasPrice.CalledCount, 0; got != want {
+		t.Errorf("mock.MockGasPrice.CalledCount = %d want %d", got, want)
+	}
+
+	tx := &eth.Transaction{
+		GasPrice: big.NewInt(42),
+		GasLimit: DefaultGasLimit,
+		To:       &contract,
+		Value:    new(big.Int),
+		Data:     eth.AnchorCallData(hash),
+	}
+	raw, _, _ := tx.Sign(ts.privKey, 3)
+
+	if got, want := mock.MockSendRawTransaction.LastCalledWith, raw; string(got) != string(want) {
+		t.Errorf("mock.MockSendRawTransaction.LastCalledWith = %x want %x", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash_Nonce(t *testing.T) {
+	mock := &ethtesting.Mock{}
+	ts := createTimestamper(t, mock, nil)
+
+	// The node keeps returning the same pending nonce.
+	for i := uint64(0); i < 3; i++ {
+		if _, err := ts.TimestampHash(testutil.RandomHash()); err != nil {
+			t.Fatalf("ts.TimestampHash(): err: %s", err)
+		}
+		if got, want := ts.nonce, i+1; got != want {
+			t.Errorf("ts.nonce = %d want %d", got, want)
+		}
+	}
+
+	mock.MockSendRawTransaction.Fn = func([]byte) (types.TransactionID, error) {
+		return nil, errors.New("nonce too low")
+	}
+
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err == nil {
+		t.Fatal("ts.TimestampHash(): err = nil want Error")
+	}
+	if got, want := ts.nonce, uint64(0); got != want {
+		t.Errorf("ts.nonce = %d want %d", got, want)
+	}
+}
+
+func TestTimestamperTimestampHash_GasPriceError(t *testing.T) {
+	mock := &ethtesting.Mock{}
+	mock.MockGasPrice.Fn = func() (*big.Int, error) { return nil, errors.New("error") }
+
+	ts := createTimestamper(t, mock, nil)
+	if _, err := ts.TimestampHash(testutil.RandomHash()); err == nil {
+		t.Error("ts.TimestampHash(): err = nil want Error")
+	}
+	if got, want := mock.MockSendRawTransaction.CalledCount, 0; got != want {
+		t.Errorf("mock.MockSendRawTransaction.CalledCount = %d want %d", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"encoding/binary"
+	"math/big"
+)
+
+// This file implements the subset of the Recursive Length Prefix encoding
+// needed to encode transactions.
+
+// rlpBytes encodes a byte string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpUint encodes an unsigned integer.
+func rlpUint(n uint64) []byte {
+	return rlpBytes(bigEndian(n))
+}
+
+// rlpBigInt encodes a positive big integer.
+func rlpBigInt(n *big.Int) []byte {
+	if n == nil {
+		return rlpBytes(nil)
+	}
+
+	return rlpBytes(n.Bytes())
+}
+
+// rlpList encodes a list of encoded items.
+func rlpList(items ...[]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+
+	return append(rlpHeader(0xc0, len(payload)), payload...)
+}
+
+// rlpHeader encodes the prefix of a string or list.
+func rlpHeader(offset byte, length int) []byte {
+	if length <= 55 {
+		return []byte{offset + byte(length)}
+	}
+
+	l := bigEndian(uint64(length))
+	return append([]byte{offset + 55 + byte(len(l))}, l...)
+}
+
+// bigEndian returns the big endian representation of an integer without
+// leading zeros.
+func bigEndian(n uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+
+	return b[i:]
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Transaction is an Ethereum transaction.
+type Transaction struct {
+	Nonce    uint64
+	GasPrice *big.Int
+	GasLimit uint64
+
+	// The recipient of the transaction, nil for a contract creation.
+	To *types.Bytes20
+
+	Value *big.Int
+	Data  []byte
+}
+
+// SignedTransaction is an Ethereum transaction and its signature.
+type SignedTransaction struct {
+	Transaction
+
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// Raw returns the raw transaction.
+func (tx *SignedTransaction) Raw() []byte {
+	fields := append(tx.fields(), rlpBigInt(tx.V), rlpBigInt(tx.R), rlpBigInt(tx.S))
+	return rlpList(fields...)
+}
+
+// Hash returns the hash of the transaction, which is its ID.
+func (tx *SignedTransaction) Hash() types.TransactionID {
+	return Keccak256(tx.Raw())
+}
+
+// fields returns the RLP encoded fields of the transaction.
+func (tx *Transaction) fields() [][]byte {
+	to := rlpBytes(nil)
+	if tx.To != nil {
+		to = rlpBytes(tx.To[:])
+	}
+
+	return [][]byte{
+		rlpUint(tx.Nonce),
+		rlpBigInt(tx.GasPrice),
+		rlpUint(tx.GasLimit),
+		to,
+		rlpBigInt(tx.Value),
+		rlpBytes(tx.Data),
+	}
+}
+
+// SigningHash returns the hash signed by the sender of the transaction, as
+// defined by EIP-155.
+func (tx *Transaction) SigningHash(chainID int64) []byte {
+	fields := append(tx.fields(), rlpUint(uint64(chainID)), rlpUint(0), rlpUint(0))
+	return Keccak256(rlpList(fields...))
+}
+
+// Sign signs the transaction for the given chain ID.
+// It returns the raw transaction and its hash.
+func (tx *Transaction) Sign(key *btcec.PrivateKey, chainID int64) ([]byte, types.TransactionID, error) {
+	// A compact signature contains the recovery ID followed by R and S.
+	sig, err := btcec.SignCompact(btcec.S256(), key, tx.SigningHash(chainID), false)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	recoveryID := uint64(sig[0] - 27)
+	signed := &SignedTransaction{
+		Transaction: *tx,
+		V:           new(big.Int).SetUint64(recoveryID + uint64(chainID)*2 + 35),
+		R:           new(big.Int).SetBytes(sig[1:33]),
+		S:           new(big.Int).SetBytes(sig[33:65]),
+	}
+	raw := signed.Raw()
+
+	return raw, Keccak256(raw), nil
+}
@@ -16,6 +16,8 @@ package bufferedbatch
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/monitoring"
@@ -27,17 +29,41 @@ import (
 	"go.opencensus.io/trace"
 )
 
+// WriteError is returned by Write when the store fails to save a link.
+// Links are saved one at a time, so the links that come before it in the
+// batch are saved and the others are not.
+type WriteError struct {
+	// Written is the number of links that were saved.
+	Written int
+
+	Err error
+}
+
+// Error implements error.Error.
+func (e *WriteError) Error() string {
+	return e.Err.Error()
+}
+
+// Store is what a batch reads segments from and writes its links to. Both
+// github.com/stratumn/go-indigocore/store.Adapter and
+// github.com/stratumn/go-indigocore/store.Batch are stores, so a batch can
+// stage links on top of another batch.
+type Store interface {
+	store.SegmentReader
+	store.LinkWriter
+}
+
 // Batch can be used as a base class for types
 // that want to implement github.com/stratumn/go-indigocore/store.Batch.
 // All operations are stored in arrays and can be replayed.
 // Only the Write method must be implemented.
 type Batch struct {
-	originalStore store.Adapter
+	originalStore Store
 	Links         []*cs.Link
 }
 
 // NewBatch creates a new Batch.
-func NewBatch(ctx context.Context, a store.Adapter) *Batch {
+func NewBatch(ctx context.Context, a Store) *Batch {
 	stats.Record(ctx, batchCount.M(1))
 	return &Batch{originalStore: a}
 }
@@ -84,12 +110,30 @@ func (b *Batch) FindSegments(ctx context.Context, filter *store.SegmentFilter) (
 		return segments, err
 	}
 
+	// The store returns its segments in order and buffered links are the
+	// last ones created, so creation times are derived from positions.
+	now := time.Now()
+	times := make(map[*cs.Segment]time.Time, len(segments))
+	for i, segment := range segments {
+		if filter.Sort != nil && filter.Sort.Desc {
+			times[segment] = now.Add(-time.Duration(i + 1))
+		} else {
+			times[segment] = now.Add(-time.Duration(len(segments) - i))
+		}
+	}
+
 	for _, link := range b.Links {
 		if filter.MatchLink(link) {
-			segments = append(segments, link.Segmentify())
+			segment := link.Segmentify()
+			segments = append(segments, segment)
+			times[segment] = now
 		}
 	}
 
+	filter.SortSegments(segments, func(segment *cs.Segment) time.Time {
+		return times[segment]
+	})
+
 	return filter.Pagination.PaginateSegments(segments), nil
 }
 
@@ -118,20 +162,23 @@ func (b *Batch) GetMapIDs(ctx context.Context, filter *store.MapFilter) (_ []str
 	for k := range mapIDs {
 		ids = append(ids, k)
 	}
+	sort.Strings(ids)
 
 	return filter.Pagination.PaginateStrings(ids), err
 }
 
 // Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+// It is not atomic: if the store fails to save a link, it returns a
+// WriteError and the links before it stay saved.
 func (b *Batch) Write(ctx context.Context) (err error) {
 	ctx, span := trace.StartSpan(ctx, "bufferedbatch/Write")
 	defer monitoring.SetSpanStatusAndEnd(span, err)
 
 	stats.Record(ctx, linksPerBatch.M(int64(len(b.Links))))
 
-	for _, link := range b.Links {
-		_, err = b.originalStore.CreateLink(ctx, link)
-		if err != nil {
+	for i, link := range b.Links {
+		if _, e := b.originalStore.CreateLink(ctx, link); e != nil {
+			err = &WriteError{Written: i, Err: e}
 			break
 		}
 	}
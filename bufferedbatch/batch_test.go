@@ -26,6 +26,7 @@ import (
 	"github.com/stratumn/go-indigocore/testutil"
 	"github.com/stratumn/go-indigocore/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBatch_CreateLink(t *testing.T) {
@@ -254,4 +255,6 @@ func TestBatch_WriteLinkWithFailure(t *testing.T) {
 
 	err = batch.Write(ctx)
 	assert.EqualError(t, err, mockError.Error())
+	require.IsType(t, &WriteError{}, err)
+	assert.Equal(t, 0, err.(*WriteError).Written)
 }
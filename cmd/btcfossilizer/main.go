@@ -24,6 +24,8 @@ import (
 	"github.com/stratumn/go-indigocore/utils"
 
 	"github.com/stratumn/go-indigocore/bcbatchfossilizer"
+	"github.com/stratumn/go-indigocore/blockchain/btc"
+	"github.com/stratumn/go-indigocore/blockchain/btc/bitcoind"
 	"github.com/stratumn/go-indigocore/blockchain/btc/blockcypher"
 	"github.com/stratumn/go-indigocore/blockchain/btc/btctimestamper"
 )
@@ -35,9 +37,18 @@ var (
 	commit  = "00000000000000000000000000000000"
 )
 
+// backend is the Bitcoin API used by the fossilizer.
+type backend interface {
+	btc.UnspentFinder
+	btc.Broadcaster
+	btc.FeeEstimator
+	btc.ConfirmationFinder
+}
+
 func init() {
 	fossilizerhttp.RegisterFlags()
 	blockcypher.RegisterFlags()
+	bitcoind.RegisterFlags()
 	btctimestamper.RegisterFlags()
 	bcbatchfossilizer.RegisterFlags()
 	monitoring.RegisterFlags()
@@ -49,10 +60,16 @@ func main() {
 	ctx := context.Background()
 	ctx = utils.CancelOnInterrupt(ctx)
 
-	bcy := blockcypher.RunWithFlags(ctx, *key)
-	ts := btctimestamper.InitializeWithFlags(version, commit, *key, bcy, bcy)
+	var api backend
+	if bitcoind.Enabled() {
+		api = bitcoind.InitializeWithFlags(*key)
+	} else {
+		api = blockcypher.RunWithFlags(ctx, *key)
+	}
+
+	ts := btctimestamper.InitializeWithFlags(version, commit, *key, api, api, api)
 	a := monitoring.NewFossilizerAdapter(
-		bcbatchfossilizer.RunWithFlags(ctx, version, commit, ts),
+		bcbatchfossilizer.RunWithFlags(ctx, version, commit, ts, api),
 		"bcbatchfossilizer",
 	)
 	fossilizerhttp.RunWithFlags(ctx, a)
@@ -44,7 +44,7 @@ func main() {
 	ctx = utils.CancelOnInterrupt(ctx)
 
 	a := monitoring.NewFossilizerAdapter(
-		bcbatchfossilizer.RunWithFlags(ctx, version, commit, dummytimestamper.Timestamper{}),
+		bcbatchfossilizer.RunWithFlags(ctx, version, commit, dummytimestamper.Timestamper{}, nil),
 		"dummybatchfossilizer",
 	)
 	fossilizerhttp.RunWithFlags(ctx, a)
@@ -0,0 +1,61 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The command leveldbtmpop starts a tmpop node with a LevelDB store.
+package main
+
+import (
+	"flag"
+
+	log "github.com/sirupsen/logrus"
+	_ "github.com/stratumn/go-indigocore/fossilizer/evidences"
+	"github.com/stratumn/go-indigocore/leveldbstore"
+	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/tendermint"
+	"github.com/stratumn/go-indigocore/tmpop"
+	"github.com/stratumn/go-indigocore/validation"
+)
+
+var (
+	path    = flag.String("path", leveldbstore.DefaultPath, "Path to directory where the database is stored")
+	version = "x.x.x"
+	commit  = "00000000000000000000000000000000"
+)
+
+func init() {
+	tendermint.RegisterFlags()
+	monitoring.RegisterFlags()
+	validation.RegisterFlags()
+}
+
+func main() {
+	flag.Parse()
+
+	a, err := leveldbstore.New(&leveldbstore.Config{Path: *path, Version: version, Commit: commit})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tmpopConfig := &tmpop.Config{
+		Commit:     commit,
+		Version:    version,
+		Validation: validation.ConfigurationFromFlags(),
+		Monitoring: monitoring.ConfigurationFromFlags(),
+	}
+	tmpop.Run(
+		monitoring.NewStoreAdapter(a, "leveldbstore"),
+		monitoring.NewKeyValueStoreAdapter(a, "leveldbstore"),
+		tmpopConfig,
+	)
+}
@@ -0,0 +1,224 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The command storeverifier checks all the segments of a process or a map
+// and outputs a JSON report.
+//
+// It re-hashes every link, validates links (including signatures and
+// references), checks that previous links exist, detects orphans and forks
+// and verifies the proofs of evidences.
+//
+// Bitcoin proofs are only valid if their transaction carries their Merkle
+// root, which is checked using a Bitcoin Core node. The same goes for
+// Ethereum proofs, which are checked using an Ethereum node. Timestamping
+// proofs are only valid if their token was issued by an authority trusted by
+// the given root certificates. Generic proofs are only valid if they are signed with
+// one of the given trusted public keys.
+//
+// The command exits with status 1 if the report contains errors.
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	bcbatchevidences "github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/blockchain/btc/bitcoind"
+	"github.com/stratumn/go-indigocore/blockchain/eth/ethrpc"
+	"github.com/stratumn/go-indigocore/couchstore"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/elasticsearchstore"
+	"github.com/stratumn/go-indigocore/filestore"
+	_ "github.com/stratumn/go-indigocore/fossilizer/evidences"
+	"github.com/stratumn/go-indigocore/leveldbstore"
+	"github.com/stratumn/go-indigocore/postgresstore"
+	"github.com/stratumn/go-indigocore/rethinkstore"
+	"github.com/stratumn/go-indigocore/sqlitestore"
+	"github.com/stratumn/go-indigocore/store"
+	_ "github.com/stratumn/go-indigocore/tmpop/evidences"
+	tsaevidences "github.com/stratumn/go-indigocore/tsafossilizer/evidences"
+	"github.com/stratumn/go-indigocore/utils"
+)
+
+var (
+	storeType = flag.String("store", "file", "type of the store: file, leveldb, sqlite, postgres, rethink, couch or elasticsearch")
+	path      = flag.String("path", "", "path to the directory of a file or LevelDB store, or to the database file of a SQLite store")
+	url       = flag.String("url", "", "URL of the database of the store")
+	db        = flag.String("db", rethinkstore.DefaultDB, "name of the RethinkDB database")
+	process   = flag.String("process", "", "process of the segments to verify")
+	mapIDs    = flag.String("mapIds", "", "comma-separated map IDs of the segments to verify")
+	out       = flag.String("out", "", "file where the report is written (defaults to standard output)")
+
+	bitcoindURL      = flag.String("bitcoind", os.Getenv("BITCOIND_URL"), "URL of the JSON-RPC API of a Bitcoin Core node with a transaction index, used to verify Bitcoin proofs")
+	bitcoindUser     = flag.String("bitcoinduser", os.Getenv("BITCOIND_USER"), "user of the JSON-RPC API of the Bitcoin Core node")
+	bitcoindPassword = flag.String("bitcoindpassword", os.Getenv("BITCOIND_PASSWORD"), "password of the JSON-RPC API of the Bitcoin Core node")
+	ethereumURL      = flag.String("ethereum", os.Getenv("ETHEREUM_URL"), "URL of the JSON-RPC API of an Ethereum node, used to verify Ethereum proofs")
+	tsaRoots         = flag.String("tsaroots", "", "PEM file of the root certificates of the trusted timestamping authorities, used to verify timestamping proofs")
+	genericKeys      = flag.String("generickeys", "", "PEM file of the public keys of the trusted issuers of generic proofs")
+
+	version = "x.x.x"
+	commit  = "00000000000000000000000000000000"
+)
+
+func newAdapter() (store.Adapter, error) {
+	switch *storeType {
+	case "file":
+		return filestore.New(&filestore.Config{Path: utils.OrStrings(*path, filestore.DefaultPath), Version: version, Commit: commit})
+	case "leveldb":
+		return leveldbstore.New(&leveldbstore.Config{Path: utils.OrStrings(*path, leveldbstore.DefaultPath), Version: version, Commit: commit})
+	case "sqlite":
+		a, err := sqlitestore.New(&sqlitestore.Config{Path: utils.OrStrings(*path, sqlitestore.DefaultPath), Version: version, Commit: commit})
+		if err != nil {
+			return nil, err
+		}
+		return a, a.Prepare()
+	case "postgres":
+		a, err := postgresstore.New(&postgresstore.Config{URL: utils.OrStrings(*url, postgresstore.DefaultURL), Version: version, Commit: commit})
+		if err != nil {
+			return nil, err
+		}
+		return a, a.Prepare()
+	case "rethink":
+		return rethinkstore.New(&rethinkstore.Config{URL: utils.OrStrings(*url, rethinkstore.DefaultURL), DB: *db, Version: version, Commit: commit})
+	case "couch":
+		return couchstore.New(&couchstore.Config{Address: utils.OrStrings(*url, "http://localhost:5984"), Version: version, Commit: commit})
+	case "elasticsearch":
+		return elasticsearchstore.New(&elasticsearchstore.Config{URL: utils.OrStrings(*url, elasticsearchstore.DefaultURL), Version: version, Commit: commit})
+	default:
+		return nil, errors.Errorf("unknown store type %q", *storeType)
+	}
+}
+
+// newVerifiers returns the verifiers of the proofs that cannot be verified
+// on their own.
+func newVerifiers() (map[string]store.ProofVerifier, error) {
+	verifiers := map[string]store.ProofVerifier{}
+
+	if *bitcoindURL != "" {
+		finder := bitcoind.New(&bitcoind.Config{
+			URL:      *bitcoindURL,
+			User:     *bitcoindUser,
+			Password: *bitcoindPassword,
+		})
+		verifiers[bcbatchevidences.BcBatchFossilizerName] = bcbatchevidences.NewVerifier(finder)
+	} else {
+		log.Warn("Bitcoin proofs cannot be verified without a Bitcoin Core node")
+	}
+
+	if *ethereumURL != "" {
+		client := ethrpc.New(&ethrpc.Config{URL: *ethereumURL})
+		verifiers[bcbatchevidences.EthBatchFossilizerName] = bcbatchevidences.NewEthVerifier(client)
+	} else {
+		log.Warn("Ethereum proofs cannot be verified without an Ethereum node")
+	}
+
+	if *tsaRoots != "" {
+		pem, err := ioutil.ReadFile(*tsaRoots)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no root certificate found in %q", *tsaRoots)
+		}
+		verifiers[tsaevidences.TSAFossilizerName] = tsaevidences.NewVerifier(roots)
+	} else {
+		log.Warn("Timestamping proofs cannot be verified without trusted root certificates")
+	}
+
+	if *genericKeys != "" {
+		data, err := ioutil.ReadFile(*genericKeys)
+		if err != nil {
+			return nil, err
+		}
+		var publicKeys [][]byte
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			publicKeys = append(publicKeys, pem.EncodeToMemory(block))
+		}
+		if len(publicKeys) == 0 {
+			return nil, errors.Errorf("no public key found in %q", *genericKeys)
+		}
+		verifier, err := cs.NewGenericProofVerifier(publicKeys)
+		if err != nil {
+			return nil, err
+		}
+		verifiers[cs.GenericProofName] = verifier
+	} else {
+		log.Warn("Generic proofs are only checked against the public key they contain without trusted public keys")
+	}
+
+	return verifiers, nil
+}
+
+func main() {
+	flag.Parse()
+	log.Infof("Indigo's store verifier v%s@%s", version, commit[:7])
+
+	a, err := newAdapter()
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to create store")
+	}
+
+	filter := &store.SegmentFilter{Process: *process}
+	if *mapIDs != "" {
+		filter.MapIDs = strings.Split(*mapIDs, ",")
+	}
+
+	verifiers, err := newVerifiers()
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to create proof verifiers")
+	}
+
+	report, err := store.VerifySegments(context.Background(), a, filter, verifiers)
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to verify segments")
+	}
+
+	if err := writeReport(report); err != nil {
+		log.WithField("error", err).Fatal("Failed to write report")
+	}
+
+	if !report.Valid {
+		log.WithField("errors", len(report.Errors)).Error("Verification failed")
+		os.Exit(1)
+	}
+
+	log.WithField("segments", report.SegmentsCount).Info("Verification succeeded")
+}
+
+func writeReport(report *store.VerifyReport) error {
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(report)
+}
@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,6 +36,7 @@ const (
 	statusDBExists        = 412
 	statusDocumentMissing = 404
 	statusDBMissing       = 404
+	statusConflict        = 409
 
 	dbLink      = "pop_link"
 	dbEvidences = "pop_evidences"
@@ -41,6 +44,26 @@ const (
 
 	objectTypeLink = "link"
 	objectTypeMap  = "map"
+
+	// refsDesign is the design document of the view indexing links by the
+	// link hashes of their references.
+	refsDesign = "refs"
+	refsView   = "linkHashes"
+	refsMap    = `function (doc) {
+	if (doc.docType === "link" && doc.link.meta.refs) {
+		doc.link.meta.refs.forEach(function (ref) {
+			emit(ref.linkHash, null);
+		});
+	}
+}`
+
+	// sortKeysDoc is the local document recording that the sort keys of
+	// existing links were set.
+	sortKeysDoc = "_local/sortKeys"
+
+	// createdAtLayout formats the time at which links are saved so that
+	// the lexical order of the strings matches the chronological order.
+	createdAtLayout = "2006-01-02T15:04:05.000000000Z07:00"
 )
 
 // CouchResponseStatus contains couch specific response when querying the API.
@@ -62,7 +85,9 @@ type Document struct {
 	ObjectType string `json:"docType,omitempty"`
 
 	// The following fields are used when querying couchdb for link documents.
-	Link *cs.Link `json:"link,omitempty"`
+	Link      *cs.Link `json:"link,omitempty"`
+	CreatedAt string   `json:"createdAt,omitempty"`
+	SortKey   string   `json:"sortKey,omitempty"`
 
 	// The following fields are used when querying couchdb for evidences documents.
 	Evidences *cs.Evidences `json:"evidences,omitempty"`
@@ -172,6 +197,41 @@ func (c *CouchStore) CreateIndex(dbName string, indexName string, fields []strin
 	return nil
 }
 
+// CreateView creates a view in a design document if the design document
+// does not exist.
+func (c *CouchStore) CreateView(dbName string, designName string, viewName string, mapFunc string) error {
+	path := fmt.Sprintf("/%s/_design/%s", dbName, designName)
+
+	type viewDesc struct {
+		Map string `json:"map"`
+	}
+	type designDoc struct {
+		Views map[string]viewDesc `json:"views"`
+	}
+
+	payload := designDoc{
+		Views: map[string]viewDesc{viewName: {Map: mapFunc}},
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, couchResponseStatus, err := c.put(path, payloadBytes)
+	if err != nil {
+		return err
+	}
+
+	if !couchResponseStatus.Ok {
+		if couchResponseStatus.StatusCode != statusConflict {
+			return couchResponseStatus.error()
+		}
+	}
+
+	return nil
+}
+
 func (c *CouchStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 	linkHash, err := link.Hash()
 	if err != nil {
@@ -182,6 +242,8 @@ func (c *CouchStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 	linkDoc := &Document{
 		ObjectType: objectTypeLink,
 		Link:       link,
+		CreatedAt:  formatCreatedAt(time.Now()),
+		SortKey:    sortKey(link.Meta.Priority, linkHashStr),
 		ID:         linkHashStr,
 	}
 
@@ -205,6 +267,129 @@ func (c *CouchStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 	return linkHash, c.saveDocuments(dbLink, docs)
 }
 
+func formatCreatedAt(t time.Time) string {
+	return t.UTC().Format(createdAtLayout)
+}
+
+// sortKey returns a string whose lexical order matches the default order of
+// segments: descending priority, then ascending link hash.
+// CouchDB cannot sort on several fields in different directions, so the
+// priority is encoded so that higher priorities come first.
+func sortKey(priority float64, linkHash string) string {
+	if priority == 0 {
+		// Negative zero must not sort before positive zero.
+		priority = 0
+	}
+
+	bits := math.Float64bits(priority)
+	if bits&(1<<63) == 0 {
+		bits |= 1 << 63
+	} else {
+		bits = ^bits
+	}
+
+	return fmt.Sprintf("%016x%s", ^bits, linkHash)
+}
+
+// addSortKeys sets the sort key of the links saved before it existed.
+// Once done, a local document is saved so that links are not scanned again.
+func (c *CouchStore) addSortKeys() error {
+	done, err := c.getDocument(dbLink, sortKeysDoc)
+	if err != nil || done != nil {
+		return err
+	}
+
+	type sortKeyQuery struct {
+		Selector map[string]interface{} `json:"selector"`
+		Limit    int                    `json:"limit"`
+		Bookmark string                 `json:"bookmark,omitempty"`
+	}
+
+	query := sortKeyQuery{
+		Selector: map[string]interface{}{
+			"docType": objectTypeLink,
+			"sortKey": map[string]bool{"$exists": false},
+		},
+		Limit: findBatchSize,
+	}
+
+	for {
+		queryBytes, err := json.Marshal(query)
+		if err != nil {
+			return err
+		}
+
+		body, couchResponseStatus, err := c.post("/"+dbLink+"/_find", queryBytes)
+		if err != nil {
+			return err
+		}
+		if !couchResponseStatus.Ok {
+			return couchResponseStatus.error()
+		}
+
+		couchFindResponse := &CouchFindResponse{}
+		if err := json.Unmarshal(body, couchFindResponse); err != nil {
+			return err
+		}
+
+		for _, doc := range couchFindResponse.Docs {
+			doc.SortKey = sortKey(doc.Link.Meta.Priority, doc.ID)
+		}
+		if len(couchFindResponse.Docs) > 0 {
+			if err := c.saveDocuments(dbLink, couchFindResponse.Docs); err != nil {
+				return err
+			}
+		}
+
+		if len(couchFindResponse.Docs) < findBatchSize {
+			break
+		}
+		query.Bookmark = couchFindResponse.Bookmark
+	}
+
+	return c.saveDocument(dbLink, sortKeysDoc, Document{})
+}
+
+// getReferencingLinks returns the documents of the links referencing the
+// given link hash using the refs view.
+func (c *CouchStore) getReferencingLinks(refLinkHash string) ([]*Document, error) {
+	key, err := json.Marshal(refLinkHash)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/%s/_design/%s/_view/%s?include_docs=true&key=%s", dbLink, refsDesign, refsView, url.QueryEscape(string(key)))
+	body, couchResponseStatus, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !couchResponseStatus.Ok {
+		return nil, couchResponseStatus.error()
+	}
+
+	viewResponse := struct {
+		Rows []struct {
+			Doc *Document `json:"doc"`
+		} `json:"rows"`
+	}{}
+	if err := json.Unmarshal(body, &viewResponse); err != nil {
+		return nil, err
+	}
+
+	// A link referencing the same segment twice is emitted twice.
+	docs := []*Document{}
+	seen := map[string]bool{}
+	for _, row := range viewResponse.Rows {
+		if row.Doc != nil && !seen[row.Doc.ID] {
+			seen[row.Doc.ID] = true
+			docs = append(docs, row.Doc)
+		}
+	}
+
+	return docs, nil
+}
+
 func (c *CouchStore) addEvidence(linkHash string, evidence *cs.Evidence) error {
 	currentDoc, err := c.getDocument(dbEvidences, linkHash)
 	if err != nil {
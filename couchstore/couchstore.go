@@ -19,7 +19,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"time"
 
 	"github.com/stratumn/go-indigocore/bufferedbatch"
 	"github.com/stratumn/go-indigocore/cs"
@@ -33,6 +33,9 @@ const (
 
 	// Description is the description set in the store's information.
 	Description = "Indigo's CouchDB Store"
+
+	// findBatchSize is the number of documents fetched per _find request.
+	findBatchSize = 1000
 )
 
 // CouchStore is the type that implements github.com/stratumn/go-indigocore/store.Adapter.
@@ -109,6 +112,15 @@ func New(config *Config) (*CouchStore, error) {
 	if err := couchstore.CreateIndex(dbLink, "mapID", []string{"link.meta.mapId"}); err != nil {
 		return nil, err
 	}
+	if err := couchstore.CreateIndex(dbLink, "sortKey", []string{"sortKey"}); err != nil {
+		return nil, err
+	}
+	if err := couchstore.CreateView(dbLink, refsDesign, refsView, refsMap); err != nil {
+		return nil, err
+	}
+	if err := couchstore.addSortKeys(); err != nil {
+		return nil, err
+	}
 
 	return couchstore, nil
 }
@@ -176,7 +188,159 @@ func (c *CouchStore) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*
 }
 
 // FindSegments implements github.com/stratumn/go-indigocore/store.Adapter.FindSegments.
+// Segments sorted in the default order are paginated by CouchDB. Otherwise
+// all the matching links are fetched to be filtered, sorted and paginated in
+// memory. Links referencing a segment are found using the refs view.
 func (c *CouchStore) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	if canQuerySorted(filter) {
+		return c.findSortedSegments(ctx, filter)
+	}
+
+	segments := cs.SegmentSlice{}
+	times := map[*cs.Segment]time.Time{}
+
+	addDocs := func(docs []*Document) error {
+		for _, doc := range docs {
+			// Predicates are not part of the selector, they are
+			// applied in memory before pagination.
+			if len(filter.Where) > 0 && !filter.MatchLink(doc.Link) {
+				continue
+			}
+
+			segment := doc.Link.Segmentify()
+
+			// Evidences are saved in another database, so they
+			// must be fetched to filter segments on their backend.
+			if filter.EvidenceBackend != "" {
+				evidences, err := c.GetEvidences(ctx, segment.GetLinkHash())
+				if err != nil {
+					return err
+				}
+				if evidences != nil {
+					segment.Meta.Evidences = *evidences
+				}
+				if !filter.Match(segment) {
+					continue
+				}
+			}
+
+			segments = append(segments, segment)
+
+			// Links saved before their creation time was recorded
+			// have a zero time.
+			times[segment], _ = time.Parse(createdAtLayout, doc.CreatedAt)
+		}
+
+		return nil
+	}
+
+	if filter.RefLinkHash != "" {
+		docs, err := c.findReferencingLinks(filter)
+		if err != nil {
+			return nil, err
+		}
+		if err := addDocs(docs); err != nil {
+			return nil, err
+		}
+	} else {
+		bookmark := ""
+		for {
+			queryBytes, err := NewSegmentScanQuery(filter, bookmark)
+			if err != nil {
+				return nil, err
+			}
+
+			body, couchResponseStatus, err := c.post("/"+dbLink+"/_find", queryBytes)
+			if err != nil {
+				return nil, err
+			}
+
+			if !couchResponseStatus.Ok {
+				return nil, couchResponseStatus.error()
+			}
+
+			couchFindResponse := &CouchFindResponse{}
+			if err := json.Unmarshal(body, couchFindResponse); err != nil {
+				return nil, err
+			}
+
+			if err := addDocs(couchFindResponse.Docs); err != nil {
+				return nil, err
+			}
+
+			if len(couchFindResponse.Docs) < findBatchSize {
+				break
+			}
+			bookmark = couchFindResponse.Bookmark
+		}
+	}
+
+	filter.SortSegments(segments, func(segment *cs.Segment) time.Time {
+		return times[segment]
+	})
+	segments = filter.Pagination.PaginateSegments(segments)
+
+	// Otherwise evidences are only fetched for the requested page.
+	if filter.EvidenceBackend == "" {
+		for _, segment := range segments {
+			if evidences, err := c.GetEvidences(ctx, segment.GetLinkHash()); evidences != nil && err == nil {
+				segment.Meta.Evidences = *evidences
+			}
+		}
+	}
+
+	return segments, nil
+}
+
+// findReferencingLinks returns the documents of the links referencing the
+// link hash of the filter that match the fields of the filter handled by
+// the selector of the other queries.
+func (c *CouchStore) findReferencingLinks(filter *store.SegmentFilter) ([]*Document, error) {
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	after := ""
+	if cursor != nil {
+		after = sortKey(cursor.Priority, cursor.LinkHash.String())
+	}
+
+	docs, err := c.getReferencingLinks(filter.RefLinkHash)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []*Document{}
+	for _, doc := range docs {
+		if !filter.MatchLink(doc.Link) {
+			continue
+		}
+		if filter.HasCreatedAtBounds() {
+			createdAt, err := time.Parse(createdAtLayout, doc.CreatedAt)
+			if err != nil || !filter.MatchCreatedAt(createdAt) {
+				continue
+			}
+		}
+		if cursor != nil && doc.SortKey <= after {
+			continue
+		}
+		matching = append(matching, doc)
+	}
+
+	return matching, nil
+}
+
+// canQuerySorted returns true if CouchDB can find the segments matching the
+// filter in the right order, which is the case when they are sorted in the
+// default order and no filter is applied in memory.
+// Links referencing a segment are found using a view, which cannot be
+// combined with the sort key index.
+func canQuerySorted(filter *store.SegmentFilter) bool {
+	return filter.Sort.IsDefault() && len(filter.Where) == 0 && filter.EvidenceBackend == "" && filter.RefLinkHash == ""
+}
+
+func (c *CouchStore) findSortedSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
 	queryBytes, err := NewSegmentQuery(filter)
 	if err != nil {
 		return nil, err
@@ -200,7 +364,6 @@ func (c *CouchStore) FindSegments(ctx context.Context, filter *store.SegmentFilt
 	for _, doc := range couchFindResponse.Docs {
 		segments = append(segments, c.segmentify(ctx, doc.Link))
 	}
-	sort.Sort(segments)
 
 	return segments, nil
 }
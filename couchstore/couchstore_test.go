@@ -17,6 +17,7 @@ package couchstore
 import (
 	"flag"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"testing"
@@ -108,6 +109,28 @@ func TestCouchTMPop(t *testing.T) {
 	}.RunTests(t)
 }
 
+func TestSortKey(t *testing.T) {
+	// In the default order of segments.
+	keys := []string{
+		sortKey(math.Inf(1), "aa"),
+		sortKey(1e10, "aa"),
+		sortKey(2, "aa"),
+		sortKey(2, "bb"),
+		sortKey(0.5, "aa"),
+		sortKey(0, "aa"),
+		sortKey(math.Copysign(0, -1), "bb"),
+		sortKey(-0.5, "aa"),
+		sortKey(-3, "aa"),
+		sortKey(math.Inf(-1), "aa"),
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] >= keys[i] {
+			t.Errorf("sortKey #%d = %q, want > %q", i, keys[i], keys[i-1])
+		}
+	}
+}
+
 func newTestCouchStore() (*CouchStore, error) {
 	config := &Config{
 		Address: fmt.Sprintf("http://%s:%s", domain, port),
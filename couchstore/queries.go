@@ -28,7 +28,34 @@ type LinkSelector struct {
 	Process      string        `json:"link.meta.process,omitempty"`
 	MapIds       *MapIdsIn     `json:"link.meta.mapId,omitempty"`
 	Tags         *TagsAll      `json:"link.meta.tags,omitempty"`
+	Refs         *RefsMatch    `json:"link.meta.refs,omitempty"`
 	LinkHash     *LinkHashIn   `json:"_id,omitempty"`
+	SortKey      *SortKeyGt    `json:"sortKey,omitempty"`
+	LinkTypes    *LinkTypesIn  `json:"link.meta.type,omitempty"`
+	Actions      *ActionsIn    `json:"link.meta.action,omitempty"`
+	CreatedAt    *CreatedAtIn  `json:"createdAt,omitempty"`
+}
+
+// LinkTypesIn specifies that segment type should be in specified list
+type LinkTypesIn struct {
+	LinkTypes []string `json:"$in,omitempty"`
+}
+
+// ActionsIn specifies that segment action should be in specified list
+type ActionsIn struct {
+	Actions []string `json:"$in,omitempty"`
+}
+
+// CreatedAtIn specifies the bounds of the time at which links were saved.
+type CreatedAtIn struct {
+	After  string `json:"$gte,omitempty"`
+	Before string `json:"$lt,omitempty"`
+}
+
+// SortKeyGt specifies the sort key after which segments should start.
+// An empty sort key matches every link that has one.
+type SortKeyGt struct {
+	SortKey string `json:"$gt"`
 }
 
 // LinkHashIn specifies the list of link hashes to search for
@@ -46,6 +73,13 @@ type TagsAll struct {
 	Tags []string `json:"$all,omitempty"`
 }
 
+// RefsMatch specifies a link hash that should be in segment refs
+type RefsMatch struct {
+	Ref struct {
+		LinkHash string `json:"linkHash"`
+	} `json:"$elemMatch"`
+}
+
 // PrevLinkHash is used to specify PrevLinkHash in selector.
 type PrevLinkHash struct {
 	Exists *bool  `json:"$exists,omitempty"`
@@ -54,18 +88,59 @@ type PrevLinkHash struct {
 
 // LinkQuery used in CouchDB rich queries
 type LinkQuery struct {
-	Selector LinkSelector `json:"selector,omitempty"`
-	Limit    int          `json:"limit,omitempty"`
-	Skip     int          `json:"skip,omitempty"`
+	Selector LinkSelector        `json:"selector,omitempty"`
+	Sort     []map[string]string `json:"sort,omitempty"`
+	Limit    int                 `json:"limit,omitempty"`
+	Skip     int                 `json:"skip,omitempty"`
+	Bookmark string              `json:"bookmark,omitempty"`
 }
 
 // CouchFindResponse is couchdb response type when posting to /db/_find
 type CouchFindResponse struct {
-	Docs []*Document `json:"docs"`
+	Docs     []*Document `json:"docs"`
+	Bookmark string      `json:"bookmark"`
 }
 
 // NewSegmentQuery generates json data used to filter queries using couchdb _find api.
+// Segments are sorted in the default order using the sort key index, so the
+// query can only be used for filters that are entirely handled by CouchDB
+// (see canQuerySorted).
 func NewSegmentQuery(filter *store.SegmentFilter) ([]byte, error) {
+	linkSelector, err := newLinkSelector(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	linkQuery := LinkQuery{
+		Selector: *linkSelector,
+		Sort:     []map[string]string{{"sortKey": "asc"}},
+		Limit:    filter.Pagination.Limit,
+		Skip:     filter.Pagination.Offset,
+	}
+
+	return json.Marshal(linkQuery)
+}
+
+// NewSegmentScanQuery generates json data used to find all the links
+// matching a filter using couchdb _find api.
+// It returns links in batches of findBatchSize documents, starting at the
+// given bookmark, so that they can be sorted and paginated in memory.
+func NewSegmentScanQuery(filter *store.SegmentFilter, bookmark string) ([]byte, error) {
+	linkSelector, err := newLinkSelector(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	linkQuery := LinkQuery{
+		Selector: *linkSelector,
+		Limit:    findBatchSize,
+		Bookmark: bookmark,
+	}
+
+	return json.Marshal(linkQuery)
+}
+
+func newLinkSelector(filter *store.SegmentFilter) (*LinkSelector, error) {
 	linkSelector := LinkSelector{}
 	linkSelector.ObjectType = objectTypeLink
 
@@ -87,19 +162,45 @@ func NewSegmentQuery(filter *store.SegmentFilter) ([]byte, error) {
 	} else {
 		linkSelector.Tags = nil
 	}
+	if filter.RefLinkHash != "" {
+		linkSelector.Refs = &RefsMatch{}
+		linkSelector.Refs.Ref.LinkHash = filter.RefLinkHash
+	}
 	if len(filter.LinkHashes) > 0 {
 		linkSelector.LinkHash = &LinkHashIn{
 			LinkHashes: filter.LinkHashes,
 		}
 	}
+	if len(filter.LinkTypes) > 0 {
+		linkSelector.LinkTypes = &LinkTypesIn{LinkTypes: filter.LinkTypes}
+	}
+	if len(filter.Actions) > 0 {
+		linkSelector.Actions = &ActionsIn{Actions: filter.Actions}
+	}
+	if filter.HasCreatedAtBounds() {
+		linkSelector.CreatedAt = &CreatedAtIn{}
+		if filter.CreatedAfter != nil {
+			linkSelector.CreatedAt.After = formatCreatedAt(*filter.CreatedAfter)
+		}
+		if filter.CreatedBefore != nil {
+			linkSelector.CreatedAt.Before = formatCreatedAt(*filter.CreatedBefore)
+		}
+	}
 
-	linkQuery := LinkQuery{
-		Selector: linkSelector,
-		Limit:    filter.Pagination.Limit,
-		Skip:     filter.Pagination.Offset,
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		linkSelector.SortKey = &SortKeyGt{
+			SortKey: sortKey(cursor.Priority, cursor.LinkHash.String()),
+		}
+	} else if filter.Sort.IsDefault() {
+		// The sort key index is only used if the selector refers to it.
+		linkSelector.SortKey = &SortKeyGt{}
 	}
 
-	return json.Marshal(linkQuery)
+	return &linkSelector, nil
 }
 
 // MapSelector used in MapQuery
@@ -111,8 +212,10 @@ type MapSelector struct {
 
 // MapIdsFilters contain the filters on the segment map ID.
 // MapIdsFilters.And is a list of MapIdsFilter.
+// MapIdsFilters.After is the map ID after which results should start.
 type MapIdsFilters struct {
 	Filters []MapIdsFilter `json:"$and,omitempty"`
+	After   string         `json:"$gt,omitempty"`
 }
 
 // MapIdsFilter specifies that segment mapId should match a given regex.
@@ -122,9 +225,10 @@ type MapIdsFilter struct {
 
 // MapQuery used in CouchDB rich queries
 type MapQuery struct {
-	Selector MapSelector `json:"selector,omitempty"`
-	Limit    int         `json:"limit,omitempty"`
-	Skip     int         `json:"skip,omitempty"`
+	Selector MapSelector         `json:"selector,omitempty"`
+	Sort     []map[string]string `json:"sort,omitempty"`
+	Limit    int                 `json:"limit,omitempty"`
+	Skip     int                 `json:"skip,omitempty"`
 }
 
 // NewMapQuery generates json data used to filter queries using couchdb _find api.
@@ -148,12 +252,19 @@ func NewMapQuery(filter *store.MapFilter) ([]byte, error) {
 		)
 	}
 
-	if len(mapIdsFilters.Filters) > 0 {
+	mapCursor, err := filter.MapCursor()
+	if err != nil {
+		return nil, err
+	}
+	mapIdsFilters.After = mapCursor
+
+	if len(mapIdsFilters.Filters) > 0 || mapIdsFilters.After != "" {
 		mapSelector.MapIds = mapIdsFilters
 	}
 
 	mapQuery := MapQuery{
 		Selector: mapSelector,
+		Sort:     []map[string]string{{"_id": "asc"}},
 		Limit:    filter.Pagination.Limit,
 		Skip:     filter.Pagination.Offset,
 	}
@@ -190,6 +190,25 @@ func (m *LinkMeta) GetTagMap() map[string]struct{} {
 	return tags
 }
 
+// HasRef returns true if the link references the given link hash.
+func (m *LinkMeta) HasRef(linkHash string) bool {
+	for _, ref := range m.Refs {
+		if ref.LinkHash == linkHash {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRefLinkHashes returns the link hashes of the referenced segments.
+func (m *LinkMeta) GetRefLinkHashes() []string {
+	linkHashes := make([]string, len(m.Refs))
+	for i, ref := range m.Refs {
+		linkHashes[i] = ref.LinkHash
+	}
+	return linkHashes
+}
+
 // Validate checks for errors in a link.
 // It checks the validity of: format, signatures and references.
 func (l *Link) Validate(ctx context.Context, getSegment GetSegmentFunc) error {
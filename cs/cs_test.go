@@ -327,6 +327,15 @@ func TestLinkGetTagMap(t *testing.T) {
 	assert.False(t, got, `tags["three"]`)
 }
 
+func TestLinkHasRef(t *testing.T) {
+	l := cstesting.RandomLink()
+	l.Meta.Refs = []cs.SegmentReference{{Process: "p", LinkHash: "one"}, {Process: "p", LinkHash: "two"}}
+	assert.True(t, l.Meta.HasRef("one"), `HasRef("one")`)
+	assert.True(t, l.Meta.HasRef("two"), `HasRef("two")`)
+	assert.False(t, l.Meta.HasRef("three"), `HasRef("three")`)
+	assert.Equal(t, []string{"one", "two"}, l.Meta.GetRefLinkHashes(), "GetRefLinkHashes()")
+}
+
 func TestLinkGetProcess(t *testing.T) {
 	l := cstesting.RandomLink()
 	want := "hello"
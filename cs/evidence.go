@@ -16,12 +16,20 @@
 package cs
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 
+	cj "github.com/gibson042/canonicaljson-go"
 	"github.com/pkg/errors"
+	"github.com/stratumn/go-crypto/signatures"
+	"github.com/stratumn/go-indigocore/types"
 )
 
+// GenericProofName is the backend of generic proofs.
+const GenericProofName = "generic"
+
 // DeserializeMethods maps a proof backend (like "TMPop") to a deserializer function returning a specific proof
 var DeserializeMethods = make(map[string]func(json.RawMessage) (Proof, error))
 
@@ -82,9 +90,12 @@ func (e *Evidence) UnmarshalJSON(data []byte) error {
 		return errors.New("Evidence type does not exist")
 	}
 
-	proof, err := deserializer(serialized.Proof)
-	if err != nil {
-		return err
+	// An evidence without proof is decoded as it was encoded.
+	var proof Proof
+	if len(serialized.Proof) > 0 && string(serialized.Proof) != "null" {
+		if proof, err = deserializer(serialized.Proof); err != nil {
+			return err
+		}
 	}
 
 	*e = Evidence{
@@ -116,12 +127,60 @@ type Proof interface {
 	Verify(interface{}) bool // Checks the validity of the proof
 }
 
-// GenericProof implements the Proof interface
+// GenericProof implements the Proof interface.
+// It is issued by a trusted third party which signs the link hash,
+// the timestamp and some arbitrary data.
 type GenericProof struct {
 	Timestamp uint64      `json:"timestamp"`
 	Data      interface{} `json:"data"`
+	Type      string      `json:"type,omitempty"` // signature algorithm (eg: "EdDSA")
 	Pubkey    []byte      `json:"pubkey"`
-	Signature []byte      `json:"signature"` //sign(hash(time+data))
+	Signature []byte      `json:"signature"` //sign(canonicaljson(linkHash+time+data))
+}
+
+// NewGenericProof creates a proof of existence of a link hash at the given
+// time, signed with a PEM encoded private key.
+func NewGenericProof(linkHash *types.Bytes32, timestamp uint64, data interface{}, privateKey []byte) (*GenericProof, error) {
+	payload, err := genericProofPayload(linkHash, timestamp, data)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signatures.Sign(privateKey, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GenericProof{
+		Timestamp: timestamp,
+		Data:      data,
+		Type:      signature.AI,
+		Pubkey:    signature.PublicKey,
+		Signature: signature.Signature,
+	}, nil
+}
+
+// genericProofPayload returns the canonical JSON payload signed by a
+// generic proof.
+func genericProofPayload(linkHash *types.Bytes32, timestamp uint64, data interface{}) ([]byte, error) {
+	if linkHash == nil {
+		return nil, errors.New("link hash is required")
+	}
+
+	payload, err := cj.Marshal(struct {
+		LinkHash  string      `json:"linkHash"`
+		Timestamp uint64      `json:"timestamp"`
+		Data      interface{} `json:"data"`
+	}{
+		LinkHash:  linkHash.String(),
+		Timestamp: timestamp,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return payload, nil
 }
 
 // Time returns the timestamp from the block header
@@ -138,14 +197,86 @@ func (p *GenericProof) FullProof() []byte {
 	return bytes
 }
 
-// Verify returns true if the proof of a given linkHash is correct
-func (p *GenericProof) Verify(_ interface{}) bool {
-	return true
+// Verify returns true if the proof of a given linkHash is correct.
+// It only checks the signature against the public key embedded in the proof,
+// so anyone can issue a proof that passes. Use a GenericProofVerifier to
+// also check that the key belongs to a trusted third party.
+func (p *GenericProof) Verify(linkHash interface{}) bool {
+	lh, ok := linkHash.(*types.Bytes32)
+	if !ok || len(p.Pubkey) == 0 || len(p.Signature) == 0 {
+		return false
+	}
+
+	payload, err := genericProofPayload(lh, p.Timestamp, p.Data)
+	if err != nil {
+		return false
+	}
+
+	err = signatures.Verify(&signatures.Signature{
+		AI:        p.Type,
+		PublicKey: p.Pubkey,
+		Message:   payload,
+		Signature: p.Signature,
+	})
+
+	return err == nil
+}
+
+// GenericProofVerifier verifies generic proofs signed by trusted third
+// parties. It implements github.com/stratumn/go-indigocore/store.ProofVerifier.
+type GenericProofVerifier struct {
+	publicKeys [][]byte
+}
+
+// NewGenericProofVerifier creates a verifier that trusts generic proofs
+// signed with one of the given PEM encoded public keys.
+func NewGenericProofVerifier(publicKeys [][]byte) (*GenericProofVerifier, error) {
+	v := &GenericProofVerifier{}
+	for _, publicKey := range publicKeys {
+		block, _ := pem.Decode(publicKey)
+		if block == nil {
+			return nil, errors.New("public key is not PEM encoded")
+		}
+		v.publicKeys = append(v.publicKeys, block.Bytes)
+	}
+	return v, nil
+}
+
+// VerifyProof checks that a generic proof of a given linkHash is correct and
+// signed with a trusted public key.
+func (v *GenericProofVerifier) VerifyProof(proof Proof, linkHash *types.Bytes32) error {
+	p, ok := proof.(*GenericProof)
+	if !ok {
+		return errors.Errorf("unexpected proof type %T", proof)
+	}
+
+	if !v.trusts(p.Pubkey) {
+		return errors.New("proof is not signed with a trusted public key")
+	}
+	if !p.Verify(linkHash) {
+		return errors.New("invalid generic proof")
+	}
+
+	return nil
+}
+
+// trusts returns whether a PEM encoded public key is trusted.
+func (v *GenericProofVerifier) trusts(publicKey []byte) bool {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return false
+	}
+	for _, trusted := range v.publicKeys {
+		if bytes.Equal(block.Bytes, trusted) {
+			return true
+		}
+	}
+	return false
 }
 
 // init needs to define a way to deserialize a DummyProof
 func init() {
-	DeserializeMethods["generic"] = func(rawProof json.RawMessage) (Proof, error) {
+	DeserializeMethods[GenericProofName] = func(rawProof json.RawMessage) (Proof, error) {
 		p := GenericProof{}
 		if err := json.Unmarshal(rawProof, &p); err != nil {
 			return nil, err
@@ -18,7 +18,12 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/stratumn/go-crypto/keys"
 	"github.com/stratumn/go-indigocore/cs"
+	dummyevidences "github.com/stratumn/go-indigocore/dummyfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	// Needed to deserialize fossilizer evidences.
 	_ "github.com/stratumn/go-indigocore/fossilizer/evidences"
 )
@@ -108,6 +113,17 @@ func TestSerializeWrongEvidence(t *testing.T) {
 
 }
 
+func TestSerializeEvidenceWithoutProof(t *testing.T) {
+	e := cs.Evidence{Provider: TestChainId, Backend: "generic"}
+
+	js, err := json.Marshal(&e)
+	require.NoError(t, err)
+
+	var got cs.Evidence
+	require.NoError(t, json.Unmarshal(js, &got))
+	assert.Equal(t, e, got)
+}
+
 func TestGenericProof(t *testing.T) {
 	p := TestEvidence.Proof
 	t.Run("Time()", func(t *testing.T) {
@@ -125,8 +141,108 @@ func TestGenericProof(t *testing.T) {
 	})
 
 	t.Run("Verify()", func(t *testing.T) {
-		if got, want := p.Verify(""), true; got != want {
+		if got, want := p.Verify(testutil.RandomHash()), false; got != want {
 			t.Errorf(`Evidence.originalProof.Verify() = %v, want %v`, got, want)
 		}
 	})
 }
+
+func TestNewGenericProof(t *testing.T) {
+	_, privPEM, err := keys.GenerateKey(keys.ED25519)
+	require.NoError(t, err)
+
+	linkHash := testutil.RandomHash()
+	data := map[string]interface{}{"authority": "test", "serial": 42}
+
+	t.Run("Valid proof", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1507187163), p.Time())
+		assert.True(t, p.Verify(linkHash), "p.Verify()")
+	})
+
+	t.Run("Valid after serialization", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+
+		js, err := json.Marshal(&cs.Evidence{Backend: "generic", Provider: "tsa", Proof: p})
+		require.NoError(t, err)
+
+		var e cs.Evidence
+		require.NoError(t, json.Unmarshal(js, &e))
+		assert.True(t, e.Proof.Verify(linkHash), "e.Proof.Verify()")
+	})
+
+	t.Run("Other link hash", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+		assert.False(t, p.Verify(testutil.RandomHash()), "p.Verify()")
+	})
+
+	t.Run("Tampered timestamp", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+		p.Timestamp++
+		assert.False(t, p.Verify(linkHash), "p.Verify()")
+	})
+
+	t.Run("Tampered data", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+		p.Data = "forged"
+		assert.False(t, p.Verify(linkHash), "p.Verify()")
+	})
+
+	t.Run("Invalid link hash", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, data, privPEM)
+		require.NoError(t, err)
+		assert.False(t, p.Verify(linkHash.String()), "p.Verify()")
+	})
+
+	t.Run("Invalid private key", func(t *testing.T) {
+		_, err := cs.NewGenericProof(linkHash, 1507187163, data, []byte("test"))
+		assert.Error(t, err)
+	})
+}
+
+func TestGenericProofVerifier(t *testing.T) {
+	pubPEM, privPEM, err := keys.GenerateKey(keys.ED25519)
+	require.NoError(t, err)
+	otherPubPEM, otherPrivPEM, err := keys.GenerateKey(keys.ED25519)
+	require.NoError(t, err)
+
+	v, err := cs.NewGenericProofVerifier([][]byte{pubPEM})
+	require.NoError(t, err)
+
+	linkHash := testutil.RandomHash()
+
+	t.Run("Trusted key", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, "data", privPEM)
+		require.NoError(t, err)
+		assert.NoError(t, v.VerifyProof(p, linkHash))
+		assert.Error(t, v.VerifyProof(p, testutil.RandomHash()), "other link hash")
+	})
+
+	t.Run("Untrusted key", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, "data", otherPrivPEM)
+		require.NoError(t, err)
+		require.True(t, p.Verify(linkHash), "p.Verify()")
+		assert.Error(t, v.VerifyProof(p, linkHash))
+	})
+
+	t.Run("Key replaced with a trusted one", func(t *testing.T) {
+		p, err := cs.NewGenericProof(linkHash, 1507187163, "data", otherPrivPEM)
+		require.NoError(t, err)
+		p.Pubkey = pubPEM
+		assert.Error(t, v.VerifyProof(p, linkHash))
+	})
+
+	t.Run("Other proof type", func(t *testing.T) {
+		assert.Error(t, v.VerifyProof(&dummyevidences.DummyProof{}, linkHash))
+	})
+
+	t.Run("Invalid trusted key", func(t *testing.T) {
+		_, err := cs.NewGenericProofVerifier([][]byte{otherPubPEM, []byte("test")})
+		assert.Error(t, err)
+	})
+}
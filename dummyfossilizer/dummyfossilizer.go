@@ -19,6 +19,7 @@ package dummyfossilizer
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/stratumn/go-indigocore/cs"
@@ -53,6 +54,7 @@ type Info struct {
 type DummyFossilizer struct {
 	config               *Config
 	fossilizerEventChans []chan *fossilizer.Event
+	mutex                sync.RWMutex
 }
 
 // New creates an instance of a DummyFossilizer.
@@ -73,6 +75,9 @@ func (a *DummyFossilizer) GetInfo(ctx context.Context) (interface{}, error) {
 // AddFossilizerEventChan implements
 // github.com/stratumn/go-indigocore/fossilizer.Adapter.AddFossilizerEventChan.
 func (a *DummyFossilizer) AddFossilizerEventChan(fossilizerEventChan chan *fossilizer.Event) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
 	a.fossilizerEventChans = append(a.fossilizerEventChans, fossilizerEventChan)
 }
 
@@ -94,6 +99,9 @@ func (a *DummyFossilizer) Fossilize(ctx context.Context, data []byte, meta []byt
 		Data:      r,
 	}
 
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
 	for _, c := range a.fossilizerEventChans {
 		c <- event
 	}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/stratumn/go-indigocore/bufferedbatch"
 	"github.com/stratumn/go-indigocore/cs"
@@ -60,13 +61,16 @@ type DummyStore struct {
 	config     *Config
 	eventChans []chan *store.Event
 	links      linkMap      // maps link hashes to segments
+	createdAt  timeMap      // maps link hashes to the time they were saved
 	evidences  evidenceMap  // maps link hashes to evidences
 	values     valueMap     // maps keys to values
 	maps       hashSetMap   // maps chains IDs to sets of link hashes
+	refs       hashSetMap   // maps referenced link hashes to sets of link hashes
 	mutex      sync.RWMutex // simple global mutex
 }
 
 type linkMap map[string]*cs.Link
+type timeMap map[string]time.Time
 type evidenceMap map[string]*cs.Evidences
 type hashSet map[string]struct{}
 type hashSetMap map[string]hashSet
@@ -78,9 +82,11 @@ func New(config *Config) *DummyStore {
 		config:     config,
 		eventChans: nil,
 		links:      linkMap{},
+		createdAt:  timeMap{},
 		evidences:  evidenceMap{},
 		values:     valueMap{},
 		maps:       hashSetMap{},
+		refs:       hashSetMap{},
 		mutex:      sync.RWMutex{},
 	}
 }
@@ -97,6 +103,9 @@ func (a *DummyStore) GetInfo(ctx context.Context) (interface{}, error) {
 
 // AddStoreEventChannel implements github.com/stratumn/go-indigocore/store.Adapter.AddStoreEventChannel
 func (a *DummyStore) AddStoreEventChannel(eventChan chan *store.Event) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
 	a.eventChans = append(a.eventChans, eventChan)
 }
 
@@ -118,6 +127,9 @@ func (a *DummyStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 
 	linkHashStr := linkHash.String()
 	a.links[linkHashStr] = link
+	if _, exists := a.createdAt[linkHashStr]; !exists {
+		a.createdAt[linkHashStr] = time.Now()
+	}
 
 	mapID := link.Meta.MapID
 	_, exists := a.maps[mapID]
@@ -127,6 +139,13 @@ func (a *DummyStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 
 	a.maps[mapID][linkHashStr] = struct{}{}
 
+	for _, refLinkHash := range link.Meta.GetRefLinkHashes() {
+		if _, exists := a.refs[refLinkHash]; !exists {
+			a.refs[refLinkHash] = hashSet{}
+		}
+		a.refs[refLinkHash][linkHashStr] = struct{}{}
+	}
+
 	linkEvent := store.NewSavedLinks(link)
 
 	for _, c := range a.eventChans {
@@ -205,16 +224,75 @@ func (a *DummyStore) getSegment(linkHash string) (*cs.Segment, error) {
 
 // FindSegments implements github.com/stratumn/go-indigocore/store.Adapter.FindSegments.
 func (a *DummyStore) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	if _, err := filter.SegmentCursor(); err != nil {
+		return nil, err
+	}
+
+	segments, err := a.findSegments(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.Pagination.PaginateSegments(segments), nil
+}
+
+// StreamSegments implements github.com/stratumn/go-indigocore/store.SegmentStreamer.StreamSegments.
+func (a *DummyStore) StreamSegments(ctx context.Context, filter *store.SegmentFilter) (store.SegmentIterator, error) {
+	segments, err := a.findSegments(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.NewSliceIterator(segments), nil
+}
+
+// CountSegments implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegments.
+func (a *DummyStore) CountSegments(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	segments, err := a.findSegments(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(segments), nil
+}
+
+// CountSegmentsBy implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegmentsBy.
+func (a *DummyStore) CountSegmentsBy(ctx context.Context, filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	segments, err := a.findSegments(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, segment := range segments {
+		counts[store.GroupValue(segment, groupBy)]++
+	}
+
+	return counts, nil
+}
+
+// findSegments returns all the segments matching the filter, ignoring
+// pagination.
+func (a *DummyStore) findSegments(filter *store.SegmentFilter) (cs.SegmentSlice, error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
 	var linkHashes = hashSet{}
 
-	if len(filter.MapIDs) == 0 {
+	switch {
+	case filter.RefLinkHash != "":
+		for k, v := range a.refs[filter.RefLinkHash] {
+			linkHashes[k] = v
+		}
+	case len(filter.MapIDs) == 0:
 		for linkHash := range a.links {
 			linkHashes[linkHash] = struct{}{}
 		}
-	} else {
+	default:
 		for _, mapID := range filter.MapIDs {
 			l, e := a.maps[mapID]
 			if e {
@@ -230,6 +308,10 @@ func (a *DummyStore) FindSegments(ctx context.Context, filter *store.SegmentFilt
 
 // GetMapIDs implements github.com/stratumn/go-indigocore/store.Adapter.GetMapIDs.
 func (a *DummyStore) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
+	if _, err := filter.MapCursor(); err != nil {
+		return nil, err
+	}
+
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
@@ -320,14 +402,16 @@ func (a *DummyStore) findHashesSegments(linkHashes hashSet, filter *store.Segmen
 			return nil, err
 		}
 
-		if filter.Match(segment) {
+		if filter.Match(segment) && filter.MatchCreatedAt(a.createdAt[linkHash]) {
 			segments = append(segments, segment)
 		}
 	}
 
-	sort.Sort(segments)
+	filter.SortSegments(segments, func(segment *cs.Segment) time.Time {
+		return a.createdAt[segment.GetLinkHashString()]
+	})
 
-	return filter.Pagination.PaginateSegments(segments), nil
+	return segments, nil
 }
 
 func createKey(k []byte) string {
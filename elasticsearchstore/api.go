@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/olivere/elastic"
 	"github.com/stratumn/go-indigocore/cs"
@@ -31,9 +32,20 @@ const (
 	evidencesIndex = "evidences"
 	valuesIndex    = "values"
 
+	// maxCountGroups is the maximum number of values returned when
+	// counting segments grouped by a field.
+	maxCountGroups = 10000
+
+	// countMapsPrecision is the number of maps below which they are
+	// expected to be counted exactly. It is the maximum supported by
+	// Elasticsearch, above it the count is approximate.
+	countMapsPrecision = 40000
+
 	// This is the mapping for the links index.
 	// We voluntarily disable indexing of the following fields:
-	// meta.inputs, meta.refs, state, signatures
+	// meta.inputs, meta.refs, meta.data, state, signatures
+	// The values of the state and of meta.data are indexed as nested paths
+	// instead, so that predicates can be applied to them.
 	linksMapping = `{
 		"mappings": {
 			"_doc": {
@@ -110,6 +122,35 @@ const (
 					},
 					"stateTokens": {
 						"type": "text"
+					},
+					"linkHash": {
+						"type": "keyword"
+					},
+					"createdAt": {
+						"type": "date"
+					},
+					"evidenceBackends": {
+						"type": "keyword"
+					},
+					"refLinkHashes": {
+						"type": "keyword"
+					},
+					"paths": {
+						"type": "nested",
+						"properties": {
+							"path": {
+								"type": "keyword"
+							},
+							"string": {
+								"type": "keyword"
+							},
+							"number": {
+								"type": "double"
+							},
+							"boolean": {
+								"type": "boolean"
+							}
+						}
 					}
 				}
 			}
@@ -143,7 +184,18 @@ type Value struct {
 
 type linkDoc struct {
 	cs.Link
-	StateTokens []string `json:"stateTokens"`
+	StateTokens []string  `json:"stateTokens"`
+	LinkHash    string    `json:"linkHash"`
+	Paths       []pathDoc `json:"paths"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	// The evidences are saved in another index, but their backends are
+	// copied to the link so that segments can be filtered on them.
+	EvidenceBackends []string `json:"evidenceBackends"`
+
+	// The link hashes of meta.refs, which isn't indexed, so that segments
+	// can be found from the segments they reference.
+	RefLinkHashes []string `json:"refLinkHashes"`
 }
 
 // SearchQuery contains pagination and query string information.
@@ -174,7 +226,46 @@ func (es *ESStore) createIndex(indexName, mapping string) error {
 }
 
 func (es *ESStore) createLinksIndex() error {
-	return es.createIndex(linksIndex, linksMapping)
+	if err := es.createIndex(linksIndex, linksMapping); err != nil {
+		return err
+	}
+
+	return es.updateLinksIndex()
+}
+
+// updateLinksIndex updates a links index created by a previous version.
+// Fields added to the mapping since then are mapped before documents using
+// them are indexed, and the link hash of older documents is set so that they
+// can be sorted by it.
+func (es *ESStore) updateLinksIndex() error {
+	var mapping struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(linksMapping), &mapping); err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	_, err := es.client.
+		PutMapping().
+		Index(linksIndex).
+		Type(docType).
+		BodyString(string(mapping.Mappings[docType])).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The ID of a link document is its link hash.
+	_, err = es.client.
+		UpdateByQuery(linksIndex).
+		Type(docType).
+		Query(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("linkHash"))).
+		Script(elastic.NewScript("ctx._source.linkHash = ctx._id")).
+		ProceedOnVersionConflict().
+		Do(ctx)
+
+	return err
 }
 
 func (es *ESStore) createEvidencesIndex() error {
@@ -238,13 +329,33 @@ func (o *linkDoc) extractTokens(obj interface{}) {
 }
 
 func fromLink(link *cs.Link) (*linkDoc, error) {
+	linkHash, err := link.HashString()
+	if err != nil {
+		return nil, err
+	}
+
 	doc := linkDoc{
-		Link:        *link,
-		StateTokens: []string{},
+		Link:          *link,
+		StateTokens:   []string{},
+		LinkHash:      linkHash,
+		RefLinkHashes: link.Meta.GetRefLinkHashes(),
 	}
 
 	doc.extractTokens(link.State)
 
+	// Values are extracted from the JSON representation of the link so
+	// that numbers are always float64.
+	js, err := json.Marshal(link)
+	if err != nil {
+		return nil, err
+	}
+	var values cs.Link
+	if err := json.Unmarshal(js, &values); err != nil {
+		return nil, err
+	}
+	doc.extractPaths("state", values.State)
+	doc.extractPaths("meta.data", values.Meta.Data)
+
 	return &doc, nil
 }
 
@@ -269,6 +380,14 @@ func (es *ESStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 		return nil, err
 	}
 
+	evidences, err := es.getEvidences(linkHashStr)
+	if err != nil {
+		return nil, err
+	}
+
+	linkDoc.CreatedAt = time.Now().UTC()
+	linkDoc.EvidenceBackends = evidenceBackends(evidences)
+
 	return linkHash, es.indexDocument(linksIndex, linkHashStr, linkDoc)
 }
 
@@ -348,7 +467,41 @@ func (es *ESStore) addEvidence(linkHash string, evidence *cs.Evidence) error {
 		Evidences: currentDoc,
 	}
 
-	return es.indexDocument(evidencesIndex, linkHash, &evidences)
+	if err := es.indexDocument(evidencesIndex, linkHash, &evidences); err != nil {
+		return err
+	}
+
+	has, err := es.hasDocument(linksIndex, linkHash)
+	if err != nil || !has {
+		return err
+	}
+
+	ctx := context.TODO()
+	_, err = es.client.Update().Index(linksIndex).Type(docType).Id(linkHash).
+		Doc(map[string]interface{}{"evidenceBackends": evidenceBackends(currentDoc)}).
+		Do(ctx)
+	return err
+}
+
+// evidenceBackends returns the distinct backends of evidences.
+func evidenceBackends(evidences *cs.Evidences) []string {
+	backends := []string{}
+	seen := map[string]struct{}{}
+	for _, e := range *evidences {
+		if _, ok := seen[e.Backend]; !ok {
+			seen[e.Backend] = struct{}{}
+			backends = append(backends, e.Backend)
+		}
+	}
+	return backends
+}
+
+func stringValues(values []string) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = v
+	}
+	return res
 }
 
 func (es *ESStore) getValue(key string) ([]byte, error) {
@@ -394,6 +547,10 @@ func (es *ESStore) segmentify(ctx context.Context, link *cs.Link) *cs.Segment {
 }
 
 func (es *ESStore) getMapIDs(filter *store.MapFilter) ([]string, error) {
+	if _, err := filter.MapCursor(); err != nil {
+		return nil, err
+	}
+
 	// Flush to make sure the documents got written.
 	ctx := context.TODO()
 	_, err := es.client.Flush().Index(linksIndex).Do(ctx)
@@ -451,7 +608,7 @@ func (es *ESStore) getMapIDs(filter *store.MapFilter) ([]string, error) {
 	return filter.PaginateStrings(res), nil
 }
 
-func makeFilterQueries(filter *store.SegmentFilter) []elastic.Query {
+func makeFilterQueries(filter *store.SegmentFilter) ([]elastic.Query, error) {
 	// prepare filter queries.
 	filterQueries := []elastic.Query{}
 
@@ -489,19 +646,70 @@ func makeFilterQueries(filter *store.SegmentFilter) []elastic.Query {
 		filterQueries = append(filterQueries, shouldQuery)
 	}
 
+	// refLinkHash filter.
+	if filter.RefLinkHash != "" {
+		filterQueries = append(filterQueries, elastic.NewTermQuery("refLinkHashes", filter.RefLinkHash))
+	}
+
 	// linkHashes filter.
 	if len(filter.LinkHashes) > 0 {
 		q := elastic.NewIdsQuery(docType).Ids(filter.LinkHashes...)
 		filterQueries = append(filterQueries, q)
 	}
 
-	return filterQueries
+	// link types filter.
+	if len(filter.LinkTypes) > 0 {
+		filterQueries = append(filterQueries, elastic.NewTermsQuery("meta.type.keyword", stringValues(filter.LinkTypes)...))
+	}
+
+	// actions filter.
+	if len(filter.Actions) > 0 {
+		filterQueries = append(filterQueries, elastic.NewTermsQuery("meta.action.keyword", stringValues(filter.Actions)...))
+	}
+
+	// creation time filter.
+	if filter.HasCreatedAtBounds() {
+		q := elastic.NewRangeQuery("createdAt")
+		if filter.CreatedAfter != nil {
+			q = q.Gte(filter.CreatedAfter.UTC())
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Lt(filter.CreatedBefore.UTC())
+		}
+		filterQueries = append(filterQueries, q)
+	}
+
+	// evidence backend filter.
+	if filter.EvidenceBackend != "" {
+		filterQueries = append(filterQueries, elastic.NewTermQuery("evidenceBackends", filter.EvidenceBackend))
+	}
+
+	// predicates filter.
+	for i := range filter.Where {
+		q, err := predicateQuery(&filter.Where[i])
+		if err != nil {
+			return nil, err
+		}
+		filterQueries = append(filterQueries, q)
+	}
+
+	return filterQueries, nil
 }
 
 func (es *ESStore) genericSearch(filter *store.SegmentFilter, q elastic.Query) (cs.SegmentSlice, error) {
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	sortBy, err := sorters(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
 	// Flush to make sure the documents got written.
 	ctx := context.TODO()
-	_, err := es.client.Flush().Index(linksIndex).Do(ctx)
+	_, err = es.client.Flush().Index(linksIndex).Do(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -512,10 +720,22 @@ func (es *ESStore) genericSearch(filter *store.SegmentFilter, q elastic.Query) (
 		Index(linksIndex).
 		Type(docType)
 
+	svc = svc.SortBy(sortBy...)
+
 	// add pagination.
-	svc = svc.
-		From(filter.Pagination.Offset).
-		Size(filter.Pagination.Limit)
+	// Elasticsearch doesn't allow an offset after a cursor, so the
+	// results before the offset are fetched and skipped.
+	skip := 0
+	if cursor != nil {
+		skip = filter.Pagination.Offset
+		svc = svc.
+			SearchAfter(cursor.Priority, cursor.LinkHash.String()).
+			Size(skip + filter.Pagination.Limit)
+	} else {
+		svc = svc.
+			From(filter.Pagination.Offset).
+			Size(filter.Pagination.Limit)
+	}
 
 	// run search.
 	sr, err := svc.Query(q).Do(ctx)
@@ -529,7 +749,10 @@ func (es *ESStore) genericSearch(filter *store.SegmentFilter, q elastic.Query) (
 		return res, nil
 	}
 
-	for _, hit := range sr.Hits.Hits {
+	for i, hit := range sr.Hits.Hits {
+		if i < skip {
+			continue
+		}
 		var link cs.Link
 		if err := json.Unmarshal(*hit.Source, &link); err != nil {
 			return nil, err
@@ -537,24 +760,152 @@ func (es *ESStore) genericSearch(filter *store.SegmentFilter, q elastic.Query) (
 		res = append(res, es.segmentify(ctx, &link))
 	}
 
-	sort.Sort(res)
+	if filter.Sort.IsDefault() {
+		sort.Sort(res)
+	}
 
 	return res, nil
 }
 
 func (es *ESStore) findSegments(filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	filterQueries, err := makeFilterQueries(filter)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare query.
-	q := elastic.NewBoolQuery().Filter(makeFilterQueries(filter)...)
+	q := elastic.NewBoolQuery().Filter(filterQueries...)
 
 	// run search.
 	return es.genericSearch(filter, q)
 }
 
+func (es *ESStore) countSegments(filter *store.SegmentFilter) (int, error) {
+	filterQueries, err := makeFilterQueries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// Flush to make sure the documents got written.
+	ctx := context.TODO()
+	_, err = es.client.Flush().Index(linksIndex).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := es.client.
+		Count(linksIndex).
+		Type(docType).
+		Query(elastic.NewBoolQuery().Filter(filterQueries...)).
+		Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (es *ESStore) countSegmentsBy(filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	filterQueries, err := makeFilterQueries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flush to make sure the documents got written.
+	ctx := context.TODO()
+	_, err = es.client.Flush().Index(linksIndex).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// add aggregation for the field, links without a value are counted
+	// with an empty one.
+	a := elastic.
+		NewTermsAggregation().
+		Field("meta." + groupBy + ".keyword").
+		Missing("").
+		Size(maxCountGroups)
+
+	// run search.
+	sr, err := es.client.
+		Search().
+		Index(linksIndex).
+		Type(docType).
+		Query(elastic.NewBoolQuery().Filter(filterQueries...)).
+		Size(0).
+		Aggregation("groups", a).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	if agg, found := sr.Aggregations.Terms("groups"); found {
+		// Do not silently return partial counts.
+		if agg.SumOfOtherDocCount > 0 {
+			return nil, fmt.Errorf("cannot count segments by %s: more than %d values", groupBy, maxCountGroups)
+		}
+		for _, bucket := range agg.Buckets {
+			counts[bucket.Key.(string)] = int(bucket.DocCount)
+		}
+	}
+
+	return counts, nil
+}
+
+func (es *ESStore) countMaps(filter *store.SegmentFilter) (int, error) {
+	filterQueries, err := makeFilterQueries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// Flush to make sure the documents got written.
+	ctx := context.TODO()
+	_, err = es.client.Flush().Index(linksIndex).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	a := elastic.
+		NewCardinalityAggregation().
+		Field("meta.mapId.keyword").
+		PrecisionThreshold(countMapsPrecision)
+
+	// run search.
+	sr, err := es.client.
+		Search().
+		Index(linksIndex).
+		Type(docType).
+		Query(elastic.NewBoolQuery().Filter(filterQueries...)).
+		Size(0).
+		Aggregation("maps", a).
+		Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	agg, found := sr.Aggregations.Cardinality("maps")
+	if !found || agg.Value == nil {
+		return 0, nil
+	}
+
+	return int(*agg.Value), nil
+}
+
 func (es *ESStore) simpleSearchQuery(query *SearchQuery) (cs.SegmentSlice, error) {
+	filterQueries, err := makeFilterQueries(&query.SegmentFilter)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare Query.
 	q := elastic.NewBoolQuery().
 		// add filter queries.
-		Filter(makeFilterQueries(&query.SegmentFilter)...).
+		Filter(filterQueries...).
 		// add simple search query.
 		Must(elastic.NewSimpleQueryStringQuery(query.Query))
 
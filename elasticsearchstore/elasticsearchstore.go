@@ -201,6 +201,21 @@ func (es *ESStore) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]st
 	return es.getMapIDs(filter)
 }
 
+// CountSegments implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegments.
+func (es *ESStore) CountSegments(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	return es.countSegments(filter)
+}
+
+// CountSegmentsBy implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegmentsBy.
+func (es *ESStore) CountSegmentsBy(ctx context.Context, filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	return es.countSegmentsBy(filter, groupBy)
+}
+
+// CountMaps implements github.com/stratumn/go-indigocore/store.MapCounter.CountMaps.
+func (es *ESStore) CountMaps(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	return es.countMaps(filter)
+}
+
 // GetEvidences implements github.com/stratumn/go-indigocore/store.EvidenceReader.GetEvidences.
 func (es *ESStore) GetEvidences(ctx context.Context, linkHash *types.Bytes32) (*cs.Evidences, error) {
 	return es.getEvidences(linkHash.String())
@@ -0,0 +1,114 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearchstore
+
+import (
+	"github.com/olivere/elastic"
+	"github.com/stratumn/go-indigocore/store"
+)
+
+// pathDoc is a value of the state or of the meta data of a link, indexed by
+// its path. Only one of the typed fields is set for scalar values, none for
+// objects and arrays.
+// Nested documents are used instead of mapping the state itself since
+// different links can have different types at the same path.
+type pathDoc struct {
+	Path    string   `json:"path"`
+	String  *string  `json:"string,omitempty"`
+	Number  *float64 `json:"number,omitempty"`
+	Boolean *bool    `json:"boolean,omitempty"`
+}
+
+// extractPaths adds the paths of all the values of an object that are not
+// null.
+func (o *linkDoc) extractPaths(prefix string, obj map[string]interface{}) {
+	for key, value := range obj {
+		path := prefix + "." + key
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			o.Paths = append(o.Paths, pathDoc{Path: path, String: &v})
+		case float64:
+			o.Paths = append(o.Paths, pathDoc{Path: path, Number: &v})
+		case bool:
+			o.Paths = append(o.Paths, pathDoc{Path: path, Boolean: &v})
+		case map[string]interface{}:
+			o.Paths = append(o.Paths, pathDoc{Path: path})
+			o.extractPaths(path, v)
+		default:
+			o.Paths = append(o.Paths, pathDoc{Path: path})
+		}
+	}
+}
+
+// predicateQuery translates a predicate to a query on the nested paths.
+func predicateQuery(p *store.Predicate) (elastic.Query, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	q := elastic.NewBoolQuery().Filter(elastic.NewTermQuery("paths.path", p.Path))
+
+	switch p.Op {
+	case store.OpExists:
+
+	case store.OpEq:
+		q = q.Filter(valueQuery(p.Value))
+
+	case store.OpIn:
+		values := p.Value.([]interface{})
+		queries := make([]elastic.Query, len(values))
+		for i, v := range values {
+			queries[i] = valueQuery(v)
+		}
+		q = q.Filter(elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1))
+
+	default:
+		field := "paths.number"
+		if _, ok := p.Value.(string); ok {
+			field = "paths.string"
+		}
+
+		r := elastic.NewRangeQuery(field)
+		switch p.Op {
+		case store.OpGt:
+			r = r.Gt(p.Value)
+		case store.OpGte:
+			r = r.Gte(p.Value)
+		case store.OpLt:
+			r = r.Lt(p.Value)
+		case store.OpLte:
+			r = r.Lte(p.Value)
+		}
+		q = q.Filter(r)
+	}
+
+	// Indexes created before paths were indexed have no nested mapping.
+	return elastic.NewNestedQuery("paths", q).IgnoreUnmapped(true), nil
+}
+
+// valueQuery returns a query matching paths that have the given value.
+func valueQuery(v interface{}) elastic.Query {
+	switch v.(type) {
+	case string:
+		return elastic.NewTermQuery("paths.string", v)
+	case bool:
+		return elastic.NewTermQuery("paths.boolean", v)
+	default:
+		return elastic.NewTermQuery("paths.number", v)
+	}
+}
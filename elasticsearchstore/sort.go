@@ -0,0 +1,63 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearchstore
+
+import (
+	"github.com/olivere/elastic"
+	"github.com/stratumn/go-indigocore/store"
+)
+
+// sorters returns the sorters of link documents in the given sort order.
+func sorters(s *store.Sort) ([]elastic.Sorter, error) {
+	if s.IsDefault() {
+		// sort the same way as cs.SegmentSlice so that pages are
+		// consistent.
+		return []elastic.Sorter{
+			elastic.NewFieldSort("meta.priority").Desc(),
+			elastic.NewFieldSort("linkHash").Asc(),
+		}, nil
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	linkHash := elastic.NewFieldSort("linkHash").Asc()
+
+	switch s.Field {
+	case store.SortByPriority:
+		return []elastic.Sorter{elastic.NewFieldSort("meta.priority").Order(!s.Desc), linkHash}, nil
+	case store.SortByCreatedAt:
+		return []elastic.Sorter{elastic.NewFieldSort("createdAt").Order(!s.Desc), linkHash}, nil
+	case store.SortByLinkHash:
+		return []elastic.Sorter{elastic.NewFieldSort("linkHash").Order(!s.Desc)}, nil
+	}
+
+	// Numbers come first, then strings, then other values whatever the
+	// direction.
+	path := elastic.NewTermQuery("paths.path", s.Field)
+	number := elastic.NewFieldSort("paths.number").
+		Order(!s.Desc).
+		Missing("_last").
+		NestedPath("paths").
+		NestedFilter(path)
+	text := elastic.NewFieldSort("paths.string").
+		Order(!s.Desc).
+		Missing("_last").
+		NestedPath("paths").
+		NestedFilter(path)
+
+	return []elastic.Sorter{number, text, linkHash}, nil
+}
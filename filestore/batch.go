@@ -37,7 +37,9 @@ func NewBatch(ctx context.Context, a *FileStore) *Batch {
 	}
 }
 
-// Write implements github.com/stratumn/go-indigocore/store.Batch.Write
+// Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+// Like bufferedbatch.Batch.Write, it is not atomic and returns a
+// bufferedbatch.WriteError if it fails to save a link.
 func (b *Batch) Write(ctx context.Context) (err error) {
 	_, span := trace.StartSpan(ctx, "filestore/batch/Write")
 	defer monitoring.SetSpanStatusAndEnd(span, err)
@@ -45,8 +47,9 @@ func (b *Batch) Write(ctx context.Context) (err error) {
 	b.originalFileStore.mutex.Lock()
 	defer b.originalFileStore.mutex.Unlock()
 
-	for _, link := range b.Links {
-		if _, err := b.originalFileStore.createLink(link); err != nil {
+	for i, link := range b.Links {
+		if _, e := b.originalFileStore.createLink(link); e != nil {
+			err = &bufferedbatch.WriteError{Written: i, Err: e}
 			return err
 		}
 	}
@@ -16,9 +16,11 @@
 // system.
 //
 // The segments are stored as JSON files named after the link hashes.
+// The time at which a link was saved is the modification time of its file.
 // It's a convenient store to use during the development of an agent.
 // However, because it doesn't use an index, it's very slow, and shouldn't be
-// used for production.
+// used for production. The leveldbstore package provides an embedded store
+// with indexes.
 package filestore
 
 import (
@@ -32,6 +34,7 @@ import (
 	"regexp"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/leveldbstore"
@@ -146,10 +149,22 @@ func (a *FileStore) createLink(link *cs.Link) (*types.Bytes32, error) {
 
 	linkPath := a.getLinkPath(linkHash)
 
+	// Saving a link again must not change the time it was first saved.
+	info, err := os.Stat(linkPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	if err := ioutil.WriteFile(linkPath, js, 0644); err != nil {
 		return nil, err
 	}
 
+	if info != nil {
+		if err := os.Chtimes(linkPath, info.ModTime(), info.ModTime()); err != nil {
+			return nil, err
+		}
+	}
+
 	linkEvent := store.NewSavedLinks(link)
 
 	for _, c := range a.eventChans {
@@ -222,11 +237,17 @@ func (a *FileStore) getSegment(ctx context.Context, linkHash *types.Bytes32) (_
 
 // FindSegments implements github.com/stratumn/go-indigocore/store.SegmentReader.FindSegments.
 func (a *FileStore) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	if _, err := filter.SegmentCursor(); err != nil {
+		return nil, err
+	}
+
 	var segments cs.SegmentSlice
+	times := map[*cs.Segment]time.Time{}
 
-	err := a.forEach(ctx, func(segment *cs.Segment) error {
-		if filter.Match(segment) {
+	err := a.forEach(ctx, func(segment *cs.Segment, createdAt time.Time) error {
+		if filter.Match(segment) && filter.MatchCreatedAt(createdAt) {
 			segments = append(segments, segment)
+			times[segment] = createdAt
 		}
 		return nil
 	})
@@ -235,15 +256,21 @@ func (a *FileStore) FindSegments(ctx context.Context, filter *store.SegmentFilte
 		return nil, err
 	}
 
-	sort.Sort(segments)
+	filter.SortSegments(segments, func(segment *cs.Segment) time.Time {
+		return times[segment]
+	})
 
 	return filter.Pagination.PaginateSegments(segments), nil
 }
 
 // GetMapIDs implements github.com/stratumn/go-indigocore/store.SegmentReader.GetMapIDs.
 func (a *FileStore) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
+	if _, err := filter.MapCursor(); err != nil {
+		return nil, err
+	}
+
 	set := map[string]struct{}{}
-	err := a.forEach(ctx, func(segment *cs.Segment) error {
+	err := a.forEach(ctx, func(segment *cs.Segment, _ time.Time) error {
 		if filter.Match(segment) {
 			set[segment.Link.Meta.MapID] = struct{}{}
 		}
@@ -339,7 +366,7 @@ func (a *FileStore) getLinkPath(linkHash *types.Bytes32) string {
 
 var linkFileRegex = regexp.MustCompile(`(.*)\.json$`)
 
-func (a *FileStore) forEach(ctx context.Context, fn func(*cs.Segment) error) error {
+func (a *FileStore) forEach(ctx context.Context, fn func(*cs.Segment, time.Time) error) error {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
@@ -367,7 +394,7 @@ func (a *FileStore) forEach(ctx context.Context, fn func(*cs.Segment) error) err
 			if segment == nil {
 				return fmt.Errorf("could not find segment %q", filepath.Base(name))
 			}
-			if err = fn(segment); err != nil {
+			if err = fn(segment, file.ModTime()); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,147 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// readDirBatchSize is the number of file names read at once when walking
+// the segments directory.
+const readDirBatchSize = 100
+
+// StreamSegments implements github.com/stratumn/go-indigocore/store.SegmentStreamer.StreamSegments.
+// Segments are read lazily while walking the directory, so they are not
+// sorted.
+func (a *FileStore) StreamSegments(ctx context.Context, filter *store.SegmentFilter) (store.SegmentIterator, error) {
+	dir, err := os.Open(a.config.Path)
+	if os.IsNotExist(err) {
+		return store.NewSliceIterator(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentIterator{
+		ctx:    ctx,
+		store:  a,
+		filter: filter,
+		dir:    dir,
+	}, nil
+}
+
+// segmentIterator walks the segments directory.
+type segmentIterator struct {
+	ctx    context.Context
+	store  *FileStore
+	filter *store.SegmentFilter
+
+	dir     *os.File
+	names   []string
+	current *cs.Segment
+	err     error
+}
+
+// Next implements github.com/stratumn/go-indigocore/store.SegmentIterator.Next.
+func (it *segmentIterator) Next() bool {
+	it.current = nil
+
+	for it.err == nil && it.dir != nil {
+		if len(it.names) == 0 {
+			names, err := it.dir.Readdirnames(readDirBatchSize)
+			if err == io.EOF {
+				it.err = it.Close()
+				return false
+			}
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.names = names
+			continue
+		}
+
+		name := it.names[0]
+		it.names = it.names[1:]
+		if !linkFileRegex.MatchString(name) {
+			continue
+		}
+
+		linkHash, err := types.NewBytes32FromString(name[:len(name)-5])
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		segment, err := it.store.GetSegment(it.ctx, linkHash)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if segment == nil {
+			it.err = fmt.Errorf("could not find segment %q", name)
+			return false
+		}
+
+		if !it.filter.Match(segment) {
+			continue
+		}
+
+		if it.filter.HasCreatedAtBounds() {
+			info, err := os.Stat(it.store.getLinkPath(linkHash))
+			if err != nil {
+				it.err = err
+				return false
+			}
+			if !it.filter.MatchCreatedAt(info.ModTime()) {
+				continue
+			}
+		}
+
+		it.current = segment
+		return true
+	}
+
+	return false
+}
+
+// Segment implements github.com/stratumn/go-indigocore/store.SegmentIterator.Segment.
+func (it *segmentIterator) Segment() *cs.Segment {
+	return it.current
+}
+
+// Err implements github.com/stratumn/go-indigocore/store.SegmentIterator.Err.
+func (it *segmentIterator) Err() error {
+	return it.err
+}
+
+// Close implements github.com/stratumn/go-indigocore/store.SegmentIterator.Close.
+func (it *segmentIterator) Close() error {
+	if it.dir == nil {
+		return nil
+	}
+
+	err := it.dir.Close()
+	it.dir, it.names = nil, nil
+
+	return err
+}
@@ -23,4 +23,5 @@ import (
 	_ "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
 	_ "github.com/stratumn/go-indigocore/bcbatchfossilizer/evidences"
 	_ "github.com/stratumn/go-indigocore/dummyfossilizer/evidences"
+	_ "github.com/stratumn/go-indigocore/tsafossilizer/evidences"
 )
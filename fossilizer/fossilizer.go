@@ -53,6 +53,14 @@ type EventType string
 const (
 	// DidFossilizeLink means that the link was fossilized
 	DidFossilizeLink EventType = "DidFossilizeLink"
+
+	// DidConfirmLink means that the fossil of a link reached the required
+	// number of confirmations and that its evidence was upgraded
+	DidConfirmLink EventType = "DidConfirmLink"
+
+	// LowBalance means that the fossilizer refused to send a transaction
+	// because the balance of its wallet is too low
+	LowBalance EventType = "LowBalance"
 )
 
 // Event is the object fossilizers send to notify of important events.
@@ -0,0 +1,387 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fossilizerclient implements a fossilizer adapter that sends
+// requests to a fossilizerhttp server.
+//
+// Fossilizer events are received from the event stream of the server. The
+// client reconnects when the stream ends and asks the server for the events
+// it missed.
+package fossilizerclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stratumn/go-indigocore/blockchain"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/fossilizer/fossilizerhttp"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a request.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultReconnectDelay is the default delay before reconnecting to
+	// the event stream of the server.
+	DefaultReconnectDelay = time.Second
+
+	// DefaultMaxReconnectDelay is the default maximum delay before
+	// reconnecting to the event stream of the server.
+	DefaultMaxReconnectDelay = time.Minute
+)
+
+// ErrURL is returned when the URL of the server is invalid.
+var ErrURL = errors.New("fossilizer URL must be an absolute HTTP URL")
+
+// Config contains configuration options for the client.
+type Config struct {
+	// The URL of the fossilizerhttp server.
+	URL string
+
+	// Optionally, the HTTP client used to send requests, for instance to
+	// use a TLS client certificate. The default client has a timeout of
+	// DefaultTimeout, which doesn't apply to the event stream.
+	HTTPClient *http.Client
+
+	// Optionally, a function called to add credentials to each request,
+	// for instance a bearer token or a signature (see
+	// jsonhttp.SignRequest).
+	Authenticate func(*http.Request) error
+
+	// The delay before reconnecting to the event stream of the server. It
+	// is doubled after each failed attempt, up to MaxReconnectDelay.
+	ReconnectDelay time.Duration
+
+	// The maximum delay before reconnecting to the event stream of the
+	// server.
+	MaxReconnectDelay time.Duration
+}
+
+// Client is a fossilizer adapter backed by a fossilizerhttp server.
+type Client struct {
+	config *Config
+	url    *url.URL
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	eventChans []chan *fossilizer.Event
+	listenDone chan struct{}
+}
+
+// New creates a client for the server at the URL of the configuration.
+func New(config *Config) (*Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || !u.IsAbs() || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Wrapf(ErrURL, "%q", config.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := *config
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if c.ReconnectDelay <= 0 {
+		c.ReconnectDelay = DefaultReconnectDelay
+	}
+	if c.MaxReconnectDelay <= 0 {
+		c.MaxReconnectDelay = DefaultMaxReconnectDelay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Client{
+		config: &c,
+		url:    u,
+		client: c.HTTPClient,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Close stops receiving fossilizer events. Event channels are not closed.
+func (c *Client) Close() {
+	c.cancel()
+
+	c.mu.Lock()
+	done := c.listenDone
+	c.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// GetInfo implements github.com/stratumn/go-indigocore/fossilizer.Adapter.GetInfo.
+// It returns the information of the adapter of the server.
+func (c *Client) GetInfo(ctx context.Context) (interface{}, error) {
+	info, err := c.getInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return info.Adapter, nil
+}
+
+func (c *Client) getInfo(ctx context.Context) (*fossilizerhttp.Info, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(c.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var info fossilizerhttp.Info
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &info, nil
+}
+
+// Fossilize implements github.com/stratumn/go-indigocore/fossilizer.Adapter.Fossilize.
+// The meta data is sent as the name of the process, which the server
+// forwards with the result.
+func (c *Client) Fossilize(ctx context.Context, data []byte, meta []byte) error {
+	form := url.Values{
+		"data":    {hex.EncodeToString(data)},
+		"process": {string(meta)},
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/fossils", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.do(c.client, req)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+// AddFossilizerEventChan implements github.com/stratumn/go-indigocore/fossilizer.Adapter.AddFossilizerEventChan.
+// The first call connects to the event stream of the server. The channel
+// receives the events that follow the call.
+func (c *Client) AddFossilizerEventChan(eventChan chan *fossilizer.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.eventChans = append(c.eventChans, eventChan)
+	if c.listenDone != nil {
+		return
+	}
+	c.listenDone = make(chan struct{})
+
+	// Getting the ID of the last event now ensures that the events that
+	// follow are received, whenever the connection is established.
+	var since uint64
+	info, err := c.getInfo(c.ctx)
+	if err != nil {
+		log.WithField("error", err).Warn("Failed to get the last fossilizer event")
+	} else {
+		since = info.Seq
+	}
+
+	go c.listen(since, err == nil)
+}
+
+// listen receives events from the event stream of the server until the
+// client is closed. When the stream ends, it reconnects and asks for the
+// events that follow the last one it received.
+func (c *Client) listen(since uint64, resume bool) {
+	defer close(c.listenDone)
+
+	// The timeout of the client would end the stream.
+	streamClient := *c.client
+	streamClient.Timeout = 0
+
+	delay := c.config.ReconnectDelay
+
+	for {
+		if !resume {
+			if info, err := c.getInfo(c.ctx); err == nil {
+				since, resume = info.Seq, true
+			}
+		}
+
+		req, err := c.newRequest(c.ctx, http.MethodGet, "/events", nil)
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+			if resume {
+				req.Header.Set(jsonhttp.LastEventIDHeader, strconv.FormatUint(since, 10))
+			}
+
+			var res *http.Response
+			if res, err = c.do(&streamClient, req); err == nil {
+				delay = c.config.ReconnectDelay
+				err = c.readEvents(res.Body, &since, &resume)
+				res.Body.Close()
+			}
+		}
+
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"error": err,
+			"delay": delay,
+		}).Warn("Lost connection to the fossilizer event stream, reconnecting")
+
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return
+		}
+
+		if delay *= 2; delay > c.config.MaxReconnectDelay {
+			delay = c.config.MaxReconnectDelay
+		}
+	}
+}
+
+// readEvents sends the events of a stream to the event channels until the
+// stream ends. It keeps track of the ID of the last event.
+func (c *Client) readEvents(body io.Reader, since *uint64, resume *bool) error {
+	r := jsonhttp.NewEventStreamReader(body)
+
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+
+		if e.Type == fossilizerhttp.EventsLost {
+			log.WithField("seq", e.ID).Warn("Some fossilizer events were lost")
+		} else if event, err := decodeEvent(e); err != nil {
+			log.WithFields(log.Fields{
+				"type":  e.Type,
+				"error": err,
+			}).Warn("Failed to decode a fossilizer event")
+		} else if event != nil {
+			c.sendEvent(event)
+		}
+
+		if e.ID > 0 || e.Type == fossilizerhttp.EventsLost {
+			*since, *resume = e.ID, true
+		}
+	}
+}
+
+// sendEvent sends an event to all the event channels.
+func (c *Client) sendEvent(event *fossilizer.Event) {
+	c.mu.Lock()
+	eventChans := c.eventChans
+	c.mu.Unlock()
+
+	for _, eventChan := range eventChans {
+		select {
+		case eventChan <- event:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// decodeEvent decodes the data of a stream event. It returns nil if the
+// type of the event is unknown.
+func decodeEvent(e *jsonhttp.StreamEvent) (*fossilizer.Event, error) {
+	var data interface{}
+
+	switch fossilizer.EventType(e.Type) {
+	case fossilizer.DidFossilizeLink, fossilizer.DidConfirmLink:
+		data = &fossilizer.Result{}
+	case fossilizer.LowBalance:
+		data = &blockchain.LowBalanceError{}
+	default:
+		return nil, nil
+	}
+
+	raw, _ := e.Data.(json.RawMessage)
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &fossilizer.Event{
+		EventType: fossilizer.EventType(e.Type),
+		Data:      data,
+	}, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	u := *c.url
+	u.Path += path
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return req.WithContext(ctx), nil
+}
+
+// do authenticates and sends a request. The body of the response must be
+// closed if there is no error.
+func (c *Client) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if c.config.Authenticate != nil {
+		if err := c.config.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, decodeErr(res)
+	}
+
+	return res, nil
+}
+
+// decodeErr converts an error response to an HTTP error.
+func decodeErr(res *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = http.StatusText(res.StatusCode)
+	}
+
+	return jsonhttp.NewErrHTTP(body.Error, res.StatusCode)
+}
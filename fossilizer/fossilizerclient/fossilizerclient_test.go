@@ -0,0 +1,228 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fossilizerclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/dummyfossilizer"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/fossilizer/fossilizerhttp"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listener keeps track of the connections it accepts so that tests can
+// break them.
+type listener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *listener) closeConns() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+type testServer struct {
+	*httptest.Server
+	fossilizer *fossilizerhttp.Server
+	listener   *listener
+}
+
+func startServer(httpConfig *jsonhttp.Config) *testServer {
+	a := dummyfossilizer.New(&dummyfossilizer.Config{Version: "x.x.x", Commit: "abc"})
+	s := fossilizerhttp.New(a, &fossilizerhttp.Config{
+		FossilizerEventChanSize: fossilizerhttp.DefaultFossilizerEventChanSize,
+		JournalSize:             fossilizerhttp.DefaultJournalSize,
+	}, httpConfig, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{
+		Size:         256,
+		WriteTimeout: 10 * time.Second,
+		PongTimeout:  70 * time.Second,
+		PingInterval: time.Minute,
+		MaxMsgSize:   1024,
+	})
+	go s.Start()
+
+	ts := httptest.NewUnstartedServer(s)
+	l := &listener{Listener: ts.Listener}
+	ts.Listener = l
+	ts.Start()
+
+	return &testServer{Server: ts, fossilizer: s, listener: l}
+}
+
+func (s *testServer) stop() {
+	s.listener.closeConns()
+	s.Close()
+	s.fossilizer.Shutdown(context.Background())
+}
+
+func receive(t *testing.T, events chan *fossilizer.Event, data, meta []byte) {
+	select {
+	case e := <-events:
+		require.Equal(t, fossilizer.DidFossilizeLink, e.EventType)
+		r := e.Data.(*fossilizer.Result)
+		assert.Equal(t, data, r.Data)
+		assert.Equal(t, meta, r.Meta)
+		assert.Equal(t, "dummy", r.Evidence.Backend)
+		assert.NotNil(t, r.Evidence.Proof)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no fossilizer event received")
+	}
+}
+
+func TestNew_invalidURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:6000", "ftp://localhost", "http://%zz"} {
+		_, err := New(&Config{URL: u})
+		assert.Error(t, err, u)
+	}
+}
+
+func TestClient_GetInfo(t *testing.T) {
+	s := startServer(&jsonhttp.Config{})
+	defer s.stop()
+
+	c, err := New(&Config{URL: s.URL})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	info, err := c.GetInfo(context.Background())
+	require.NoError(t, err, "c.GetInfo()")
+	assert.Equal(t, map[string]interface{}{
+		"name":        "dummy",
+		"description": "Indigo's Dummy Fossilizer",
+		"version":     "x.x.x",
+		"commit":      "abc",
+	}, info)
+}
+
+func TestClient_Fossilize(t *testing.T) {
+	s := startServer(&jsonhttp.Config{})
+	defer s.stop()
+
+	c, err := New(&Config{URL: s.URL})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	events := make(chan *fossilizer.Event, 1)
+	c.AddFossilizerEventChan(events)
+
+	ctx := context.Background()
+	data, meta := []byte{0x12, 0x34}, []byte("process")
+	require.NoError(t, c.Fossilize(ctx, data, meta), "c.Fossilize()")
+	receive(t, events, data, meta)
+
+	err = c.Fossilize(ctx, nil, meta)
+	require.Error(t, err, "c.Fossilize()")
+	assert.Equal(t, http.StatusBadRequest, err.(jsonhttp.ErrHTTP).Status())
+}
+
+func TestClient_reconnect(t *testing.T) {
+	s := startServer(&jsonhttp.Config{})
+	defer s.stop()
+
+	// Requests don't reuse connections so that only the event stream is
+	// broken.
+	c, err := New(&Config{
+		URL:            s.URL,
+		HTTPClient:     &http.Client{Transport: &http.Transport{DisableKeepAlives: true}},
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	events := make(chan *fossilizer.Event, 10)
+	c.AddFossilizerEventChan(events)
+
+	ctx := context.Background()
+	meta := []byte("process")
+
+	require.NoError(t, c.Fossilize(ctx, []byte{1}, meta), "c.Fossilize()")
+	receive(t, events, []byte{1}, meta)
+
+	// Results produced while the client is disconnected are received
+	// when it reconnects.
+	s.listener.closeConns()
+	require.NoError(t, c.Fossilize(ctx, []byte{2}, meta), "c.Fossilize()")
+	receive(t, events, []byte{2}, meta)
+
+	require.NoError(t, c.Fossilize(ctx, []byte{3}, meta), "c.Fossilize()")
+	receive(t, events, []byte{3}, meta)
+
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %v", e)
+	default:
+	}
+}
+
+func TestClient_auth(t *testing.T) {
+	s := startServer(&jsonhttp.Config{
+		Authenticator: jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+			"token": {Name: "client"},
+		}),
+	})
+	defer s.stop()
+
+	c, err := New(&Config{URL: s.URL})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	_, err = c.GetInfo(context.Background())
+	require.Error(t, err, "c.GetInfo()")
+	assert.Equal(t, http.StatusUnauthorized, err.(jsonhttp.ErrHTTP).Status())
+
+	c, err = New(&Config{
+		URL: s.URL,
+		Authenticate: func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	events := make(chan *fossilizer.Event, 1)
+	c.AddFossilizerEventChan(events)
+
+	data, meta := []byte{0x42}, []byte("process")
+	require.NoError(t, c.Fossilize(context.Background(), data, meta), "c.Fossilize()")
+	receive(t, events, data, meta)
+}
@@ -27,10 +27,12 @@ import (
 	"github.com/stratumn/go-indigocore/jsonhttp"
 	"github.com/stratumn/go-indigocore/jsonws"
 	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/webhook"
 )
 
 var (
 	fossilizerEventChanSize int
+	journalSize             int
 	addr                    string
 	wsReadBufSize           int
 	wsWriteBufSize          int
@@ -41,12 +43,18 @@ var (
 	wsMaxMsgSize            int64
 	certFile                string
 	keyFile                 string
+	clientCAFile            string
+	authConfig              string
 	minDataLen              int
 	maxDataLen              int
 	readTimeout             time.Duration
 	writeTimeout            time.Duration
 	maxHeaderBytes          int
 	shutdownTimeout         time.Duration
+	webhooks                bool
+	webhookMaxRetries       int
+	webhookRetryDelay       time.Duration
+	webhookTimeout          time.Duration
 )
 
 // Run launches a fossilizerhttp server.
@@ -85,9 +93,12 @@ func Run(
 // RegisterFlags register the flags used by RunWithFlags.
 func RegisterFlags() {
 	flag.IntVar(&fossilizerEventChanSize, "event_chan_size", DefaultFossilizerEventChanSize, "Size of the FossilizerEvent channel")
+	flag.IntVar(&journalSize, "journal_size", DefaultJournalSize, "Number of fossilizer events kept for clients that reconnect")
 	flag.StringVar(&addr, "http", DefaultAddress, "HTTP address")
 	flag.StringVar(&certFile, "tls_cert", "", "TLS certificate file")
 	flag.StringVar(&keyFile, "tls_key", "", "TLS private key file")
+	flag.StringVar(&clientCAFile, "tls_client_ca", "", "Certificates of the authorities of TLS client certificates")
+	flag.StringVar(&authConfig, "auth_config", "", "JSON file describing the clients allowed to send requests")
 	flag.IntVar(&minDataLen, "mindata", DefaultMinDataLen, "Minimum data length")
 	flag.IntVar(&maxDataLen, "maxdata", DefaultMaxDataLen, "Maximum data length")
 	flag.DurationVar(&readTimeout, "read_timeout", jsonhttp.DefaultReadTimeout, "Read timeout")
@@ -101,6 +112,10 @@ func RegisterFlags() {
 	flag.DurationVar(&wsPongTimeout, "ws_pong_timeout", jsonws.DefaultWebSocketPongTimeout, "Timeout for a web socket expected pong")
 	flag.DurationVar(&wsPingInterval, "ws_ping_interval", jsonws.DefaultWebSocketPingInterval, "Interval between web socket pings")
 	flag.Int64Var(&wsMaxMsgSize, "max_msg_size", jsonws.DefaultWebSocketMaxMsgSize, "Maximum size of a received web socket message")
+	flag.BoolVar(&webhooks, "webhooks", false, "Post fossilizer events to webhooks, which are kept in memory")
+	flag.IntVar(&webhookMaxRetries, "webhook_max_retries", webhook.DefaultMaxRetries, "Maximum number of attempts of a webhook delivery")
+	flag.DurationVar(&webhookRetryDelay, "webhook_retry_delay", webhook.DefaultRetryDelay, "Delay before retrying a webhook delivery, doubled at each attempt")
+	flag.DurationVar(&webhookTimeout, "webhook_timeout", webhook.DefaultTimeout, "Timeout of a webhook request")
 }
 
 // RunWithFlags should be called after RegisterFlags and flag.Parse to launch
@@ -110,6 +125,16 @@ func RunWithFlags(ctx context.Context, a fossilizer.Adapter) {
 		MinDataLen:              minDataLen,
 		MaxDataLen:              maxDataLen,
 		FossilizerEventChanSize: fossilizerEventChanSize,
+		JournalSize:             journalSize,
+	}
+	if webhooks {
+		// Fossilizers have no key-value store, so endpoints are lost
+		// when the server stops.
+		config.Webhooks = webhook.New(&webhook.Config{
+			MaxRetries: webhookMaxRetries,
+			RetryDelay: webhookRetryDelay,
+			Timeout:    webhookTimeout,
+		}, nil)
 	}
 	monitoringConfig := monitoring.ConfigurationFromFlags()
 	httpConfig := &jsonhttp.Config{
@@ -119,6 +144,14 @@ func RunWithFlags(ctx context.Context, a fossilizer.Adapter) {
 		MaxHeaderBytes: maxHeaderBytes,
 		CertFile:       certFile,
 		KeyFile:        keyFile,
+		ClientCAFile:   clientCAFile,
+	}
+	if authConfig != "" {
+		authenticator, err := jsonhttp.LoadAuthenticator(authConfig)
+		if err != nil {
+			log.WithField("error", err).Fatal("Failed to load auth config")
+		}
+		httpConfig.Authenticator = authenticator
 	}
 	basicConfig := &jsonws.BasicConfig{
 		ReadBufferSize:  wsReadBufSize,
@@ -38,3 +38,10 @@ func newErrProcess(msg string) jsonhttp.ErrHTTP {
 	}
 	return jsonhttp.NewErrBadRequest(msg)
 }
+
+func newErrSince(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "since must be a positive integer"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
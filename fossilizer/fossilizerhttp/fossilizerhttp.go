@@ -17,26 +17,51 @@
 //
 // It serves the following routes:
 //	GET /
-//		Renders information about the fossilizer.
+//		Renders information about the fossilizer and the ID of the last
+//		fossilizer event:
+//			{ "adapter": info, "seq": seq }
 //
 //	POST /fossils
 //		Requests data to be fossilized.
 //		Form.data should be a hex encoded buffer.
 //		Form.process should be the name of the process that generated
 // 		the data
+//
+//	GET /websocket
+//		A web socket that broadcasts events from the fossilizer:
+//			{ "type": "DidFossilizeLink", "data": result }
+//
+//	GET /events?[since=seq]
+//		A server-sent events stream of the same events as /websocket.
+//		Event IDs are sequence numbers. A client that reconnects with
+//		since or the Last-Event-ID header set to the last ID it received
+//		is first sent the events it missed. If some of them are no longer
+//		available, an EventsLost event is sent first.
+//
+// If the server has a webhook dispatcher, it posts fossilizer events to
+// webhooks and also serves the routes of package webhookhttp, which manage
+// the endpoints and the failed deliveries.
+//
+// If the HTTP configuration has an authenticator, clients must be
+// authenticated, and requesting a fossil requires a policy that allows its
+// process. The webhook routes require an admin policy.
 package fossilizerhttp
 
 import (
 	"context"
 	"encoding/hex"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/stratumn/go-indigocore/fossilizer"
 	"github.com/stratumn/go-indigocore/jsonhttp"
 	"github.com/stratumn/go-indigocore/jsonws"
 	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/webhook"
+	"github.com/stratumn/go-indigocore/webhook/webhookhttp"
 
 	"go.opencensus.io/trace"
 )
@@ -53,6 +78,18 @@ const (
 
 	// DefaultFossilizerEventChanSize is the default size of the fossilizer event channel.
 	DefaultFossilizerEventChanSize = 256
+
+	// DefaultJournalSize is the default number of fossilizer events kept
+	// for clients that reconnect.
+	DefaultJournalSize = 256
+
+	// EventsLost is the type of the event sent when some of the events a
+	// client asked for are no longer available.
+	EventsLost = "EventsLost"
+
+	// streamChanSize is the number of events an event stream can lag
+	// behind, in addition to the replayed ones, before it is ended.
+	streamChanSize = 64
 )
 
 // Config contains configuration options for the server.
@@ -65,20 +102,52 @@ type Config struct {
 
 	// The size of the EventChan channel.
 	FossilizerEventChanSize int
+
+	// The number of fossilizer events kept for clients that reconnect.
+	JournalSize int
+
+	// Optionally, a dispatcher that posts fossilizer events to webhooks.
+	// Endpoints that have a segment filter don't receive them.
+	Webhooks *webhook.Dispatcher
 }
 
 // Info is the info returned by the root route.
 type Info struct {
 	Adapter interface{} `json:"adapter"`
+
+	// Seq is the ID of the last fossilizer event. Event stream clients
+	// can pass it as since to get all the events that follow.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 // Server is an HTTP server for fossilizers.
 type Server struct {
+	// seq is the ID of the last fossilizer event. It is first so that it
+	// is aligned for atomic operations.
+	seq uint64
+
 	*jsonhttp.Server
 	adapter             fossilizer.Adapter
 	config              *Config
 	ws                  *jsonws.Basic
 	fossilizerEventChan chan *fossilizer.Event
+	journal             *jsonhttp.Journal
+	streamChan          chan *stream
+	unstreamChan        chan *stream
+	eventsDone          chan struct{}
+	webhooks            *webhook.Dispatcher
+	webhooksCtx         context.Context
+	stopWebhooks        context.CancelFunc
+	webhooksDone        chan struct{}
+}
+
+// stream is the state of an event stream.
+type stream struct {
+	// since is the ID of the last event received by the client, if
+	// resume is true.
+	since  uint64
+	resume bool
+	events chan *jsonhttp.StreamEvent
 }
 
 // New create an instance of a server.
@@ -95,11 +164,23 @@ func New(
 		config:              config,
 		ws:                  jsonws.NewBasic(basicConfig, bufConnConfig),
 		fossilizerEventChan: make(chan *fossilizer.Event, config.FossilizerEventChanSize),
+		journal:             jsonhttp.NewJournal(config.JournalSize),
+		streamChan:          make(chan *stream),
+		unstreamChan:        make(chan *stream),
+		eventsDone:          make(chan struct{}),
+		webhooks:            config.Webhooks,
+		webhooksDone:        make(chan struct{}),
 	}
+	s.webhooksCtx, s.stopWebhooks = context.WithCancel(context.Background())
 
 	s.Get("/", s.root)
 	s.Post("/fossils", s.fossilize)
 	s.GetRaw("/websocket", s.getWebSocket)
+	s.GetRaw("/events", s.getEvents)
+
+	if s.webhooks != nil {
+		webhookhttp.Register(s.Server, s.webhooks)
+	}
 
 	return &s
 }
@@ -128,6 +209,13 @@ func (s *Server) ListenAndServe() (err error) {
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.ws.Stop()
 	close(s.fossilizerEventChan)
+	if s.webhooks != nil {
+		s.stopWebhooks()
+		select {
+		case <-s.webhooksDone:
+		case <-ctx.Done():
+		}
+	}
 	return s.Server.Shutdown(ctx)
 }
 
@@ -139,6 +227,17 @@ func (s *Server) Start() {
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
+	if s.webhooks != nil {
+		s.webhooks.ListenFossilizer(s.adapter)
+		wg.Add(1)
+
+		go func() {
+			s.webhooks.Start(s.webhooksCtx)
+			close(s.webhooksDone)
+			wg.Done()
+		}()
+	}
+
 	go func() {
 		s.ws.Start()
 		wg.Done()
@@ -152,16 +251,74 @@ func (s *Server) Start() {
 	wg.Wait()
 }
 
-// Forward events to websocket
+// Forward events to websocket and event streams
 func (s *Server) handleEvents() {
-	for event := range s.fossilizerEventChan {
-		s.ws.Broadcast(&jsonws.Message{
-			Type: string(event.EventType),
-			Data: event.Data,
-		}, nil)
+	defer close(s.eventsDone)
+
+	streams := map[*stream]struct{}{}
+
+	for {
+		select {
+		case event, ok := <-s.fossilizerEventChan:
+			if !ok {
+				for st := range streams {
+					s.endStream(streams, st)
+				}
+				return
+			}
+			e := &jsonhttp.StreamEvent{
+				Type: string(event.EventType),
+				Data: event.Data,
+			}
+			s.journal.Add(e)
+			atomic.StoreUint64(&s.seq, e.ID)
+			s.ws.Broadcast(&jsonws.Message{
+				Type: e.Type,
+				Data: e.Data,
+			}, nil)
+			for st := range streams {
+				s.sendStream(streams, st, e)
+			}
+		case st := <-s.streamChan:
+			streams[st] = struct{}{}
+			if st.resume {
+				events, ok := s.journal.Since(st.since)
+				if !ok {
+					s.sendStream(streams, st, &jsonhttp.StreamEvent{
+						ID:   s.journal.LastID(),
+						Type: EventsLost,
+					})
+				}
+				for _, e := range events {
+					s.sendStream(streams, st, e)
+				}
+			}
+		case st := <-s.unstreamChan:
+			delete(streams, st)
+		}
 	}
 }
 
+// sendStream sends an event to a stream, or ends the stream if the client
+// is too slow. The client can then resume it using the ID of the last event
+// it received.
+func (s *Server) sendStream(streams map[*stream]struct{}, st *stream, e *jsonhttp.StreamEvent) {
+	if _, ok := streams[st]; !ok {
+		return
+	}
+
+	select {
+	case st.events <- e:
+	default:
+		s.endStream(streams, st)
+	}
+}
+
+func (s *Server) endStream(streams map[*stream]struct{}, st *stream) {
+	delete(streams, st)
+	close(st.events)
+}
+
 func (s *Server) root(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
 	ctx, span := trace.StartSpan(r.Context(), "fossilizerhttp/root")
 	defer span.End()
@@ -173,6 +330,7 @@ func (s *Server) root(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 
 	return &Info{
 		Adapter: adapterInfo,
+		Seq:     atomic.LoadUint64(&s.seq),
 	}, nil
 }
 
@@ -185,6 +343,10 @@ func (s *Server) fossilize(w http.ResponseWriter, r *http.Request, p httprouter.
 		return nil, err
 	}
 
+	if err := jsonhttp.AuthorizeProcess(ctx, process); err != nil {
+		return nil, err
+	}
+
 	if err := s.adapter.Fossilize(ctx, data, []byte(process)); err != nil {
 		return nil, err
 	}
@@ -226,3 +388,59 @@ func (s *Server) parseFossilizeValues(r *http.Request) ([]byte, string, error) {
 func (s *Server) getWebSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	s.ws.Handle(w, r)
 }
+
+func (s *Server) getEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	st, err := s.parseStream(r)
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+
+	select {
+	case s.streamChan <- st:
+	case <-s.eventsDone:
+		renderErr(w, jsonhttp.NewErrHTTP("server is shutting down", http.StatusServiceUnavailable))
+		return
+	}
+
+	jsonhttp.ServeEventStream(w, r, st.events, jsonhttp.DefaultStreamHeartbeatInterval)
+
+	select {
+	case s.unstreamChan <- st:
+	case <-s.eventsDone:
+	}
+}
+
+func (s *Server) parseStream(r *http.Request) (*stream, error) {
+	st := stream{
+		events: make(chan *jsonhttp.StreamEvent, s.config.JournalSize+streamChanSize),
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return nil, newErrSince("")
+		}
+		st.since, st.resume = seq, true
+	}
+
+	lastEventID, ok, err := jsonhttp.LastEventID(r)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		st.since, st.resume = lastEventID, true
+	}
+
+	return &st, nil
+}
+
+// renderErr renders an error from a raw handler.
+func renderErr(w http.ResponseWriter, err error) {
+	e, ok := err.(jsonhttp.ErrHTTP)
+	if !ok {
+		e = jsonhttp.NewErrInternalServer("")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.Error(w, string(e.JSONMarshal()), e.Status())
+}
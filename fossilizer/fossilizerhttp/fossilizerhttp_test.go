@@ -15,13 +15,16 @@
 package fossilizerhttp
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,6 +34,8 @@ import (
 	"github.com/stratumn/go-indigocore/jsonws"
 	"github.com/stratumn/go-indigocore/jsonws/jsonwstesting"
 	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRoot(t *testing.T) {
@@ -95,6 +100,49 @@ func TestFossilize(t *testing.T) {
 	}
 }
 
+func TestFossilize_auth(t *testing.T) {
+	a := &fossilizertesting.MockAdapter{}
+	a.MockFossilize.Fn = func(data []byte, meta []byte) error {
+		return nil
+	}
+	s := New(a, &Config{MinDataLen: 2, MaxDataLen: 16}, &jsonhttp.Config{
+		Authenticator: jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+			"agent": {Name: "agent", Policy: jsonhttp.Policy{Processes: []string{"zou"}}},
+		}),
+	}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	tests := []struct {
+		token   string
+		process string
+		want    int
+	}{
+		{"", "zou", http.StatusUnauthorized},
+		{"agent", "other", http.StatusForbidden},
+		{"agent", "zou", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/fossils", nil)
+		req.Form = url.Values{}
+		req.Form.Set("data", "42")
+		req.Form.Set("process", tt.process)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if got, want := w.Code, tt.want; got != want {
+			t.Errorf("token %q, process %q: w.Code = %d want %d", tt.token, tt.process, got, want)
+		}
+	}
+
+	if got, want := a.MockFossilize.CalledCount, 1; got != want {
+		t.Errorf("a.MockFossilize.CalledCount = %d want %d", got, want)
+	}
+}
+
 func TestFossilize_noData(t *testing.T) {
 	s, _ := createServer()
 
@@ -295,3 +343,135 @@ func TestGetSocket(t *testing.T) {
 		t.Fatalf("fossilized segment not broadcasted")
 	}
 }
+
+func TestGetEvents(t *testing.T) {
+	sendChan := make(chan chan *fossilizer.Event)
+	a := &fossilizertesting.MockAdapter{}
+	a.MockAddFossilizerEventChan.Fn = func(c chan *fossilizer.Event) {
+		sendChan <- c
+	}
+
+	s := New(a, &Config{JournalSize: 8}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	go s.Start()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var eventChan chan *fossilizer.Event
+	select {
+	case eventChan = <-sendChan:
+	case <-time.After(time.Second):
+		t.Fatalf("event channel not added")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	openStream := func(lastEventID string) *bufio.Reader {
+		req, err := http.NewRequest("GET", ts.URL+"/events", nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set(jsonhttp.LastEventIDHeader, lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		return bufio.NewReader(res.Body)
+	}
+
+	body := openStream("")
+
+	eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: &fossilizer.Result{Meta: []byte("1")}}
+	eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: &fossilizer.Result{Meta: []byte("2")}}
+
+	assert.Equal(t, []string{"id: 1", "event: DidFossilizeLink"}, readStreamLines(t, body)[:2])
+	assert.Equal(t, []string{"id: 2", "event: DidFossilizeLink"}, readStreamLines(t, body)[:2])
+
+	lines := readStreamLines(t, openStream("1"))
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id: 2", lines[0])
+
+	var result struct{ Meta []byte }
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &result))
+	assert.Equal(t, []byte("2"), result.Meta)
+}
+
+func TestGetEvents_defaultConfig(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the default write timeout")
+	}
+
+	sendChan := make(chan chan *fossilizer.Event)
+	a := &fossilizertesting.MockAdapter{}
+	a.MockAddFossilizerEventChan.Fn = func(c chan *fossilizer.Event) {
+		sendChan <- c
+	}
+
+	s := New(a, &Config{
+		MinDataLen:              DefaultMinDataLen,
+		MaxDataLen:              DefaultMaxDataLen,
+		FossilizerEventChanSize: DefaultFossilizerEventChanSize,
+		JournalSize:             DefaultJournalSize,
+	}, &jsonhttp.Config{
+		ReadTimeout:    jsonhttp.DefaultReadTimeout,
+		WriteTimeout:   jsonhttp.DefaultWriteTimeout,
+		MaxHeaderBytes: jsonhttp.DefaultMaxHeaderBytes,
+	}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	go s.Start()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(l)
+
+	var eventChan chan *fossilizer.Event
+	select {
+	case eventChan = <-sendChan:
+	case <-time.After(time.Second):
+		t.Fatalf("event channel not added")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body := bufio.NewReader(res.Body)
+
+	eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: &fossilizer.Result{Meta: []byte("1")}}
+	assert.Equal(t, "id: 1", readStreamLines(t, body)[0])
+
+	// The stream must outlive the write timeout of the server.
+	time.Sleep(jsonhttp.DefaultWriteTimeout + time.Second)
+
+	eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: &fossilizer.Result{Meta: []byte("2")}}
+	assert.Equal(t, "id: 2", readStreamLines(t, body)[0])
+}
+
+func readStreamLines(t *testing.T, r *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "r.ReadString()")
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && len(lines) > 0 {
+			return lines
+		}
+		if line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+}
+
+func TestGetEvents_invalidSince(t *testing.T) {
+	s, _ := createServer()
+
+	req := httptest.NewRequest("GET", "/events?since=abc", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	assert.Equal(t, newErrSince("").Status(), w.Code)
+}
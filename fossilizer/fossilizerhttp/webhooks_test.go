@@ -0,0 +1,88 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fossilizerhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/fossilizer/fossilizertesting"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	messages := make(chan *webhook.Message, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m webhook.Message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		messages <- &m
+	}))
+	defer hook.Close()
+
+	sendChan := make(chan chan *fossilizer.Event, 2)
+	a := &fossilizertesting.MockAdapter{}
+	a.MockAddFossilizerEventChan.Fn = func(c chan *fossilizer.Event) {
+		sendChan <- c
+	}
+
+	d := webhook.New(&webhook.Config{}, nil)
+	s := New(a, &Config{Webhooks: d}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/webhooks", &webhook.Endpoint{URL: hook.URL}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	go s.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	// The server and the dispatcher each add an event channel.
+	for i := 0; i < 2; i++ {
+		select {
+		case c := <-sendChan:
+			c <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: &fossilizer.Result{Meta: []byte("1")}}
+		case <-time.After(time.Second):
+			t.Fatalf("event channel not added")
+		}
+	}
+
+	select {
+	case m := <-messages:
+		assert.Equal(t, string(fossilizer.DidFossilizeLink), m.Type)
+	case <-time.After(time.Second):
+		t.Fatalf("webhook not called")
+	}
+}
+
+func TestWebhooks_disabled(t *testing.T) {
+	s, _ := createServer()
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/webhooks", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,347 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Identity is an authenticated client.
+type Identity struct {
+	// Name identifies the client in logs.
+	Name string `json:"name"`
+
+	// Policy restricts what the client can do.
+	Policy Policy `json:"policy"`
+}
+
+// Policy contains the restrictions of a client.
+type Policy struct {
+	// ReadOnly clients can only send GET, HEAD and OPTIONS requests.
+	ReadOnly bool `json:"readOnly"`
+
+	// Processes the client can save links to. Empty allows all of them.
+	Processes []string `json:"processes"`
+
+	// Backends of the evidences the client can add. Empty allows all of
+	// them.
+	EvidenceBackends []string `json:"evidenceBackends"`
+
+	// Admin clients can manage the server, for instance its webhooks.
+	// Their access is still limited to their processes.
+	Admin bool `json:"admin"`
+}
+
+// AllowsMethod returns true if the policy allows requests with the given
+// HTTP method.
+func (p *Policy) AllowsMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return !p.ReadOnly
+}
+
+// AllowsProcess returns true if the policy allows saving links to the given
+// process.
+func (p *Policy) AllowsProcess(process string) bool {
+	return !p.ReadOnly && p.HasProcess(process)
+}
+
+// HasProcess returns true if the process is one of the processes of the
+// policy, even if the client is read-only.
+func (p *Policy) HasProcess(process string) bool {
+	return allows(p.Processes, process)
+}
+
+// AllowsEvidenceBackend returns true if the policy allows adding evidences
+// produced by the given backend.
+func (p *Policy) AllowsEvidenceBackend(backend string) bool {
+	return !p.ReadOnly && allows(p.EvidenceBackends, backend)
+}
+
+func allows(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator finds the identity of the client that sent a request.
+type Authenticator interface {
+	// Authenticate returns nil without an error if the request doesn't
+	// contain credentials handled by the authenticator. It returns an
+	// error if the credentials are invalid.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Authenticators tries several authenticators in order and returns the
+// first identity found.
+type Authenticators []Authenticator
+
+// Authenticate implements Authenticator.Authenticate.
+func (a Authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(r)
+		if err != nil || identity != nil {
+			return identity, err
+		}
+	}
+	return nil, nil
+}
+
+// TokenAuthenticator authenticates requests that have a static bearer
+// token in their Authorization header.
+type TokenAuthenticator struct {
+	// Tokens are hashed so that looking them up doesn't leak their value
+	// through timing.
+	identities map[[sha256.Size]byte]*Identity
+}
+
+// NewTokenAuthenticator creates an authenticator from a map of tokens to
+// identities.
+func NewTokenAuthenticator(tokens map[string]*Identity) *TokenAuthenticator {
+	a := &TokenAuthenticator{identities: make(map[[sha256.Size]byte]*Identity, len(tokens))}
+	for token, identity := range tokens {
+		a.identities[sha256.Sum256([]byte(token))] = identity
+	}
+	return a
+}
+
+// Authenticate implements Authenticator.Authenticate.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	const prefix = "Bearer "
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return nil, nil
+	}
+
+	identity, ok := a.identities[sha256.Sum256([]byte(auth[len(prefix):]))]
+	if !ok {
+		return nil, NewErrUnauthorized("invalid bearer token")
+	}
+
+	return identity, nil
+}
+
+// ClientCertAuthenticator authenticates requests using the common name of
+// the TLS certificate of the client. The certificate must have been
+// verified by the server, see Config.ClientCAFile.
+type ClientCertAuthenticator struct {
+	identities map[string]*Identity
+}
+
+// NewClientCertAuthenticator creates an authenticator from a map of
+// certificate common names to identities.
+func NewClientCertAuthenticator(commonNames map[string]*Identity) *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{identities: commonNames}
+}
+
+// Authenticate implements Authenticator.Authenticate.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	identity, ok := a.identities[cert.Subject.CommonName]
+	if !ok {
+		return nil, NewErrUnauthorized("unknown client certificate")
+	}
+
+	return identity, nil
+}
+
+// AuthConfig is the content of a file describing the clients allowed to
+// send requests.
+type AuthConfig struct {
+	Clients []ClientConfig `json:"clients"`
+}
+
+// ClientConfig describes the credentials and the policy of a client.
+// A client can have any number of credentials.
+type ClientConfig struct {
+	Identity
+
+	// A static bearer token.
+	Token string `json:"token"`
+
+	// A base64 encoded key used to sign requests, see HMACAuthenticator.
+	// The name of the client is used as key ID.
+	HMACKey []byte `json:"hmacKey"`
+
+	// The common name of the TLS certificate of the client.
+	CertCommonName string `json:"certCommonName"`
+}
+
+// LoadAuthenticator creates an authenticator from a JSON encoded AuthConfig
+// file.
+func LoadAuthenticator(path string) (Authenticator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var config AuthConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return config.Authenticator()
+}
+
+// Authenticator creates an authenticator that accepts the credentials of
+// all the clients.
+func (c *AuthConfig) Authenticator() (Authenticator, error) {
+	var (
+		tokens      = map[string]*Identity{}
+		keys        = map[string]HMACKey{}
+		commonNames = map[string]*Identity{}
+	)
+
+	for i := range c.Clients {
+		client := &c.Clients[i]
+		if client.Name == "" {
+			return nil, errors.Errorf("client %d has no name", i)
+		}
+		if client.Token == "" && len(client.HMACKey) == 0 && client.CertCommonName == "" {
+			return nil, errors.Errorf("client %q has no credentials", client.Name)
+		}
+
+		if client.Token != "" {
+			if _, ok := tokens[client.Token]; ok {
+				return nil, errors.Errorf("client %q reuses a token", client.Name)
+			}
+			tokens[client.Token] = &client.Identity
+		}
+
+		if len(client.HMACKey) > 0 {
+			if _, ok := keys[client.Name]; ok {
+				return nil, errors.Errorf("client %q has a duplicate name", client.Name)
+			}
+			keys[client.Name] = HMACKey{Key: client.HMACKey, Identity: &client.Identity}
+		}
+
+		if client.CertCommonName != "" {
+			if _, ok := commonNames[client.CertCommonName]; ok {
+				return nil, errors.Errorf("client %q reuses a certificate common name", client.Name)
+			}
+			commonNames[client.CertCommonName] = &client.Identity
+		}
+	}
+
+	return Authenticators{
+		NewTokenAuthenticator(tokens),
+		NewHMACAuthenticator(keys),
+		NewClientCertAuthenticator(commonNames),
+	}, nil
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity of a client.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the client that sent a
+// request, or nil if the server doesn't authenticate requests.
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// AuthorizeProcess returns a forbidden error if the client that sent a
+// request is not allowed to save links to the given process.
+func AuthorizeProcess(ctx context.Context, process string) error {
+	if identity := IdentityFromContext(ctx); identity != nil && !identity.Policy.AllowsProcess(process) {
+		return NewErrForbidden("not allowed to write to process " + process)
+	}
+	return nil
+}
+
+// AuthorizeEvidenceBackend returns a forbidden error if the client that
+// sent a request is not allowed to add evidences produced by the given
+// backend.
+func AuthorizeEvidenceBackend(ctx context.Context, backend string) error {
+	if identity := IdentityFromContext(ctx); identity != nil && !identity.Policy.AllowsEvidenceBackend(backend) {
+		return NewErrForbidden("not allowed to add evidences of backend " + backend)
+	}
+	return nil
+}
+
+// AuthorizeAdmin returns a forbidden error if the client that sent a
+// request doesn't have an admin policy.
+func AuthorizeAdmin(ctx context.Context) error {
+	if identity := IdentityFromContext(ctx); identity != nil && !identity.Policy.Admin {
+		return NewErrForbidden("admin policy required")
+	}
+	return nil
+}
+
+// authenticate returns the request with the identity of the client in its
+// context. It returns the unchanged request and an error if the client
+// cannot be authenticated or if its policy doesn't allow the method of the
+// request.
+func authenticate(authenticator Authenticator, r *http.Request) (*http.Request, error) {
+	if authenticator == nil {
+		return r, nil
+	}
+
+	identity, err := authenticator.Authenticate(r)
+	if err != nil {
+		if _, ok := err.(ErrHTTP); !ok {
+			err = NewErrUnauthorized(err.Error())
+		}
+		return r, err
+	}
+	if identity == nil {
+		return r, NewErrUnauthorized("")
+	}
+	if !identity.Policy.AllowsMethod(r.Method) {
+		return r, NewErrForbidden("client is read-only")
+	}
+
+	return r.WithContext(WithIdentity(r.Context(), identity)), nil
+}
+
+// loadClientCAs reads a PEM encoded file of certificate authorities.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("%s: no certificate found", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthServer(authenticator Authenticator) *Server {
+	s := New(&Config{Authenticator: authenticator})
+	handle := func(_ http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+		return IdentityFromContext(r.Context()), nil
+	}
+	s.Get("/test", handle)
+	s.Post("/test", handle)
+	return s
+}
+
+func serveWithToken(s *Server, method, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/test", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth_disabled(t *testing.T) {
+	s := newAuthServer(nil)
+	w := serveWithToken(s, "POST", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "null", w.Body.String())
+}
+
+func TestAuth_token(t *testing.T) {
+	s := newAuthServer(NewTokenAuthenticator(map[string]*Identity{
+		"writer": {Name: "writer"},
+		"reader": {Name: "reader", Policy: Policy{ReadOnly: true}},
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+		body   string
+	}{
+		{"no token", "GET", "", http.StatusUnauthorized, ""},
+		{"invalid token", "GET", "nope", http.StatusUnauthorized, ""},
+		{"writer read", "GET", "writer", http.StatusOK, `{"name":"writer","policy":{"readOnly":false,"processes":null,"evidenceBackends":null,"admin":false}}`},
+		{"writer write", "POST", "writer", http.StatusOK, ""},
+		{"reader read", "GET", "reader", http.StatusOK, ""},
+		{"reader write", "POST", "reader", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveWithToken(s, tt.method, tt.token)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_clientCert(t *testing.T) {
+	a := NewClientCertAuthenticator(map[string]*Identity{"client": {Name: "client"}})
+
+	newRequest := func(commonName string) *http.Request {
+		req := httptest.NewRequest("GET", "/test", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+
+	identity, err := a.Authenticate(newRequest("client"))
+	assert.NoError(t, err)
+	assert.Equal(t, "client", identity.Name)
+
+	_, err = a.Authenticate(newRequest("other"))
+	assert.Error(t, err)
+
+	identity, err = a.Authenticate(httptest.NewRequest("GET", "/test", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{Processes: []string{"p1"}, EvidenceBackends: []string{"bitcoin"}}
+	assert.True(t, p.AllowsMethod("POST"), "AllowsMethod(POST)")
+	assert.True(t, p.AllowsProcess("p1"), "AllowsProcess(p1)")
+	assert.False(t, p.AllowsProcess("p2"), "AllowsProcess(p2)")
+	assert.True(t, p.AllowsEvidenceBackend("bitcoin"), "AllowsEvidenceBackend(bitcoin)")
+	assert.False(t, p.AllowsEvidenceBackend("dummy"), "AllowsEvidenceBackend(dummy)")
+
+	p = Policy{ReadOnly: true}
+	assert.True(t, p.AllowsMethod("GET"), "AllowsMethod(GET)")
+	assert.False(t, p.AllowsMethod("POST"), "AllowsMethod(POST)")
+	assert.False(t, p.AllowsProcess("p1"), "AllowsProcess(p1)")
+	assert.True(t, p.HasProcess("p1"), "HasProcess(p1)")
+	assert.False(t, p.AllowsEvidenceBackend("bitcoin"), "AllowsEvidenceBackend(bitcoin)")
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, AuthorizeProcess(ctx, "p1"), "no identity")
+	assert.NoError(t, AuthorizeEvidenceBackend(ctx, "dummy"), "no identity")
+	assert.NoError(t, AuthorizeAdmin(ctx), "no identity")
+
+	ctx = WithIdentity(ctx, &Identity{Name: "client", Policy: Policy{
+		Processes:        []string{"p1"},
+		EvidenceBackends: []string{"bitcoin"},
+	}})
+	assert.NoError(t, AuthorizeProcess(ctx, "p1"))
+	assert.NoError(t, AuthorizeEvidenceBackend(ctx, "bitcoin"))
+
+	err := AuthorizeProcess(ctx, "p2")
+	require.IsType(t, ErrHTTP{}, err)
+	assert.Equal(t, http.StatusForbidden, err.(ErrHTTP).Status())
+
+	err = AuthorizeEvidenceBackend(ctx, "dummy")
+	require.IsType(t, ErrHTTP{}, err)
+	assert.Equal(t, http.StatusForbidden, err.(ErrHTTP).Status())
+
+	err = AuthorizeAdmin(ctx)
+	require.IsType(t, ErrHTTP{}, err)
+	assert.Equal(t, http.StatusForbidden, err.(ErrHTTP).Status())
+
+	ctx = WithIdentity(ctx, &Identity{Name: "admin", Policy: Policy{Admin: true}})
+	assert.NoError(t, AuthorizeAdmin(ctx))
+}
+
+func TestLoadAuthenticator(t *testing.T) {
+	f, err := ioutil.TempFile("", "auth")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"clients": [
+		{"name": "reader", "token": "secret", "policy": {"readOnly": true}},
+		{"name": "agent", "hmacKey": "a2V5", "policy": {"processes": ["p1"]}},
+		{"name": "fossilizer", "certCommonName": "fossilizer.example.com"}
+	]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	a, err := LoadAuthenticator(f.Name())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	identity, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "reader", identity.Name)
+	assert.True(t, identity.Policy.ReadOnly)
+
+	req = httptest.NewRequest("POST", "/test", nil)
+	require.NoError(t, SignRequest(req, "agent", []byte("key")))
+	identity, err = a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "agent", identity.Name)
+	assert.Equal(t, []string{"p1"}, identity.Policy.Processes)
+}
+
+func TestAuthConfig_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients []ClientConfig
+	}{
+		{"no name", []ClientConfig{{Token: "secret"}}},
+		{"no credentials", []ClientConfig{{Identity: Identity{Name: "client"}}}},
+		{"same token", []ClientConfig{
+			{Identity: Identity{Name: "c1"}, Token: "secret"},
+			{Identity: Identity{Name: "c2"}, Token: "secret"},
+		}},
+		{"same common name", []ClientConfig{
+			{Identity: Identity{Name: "c1"}, CertCommonName: "client"},
+			{Identity: Identity{Name: "c2"}, CertCommonName: "client"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := AuthConfig{Clients: tt.clients}
+			_, err := config.Authenticator()
+			assert.Error(t, err)
+		})
+	}
+}
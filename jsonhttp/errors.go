@@ -24,6 +24,8 @@ import (
 type ErrHTTP struct {
 	msg    string
 	status int
+	// data is JSON encoded so that errors stay comparable.
+	data string
 }
 
 // NewErrHTTP creates a, error with a message and HTTP status code.
@@ -59,6 +61,15 @@ func NewErrUnauthorized(msg string) ErrHTTP {
 	return NewErrHTTP(msg, http.StatusUnauthorized)
 }
 
+// NewErrForbidden creates an error with a forbidden HTTP status code.
+// If the message is empty, the default is "forbidden".
+func NewErrForbidden(msg string) ErrHTTP {
+	if msg == "" {
+		msg = "forbidden"
+	}
+	return NewErrHTTP(msg, http.StatusForbidden)
+}
+
 // NewErrNotFound creates an error with a not found HTTP status code.
 // If the message is empty, the default is "not found".
 func NewErrNotFound(msg string) ErrHTTP {
@@ -68,6 +79,17 @@ func NewErrNotFound(msg string) ErrHTTP {
 	return NewErrHTTP(msg, http.StatusNotFound)
 }
 
+// WithData returns a copy of the error that also renders the given JSON
+// serializable data, for instance to give details about the error.
+func (e ErrHTTP) WithData(data interface{}) ErrHTTP {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return e
+	}
+	e.data = string(js)
+	return e
+}
+
 // Status returns the HTTP status code of the error.
 func (e ErrHTTP) Status() int {
 	return e.status
@@ -82,10 +104,15 @@ var internalServerJSON = fmt.Sprintf(`{"error:":"internal server error","status"
 
 // JSONMarshal marshals an error to JSON.
 func (e ErrHTTP) JSONMarshal() []byte {
-	js, err := json.Marshal(map[string]interface{}{
+	fields := map[string]interface{}{
 		"error":  e.msg,
 		"status": e.status,
-	})
+	}
+	if e.data != "" {
+		fields["data"] = json.RawMessage(e.data)
+	}
+
+	js, err := json.Marshal(fields)
 	if err != nil {
 		msg := internalServerJSON
 		return []byte(msg)
@@ -37,8 +37,26 @@ func TestNewErrUnauthorized(t *testing.T) {
 	testErrError(t, NewErrUnauthorized("test"), "test")
 }
 
+func TestNewErrForbidden(t *testing.T) {
+	testErrStatus(t, NewErrForbidden(""), http.StatusForbidden)
+	testErrError(t, NewErrForbidden(""), "forbidden")
+	testErrError(t, NewErrForbidden("test"), "test")
+}
+
 func TestNewErrNotFound(t *testing.T) {
 	testErrStatus(t, NewErrNotFound(""), http.StatusNotFound)
 	testErrError(t, NewErrNotFound(""), "not found")
 	testErrError(t, NewErrNotFound("test"), "test")
 }
+
+func TestErrHTTPWithData(t *testing.T) {
+	err := NewErrBadRequest("").WithData([]string{"a"})
+	testErrStatus(t, err, http.StatusBadRequest)
+
+	if got, want := string(err.JSONMarshal()), `{"data":["a"],"error":"bad request","status":400}`; got != want {
+		t.Errorf("err.JSONMarshal() = %s want %s", got, want)
+	}
+	if got, want := string(NewErrBadRequest("").JSONMarshal()), `{"error":"bad request","status":400}`; got != want {
+		t.Errorf("err.JSONMarshal() = %s want %s", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HMACScheme is the scheme of the Authorization header of signed
+	// requests.
+	HMACScheme = "HMAC-SHA256"
+
+	// DefaultHMACMaxSkew is the default maximum difference between the
+	// Date header of a signed request and the time of the server.
+	DefaultHMACMaxSkew = 5 * time.Minute
+)
+
+// HMACKey is a key used to sign requests.
+type HMACKey struct {
+	Key      []byte
+	Identity *Identity
+}
+
+// HMACAuthenticator authenticates signed requests.
+//
+// Requests must have a Date header and an Authorization header of the form:
+//
+//	HMAC-SHA256 <keyID>:<signature>
+//
+// The signature is the base64 encoded HMAC-SHA256 of the method, the
+// request URI, the date and the hex encoded SHA-256 of the body, separated
+// by line feeds. SignRequest signs requests this way.
+type HMACAuthenticator struct {
+	keys map[string]HMACKey
+
+	// MaxSkew is the maximum difference between the date of a request
+	// and the time of the server. It limits replays.
+	MaxSkew time.Duration
+}
+
+// NewHMACAuthenticator creates an authenticator from a map of key IDs to
+// keys.
+func NewHMACAuthenticator(keys map[string]HMACKey) *HMACAuthenticator {
+	return &HMACAuthenticator{keys: keys, MaxSkew: DefaultHMACMaxSkew}
+}
+
+// Authenticate implements Authenticator.Authenticate.
+func (a *HMACAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	const prefix = HMACScheme + " "
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(auth[len(prefix):], ":", 2)
+	if len(parts) != 2 {
+		return nil, NewErrUnauthorized("invalid signature")
+	}
+
+	key, ok := a.keys[parts[0]]
+	if !ok {
+		return nil, NewErrUnauthorized("unknown key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, NewErrUnauthorized("invalid signature")
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return nil, NewErrUnauthorized("invalid date")
+	}
+	if skew := time.Since(date); skew > a.MaxSkew || skew < -a.MaxSkew {
+		return nil, NewErrUnauthorized("request expired")
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(signature, sign(key.Key, r, body)) {
+		return nil, NewErrUnauthorized("invalid signature")
+	}
+
+	return key.Identity, nil
+}
+
+// SignRequest signs a request that will be authenticated by a
+// HMACAuthenticator. It sets the Date header if it is missing.
+// It must be called after the body and the URL of the request are set.
+func SignRequest(r *http.Request, keyID string, key []byte) error {
+	if r.Header.Get("Date") == "" {
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	var body []byte
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer rc.Close()
+		if body, err = ioutil.ReadAll(rc); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		var err error
+		if body, err = readBody(r); err != nil {
+			return err
+		}
+	}
+
+	signature := base64.StdEncoding.EncodeToString(sign(key, r, body))
+	r.Header.Set("Authorization", HMACScheme+" "+keyID+":"+signature)
+
+	return nil
+}
+
+func sign(key []byte, r *http.Request, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("Date"),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+
+	return mac.Sum(nil)
+}
+
+// readBody reads the body of a request and replaces it so that it can be
+// read again.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACAuthenticator(t *testing.T) {
+	identity := &Identity{Name: "agent"}
+	a := NewHMACAuthenticator(map[string]HMACKey{"agent": {Key: []byte("key"), Identity: identity}})
+
+	newRequest := func(body string) *http.Request {
+		return httptest.NewRequest("POST", "/links?a=b", strings.NewReader(body))
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		req := newRequest(`{"state":{}}`)
+		require.NoError(t, SignRequest(req, "agent", []byte("key")))
+
+		got, err := a.Authenticate(req)
+		require.NoError(t, err)
+		assert.Equal(t, identity, got)
+
+		// The body can still be read by the handler.
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"state":{}}`, string(body))
+	})
+
+	t.Run("no signature", func(t *testing.T) {
+		got, err := a.Authenticate(newRequest(""))
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		req := newRequest("")
+		require.NoError(t, SignRequest(req, "other", []byte("key")))
+		_, err := a.Authenticate(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		req := newRequest("")
+		require.NoError(t, SignRequest(req, "agent", []byte("nope")))
+		_, err := a.Authenticate(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := newRequest("data")
+		require.NoError(t, SignRequest(req, "agent", []byte("key")))
+		req.Body = ioutil.NopCloser(strings.NewReader("other data"))
+		_, err := a.Authenticate(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered URL", func(t *testing.T) {
+		req := newRequest("")
+		require.NoError(t, SignRequest(req, "agent", []byte("key")))
+		req.URL.RawQuery = "a=c"
+		_, err := a.Authenticate(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		req := newRequest("")
+		req.Header.Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		require.NoError(t, SignRequest(req, "agent", []byte("key")))
+		_, err := a.Authenticate(req)
+		assert.Error(t, err)
+	})
+}
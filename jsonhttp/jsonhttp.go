@@ -20,7 +20,9 @@ package jsonhttp
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"net"
 	"net/http"
 	"time"
 
@@ -48,7 +50,8 @@ type Config struct {
 	// ReadTimeout is the read timeout.
 	ReadTimeout time.Duration
 
-	// WriteTimeout is the read timeout.
+	// WriteTimeout is the write timeout. It applies to each write rather
+	// than to the whole response, so that event streams can stay open.
 	WriteTimeout time.Duration
 
 	// MaxHeaderBytes is the max header bytes.
@@ -59,6 +62,16 @@ type Config struct {
 
 	// Optionally, the path to a TLS private key.
 	KeyFile string
+
+	// Optionally, the path to the PEM encoded certificates of the
+	// authorities that sign client certificates. Clients that present a
+	// certificate must then have a valid one. It requires TLS.
+	ClientCAFile string
+
+	// Optionally, the authenticator of requests. If set, requests from
+	// clients that cannot be authenticated are rejected, as well as
+	// requests that the policy of the client doesn't allow.
+	Authenticator Authenticator
 }
 
 // Server is the type that implements net/http.Handler.
@@ -83,11 +96,11 @@ func NotFound(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (inte
 func New(config *Config) *Server {
 	router := httprouter.New()
 	router.NotFound = notFoundHandler{config: config, serve: NotFound}.ServeHTTP
+	// The write timeout is set on connections by the listener, see Serve.
 	server := &http.Server{
 		Addr:           config.Address,
 		Handler:        &ochttp.Handler{Handler: router, IsPublicEndpoint: true},
 		ReadTimeout:    config.ReadTimeout,
-		WriteTimeout:   config.WriteTimeout,
 		MaxHeaderBytes: config.MaxHeaderBytes,
 	}
 	return &Server{server: server, router: router, config: config}
@@ -135,11 +148,47 @@ func (s *Server) GetRaw(path string, handle RawHandle) {
 
 // ListenAndServe starts the server.
 func (s *Server) ListenAndServe() error {
-	if s.config.CertFile != "" && s.config.KeyFile != "" {
-		return s.server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile)
+	if s.config.ClientCAFile != "" {
+		clientCAs, err := loadClientCAs(s.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = &tls.Config{
+			ClientCAs:  clientCAs,
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
+	}
+
+	useTLS := s.config.CertFile != "" && s.config.KeyFile != ""
+
+	addr := s.config.Address
+	if addr == "" {
+		addr = ":http"
+		if useTLS {
+			addr = ":https"
+		}
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if useTLS {
+		return s.server.ServeTLS(s.listener(l), s.config.CertFile, s.config.KeyFile)
 	}
 
-	return s.server.ListenAndServe()
+	return s.Serve(l)
+}
+
+// Serve accepts HTTP connections on a listener.
+// Each write to a connection times out after the write timeout.
+func (s *Server) Serve(l net.Listener) error {
+	return s.server.Serve(s.listener(l))
+}
+
+func (s *Server) listener(l net.Listener) net.Listener {
+	return writeTimeoutListener{Listener: l, timeout: s.config.WriteTimeout}
 }
 
 // Shutdown stops the server.
@@ -153,7 +202,11 @@ type handler struct {
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	var err error
+	r, err := authenticate(h.config.Authenticator, r)
+	if err != nil {
+		renderErr(w, r, err)
+		return
+	}
 
 	data, err := h.serve(w, r, p)
 	if err != nil {
@@ -180,6 +233,12 @@ type rawHandler struct {
 }
 
 func (h rawHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	r, err := authenticate(h.config.Authenticator, r)
+	if err != nil {
+		renderErr(w, r, err)
+		return
+	}
+
 	h.serve(w, r, p)
 }
 
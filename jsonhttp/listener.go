@@ -0,0 +1,94 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// tcpKeepAlivePeriod is the keep-alive period of accepted connections, as
+// set by net/http.ListenAndServe.
+const tcpKeepAlivePeriod = 3 * time.Minute
+
+// writeTimeoutListener accepts connections that time out each write
+// instead of each response, so that long-lived responses such as event
+// streams are not closed while a client that stops reading still is.
+type writeTimeoutListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+// Accept implements net.Listener.Accept.
+func (l writeTimeoutListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetKeepAlive(true)
+		tc.SetKeepAlivePeriod(tcpKeepAlivePeriod)
+	}
+
+	if l.timeout <= 0 {
+		return conn, nil
+	}
+
+	return &writeTimeoutConn{Conn: conn, timeout: l.timeout}, nil
+}
+
+// writeTimeoutConn sets a write deadline before each write, unless the
+// user of the connection manages its own deadlines, like web sockets do.
+// Setting a zero deadline, as net/http does after each response, switches
+// back to the write timeout.
+type writeTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+	manual  int32
+}
+
+// Write implements net.Conn.Write.
+func (c *writeTimeoutConn) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(&c.manual) == 0 {
+		if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.Conn.Write(b)
+}
+
+// SetDeadline implements net.Conn.SetDeadline.
+func (c *writeTimeoutConn) SetDeadline(t time.Time) error {
+	c.setManual(t)
+	return c.Conn.SetDeadline(t)
+}
+
+// SetWriteDeadline implements net.Conn.SetWriteDeadline.
+func (c *writeTimeoutConn) SetWriteDeadline(t time.Time) error {
+	c.setManual(t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// setManual disables the write timeout while the write deadline is set.
+func (c *writeTimeoutConn) setManual(t time.Time) {
+	var manual int32
+	if !t.IsZero() {
+		manual = 1
+	}
+	atomic.StoreInt32(&c.manual, manual)
+}
@@ -0,0 +1,278 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LastEventIDHeader is the request header containing the ID of the
+	// last event received by a server-sent events client.
+	LastEventIDHeader = "Last-Event-ID"
+
+	// DefaultStreamHeartbeatInterval is the default interval between
+	// comments sent to keep idle event streams open.
+	DefaultStreamHeartbeatInterval = 15 * time.Second
+)
+
+// StreamEvent is a server-sent event. Its data is encoded to JSON.
+type StreamEvent struct {
+	ID   uint64
+	Type string
+	Data interface{}
+}
+
+// EventStream writes server-sent events to an HTTP response.
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewEventStream writes the headers of a server-sent events response and
+// returns a stream to send events to the client.
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, NewErrInternalServer("streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{w: w, flusher: flusher}, nil
+}
+
+// Send writes an event to the stream.
+func (s *EventStream) Send(e *StreamEvent) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	if e.ID > 0 {
+		if _, err := fmt.Fprintf(s.w, "id: %d\n", e.ID); err != nil {
+			return err
+		}
+	}
+	if e.Type != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", e.Type); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+// Heartbeat writes a comment to the stream to keep it open.
+func (s *EventStream) Heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ":\n\n"); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+// LastEventID returns the ID of the last event received by a client that
+// reconnects to an event stream. The boolean is false if the client did not
+// send one.
+func LastEventID(r *http.Request) (uint64, bool, error) {
+	value := r.Header.Get(LastEventIDHeader)
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, NewErrBadRequest("Last-Event-ID must be a positive integer")
+	}
+
+	return id, true, nil
+}
+
+// ServeEventStream sends the events of a channel to a client until the
+// channel is closed or the client goes away. Idle streams are kept open by
+// sending a heartbeat at the given interval.
+//
+// The write timeout of the server applies to each event, so a stream ends
+// if the client stops reading. Clients should reconnect with the
+// Last-Event-ID header when a stream ends.
+func ServeEventStream(w http.ResponseWriter, r *http.Request, events <-chan *StreamEvent, heartbeat time.Duration) {
+	stream, err := NewEventStream(w)
+	if err != nil {
+		renderErr(w, r, err)
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			err = stream.Send(e)
+		case <-ticker.C:
+			err = stream.Heartbeat()
+		case <-r.Context().Done():
+			return
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"url":    r.RequestURI,
+				"origin": r.RemoteAddr,
+			}).Info("Closing event stream")
+			return
+		}
+	}
+}
+
+// EventStreamReader reads the server-sent events of a response, such as
+// the ones sent by an EventStream. The data of the events it reads is a
+// json.RawMessage.
+type EventStreamReader struct {
+	r *bufio.Reader
+}
+
+// NewEventStreamReader creates a reader of the events of a response body.
+func NewEventStreamReader(r io.Reader) *EventStreamReader {
+	return &EventStreamReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event of the stream, skipping heartbeats. It returns
+// io.EOF when the stream ends.
+func (s *EventStreamReader) Next() (*StreamEvent, error) {
+	var (
+		e    StreamEvent
+		data []string
+		seen bool
+	)
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if !seen {
+				continue
+			}
+			if data != nil {
+				e.Data = json.RawMessage(strings.Join(data, "\n"))
+			}
+			return &e, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				e.ID = id
+			}
+		case "event":
+			e.Type = value
+		case "data":
+			data = append(data, value)
+		default:
+			continue
+		}
+		seen = true
+	}
+}
+
+// Journal keeps the latest events of a stream so that clients can replay
+// the events they missed while disconnected. It is not safe for concurrent
+// use.
+type Journal struct {
+	size   int
+	lastID uint64
+	events []*StreamEvent
+}
+
+// NewJournal creates a journal that keeps up to size events.
+func NewJournal(size int) *Journal {
+	return &Journal{size: size}
+}
+
+// Add sets the ID of an event to the next one and keeps the event, dropping
+// the oldest one if the journal is full.
+func (j *Journal) Add(e *StreamEvent) {
+	j.lastID++
+	e.ID = j.lastID
+
+	if j.size > 0 {
+		if len(j.events) >= j.size {
+			j.events = j.events[1:]
+		}
+		j.events = append(j.events, e)
+	}
+}
+
+// LastID returns the ID of the last event added to the journal.
+func (j *Journal) LastID() uint64 {
+	return j.lastID
+}
+
+// Since returns the events that follow the given ID. The boolean is false
+// if some of these events are no longer in the journal.
+func (j *Journal) Since(id uint64) ([]*StreamEvent, bool) {
+	if id > j.lastID {
+		// IDs were reset, probably by a restart.
+		return nil, false
+	}
+	if id == j.lastID {
+		return nil, true
+	}
+	if len(j.events) == 0 || j.events[0].ID > id+1 {
+		return j.events, false
+	}
+
+	return j.events[id+1-j.events[0].ID:], true
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeEventStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", nil)
+
+	events := make(chan *StreamEvent, 2)
+	events <- &StreamEvent{ID: 1, Type: "saved", Data: map[string]string{"a": "b"}}
+	events <- &StreamEvent{Data: "c"}
+	close(events)
+
+	ServeEventStream(w, r, events, time.Minute)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id: 1\nevent: saved\ndata: {\"a\":\"b\"}\n\ndata: \"c\"\n\n", w.Body.String())
+}
+
+func TestEventStreamReader(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	stream, err := NewEventStream(w)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&StreamEvent{ID: 1, Type: "saved", Data: map[string]string{"a": "b"}}))
+	require.NoError(t, stream.Heartbeat())
+	require.NoError(t, stream.Send(&StreamEvent{Data: "c"}))
+
+	r := NewEventStreamReader(strings.NewReader(w.Body.String() + "data: 1\r\ndata: 2\r\n\r\nevent: partial\n"))
+
+	e, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, &StreamEvent{ID: 1, Type: "saved", Data: json.RawMessage(`{"a":"b"}`)}, e)
+
+	e, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, &StreamEvent{Data: json.RawMessage(`"c"`)}, e)
+
+	e, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, &StreamEvent{Data: json.RawMessage("1\n2")}, e)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLastEventID(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/events", nil)
+		_, ok, err := LastEventID(r)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Header.Set(LastEventIDHeader, "42")
+		id, ok, err := LastEventID(r)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(42), id)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Header.Set(LastEventIDHeader, "abc")
+		_, _, err := LastEventID(r)
+		assert.IsType(t, ErrHTTP{}, err)
+	})
+}
+
+func journalIDs(events []*StreamEvent) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestJournal(t *testing.T) {
+	j := NewJournal(3)
+	for i := 0; i < 5; i++ {
+		j.Add(&StreamEvent{})
+	}
+	assert.Equal(t, uint64(5), j.LastID())
+
+	t.Run("available", func(t *testing.T) {
+		events, ok := j.Since(2)
+		assert.True(t, ok)
+		assert.Equal(t, []uint64{3, 4, 5}, journalIDs(events))
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		events, ok := j.Since(5)
+		assert.True(t, ok)
+		assert.Empty(t, events)
+	})
+
+	t.Run("lost", func(t *testing.T) {
+		events, ok := j.Since(1)
+		assert.False(t, ok)
+		assert.Equal(t, []uint64{3, 4, 5}, journalIDs(events))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		events, ok := j.Since(6)
+		assert.False(t, ok)
+		assert.Empty(t, events)
+	})
+}
+
+func TestJournal_empty(t *testing.T) {
+	j := NewJournal(0)
+	j.Add(&StreamEvent{})
+
+	events, ok := j.Since(0)
+	assert.False(t, ok)
+	assert.Empty(t, events)
+
+	_, ok = j.Since(1)
+	assert.True(t, ok)
+}
+
+func TestServeEventStream_writeTimeout(t *testing.T) {
+	s := New(&Config{WriteTimeout: 100 * time.Millisecond})
+
+	events := make(chan *StreamEvent)
+	s.GetRaw("/events", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ServeEventStream(w, r, events, time.Minute)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	res, err := http.Get("http://" + l.Addr().String() + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// The stream outlives the write timeout.
+	go func() {
+		for i := 1; i <= 4; i++ {
+			time.Sleep(60 * time.Millisecond)
+			events <- &StreamEvent{ID: uint64(i)}
+		}
+		close(events)
+	}()
+
+	r := NewEventStreamReader(res.Body)
+	for i := 1; i <= 4; i++ {
+		e, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), e.ID)
+	}
+}
+
+func TestServeEventStream_stalledClientOnReusedConn(t *testing.T) {
+	s := New(&Config{WriteTimeout: 100 * time.Millisecond})
+
+	s.Get("/ping", func(http.ResponseWriter, *http.Request, httprouter.Params) (interface{}, error) {
+		return "pong", nil
+	})
+
+	// The stream sends large events until the client is timed out.
+	done := make(chan struct{})
+	s.GetRaw("/events", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		events := make(chan *StreamEvent)
+		stop := make(chan struct{})
+		go func() {
+			data := strings.Repeat("a", 64*1024)
+			for {
+				select {
+				case events <- &StreamEvent{Data: data}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+		ServeEventStream(w, r, events, time.Minute)
+		close(stop)
+		close(done)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A first response resets the write deadline of the connection.
+	_, err = io.WriteString(conn, "GET /ping HTTP/1.1\r\nHost: test\r\n\r\n")
+	require.NoError(t, err)
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The client then stops reading the stream.
+	_, err = io.WriteString(conn, "GET /events HTTP/1.1\r\nHost: test\r\n\r\n")
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("stalled stream not timed out")
+	}
+}
@@ -56,6 +56,10 @@ const (
 type Message struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
+
+	// Seq is an optional sequence number that lets clients detect and
+	// recover missed messages.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 // Basic implements basic web socket server meant to be used in conjunction with
@@ -153,6 +157,13 @@ func (s *Basic) AddMsgChannel(c chan BasicConnMsg) {
 // Handle handles an HTTP request for a web socket connection. The web socket
 // route of the HTTP server should pass the writer and request to this function.
 func (s *Basic) Handle(w http.ResponseWriter, r *http.Request) {
+	s.HandleRegistered(w, r, nil)
+}
+
+// HandleRegistered is like Handle, but calls the given function once the
+// connection is registered in the hub and before messages are read from it.
+// It can be used to tag the connection.
+func (s *Basic) HandleRegistered(w http.ResponseWriter, r *http.Request, registered func(*BufferedConn)) {
 	conn, err := s.upgradeHandle(w, r, nil)
 
 	if err != nil {
@@ -171,6 +182,10 @@ func (s *Basic) Handle(w http.ResponseWriter, r *http.Request) {
 
 	s.Register(bufConn)
 
+	if registered != nil {
+		registered(bufConn)
+	}
+
 	errChan := make(chan error)
 
 	go func() {
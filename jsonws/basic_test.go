@@ -66,6 +66,33 @@ func TestBasicAddConnChannel(t *testing.T) {
 	}
 }
 
+func TestBasicHandleRegistered(t *testing.T) {
+	ws := NewBasic(&BasicConfig{
+		UpgradeHandle: testUpgradeHandle,
+	}, &BufferedConnConfig{
+		PingInterval: time.Second,
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/ws", nil)
+	c := make(chan *BufferedConn)
+
+	go ws.Start()
+	go ws.HandleRegistered(w, r, func(conn *BufferedConn) {
+		ws.Tag(conn, "tag")
+		c <- conn
+	})
+	defer ws.Stop()
+
+	select {
+	case got := <-c:
+		if got == nil {
+			t.Errorf("<-c = nil want not nil")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("registered function not called")
+	}
+}
+
 func TestBasicAddMsgChannel(t *testing.T) {
 	ws := NewBasic(&BasicConfig{
 		UpgradeHandle: testUpgradeHandle,
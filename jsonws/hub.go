@@ -73,6 +73,9 @@ func (h *Hub) Start() {
 			// Remove connection from tags.
 			for t := range h.conns[c] {
 				delete(h.tags[t], c)
+				if len(h.tags[t]) == 0 {
+					delete(h.tags, t)
+				}
 			}
 			delete(h.conns, c)
 		case t := <-h.tagChan:
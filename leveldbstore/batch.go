@@ -0,0 +1,75 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldbstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/bufferedbatch"
+	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.opencensus.io/trace"
+)
+
+// Batch is the type that implements github.com/stratumn/go-indigocore/store.Batch.
+// Links are written atomically using a LevelDB write batch.
+type Batch struct {
+	*bufferedbatch.Batch
+
+	originalStore *LevelDBStore
+}
+
+// NewBatch creates a new Batch.
+func NewBatch(ctx context.Context, a *LevelDBStore) *Batch {
+	return &Batch{
+		Batch:         bufferedbatch.NewBatch(ctx, a),
+		originalStore: a,
+	}
+}
+
+// Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+func (b *Batch) Write(ctx context.Context) (err error) {
+	_, span := trace.StartSpan(ctx, "leveldbstore/batch/Write")
+	defer monitoring.SetSpanStatusAndEnd(span, err)
+
+	a := b.originalStore
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	batch := new(leveldb.Batch)
+	now := time.Now()
+	for _, link := range b.Links {
+		if _, err = putLink(batch, a.db, link, now); err != nil {
+			return err
+		}
+	}
+
+	if err = a.db.Write(batch, nil); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(b.Links) > 0 {
+		linksEvent := store.NewSavedLinks(b.Links...)
+
+		for _, c := range a.eventChans {
+			c <- linksEvent
+		}
+	}
+
+	return nil
+}
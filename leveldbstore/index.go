@@ -0,0 +1,229 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldbstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Key-value pairs are saved under their key prefixed with \x01, so that any
+// key can be used without colliding with the data of the store:
+//
+//	\x01<key> -> value
+//
+// All the other keys used by the store start with a null byte. The second
+// byte is the type of the key.
+//
+// Links and evidences are saved under their link hash:
+//
+//	\x00l<linkHash> -> link
+//	\x00e<linkHash> -> evidences
+//	\x00c<linkHash> -> time the link was first saved (Unix nanoseconds)
+//
+// Indexes have empty values and end with the link hash of the segment they
+// point to, so that a prefix search on a key returns the matching segments:
+//
+//	\x00ip<process>\x00<linkHash>
+//	\x00im<mapID>\x00<linkHash>
+//	\x00ix\x00<linkHash>                    (no previous link hash)
+//	\x00ix\x01<prevLinkHash><linkHash>
+//	\x00it<tag>\x00<linkHash>
+//	\x00ir<refLinkHash>\x00<linkHash>      (meta refs)
+//	\x00io<priority><linkHash>              (sort order)
+//
+// Map IDs are also indexed with their process to list them quickly:
+//
+//	\x00iM<mapID>\x00<process>
+//
+// The version of the layout of the database is saved under \x00v.
+const (
+	reservedByte = 0x00
+	valueByte    = 0x01
+
+	linkPrefix      = 'l'
+	evidencesPrefix = 'e'
+	createdAtPrefix = 'c'
+	indexPrefix     = 'i'
+	versionPrefix   = 'v'
+
+	processIndex  = 'p'
+	mapIndex      = 'm'
+	prevIndex     = 'x'
+	tagIndex      = 't'
+	refIndex      = 'r'
+	priorityIndex = 'o'
+	mapIDsIndex   = 'M'
+
+	separator = 0x00
+)
+
+func newKey(kind byte, size int) []byte {
+	key := make([]byte, 0, 2+size)
+	return append(key, reservedByte, kind)
+}
+
+// valueKey returns the key under which the value of a key-value pair is
+// saved.
+func valueKey(key []byte) []byte {
+	return append([]byte{valueByte}, key...)
+}
+
+func versionKey() []byte {
+	return newKey(versionPrefix, 0)
+}
+
+func linkKey(linkHash *types.Bytes32) []byte {
+	return append(newKey(linkPrefix, types.Bytes32Size), linkHash[:]...)
+}
+
+func evidencesKey(linkHash *types.Bytes32) []byte {
+	return append(newKey(evidencesPrefix, types.Bytes32Size), linkHash[:]...)
+}
+
+func createdAtKey(linkHash *types.Bytes32) []byte {
+	return append(newKey(createdAtPrefix, types.Bytes32Size), linkHash[:]...)
+}
+
+// indexKey returns the prefix of the keys of an index for the given value.
+func indexKey(index byte, value string) []byte {
+	key := newKey(indexPrefix, 2+len(value)+types.Bytes32Size)
+	key = append(key, index)
+	key = append(key, value...)
+	return append(key, separator)
+}
+
+// prevLinkHashKey returns the prefix of the keys of the previous link hash
+// index. A nil link hash means the segment doesn't have a parent.
+func prevLinkHashKey(prevLinkHash *types.Bytes32) []byte {
+	key := newKey(indexPrefix, 2+2*types.Bytes32Size)
+	key = append(key, prevIndex)
+	if prevLinkHash == nil {
+		return append(key, 0)
+	}
+	key = append(key, 1)
+	return append(key, prevLinkHash[:]...)
+}
+
+// priorityKey returns the key of a segment in the priority index.
+// Keys are sorted like cs.SegmentSlice, that is by descending priority, then
+// by ascending link hash.
+func priorityKey(priority float64, linkHash *types.Bytes32) []byte {
+	key := newKey(indexPrefix, 1+8+types.Bytes32Size)
+	key = append(key, priorityIndex)
+
+	// Flip the bits of the float so that the byte order of positive and
+	// negative numbers matches their numeric order, then invert it to get
+	// a descending order.
+	if priority == 0 {
+		// Treat negative zero like zero.
+		priority = 0
+	}
+
+	bits := math.Float64bits(priority)
+	if bits&(1<<63) == 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	bits = ^bits
+
+	for i := uint(0); i < 8; i++ {
+		key = append(key, byte(bits>>(56-8*i)))
+	}
+
+	return append(key, linkHash[:]...)
+}
+
+// priorityIndexKey returns the prefix of all the keys of the priority index.
+func priorityIndexKey() []byte {
+	return append(newKey(indexPrefix, 1), priorityIndex)
+}
+
+// mapIDsKey returns the prefix of the map ID index.
+func mapIDsKey(prefix string) []byte {
+	key := append(newKey(indexPrefix, 1+len(prefix)), mapIDsIndex)
+	return append(key, prefix...)
+}
+
+// parseMapIDsKey returns the map ID and the process of a key of the map ID
+// index.
+func parseMapIDsKey(key []byte) (mapID, process string) {
+	key = key[3:]
+	for i, b := range key {
+		if b == separator {
+			return string(key[:i]), string(key[i+1:])
+		}
+	}
+	return string(key), ""
+}
+
+// linkHashFromKey returns the link hash an index key points to.
+func linkHashFromKey(key []byte) *types.Bytes32 {
+	if len(key) < types.Bytes32Size {
+		return nil
+	}
+	return types.NewBytes32FromBytes(key[len(key)-types.Bytes32Size:])
+}
+
+// putLink adds a link and its index entries to a write batch.
+// The time at which the link is saved is only recorded if the reader doesn't
+// already contain the link.
+func putLink(batch *leveldb.Batch, r reader, link *cs.Link, now time.Time) (*types.Bytes32, error) {
+	linkHash, err := link.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := json.Marshal(link)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	batch.Put(linkKey(linkHash), js)
+
+	_, err = r.Get(createdAtKey(linkHash), nil)
+	if err == leveldb.ErrNotFound {
+		createdAt := make([]byte, 8)
+		binary.BigEndian.PutUint64(createdAt, uint64(now.UnixNano()))
+		batch.Put(createdAtKey(linkHash), createdAt)
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	meta := link.Meta
+	batch.Put(append(indexKey(processIndex, meta.Process), linkHash[:]...), nil)
+	batch.Put(append(indexKey(mapIndex, meta.MapID), linkHash[:]...), nil)
+	batch.Put(append(prevLinkHashKey(meta.GetPrevLinkHash()), linkHash[:]...), nil)
+	for _, tag := range meta.Tags {
+		batch.Put(append(indexKey(tagIndex, tag), linkHash[:]...), nil)
+	}
+	for _, refLinkHash := range meta.GetRefLinkHashes() {
+		batch.Put(append(indexKey(refIndex, refLinkHash), linkHash[:]...), nil)
+	}
+	batch.Put(priorityKey(meta.Priority, linkHash), nil)
+
+	mapIDKey := append(mapIDsKey(meta.MapID), separator)
+	batch.Put(append(mapIDKey, meta.Process...), nil)
+
+	return linkHash, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldbstore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// StreamSegments implements github.com/stratumn/go-indigocore/store.SegmentStreamer.StreamSegments.
+// Segments are read lazily from a snapshot of the database while walking
+// the most selective index, so they are only sorted if the filter doesn't
+// use an index.
+func (a *LevelDBStore) StreamSegments(ctx context.Context, filter *store.SegmentFilter) (store.SegmentIterator, error) {
+	snapshot, err := a.db.GetSnapshot()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	p := newPlan(filter)
+	if p == nil {
+		p = scanPlan()
+	}
+
+	return &segmentIterator{
+		store:    a,
+		filter:   filter,
+		plan:     p,
+		snapshot: snapshot,
+	}, nil
+}
+
+// segmentIterator walks the candidates of a plan.
+type segmentIterator struct {
+	store  *LevelDBStore
+	filter *store.SegmentFilter
+	plan   *plan
+
+	snapshot *leveldb.Snapshot
+	iter     iterator.Iterator
+	prefix   []byte
+	current  *cs.Segment
+	err      error
+}
+
+// Next implements github.com/stratumn/go-indigocore/store.SegmentIterator.Next.
+func (it *segmentIterator) Next() bool {
+	it.current = nil
+
+	for it.err == nil && it.snapshot != nil {
+		linkHash := it.nextCandidate()
+		if linkHash == nil {
+			it.Close()
+			return false
+		}
+
+		segment, err := it.store.getSegment(it.snapshot, linkHash)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		match, err := it.store.matchSegment(it.snapshot, it.filter, segment)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if match {
+			it.current = segment
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextCandidate returns the next candidate of the plan, or nil when there
+// are no more candidates or an error occurred.
+func (it *segmentIterator) nextCandidate() *types.Bytes32 {
+	p := it.plan
+
+	if len(p.linkHashes) > 0 {
+		linkHash := p.linkHashes[0]
+		p.linkHashes = p.linkHashes[1:]
+		return linkHash
+	}
+
+	for {
+		if it.iter == nil {
+			if len(p.prefixes) == 0 {
+				return nil
+			}
+			it.prefix, p.prefixes = p.prefixes[0], p.prefixes[1:]
+			it.iter = it.snapshot.NewIterator(util.BytesPrefix(it.prefix), nil)
+		}
+
+		for it.iter.Next() {
+			key := it.iter.Key()
+			if p.exact && len(key) != len(it.prefix)+types.Bytes32Size {
+				continue
+			}
+			return linkHashFromKey(key)
+		}
+
+		it.iter.Release()
+		err := it.iter.Error()
+		it.iter = nil
+		if err != nil {
+			it.err = errors.WithStack(err)
+			return nil
+		}
+	}
+}
+
+// Segment implements github.com/stratumn/go-indigocore/store.SegmentIterator.Segment.
+func (it *segmentIterator) Segment() *cs.Segment {
+	return it.current
+}
+
+// Err implements github.com/stratumn/go-indigocore/store.SegmentIterator.Err.
+func (it *segmentIterator) Err() error {
+	return it.err
+}
+
+// Close implements github.com/stratumn/go-indigocore/store.SegmentIterator.Close.
+func (it *segmentIterator) Close() error {
+	if it.iter != nil {
+		it.iter.Release()
+		it.iter = nil
+	}
+	if it.snapshot != nil {
+		it.snapshot.Release()
+		it.snapshot = nil
+	}
+	return nil
+}
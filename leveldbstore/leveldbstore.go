@@ -12,57 +12,345 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package leveldbstore implements a simple key-value local store.
-// It's efficient and can be used by stores to save key-value pairs.
+// Package leveldbstore implements a store that saves all the segments in an
+// embedded LevelDB database.
+//
+// Links, evidences and key-value pairs are saved in the same database, along
+// with secondary indexes by process, map ID, previous link hash, tag and
+// priority, so that searches don't need to scan all the segments.
+// It can also be used as a simple key-value store by other stores.
 package leveldbstore
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// Name is the name set in the store's information.
+	Name = "leveldb"
 
-	"github.com/tendermint/tmlibs/db"
+	// Description is the description set in the store's information.
+	Description = "Indigo's LevelDB Store"
+
+	// DefaultPath is the path where the database will be saved by default.
+	DefaultPath = "/var/stratumn/leveldbstore"
+
+	// dbName is the name of the database directory within the path.
+	// It was created by previous versions of the key-value store, so it is
+	// kept for compatibility.
+	dbName = "keyvalue-store.db"
 )
 
-// LevelDBStore implements github.com/stratumn/go-indigocore/store.KeyValueStore.
+// layoutVersion is the version of the layout of the database.
+// Databases without a version were created by the key-value store that
+// preceded this store and only contain key-value pairs.
+var layoutVersion = []byte{1}
+
+// LevelDBStore is the type that implements github.com/stratumn/go-indigocore/store.Adapter.
 type LevelDBStore struct {
-	config *Config
-	kvDB   db.DB
+	config     *Config
+	eventChans []chan *store.Event
+	mutex      sync.Mutex // serializes writes
+	db         *leveldb.DB
 }
 
 // Config contains configuration options for the store.
 type Config struct {
-	// Path where key-value pairs will be saved.
+	// A version string that will be set in the store's information.
+	Version string
+
+	// A git commit hash that will be set in the store's information.
+	Commit string
+
+	// Path where the database will be saved.
 	Path string
 }
 
+// Info is the info returned by GetInfo.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+}
+
 // New creates an instance of a LevelDBStore.
 func New(config *Config) (*LevelDBStore, error) {
-	db, err := db.NewGoLevelDB("keyvalue-store", config.Path)
+	db, err := leveldb.OpenFile(filepath.Join(config.Path, dbName), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &LevelDBStore{config: config, db: db}, nil
+}
+
+// migrate moves the key-value pairs of a database created by the previous
+// key-value store under their prefix.
+func migrate(db *leveldb.DB) error {
+	if _, err := db.Get(versionKey(), nil); err != leveldb.ErrNotFound {
+		return errors.WithStack(err)
+	}
+
+	batch := new(leveldb.Batch)
+
+	iter := db.NewIterator(nil, nil)
+	for iter.Next() {
+		batch.Delete(iter.Key())
+		batch.Put(valueKey(iter.Key()), iter.Value())
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	batch.Put(versionKey(), layoutVersion)
+
+	return errors.WithStack(db.Write(batch, &opt.WriteOptions{Sync: true}))
+}
+
+// Close closes the database.
+func (a *LevelDBStore) Close() error {
+	return errors.WithStack(a.db.Close())
+}
+
+/********** Store adapter implementation **********/
+
+// GetInfo implements github.com/stratumn/go-indigocore/store.Adapter.GetInfo.
+func (a *LevelDBStore) GetInfo(ctx context.Context) (interface{}, error) {
+	return &Info{
+		Name:        Name,
+		Description: Description,
+		Version:     a.config.Version,
+		Commit:      a.config.Commit,
+	}, nil
+}
+
+// AddStoreEventChannel implements github.com/stratumn/go-indigocore/store.Adapter.AddStoreEventChannel.
+func (a *LevelDBStore) AddStoreEventChannel(eventChan chan *store.Event) {
+	a.eventChans = append(a.eventChans, eventChan)
+}
+
+// NewBatch implements github.com/stratumn/go-indigocore/store.Adapter.NewBatch.
+func (a *LevelDBStore) NewBatch(ctx context.Context) (store.Batch, error) {
+	return NewBatch(ctx, a), nil
+}
+
+/********** Store writer implementation **********/
+
+// CreateLink implements github.com/stratumn/go-indigocore/store.LinkWriter.CreateLink.
+func (a *LevelDBStore) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	batch := new(leveldb.Batch)
+	linkHash, err := putLink(batch, a.db, link, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.db.Write(batch, nil); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	linkEvent := store.NewSavedLinks(link)
+
+	for _, c := range a.eventChans {
+		c <- linkEvent
+	}
+
+	return linkHash, nil
+}
+
+// AddEvidence implements github.com/stratumn/go-indigocore/store.EvidenceWriter.AddEvidence.
+func (a *LevelDBStore) AddEvidence(ctx context.Context, linkHash *types.Bytes32, evidence *cs.Evidence) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	currentEvidences, err := a.getEvidences(a.db, linkHash)
+	if err != nil {
+		return err
+	}
+
+	if err = currentEvidences.AddEvidence(*evidence); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(currentEvidences)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = a.db.Put(evidencesKey(linkHash), value, nil); err != nil {
+		return errors.WithStack(err)
+	}
+
+	evidenceEvent := store.NewSavedEvidences()
+	evidenceEvent.AddSavedEvidence(linkHash, evidence)
+
+	for _, c := range a.eventChans {
+		c <- evidenceEvent
+	}
+
+	return nil
+}
+
+/********** Store reader implementation **********/
+
+// GetSegment implements github.com/stratumn/go-indigocore/store.SegmentReader.GetSegment.
+func (a *LevelDBStore) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
+	return a.getSegment(a.db, linkHash)
+}
+
+// GetEvidences implements github.com/stratumn/go-indigocore/store.EvidenceReader.GetEvidences.
+func (a *LevelDBStore) GetEvidences(ctx context.Context, linkHash *types.Bytes32) (*cs.Evidences, error) {
+	return a.getEvidences(a.db, linkHash)
+}
+
+// reader is implemented by both the database and its snapshots.
+type reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+func (a *LevelDBStore) getSegment(r reader, linkHash *types.Bytes32) (*cs.Segment, error) {
+	link, err := a.getLink(r, linkHash)
+	if err != nil || link == nil {
+		return nil, err
+	}
+
+	evidences, err := a.getEvidences(r, linkHash)
 	if err != nil {
 		return nil, err
 	}
 
-	return &LevelDBStore{config: config, kvDB: db}, nil
+	return &cs.Segment{
+		Link: *link,
+		Meta: cs.SegmentMeta{
+			Evidences: *evidences,
+			LinkHash:  linkHash.String(),
+		},
+	}, nil
+}
+
+// matchSegment checks if a segment matches a filter, including the bounds on
+// the time at which its link was saved. Links saved without a time never
+// match these bounds.
+func (a *LevelDBStore) matchSegment(r reader, filter *store.SegmentFilter, segment *cs.Segment) (bool, error) {
+	if !filter.Match(segment) {
+		return false, nil
+	}
+	if !filter.HasCreatedAtBounds() {
+		return true, nil
+	}
+
+	createdAt, found, err := a.getCreatedAt(r, segment.GetLinkHash())
+	if err != nil || !found {
+		return false, err
+	}
+
+	return filter.MatchCreatedAt(createdAt), nil
 }
 
+// getCreatedAt returns the time at which a link was saved, if it was
+// recorded.
+func (a *LevelDBStore) getCreatedAt(r reader, linkHash *types.Bytes32) (time.Time, bool, error) {
+	data, err := r.Get(createdAtKey(linkHash), nil)
+	if err == leveldb.ErrNotFound {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, errors.WithStack(err)
+	}
+	if len(data) != 8 {
+		return time.Time{}, false, errors.Errorf("invalid creation time for link %s", linkHash)
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))), true, nil
+}
+
+func (a *LevelDBStore) getLink(r reader, linkHash *types.Bytes32) (*cs.Link, error) {
+	data, err := r.Get(linkKey(linkHash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var link cs.Link
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &link, nil
+}
+
+func (a *LevelDBStore) getEvidences(r reader, linkHash *types.Bytes32) (*cs.Evidences, error) {
+	evidences := cs.Evidences{}
+
+	data, err := r.Get(evidencesKey(linkHash), nil)
+	if err == leveldb.ErrNotFound {
+		return &evidences, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := json.Unmarshal(data, &evidences); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &evidences, nil
+}
+
+/********** github.com/stratumn/go-indigocore/store.KeyValueStore implementation **********/
+
 // SetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.SetValue.
 func (a *LevelDBStore) SetValue(ctx context.Context, key []byte, value []byte) error {
-	a.kvDB.Set(key, value)
-	return nil
+	return errors.WithStack(a.db.Put(valueKey(key), value, nil))
 }
 
 // GetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.GetValue.
 func (a *LevelDBStore) GetValue(ctx context.Context, key []byte) ([]byte, error) {
-	return a.kvDB.Get(key), nil
+	v, err := a.db.Get(valueKey(key), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+
+	return v, errors.WithStack(err)
 }
 
 // DeleteValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.DeleteValue.
 func (a *LevelDBStore) DeleteValue(ctx context.Context, key []byte) ([]byte, error) {
-	v := a.kvDB.Get(key)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-	if v != nil {
-		a.kvDB.Delete(key)
-		return v, nil
+	v, err := a.db.Get(valueKey(key), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	return nil, nil
+	return v, errors.WithStack(a.db.Delete(valueKey(key), nil))
 }
@@ -15,32 +15,143 @@
 package leveldbstore
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stratumn/go-indigocore/cs/cstesting"
 	"github.com/stratumn/go-indigocore/store"
 	"github.com/stratumn/go-indigocore/store/storetestcases"
+	"github.com/stratumn/go-indigocore/tmpop/tmpoptestcases"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func TestLevelDBStore(t *testing.T) {
 	factory := storetestcases.Factory{
-		NewKeyValueStore: func() (store.KeyValueStore, error) {
-			path, err := ioutil.TempDir("", "leveldbstore")
-			if err != nil {
-				return nil, err
-			}
-			db, err := New(&Config{Path: path})
-			if err != nil {
-				return nil, err
-			}
-			return db, nil
-		},
-		FreeKeyValueStore: func(s store.KeyValueStore) {
-			a := s.(*LevelDBStore)
-			defer os.RemoveAll(a.config.Path)
-		},
+		New:               createAdapter,
+		NewKeyValueStore:  createKeyValueStore,
+		Free:              freeAdapter,
+		FreeKeyValueStore: freeKeyValueStore,
 	}
 
+	factory.RunStoreTests(t)
 	factory.RunKeyValueStoreTests(t)
 }
+
+func TestLevelDBTMPop(t *testing.T) {
+	tmpoptestcases.Factory{
+		New:  createAdapterTMPop,
+		Free: freeAdapterTMPop,
+	}.RunTests(t)
+}
+
+func TestValueKeys(t *testing.T) {
+	a, err := createLevelDBStore()
+	require.NoError(t, err, "createLevelDBStore()")
+	defer freeLevelDBStore(a)
+
+	ctx := context.Background()
+	link := cstesting.RandomLink()
+	linkHash, err := a.CreateLink(ctx, link)
+	require.NoError(t, err, "a.CreateLink()")
+
+	// A key-value pair using the key of a link must not overwrite it.
+	key := linkKey(linkHash)
+	require.NoError(t, a.SetValue(ctx, key, []byte("value")), "a.SetValue()")
+
+	v, err := a.GetValue(ctx, key)
+	require.NoError(t, err, "a.GetValue()")
+	assert.Equal(t, []byte("value"), v, "a.GetValue()")
+
+	segment, err := a.GetSegment(ctx, linkHash)
+	require.NoError(t, err, "a.GetSegment()")
+	require.NotNil(t, segment)
+	assert.Equal(t, *link, segment.Link)
+
+	v, err = a.DeleteValue(ctx, key)
+	require.NoError(t, err, "a.DeleteValue()")
+	assert.Equal(t, []byte("value"), v, "a.DeleteValue()")
+
+	segment, err = a.GetSegment(ctx, linkHash)
+	require.NoError(t, err, "a.GetSegment()")
+	assert.NotNil(t, segment)
+}
+
+func TestMigrate(t *testing.T) {
+	path, err := ioutil.TempDir("", "leveldbstore")
+	require.NoError(t, err, "ioutil.TempDir()")
+	defer os.RemoveAll(path)
+
+	// Databases of the previous key-value store only contain raw keys.
+	db, err := leveldb.OpenFile(filepath.Join(path, dbName), nil)
+	require.NoError(t, err, "leveldb.OpenFile()")
+	require.NoError(t, db.Put([]byte("key"), []byte("value"), nil), "db.Put()")
+	require.NoError(t, db.Put([]byte{0, 'l'}, []byte("null"), nil), "db.Put()")
+	require.NoError(t, db.Close(), "db.Close()")
+
+	a, err := New(&Config{Path: path})
+	require.NoError(t, err, "New()")
+
+	ctx := context.Background()
+	v, err := a.GetValue(ctx, []byte("key"))
+	require.NoError(t, err, "a.GetValue()")
+	assert.Equal(t, []byte("value"), v, "a.GetValue()")
+
+	v, err = a.GetValue(ctx, []byte{0, 'l'})
+	require.NoError(t, err, "a.GetValue()")
+	assert.Equal(t, []byte("null"), v, "a.GetValue()")
+
+	// Reopening the store must not migrate the keys again.
+	require.NoError(t, a.Close(), "a.Close()")
+	a, err = New(&Config{Path: path})
+	require.NoError(t, err, "New()")
+	defer a.Close()
+
+	v, err = a.GetValue(ctx, []byte("key"))
+	require.NoError(t, err, "a.GetValue()")
+	assert.Equal(t, []byte("value"), v, "a.GetValue()")
+}
+
+func TestReopen(t *testing.T) {
+	a, err := createLevelDBStore()
+	require.NoError(t, err, "createLevelDBStore()")
+	defer freeLevelDBStore(a)
+
+	ctx := context.Background()
+	link := cstesting.RandomLink()
+	linkHash, err := a.CreateLink(ctx, link)
+	require.NoError(t, err, "a.CreateLink()")
+	require.NoError(t, a.AddEvidence(ctx, linkHash, cstesting.RandomEvidence()), "a.AddEvidence()")
+	require.NoError(t, a.Close(), "a.Close()")
+
+	a, err = New(a.config)
+	require.NoError(t, err, "New()")
+
+	segment, err := a.GetSegment(ctx, linkHash)
+	require.NoError(t, err, "a.GetSegment()")
+	require.NotNil(t, segment)
+	assert.Equal(t, *link, segment.Link)
+	assert.Len(t, segment.Meta.Evidences, 1)
+
+	segments, err := a.FindSegments(ctx, &store.SegmentFilter{
+		Pagination: store.Pagination{Limit: store.DefaultLimit},
+		MapIDs:     []string{link.Meta.MapID},
+	})
+	require.NoError(t, err, "a.FindSegments()")
+	assert.Len(t, segments, 1)
+}
+
+func TestPriorityKey(t *testing.T) {
+	lh, _ := cstesting.RandomLink().Hash()
+
+	priorities := []float64{1e10, 42.5, 1, 0.5, 0, -0.5, -1, -1e10}
+	for i := 1; i < len(priorities); i++ {
+		prev := string(priorityKey(priorities[i-1], lh))
+		cur := string(priorityKey(priorities[i], lh))
+		assert.True(t, prev < cur, "priorityKey(%v) < priorityKey(%v)", priorities[i-1], priorities[i])
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldbstore
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// plan tells how to find the candidates of a segment search.
+// Candidates are either given link hashes, or the link hashes found in the
+// keys starting with the given prefixes.
+// Candidates are unique but still have to be matched against the filter.
+type plan struct {
+	linkHashes []*types.Bytes32
+	prefixes   [][]byte
+
+	// If exact is true, keys must be made of a prefix followed by a link
+	// hash. Longer keys belong to other values that start with the
+	// searched value.
+	exact bool
+}
+
+// scanPlan is the plan that walks all the segments in order using the
+// priority index.
+func scanPlan() *plan {
+	return &plan{prefixes: [][]byte{priorityIndexKey()}}
+}
+
+// newPlan chooses the most selective index for a filter.
+// It returns nil if no index can be used, in which case the priority index
+// should be scanned.
+func newPlan(filter *store.SegmentFilter) *plan {
+	switch {
+	case len(filter.LinkHashes) > 0:
+		p := &plan{}
+		for _, lh := range unique(filter.LinkHashes) {
+			// Invalid link hashes cannot match anything.
+			if linkHash, err := types.NewBytes32FromString(lh); err == nil {
+				p.linkHashes = append(p.linkHashes, linkHash)
+			}
+		}
+		return p
+
+	case filter.PrevLinkHash != nil:
+		if *filter.PrevLinkHash == "" {
+			return &plan{prefixes: [][]byte{prevLinkHashKey(nil)}, exact: true}
+		}
+		prevLinkHash, err := types.NewBytes32FromString(*filter.PrevLinkHash)
+		if err != nil {
+			return &plan{exact: true}
+		}
+		return &plan{prefixes: [][]byte{prevLinkHashKey(prevLinkHash)}, exact: true}
+
+	case filter.RefLinkHash != "":
+		return &plan{prefixes: [][]byte{indexKey(refIndex, filter.RefLinkHash)}, exact: true}
+
+	case len(filter.MapIDs) > 0:
+		p := &plan{exact: true}
+		for _, mapID := range unique(filter.MapIDs) {
+			p.prefixes = append(p.prefixes, indexKey(mapIndex, mapID))
+		}
+		return p
+
+	case len(filter.Tags) > 0:
+		return &plan{prefixes: [][]byte{indexKey(tagIndex, filter.Tags[0])}, exact: true}
+
+	case filter.Process != "":
+		return &plan{prefixes: [][]byte{indexKey(processIndex, filter.Process)}, exact: true}
+	}
+
+	return nil
+}
+
+// unique removes duplicate values.
+func unique(values []string) []string {
+	var res []string
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// forEach calls the function with every candidate of the plan.
+func (p *plan) forEach(r reader, fn func(*types.Bytes32) error) error {
+	for _, linkHash := range p.linkHashes {
+		if err := fn(linkHash); err != nil {
+			return err
+		}
+	}
+
+	for _, prefix := range p.prefixes {
+		iter := r.NewIterator(util.BytesPrefix(prefix), nil)
+		for iter.Next() {
+			key := iter.Key()
+			if p.exact && len(key) != len(prefix)+types.Bytes32Size {
+				continue
+			}
+			if err := fn(linkHashFromKey(key)); err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// FindSegments implements github.com/stratumn/go-indigocore/store.SegmentReader.FindSegments.
+func (a *LevelDBStore) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := a.db.GetSnapshot()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer snapshot.Release()
+
+	p := newPlan(filter)
+	if p == nil {
+		if filter.Sort.IsDefault() {
+			return a.scanSegments(snapshot, filter, cursor)
+		}
+		// Segments must be sorted before being paginated.
+		p = scanPlan()
+	}
+
+	segments := cs.SegmentSlice{}
+	times := map[*cs.Segment]time.Time{}
+	sortByCreatedAt := filter.Sort != nil && filter.Sort.Field == store.SortByCreatedAt
+
+	err = p.forEach(snapshot, func(linkHash *types.Bytes32) error {
+		segment, err := a.getSegment(snapshot, linkHash)
+		if err != nil {
+			return err
+		}
+		match, err := a.matchSegment(snapshot, filter, segment)
+		if err != nil || !match {
+			return err
+		}
+		segments = append(segments, segment)
+
+		if sortByCreatedAt {
+			// Links saved without a time come first.
+			if times[segment], _, err = a.getCreatedAt(snapshot, linkHash); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filter.SortSegments(segments, func(segment *cs.Segment) time.Time {
+		return times[segment]
+	})
+
+	return filter.Pagination.PaginateSegments(segments), nil
+}
+
+// scanSegments walks the priority index, which is already sorted, and stops
+// as soon as the page is full.
+func (a *LevelDBStore) scanSegments(snapshot *leveldb.Snapshot, filter *store.SegmentFilter, cursor *store.SegmentCursor) (cs.SegmentSlice, error) {
+	segments := cs.SegmentSlice{}
+	if filter.Limit <= 0 {
+		return segments, nil
+	}
+
+	rng := util.BytesPrefix(priorityIndexKey())
+	var after []byte
+	if cursor != nil {
+		after = priorityKey(cursor.Priority, cursor.LinkHash)
+		rng.Start = after
+	}
+
+	iter := snapshot.NewIterator(rng, nil)
+	defer iter.Release()
+
+	skip := filter.Offset
+	for iter.Next() {
+		if after != nil && bytes.Equal(iter.Key(), after) {
+			continue
+		}
+
+		segment, err := a.getSegment(snapshot, linkHashFromKey(iter.Key()))
+		if err != nil {
+			return nil, err
+		}
+		match, err := a.matchSegment(snapshot, filter, segment)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		segments = append(segments, segment)
+		if len(segments) >= filter.Limit {
+			break
+		}
+	}
+
+	return segments, errors.WithStack(iter.Error())
+}
+
+// GetMapIDs implements github.com/stratumn/go-indigocore/store.SegmentReader.GetMapIDs.
+func (a *LevelDBStore) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
+	after, err := filter.MapCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	mapIDs := []string{}
+	if filter.Limit <= 0 {
+		return mapIDs, nil
+	}
+
+	rng := util.BytesPrefix(mapIDsKey(filter.Prefix))
+	if after != "" {
+		// Skip all the keys of the map ID of the cursor.
+		start := append(mapIDsKey(after), separator+1)
+		if bytes.Compare(start, rng.Start) > 0 {
+			rng.Start = start
+		}
+	}
+
+	iter := a.db.NewIterator(rng, nil)
+	defer iter.Release()
+
+	var (
+		last    string
+		counted bool
+	)
+	skip := filter.Offset
+
+	for iter.Next() {
+		mapID, process := parseMapIDsKey(iter.Key())
+		if counted && mapID == last {
+			continue
+		}
+		if filter.Process != "" && filter.Process != process {
+			continue
+		}
+		if !strings.HasSuffix(mapID, filter.Suffix) {
+			continue
+		}
+
+		last, counted = mapID, true
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		mapIDs = append(mapIDs, mapID)
+		if len(mapIDs) >= filter.Limit {
+			break
+		}
+	}
+
+	return mapIDs, errors.WithStack(iter.Error())
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldbstore
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/stratumn/go-indigocore/store"
+)
+
+func createLevelDBStore() (*LevelDBStore, error) {
+	path, err := ioutil.TempDir("", "leveldbstore")
+	if err != nil {
+		return nil, err
+	}
+	return New(&Config{Path: path})
+}
+
+func createAdapter() (store.Adapter, error) {
+	return createLevelDBStore()
+}
+
+func createKeyValueStore() (store.KeyValueStore, error) {
+	return createLevelDBStore()
+}
+
+func createAdapterTMPop() (store.Adapter, store.KeyValueStore, error) {
+	a, err := createLevelDBStore()
+	return a, a, err
+}
+
+func freeLevelDBStore(a *LevelDBStore) {
+	a.Close()
+	os.RemoveAll(a.config.Path)
+}
+
+func freeAdapter(a store.Adapter) {
+	freeLevelDBStore(a.(*LevelDBStore))
+}
+
+func freeKeyValueStore(a store.KeyValueStore) {
+	freeLevelDBStore(a.(*LevelDBStore))
+}
+
+func freeAdapterTMPop(a store.Adapter, _ store.KeyValueStore) {
+	freeAdapter(a)
+}
@@ -24,22 +24,25 @@ import (
 
 // Batch is the type that implements github.com/stratumn/go-indigocore/store.Batch.
 type Batch struct {
-	*reader
-	*writer
-	done bool
-	tx   *sql.Tx
+	*Reader
+	*Writer
+	tx *sql.Tx
+
+	// release removes the batch from the started batches of the store
+	// once it is written or rolled back.
+	release func()
 }
 
 // NewBatch creates a new instance of a Postgres Batch.
 func NewBatch(tx *sql.Tx) (*Batch, error) {
-	stmts, err := newBatchStmts(tx)
+	stmts, err := NewTxStmts(tx, PostgreSQL)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Batch{
-		reader: &reader{stmts: readStmts(stmts.readStmts)},
-		writer: &writer{stmts: writeStmts(stmts.writeStmts)},
+		Reader: NewReader(stmts.ReadStmts),
+		Writer: NewWriter(stmts.WriteStmts),
 		tx:     tx,
 	}, nil
 }
@@ -49,6 +52,19 @@ func (b *Batch) Write(ctx context.Context) (err error) {
 	_, span := trace.StartSpan(ctx, "postgresstore/batch/Write")
 	defer monitoring.SetSpanStatusAndEnd(span, err)
 
-	b.done = true
+	if b.release != nil {
+		defer b.release()
+	}
 	return b.tx.Commit()
 }
+
+// Rollback implements github.com/stratumn/go-indigocore/store.BatchRollbacker.Rollback.
+func (b *Batch) Rollback(ctx context.Context) (err error) {
+	_, span := trace.StartSpan(ctx, "postgresstore/batch/Rollback")
+	defer monitoring.SetSpanStatusAndEnd(span, err)
+
+	if b.release != nil {
+		defer b.release()
+	}
+	return b.tx.Rollback()
+}
@@ -20,7 +20,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/stratumn/go-indigocore/utils"
@@ -29,17 +29,19 @@ import (
 const (
 	connectAttempts = 12
 	connectTimeout  = 10 * time.Second
-	noTableCode     = pq.ErrorCode("42P01")
 )
 
 var (
-	create bool
-	drop   bool
-	url    string
+	create  bool
+	drop    bool
+	migrate bool
+	url     string
 )
 
-// Initialize initializes a postgres store adapter
-func Initialize(config *Config, create, drop bool) *Store {
+// Initialize initializes a postgres store adapter.
+// Pending schema migrations are applied before the statements are prepared.
+// It refuses to start if the database schema is newer than the store.
+func Initialize(config *Config, create, drop, migrate bool) *Store {
 	a, err := New(config)
 	if err != nil {
 		log.WithField("error", err).Fatal("Failed to create PostgreSQL store")
@@ -61,25 +63,32 @@ func Initialize(config *Config, create, drop bool) *Store {
 		os.Exit(0)
 	}
 
+	if migrate {
+		if err := a.Migrate(); err != nil {
+			log.WithField("error", err).Fatal("Failed to migrate PostgreSQL schema")
+		}
+		log.WithField("version", len(migrations)).Info("Migrated schema")
+		os.Exit(0)
+	}
+
 	for i := 1; i <= connectAttempts; i++ {
 		if err != nil {
 			time.Sleep(connectTimeout)
 		}
-		if err = a.Prepare(); err != nil {
-			if e, ok := err.(*pq.Error); ok && e.Code == noTableCode {
-				if err = a.Create(); err != nil {
-					log.WithField("error", err).Fatal("Failed to create PostgreSQL tables and indexes")
-				}
-				log.Info("Created tables and indexes")
-			} else {
-				log.WithFields(log.Fields{
-					"attempt": i,
-					"max":     connectAttempts,
-				}).Warn(fmt.Sprintf("Unable to connect to PostgreSQL, retrying in %v", connectTimeout))
-			}
-		} else {
+		if err = a.Migrate(); err == nil {
+			err = a.Prepare()
+		}
+		if err == nil {
 			break
 		}
+		if errors.Cause(err) == ErrNewerSchema {
+			log.WithField("error", err).Fatal("Refusing to start with a newer PostgreSQL schema")
+		}
+		log.WithFields(log.Fields{
+			"attempt": i,
+			"max":     connectAttempts,
+			"error":   err,
+		}).Warn(fmt.Sprintf("Unable to connect to PostgreSQL, retrying in %v", connectTimeout))
 	}
 	if err != nil {
 		log.WithField("max", connectAttempts).Fatal("Unable to connect to PostgreSQL")
@@ -91,6 +100,7 @@ func Initialize(config *Config, create, drop bool) *Store {
 func RegisterFlags() {
 	flag.BoolVar(&create, "create", false, "create tables and indexes then exit")
 	flag.BoolVar(&drop, "drop", false, "drop tables and indexes then exit")
+	flag.BoolVar(&migrate, "migrate", false, "apply pending schema migrations then exit")
 	flag.StringVar(&url, "url", utils.OrStrings(os.Getenv("POSTGRESSTORE_URL"), DefaultURL), "URL of the PostgreSQL database")
 }
 
@@ -98,6 +108,6 @@ func RegisterFlags() {
 // a postgres adapter using flag values.
 func InitializeWithFlags(version, commit string) *Store {
 	config := &Config{URL: url, Version: version, Commit: commit}
-	return Initialize(config, create, drop)
+	return Initialize(config, create, drop, migrate)
 
 }
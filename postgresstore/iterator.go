@@ -0,0 +1,202 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+)
+
+const (
+	// streamCursorName is the name of the server-side cursor used to
+	// stream segments. Cursors are scoped to a transaction so the name
+	// does not need to be unique.
+	streamCursorName = "stream_segments"
+
+	// streamBatchSize is the number of rows fetched from the server-side
+	// cursor at once.
+	streamBatchSize = 100
+)
+
+// StreamSegments implements github.com/stratumn/go-indigocore/store.SegmentStreamer.StreamSegments.
+// It uses a server-side cursor inside a read-only transaction that is
+// released when the iterator is closed.
+func (a *Store) StreamSegments(ctx context.Context, filter *store.SegmentFilter) (store.SegmentIterator, error) {
+	query, values, err := streamSegmentsQuery(PostgreSQL, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	declare := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", streamCursorName, query)
+	if _, err := tx.ExecContext(ctx, declare, values...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &segmentIterator{ctx: ctx, tx: tx}, nil
+}
+
+// segmentIterator reads segments from a server-side cursor.
+// Since links are joined with their evidences, a segment can span several
+// rows, and possibly several batches.
+type segmentIterator struct {
+	ctx  context.Context
+	tx   *sql.Tx
+	rows *sql.Rows
+
+	// Number of rows read from the current batch.
+	batchCount int
+	eof        bool
+
+	pending     *cs.Segment
+	pendingHash []byte
+	current     *cs.Segment
+	err         error
+}
+
+// Next implements github.com/stratumn/go-indigocore/store.SegmentIterator.Next.
+func (it *segmentIterator) Next() bool {
+	it.current = nil
+
+	if it.err != nil || it.tx == nil {
+		return false
+	}
+
+	for {
+		ok, err := it.nextRow()
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if !ok {
+			it.current, it.pending = it.pending, nil
+			return it.current != nil
+		}
+
+		var (
+			linkHash     []byte
+			linkData     string
+			evidenceData sql.NullString
+		)
+
+		if err := it.rows.Scan(&linkHash, &linkData, &evidenceData); err != nil {
+			it.err = err
+			return false
+		}
+
+		if it.pending == nil || !bytes.Equal(it.pendingHash, linkHash) {
+			var link cs.Link
+			if err := json.Unmarshal([]byte(linkData), &link); err != nil {
+				it.err = err
+				return false
+			}
+
+			it.current = it.pending
+			it.pending, it.pendingHash = link.Segmentify(), linkHash
+		}
+
+		if evidenceData.Valid {
+			var evidence cs.Evidence
+			if err := json.Unmarshal([]byte(evidenceData.String), &evidence); err != nil {
+				it.err = err
+				return false
+			}
+
+			if err := it.pending.Meta.AddEvidence(evidence); err != nil {
+				it.err = err
+				return false
+			}
+		}
+
+		if it.current != nil {
+			return true
+		}
+	}
+}
+
+// nextRow advances to the next row, fetching a new batch from the cursor
+// when needed. It returns false when all rows have been read.
+func (it *segmentIterator) nextRow() (bool, error) {
+	for {
+		if it.rows != nil {
+			if it.rows.Next() {
+				it.batchCount++
+				return true, nil
+			}
+
+			if err := it.rows.Err(); err != nil {
+				return false, err
+			}
+
+			it.rows.Close()
+			it.rows = nil
+
+			if it.batchCount < streamBatchSize {
+				it.eof = true
+			}
+		}
+
+		if it.eof {
+			return false, nil
+		}
+
+		rows, err := it.tx.QueryContext(it.ctx, fmt.Sprintf("FETCH %d FROM %s", streamBatchSize, streamCursorName))
+		if err != nil {
+			return false, err
+		}
+
+		it.rows, it.batchCount = rows, 0
+	}
+}
+
+// Segment implements github.com/stratumn/go-indigocore/store.SegmentIterator.Segment.
+func (it *segmentIterator) Segment() *cs.Segment {
+	return it.current
+}
+
+// Err implements github.com/stratumn/go-indigocore/store.SegmentIterator.Err.
+func (it *segmentIterator) Err() error {
+	return it.err
+}
+
+// Close implements github.com/stratumn/go-indigocore/store.SegmentIterator.Close.
+// The transaction is read-only, so it is simply rolled back.
+func (it *segmentIterator) Close() error {
+	if it.tx == nil {
+		return nil
+	}
+
+	if it.rows != nil {
+		it.rows.Close()
+		it.rows = nil
+	}
+
+	err := it.tx.Rollback()
+	it.tx, it.pending, it.current = nil, nil, nil
+
+	return err
+}
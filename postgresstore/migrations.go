@@ -0,0 +1,168 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresstore
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sqlCreateMigrations = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	sqlDropMigrations = `
+		DROP TABLE IF EXISTS schema_migrations
+	`
+	sqlGetSchemaVersion = `
+		SELECT COALESCE(MAX(version), 0) FROM schema_migrations
+	`
+	sqlSaveSchemaVersion = `
+		INSERT INTO schema_migrations (version)
+		VALUES ($1)
+	`
+	sqlTableExists = `
+		SELECT to_regclass($1) IS NOT NULL
+	`
+	sqlLockMigrations = `
+		SELECT pg_advisory_xact_lock($1)
+	`
+)
+
+// migrationsLockID is the key of the advisory lock that prevents several
+// stores from migrating the same database concurrently.
+const migrationsLockID = 0x7374726d
+
+// migrations contains the queries of each version of the schema, in order.
+// The first migration creates the initial schema. Released migrations must
+// never be modified, changes must be appended as new migrations.
+var migrations = [][]string{
+	sqlCreate,
+	sqlCreateRefs,
+}
+
+// ErrNewerSchema is returned when the schema of the database was migrated
+// by a more recent version of the store.
+var ErrNewerSchema = errors.New("database schema is newer than the store")
+
+// SchemaVersion returns the version of the database schema, or zero if the
+// tables have not been created.
+func (a *Store) SchemaVersion() (int, error) {
+	version, _, err := schemaVersion(a.db)
+	return version, err
+}
+
+// Migrate applies the migrations that have not been applied yet to the
+// database, in order, within a single transaction. It returns ErrNewerSchema
+// if the database has a newer schema than the latest known migration.
+func (a *Store) Migrate() (err error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(sqlLockMigrations, migrationsLockID); err != nil {
+		return err
+	}
+
+	version, recorded, err := schemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return errors.Wrapf(ErrNewerSchema, "version %d, latest known %d", version, len(migrations))
+	}
+
+	if _, err = tx.Exec(sqlCreateMigrations); err != nil {
+		return err
+	}
+
+	// Databases created before migrations existed have a schema but no
+	// recorded version.
+	if version > 0 && !recorded {
+		if _, err = tx.Exec(sqlSaveSchemaVersion, version); err != nil {
+			return err
+		}
+	}
+
+	for v := version + 1; v <= len(migrations); v++ {
+		for _, query := range migrations[v-1] {
+			if _, err = tx.Exec(query); err != nil {
+				return errors.Wrapf(err, "migration %d", v)
+			}
+		}
+		if _, err = tx.Exec(sqlSaveSchemaVersion, v); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkSchemaVersion returns an error if the database schema is not the
+// latest known one.
+func (a *Store) checkSchemaVersion() error {
+	version, err := a.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return errors.Wrapf(ErrNewerSchema, "version %d, latest known %d", version, len(migrations))
+	}
+	if version < len(migrations) {
+		return errors.Errorf("database schema version %d is outdated, latest is %d", version, len(migrations))
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// schemaVersion returns the version of the database schema and whether it is
+// recorded in the migrations table.
+func schemaVersion(db queryRower) (version int, recorded bool, err error) {
+	var exists bool
+
+	if err = db.QueryRow(sqlTableExists, "schema_migrations").Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if exists {
+		if err = db.QueryRow(sqlGetSchemaVersion).Scan(&version); err != nil {
+			return 0, false, err
+		}
+		if version > 0 {
+			return version, true, nil
+		}
+	}
+
+	if err = db.QueryRow(sqlTableExists, "links").Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if exists {
+		return 1, false, nil
+	}
+
+	return 0, false, nil
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"sync"
 
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/store"
@@ -63,14 +64,15 @@ type Info struct {
 
 // Store is the type that implements github.com/stratumn/go-indigocore/store.Adapter.
 type Store struct {
-	*reader
-	*writer
+	*Reader
+	*Writer
 	config     *Config
 	eventChans []chan *store.Event
 	db         *sql.DB
-	stmts      *stmts
+	stmts      *Stmts
 
-	batches map[*Batch]*sql.Tx
+	batchesMutex sync.Mutex
+	batches      map[*Batch]*sql.Tx
 }
 
 // New creates an instance of a Store.
@@ -94,21 +96,25 @@ func (a *Store) GetInfo(ctx context.Context) (interface{}, error) {
 
 // NewBatch implements github.com/stratumn/go-indigocore/store.Adapter.NewBatch.
 func (a *Store) NewBatch(ctx context.Context) (store.Batch, error) {
-	for b := range a.batches {
-		if b.done {
-			delete(a.batches, b)
-		}
-	}
-
 	tx, err := a.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	b, err := NewBatch(tx)
 	if err != nil {
+		tx.Rollback()
 		return nil, err
 	}
+
+	b.release = func() {
+		a.batchesMutex.Lock()
+		delete(a.batches, b)
+		a.batchesMutex.Unlock()
+	}
+
+	a.batchesMutex.Lock()
 	a.batches[b] = tx
+	a.batchesMutex.Unlock()
 
 	return b, nil
 }
@@ -120,7 +126,7 @@ func (a *Store) AddStoreEventChannel(eventChan chan *store.Event) {
 
 // CreateLink implements github.com/stratumn/go-indigocore/store.LinkWriter.CreateLink.
 func (a *Store) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
-	linkHash, err := a.writer.CreateLink(ctx, link)
+	linkHash, err := a.Writer.CreateLink(ctx, link)
 	if err != nil {
 		return nil, err
 	}
@@ -155,48 +161,52 @@ func (a *Store) AddEvidence(ctx context.Context, linkHash *types.Bytes32, eviden
 	return nil
 }
 
-// Create creates the database tables and indexes.
+// Create creates the database tables and indexes by applying all the
+// migrations.
 func (a *Store) Create() error {
-	for _, query := range sqlCreate {
-		if _, err := a.db.Exec(query); err != nil {
-			return err
-		}
-	}
-	return nil
+	return a.Migrate()
 }
 
 // Prepare prepares the database stmts.
 // It should be called once before interacting with segments.
-// It assumes the tables have been created using Create().
+// It assumes the tables have been created using Create() or Migrate(), and
+// fails if the schema is not the latest one.
 func (a *Store) Prepare() error {
-	stmts, err := newStmts(a.db)
+	if err := a.checkSchemaVersion(); err != nil {
+		return err
+	}
+
+	stmts, err := NewStmts(a.db, PostgreSQL)
 	if err != nil {
 		return err
 	}
 	a.stmts = stmts
-	a.reader = &reader{stmts: a.stmts.readStmts}
-	a.writer = &writer{stmts: a.stmts.writeStmts}
+	a.Reader = NewReader(a.stmts.ReadStmts)
+	a.Writer = NewWriter(a.stmts.WriteStmts)
 
 	return nil
 }
 
 // Drop drops the database tables and indexes. It also rollbacks started batches.
 func (a *Store) Drop() error {
+	a.batchesMutex.Lock()
 	for b, tx := range a.batches {
-		if !b.done {
-			err := tx.Rollback()
-			if err != nil {
-				return err
-			}
+		if err := tx.Rollback(); err != nil {
+			a.batchesMutex.Unlock()
+			return err
 		}
+		delete(a.batches, b)
 	}
+	a.batchesMutex.Unlock()
 
-	for _, query := range sqlDrop {
+	for _, query := range PostgreSQL.Drop {
 		if _, err := a.db.Exec(query); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	_, err := a.db.Exec(sqlDropMigrations)
+	return err
 }
 
 // Close closes the database connection.
@@ -17,9 +17,12 @@ package postgresstore
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stratumn/go-indigocore/store"
 	"github.com/stratumn/go-indigocore/store/storetestcases"
 	"github.com/stratumn/go-indigocore/tmpop/tmpoptestcases"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStore(t *testing.T) {
@@ -41,6 +44,43 @@ func TestPostgresTMPop(t *testing.T) {
 	}.RunTests(t)
 }
 
+func TestMigrate(t *testing.T) {
+	a, err := createStore()
+	require.NoError(t, err, "createStore()")
+	defer freeStore(a)
+
+	version, err := a.SchemaVersion()
+	require.NoError(t, err, "a.SchemaVersion()")
+	assert.Equal(t, len(migrations), version, "a.SchemaVersion()")
+
+	t.Run("Migrate is idempotent", func(t *testing.T) {
+		require.NoError(t, a.Migrate(), "a.Migrate()")
+		require.NoError(t, a.Prepare(), "a.Prepare()")
+	})
+
+	t.Run("Schema without a recorded version", func(t *testing.T) {
+		_, err := a.db.Exec(sqlDropMigrations)
+		require.NoError(t, err, "a.db.Exec()")
+
+		version, err := a.SchemaVersion()
+		require.NoError(t, err, "a.SchemaVersion()")
+		assert.Equal(t, 1, version, "a.SchemaVersion()")
+
+		require.NoError(t, a.Migrate(), "a.Migrate()")
+		version, err = a.SchemaVersion()
+		require.NoError(t, err, "a.SchemaVersion()")
+		assert.Equal(t, len(migrations), version, "a.SchemaVersion()")
+	})
+
+	t.Run("Newer schema", func(t *testing.T) {
+		_, err := a.db.Exec(sqlSaveSchemaVersion, len(migrations)+1)
+		require.NoError(t, err, "a.db.Exec()")
+
+		assert.Equal(t, ErrNewerSchema, errors.Cause(a.Migrate()), "a.Migrate()")
+		assert.Equal(t, ErrNewerSchema, errors.Cause(a.Prepare()), "a.Prepare()")
+	})
+}
+
 func createStore() (*Store, error) {
 	a, err := New(&Config{URL: "postgres://postgres@localhost/sdk_test?sslmode=disable"})
 	if err := a.Create(); err != nil {
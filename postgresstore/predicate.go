@@ -0,0 +1,86 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/stratumn/go-indigocore/store"
+)
+
+// postgresPredicate translates a predicate to a condition on the JSONB data
+// column of the links table.
+func postgresPredicate(p *store.Predicate, bind func(value interface{}) string) string {
+	path := bind(pq.Array(p.Keys()))
+	value := fmt.Sprintf("l.data #> %s", path)
+
+	switch p.Op {
+	case store.OpExists:
+		return fmt.Sprintf("jsonb_typeof(%s) <> 'null'", value)
+
+	case store.OpEq:
+		return fmt.Sprintf("%s = %s::jsonb", value, bind(jsonValue(p.Value)))
+
+	case store.OpIn:
+		values := p.Value.([]interface{})
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = bind(jsonValue(v)) + "::jsonb"
+		}
+		return fmt.Sprintf("%s IN (%s)", value, strings.Join(placeholders, ", "))
+	}
+
+	// Values of different types must not be compared, and the text must
+	// only be cast when it is a number.
+	if s, ok := p.Value.(string); ok {
+		return fmt.Sprintf(
+			`CASE WHEN jsonb_typeof(%s) = 'string' THEN (l.data #>> %s) COLLATE "C" %s %s ELSE false END`,
+			value, path, p.Op, bind(s),
+		)
+	}
+
+	return fmt.Sprintf(
+		"CASE WHEN jsonb_typeof(%s) = 'number' THEN (l.data #>> %s)::numeric %s %s ELSE false END",
+		value, path, p.Op, bind(p.Value),
+	)
+}
+
+// postgresSortState returns the number and the text found at the given keys
+// of the JSONB data column of the links table.
+func postgresSortState(keys []string) (number, text string) {
+	path := "'{" + strings.Join(keys, ",") + "}'"
+	value := fmt.Sprintf("l.data #> %s", path)
+
+	number = fmt.Sprintf(
+		"CASE WHEN jsonb_typeof(%s) = 'number' THEN (l.data #>> %s)::numeric END",
+		value, path,
+	)
+	text = fmt.Sprintf(
+		`CASE WHEN jsonb_typeof(%s) = 'string' THEN (l.data #>> %s) COLLATE "C" END`,
+		value, path,
+	)
+
+	return number, text
+}
+
+func jsonValue(v interface{}) string {
+	// Predicate values are validated so they can always be marshaled.
+	js, _ := json.Marshal(v)
+	return string(js)
+}
@@ -25,12 +25,18 @@ import (
 	"github.com/stratumn/go-indigocore/types"
 )
 
-type reader struct {
-	stmts readStmts
+// Reader implements the read methods of a store using SQL statements.
+type Reader struct {
+	stmts ReadStmts
+}
+
+// NewReader creates a Reader that uses the given statements.
+func NewReader(stmts ReadStmts) *Reader {
+	return &Reader{stmts: stmts}
 }
 
 // GetSegment implements github.com/stratumn/go-indigocore/store.SegmentReader.GetSegment.
-func (a *reader) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
+func (a *Reader) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
 	var segments = make(cs.SegmentSlice, 0, 1)
 
 	rows, err := a.stmts.GetSegment.Query(linkHash[:])
@@ -50,7 +56,7 @@ func (a *reader) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.S
 }
 
 // FindSegments implements github.com/stratumn/go-indigocore/store.SegmentReader.FindSegments.
-func (a *reader) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+func (a *Reader) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
 
 	rows, err := a.stmts.FindSegmentsWithFilters(filter)
 	if err != nil {
@@ -110,8 +116,91 @@ func scanLinkAndEvidences(rows *sql.Rows, segments *cs.SegmentSlice) error {
 	return nil
 }
 
+// GetChildren implements github.com/stratumn/go-indigocore/store.GraphReader.GetChildren.
+func (a *Reader) GetChildren(ctx context.Context, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	return a.querySegments(a.stmts.GetChildren, linkHash[:])
+}
+
+// GetAncestors implements github.com/stratumn/go-indigocore/store.GraphReader.GetAncestors.
+func (a *Reader) GetAncestors(ctx context.Context, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	segments, err := a.querySegments(a.stmts.GetAncestors, linkHash[:])
+	if err != nil || len(segments) == 0 {
+		return nil, err
+	}
+
+	// The first segment is the one of the given link.
+	return segments[1:], nil
+}
+
+// GetMapHeads implements github.com/stratumn/go-indigocore/store.GraphReader.GetMapHeads.
+func (a *Reader) GetMapHeads(ctx context.Context, mapID string) (cs.SegmentSlice, error) {
+	return a.querySegments(a.stmts.GetMapHeads, mapID)
+}
+
+func (a *Reader) querySegments(stmt *sql.Stmt, args ...interface{}) (cs.SegmentSlice, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	segments := cs.SegmentSlice{}
+	err = scanLinkAndEvidences(rows, &segments)
+
+	return segments, err
+}
+
+// CountSegments implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegments.
+func (a *Reader) CountSegments(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	rows, err := a.stmts.CountSegmentsWithFilters(filter, "")
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	count := 0
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, rows.Err()
+}
+
+// CountSegmentsBy implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegmentsBy.
+func (a *Reader) CountSegmentsBy(ctx context.Context, filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	rows, err := a.stmts.CountSegmentsWithFilters(filter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[string]int{}
+
+	for rows.Next() {
+		var (
+			value string
+			count int
+		)
+		if err = rows.Scan(&value, &count); err != nil {
+			return nil, err
+		}
+
+		counts[value] += count
+	}
+
+	return counts, rows.Err()
+}
+
 // GetMapIDs implements github.com/stratumn/go-indigocore/store.SegmentReader.GetMapIDs.
-func (a *reader) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
+func (a *Reader) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
 	rows, err := a.stmts.GetMapIDsWithFilters(filter)
 	if err != nil {
 		return nil, err
@@ -134,7 +223,7 @@ func (a *reader) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]stri
 }
 
 // GetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.GetValue.
-func (a *reader) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+func (a *Reader) GetValue(ctx context.Context, key []byte) ([]byte, error) {
 	var data []byte
 
 	if err := a.stmts.GetValue.QueryRow(key).Scan(&data); err != nil {
@@ -148,7 +237,7 @@ func (a *reader) GetValue(ctx context.Context, key []byte) ([]byte, error) {
 }
 
 // GetEvidences implements github.com/stratumn/go-indigocore/store.EvidenceReader.GetEvidences.
-func (a *reader) GetEvidences(ctx context.Context, linkHash *types.Bytes32) (*cs.Evidences, error) {
+func (a *Reader) GetEvidences(ctx context.Context, linkHash *types.Bytes32) (*cs.Evidences, error) {
 	var evidences cs.Evidences
 
 	rows, err := a.stmts.GetEvidences.Query(linkHash[:])
@@ -52,13 +52,50 @@ const (
 		LEFT JOIN evidences e ON l.link_hash = e.link_hash
 		WHERE l.link_hash = $1
 	`
+	sqlGetChildren = `
+		SELECT l.link_hash, l.data, e.data FROM links l
+		LEFT JOIN evidences e ON l.link_hash = e.link_hash
+		WHERE l.prev_link_hash = $1
+		ORDER BY l.priority DESC, l.link_hash ASC
+	`
+	sqlGetAncestors = `
+		WITH RECURSIVE ancestors (link_hash, prev_link_hash, depth) AS (
+			SELECT l.link_hash, l.prev_link_hash, 0 FROM links l
+			WHERE l.link_hash = $1
+			UNION ALL
+			SELECT l.link_hash, l.prev_link_hash, a.depth + 1 FROM links l
+			JOIN ancestors a ON l.link_hash = a.prev_link_hash
+		)
+		SELECT l.link_hash, l.data, e.data FROM ancestors a
+		JOIN links l ON l.link_hash = a.link_hash
+		LEFT JOIN evidences e ON l.link_hash = e.link_hash
+		ORDER BY a.depth
+	`
+	sqlGetMapHeads = `
+		SELECT l.link_hash, l.data, e.data FROM links l
+		LEFT JOIN evidences e ON l.link_hash = e.link_hash
+		WHERE l.map_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM links c
+			WHERE c.prev_link_hash = l.link_hash AND c.map_id = l.map_id
+		)
+		ORDER BY l.priority DESC, l.link_hash ASC
+	`
+	sqlAddRef = `
+		INSERT INTO refs (
+			link_hash,
+			ref_link_hash
+		)
+		VALUES ($1, $2)
+		ON CONFLICT (link_hash, ref_link_hash)
+		DO NOTHING
+	`
 	sqlDeleteLink = `
 		DELETE FROM links
 		WHERE link_hash = $1
 		RETURNING data
 	`
 	sqlSaveValue = `
-		INSERT INTO values (
+		INSERT INTO "values" (
 			key,
 			value
 		)
@@ -68,11 +105,11 @@ const (
 			value = $2
 	`
 	sqlGetValue = `
-		SELECT value FROM values
+		SELECT value FROM "values"
 		WHERE key = $1
 	`
 	sqlDeleteValue = `
-		DELETE FROM values
+		DELETE FROM "values"
 		WHERE key = $1
 		RETURNING value
 	`
@@ -164,114 +201,223 @@ var sqlCreate = []string{
 	`,
 }
 
+// sqlCreateRefs creates the table indexing the link hashes referenced by
+// links, and fills it with the references of existing links.
+var sqlCreateRefs = []string{
+	`
+		CREATE TABLE refs (
+			id BIGSERIAL PRIMARY KEY,
+			link_hash bytea NOT NULL,
+			ref_link_hash text NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	`
+		CREATE UNIQUE INDEX refs_link_hash_ref_link_hash_idx
+		ON refs (link_hash, ref_link_hash)
+	`,
+	`
+		CREATE INDEX refs_ref_link_hash_idx
+		ON refs (ref_link_hash)
+	`,
+	`
+		INSERT INTO refs (link_hash, ref_link_hash)
+		SELECT l.link_hash, r->>'linkHash' FROM links l,
+		jsonb_array_elements(CASE jsonb_typeof(l.data->'meta'->'refs')
+			WHEN 'array' THEN l.data->'meta'->'refs'
+			ELSE '[]'::jsonb
+		END) r
+		WHERE r->>'linkHash' IS NOT NULL
+		ON CONFLICT (link_hash, ref_link_hash)
+		DO NOTHING
+	`,
+}
+
 var sqlDrop = []string{
-	"DROP TABLE links, evidences, values",
+	"DROP TABLE links, evidences, values, refs",
+}
+
+// Dialect contains what differs between the SQL databases the statements of
+// this package can run on. It lets other SQL stores reuse them.
+type Dialect struct {
+	// Create contains the queries that create the tables and indexes.
+	Create []string
+
+	// Drop contains the queries that drop the tables and indexes.
+	Drop []string
+
+	// Tags converts the tags of a link to the value saved in the tags
+	// column.
+	Tags func(tags []string) interface{}
+
+	// ContainsTags returns a condition that is true if the tags column
+	// contains all the tags bound to the given placeholder. The bound
+	// value is converted using Tags.
+	ContainsTags func(placeholder string) string
+
+	// Predicate returns a condition matching a valid predicate on the data
+	// column of links. The bind function adds a value to the query and
+	// returns its placeholder.
+	Predicate func(p *store.Predicate, bind func(value interface{}) string) string
+
+	// Timestamp converts the time bound to the given placeholder so that
+	// it can be compared with the created_at column of links.
+	Timestamp func(placeholder string) string
+
+	// HasEvidenceBackend returns a condition that is true if a link has
+	// an evidence produced by the backend bound to the given placeholder.
+	HasEvidenceBackend func(placeholder string) string
+
+	// SortState returns the expressions of the number and of the text
+	// found at the given valid keys of the data column of links. Each
+	// expression is NULL when the value has another type.
+	SortState func(keys []string) (number, text string)
+
+	// LinkType is the expression of the type in the meta data of links.
+	LinkType string
+}
+
+// PostgreSQL is the dialect of PostgreSQL databases.
+var PostgreSQL = &Dialect{
+	Create: sqlCreate,
+	Drop:   sqlDrop,
+	Tags: func(tags []string) interface{} {
+		return pq.Array(tags)
+	},
+	ContainsTags: func(placeholder string) string {
+		return "tags @> " + placeholder
+	},
+	Predicate: postgresPredicate,
+	Timestamp: func(placeholder string) string {
+		return placeholder + "::timestamptz"
+	},
+	HasEvidenceBackend: func(placeholder string) string {
+		return fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM evidences ev
+			WHERE ev.link_hash = l.link_hash AND ev.data->>'backend' = %s
+		)`, placeholder)
+	},
+	SortState: postgresSortState,
+	LinkType:  "l.data->'meta'->>'type'",
 }
 
-type writeStmts struct {
+// WriteStmts contains the prepared statements that write to the database.
+type WriteStmts struct {
+	dialect *Dialect
+
 	CreateLink  *sql.Stmt
+	AddRef      *sql.Stmt
 	DeleteLink  *sql.Stmt
 	SaveValue   *sql.Stmt
 	DeleteValue *sql.Stmt
 	AddEvidence *sql.Stmt
 }
 
-type readStmts struct {
+// ReadStmts contains the prepared statements that read from the database,
+// and builds the queries that depend on filters.
+type ReadStmts struct {
+	dialect *Dialect
+
 	// DB.Query or Tx.Query depending on if we are in batch.
 	query func(query string, args ...interface{}) (*sql.Rows, error)
 
 	GetSegment   *sql.Stmt
 	GetValue     *sql.Stmt
 	GetEvidences *sql.Stmt
+	GetChildren  *sql.Stmt
+	GetAncestors *sql.Stmt
+	GetMapHeads  *sql.Stmt
 }
 
-type stmts struct {
-	readStmts
-	writeStmts
+// Stmts contains all the prepared statements.
+type Stmts struct {
+	ReadStmts
+	WriteStmts
 }
 
-type batchStmts stmts
-
-func newStmts(db *sql.DB) (*stmts, error) {
-	var (
-		s   stmts
-		err error
-	)
-
-	prepare := func(str string) (stmt *sql.Stmt) {
-		if err == nil {
-			stmt, err = db.Prepare(str)
-		}
-		return
-	}
-
-	s.GetSegment = prepare(sqlGetSegment)
-	s.GetValue = prepare(sqlGetValue)
-	s.GetEvidences = prepare(sqlGetEvidences)
-
-	s.CreateLink = prepare(sqlCreateLink)
-	s.DeleteLink = prepare(sqlDeleteLink)
-	s.SaveValue = prepare(sqlSaveValue)
-	s.DeleteValue = prepare(sqlDeleteValue)
-	s.AddEvidence = prepare(sqlAddEvidence)
-
-	if err != nil {
-		return nil, err
-	}
-
-	s.query = db.Query
+// NewStmts prepares the statements on a database.
+func NewStmts(db *sql.DB, dialect *Dialect) (*Stmts, error) {
+	return prepareStmts(db.Prepare, db.Query, dialect)
+}
 
-	return &s, nil
+// NewTxStmts prepares the statements on a transaction.
+func NewTxStmts(tx *sql.Tx, dialect *Dialect) (*Stmts, error) {
+	return prepareStmts(tx.Prepare, tx.Query, dialect)
 }
 
-func newBatchStmts(tx *sql.Tx) (*batchStmts, error) {
+func prepareStmts(
+	prepareFunc func(query string) (*sql.Stmt, error),
+	queryFunc func(query string, args ...interface{}) (*sql.Rows, error),
+	dialect *Dialect,
+) (*Stmts, error) {
 	var (
-		s   batchStmts
+		s   Stmts
 		err error
 	)
 
 	prepare := func(str string) (stmt *sql.Stmt) {
 		if err == nil {
-			stmt, err = tx.Prepare(str)
+			stmt, err = prepareFunc(str)
 		}
 		return
 	}
 
 	s.GetSegment = prepare(sqlGetSegment)
 	s.GetValue = prepare(sqlGetValue)
+	s.GetEvidences = prepare(sqlGetEvidences)
+	s.GetChildren = prepare(sqlGetChildren)
+	s.GetAncestors = prepare(sqlGetAncestors)
+	s.GetMapHeads = prepare(sqlGetMapHeads)
 
 	s.CreateLink = prepare(sqlCreateLink)
+	s.AddRef = prepare(sqlAddRef)
 	s.DeleteLink = prepare(sqlDeleteLink)
 	s.SaveValue = prepare(sqlSaveValue)
 	s.DeleteValue = prepare(sqlDeleteValue)
+	s.AddEvidence = prepare(sqlAddEvidence)
 
 	if err != nil {
 		return nil, err
 	}
 
-	s.query = tx.Query
+	s.query = queryFunc
+	s.ReadStmts.dialect = dialect
+	s.WriteStmts.dialect = dialect
 
 	return &s, nil
 }
 
 // GetMapIDsWithFilters retrieves maps ids from the store given some filters.
-func (s *readStmts) GetMapIDsWithFilters(filter *store.MapFilter) (*sql.Rows, error) {
+// Map IDs are sorted in ascending order, which is what pagination cursors
+// expect, rather than by time of their last update.
+func (s *ReadStmts) GetMapIDsWithFilters(filter *store.MapFilter) (*sql.Rows, error) {
 	sqlHead := `
 		SELECT l.map_id FROM links l
 	`
 	sqlTail := fmt.Sprintf(`
 		GROUP BY l.map_id
-		ORDER BY MAX(l.updated_at) DESC
-		OFFSET %d LIMIT %d
+		ORDER BY l.map_id
+		LIMIT %d OFFSET %d
 	`,
-		filter.Pagination.Offset,
 		filter.Pagination.Limit,
+		filter.Pagination.Offset,
 	)
 
 	filters := []string{}
 	values := []interface{}{}
 	cnt := 1
 
+	mapCursor, err := filter.MapCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	if mapCursor != "" {
+		filters = append(filters, fmt.Sprintf("map_id > $%d", cnt))
+		values = append(values, mapCursor)
+		cnt++
+	}
+
 	if filter.Prefix != "" {
 		filters = append(filters, fmt.Sprintf("map_id LIKE $%d", cnt))
 		values = append(values, fmt.Sprintf("%s%%", filter.Prefix))
@@ -301,74 +447,282 @@ func (s *readStmts) GetMapIDsWithFilters(filter *store.MapFilter) (*sql.Rows, er
 }
 
 // FindSegments formats a read query and retrieves segments according to the filter.
-func (s *readStmts) FindSegmentsWithFilters(filter *store.SegmentFilter) (*sql.Rows, error) {
-	sqlHead := `SELECT l.link_hash, l.data, e.data FROM links l
-	LEFT JOIN evidences e ON l.link_hash = e.link_hash
+func (s *ReadStmts) FindSegmentsWithFilters(filter *store.SegmentFilter) (*sql.Rows, error) {
+	// Links are paginated before being joined with their evidences,
+	// otherwise a segment with several evidences would count as several
+	// results.
+	sqlHead := `SELECT l.link_hash, l.data, e.data FROM (
+		SELECT l.link_hash, l.data, l.priority, l.created_at FROM links l
 	`
 
+	orderBy, err := segmentsOrder(s.dialect, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlTail := fmt.Sprintf(`
-		ORDER BY priority DESC, l.created_at DESC
-		OFFSET %d LIMIT %d
-		`,
-		filter.Pagination.Offset,
+		%s
+		LIMIT %d OFFSET %d
+	) l
+	LEFT JOIN evidences e ON l.link_hash = e.link_hash
+	%s
+	`,
+		orderBy,
 		filter.Pagination.Limit,
+		filter.Pagination.Offset,
+		orderBy,
 	)
 
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return nil, err
+	}
+
 	filters := []string{}
 	values := []interface{}{}
-	cnt := 1
 
+	if cursor != nil {
+		filters = append(filters, "(l.priority < $1 OR (l.priority = $1 AND l.link_hash > $2))")
+		values = append(values, cursor.Priority, cursor.LinkHash[:])
+	}
+
+	filters, values, err = appendSegmentFilters(s.dialect, filter, filters, values)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlBody := ""
+	if len(filters) > 0 {
+		sqlBody = "\nWHERE "
+		sqlBody += strings.Join(filters, "\n AND ")
+	}
+
+	query := sqlHead + sqlBody + sqlTail
+
+	return s.query(query, values...)
+}
+
+// streamSegmentsQuery formats a query that selects all the segments matching
+// the filter, ignoring pagination. It is meant to be used with a server-side
+// cursor.
+func streamSegmentsQuery(dialect *Dialect, filter *store.SegmentFilter) (string, []interface{}, error) {
+	sqlHead := `
+		SELECT l.link_hash, l.data, e.data FROM links l
+		LEFT JOIN evidences e ON l.link_hash = e.link_hash
+	`
+	sqlTail, err := segmentsOrder(dialect, filter.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+
+	filters, values, err := appendSegmentFilters(dialect, filter, nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sqlBody := ""
+	if len(filters) > 0 {
+		sqlBody = "\nWHERE "
+		sqlBody += strings.Join(filters, "\n AND ")
+	}
+
+	return sqlHead + sqlBody + "\n" + sqlTail, values, nil
+}
+
+// CountSegmentsWithFilters counts the segments matching the filter. If a
+// field is given, there is one row per value of this field, otherwise a
+// single row with the total count.
+func (s *ReadStmts) CountSegmentsWithFilters(filter *store.SegmentFilter, groupBy string) (*sql.Rows, error) {
+	sqlHead := `
+		SELECT COUNT(*) FROM links l
+	`
+	sqlTail := ""
+
+	if groupBy != "" {
+		var column string
+		switch groupBy {
+		case store.GroupByMapID:
+			column = "l.map_id"
+		case store.GroupByProcess:
+			column = "l.process"
+		case store.GroupByType:
+			column = s.dialect.LinkType
+		default:
+			return nil, store.ValidateGroupBy(groupBy)
+		}
+
+		sqlHead = fmt.Sprintf(`
+			SELECT COALESCE(%s, ''), COUNT(*) FROM links l
+		`, column)
+		sqlTail = `
+			GROUP BY 1
+		`
+	}
+
+	filters, values, err := appendSegmentFilters(s.dialect, filter, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlBody := ""
+	if len(filters) > 0 {
+		sqlBody = "\nWHERE "
+		sqlBody += strings.Join(filters, "\n AND ")
+	}
+
+	return s.query(sqlHead+sqlBody+sqlTail, values...)
+}
+
+// segmentsOrder returns the ORDER BY clause of a sort order. Segments with
+// equal values are ordered by ascending link hash.
+func segmentsOrder(dialect *Dialect, s *store.Sort) (string, error) {
+	if s.IsDefault() {
+		return "ORDER BY l.priority DESC, l.link_hash ASC", nil
+	}
+
+	// Fields of the state are inserted in the query so they must be valid.
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+
+	direction := "ASC"
+	if s.Desc {
+		direction = "DESC"
+	}
+
+	switch s.Field {
+	case store.SortByPriority:
+		return fmt.Sprintf("ORDER BY l.priority %s, l.link_hash ASC", direction), nil
+	case store.SortByCreatedAt:
+		return fmt.Sprintf("ORDER BY l.created_at %s, l.link_hash ASC", direction), nil
+	case store.SortByLinkHash:
+		return fmt.Sprintf("ORDER BY l.link_hash %s", direction), nil
+	}
+
+	// Numbers come first, then strings, then other values whatever the
+	// direction.
+	number, text := dialect.SortState(s.Keys())
+
+	return fmt.Sprintf(
+		"ORDER BY %s %s NULLS LAST, %s %s NULLS LAST, l.link_hash ASC",
+		number, direction, text, direction,
+	), nil
+}
+
+// appendSegmentFilters appends the SQL conditions matching a segment filter
+// and their values. Placeholders are numbered after the given values.
+func appendSegmentFilters(dialect *Dialect, filter *store.SegmentFilter, filters []string, values []interface{}) ([]string, []interface{}, error) {
 	if len(filter.MapIDs) > 0 {
-		filters = append(filters, fmt.Sprintf("map_id = ANY($%d::text[])", cnt))
-		values = append(values, pq.Array(filter.MapIDs))
-		cnt++
+		mapIDs := make([]interface{}, len(filter.MapIDs))
+		for i, mapID := range filter.MapIDs {
+			mapIDs[i] = mapID
+		}
+
+		filters = append(filters, fmt.Sprintf("map_id IN (%s)", placeholders(len(values), len(mapIDs))))
+		values = append(values, mapIDs...)
 	}
 
 	if filter.Process != "" {
-		filters = append(filters, fmt.Sprintf("process = $%d", cnt))
+		filters = append(filters, fmt.Sprintf("process = $%d", len(values)+1))
 		values = append(values, filter.Process)
-		cnt++
 	}
 
 	if filter.PrevLinkHash != nil {
+		// Links without a previous link are saved with an empty hash.
+		prevLinkHash := []byte{}
 
-		if *filter.PrevLinkHash == "" {
-			filters = append(filters, "prev_link_hash = '\\x'")
-		} else {
+		if *filter.PrevLinkHash != "" {
 			prevLinkHashBytes, err := types.NewBytes32FromString(*filter.PrevLinkHash)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-
-			filters = append(filters, fmt.Sprintf("prev_link_hash = $%d", cnt))
-			values = append(values, prevLinkHashBytes[:])
-			cnt++
+			prevLinkHash = prevLinkHashBytes[:]
 		}
+
+		filters = append(filters, fmt.Sprintf("prev_link_hash = $%d", len(values)+1))
+		values = append(values, prevLinkHash)
 	}
 
 	if len(filter.LinkHashes) > 0 {
 		linkHashes, err := cs.NewLinkHashesFromStrings(filter.LinkHashes)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		filters = append(filters, fmt.Sprintf("l.link_hash = ANY($%d::bytea[])", cnt))
-		values = append(values, pq.Array(linkHashes))
-		cnt++
+		hashes := make([]interface{}, len(linkHashes))
+		for i, linkHash := range linkHashes {
+			hashes[i] = linkHash[:]
+		}
+
+		filters = append(filters, fmt.Sprintf("l.link_hash IN (%s)", placeholders(len(values), len(hashes))))
+		values = append(values, hashes...)
+	}
+
+	if filter.RefLinkHash != "" {
+		filters = append(filters, fmt.Sprintf("l.link_hash IN (SELECT r.link_hash FROM refs r WHERE r.ref_link_hash = $%d)", len(values)+1))
+		values = append(values, filter.RefLinkHash)
 	}
 
 	if len(filter.Tags) > 0 {
-		filters = append(filters, fmt.Sprintf("tags @>  $%d", cnt))
-		values = append(values, pq.Array(filter.Tags))
+		filters = append(filters, dialect.ContainsTags(fmt.Sprintf("$%d", len(values)+1)))
+		values = append(values, dialect.Tags(filter.Tags))
 	}
 
-	sqlBody := ""
-	if len(filters) > 0 {
-		sqlBody = "\nWHERE "
-		sqlBody += strings.Join(filters, "\n AND ")
+	bind := func(value interface{}) string {
+		values = append(values, value)
+		return fmt.Sprintf("$%d", len(values))
 	}
 
-	query := sqlHead + sqlBody + sqlTail
+	for i := range filter.Where {
+		p := &filter.Where[i]
+		if err := p.Validate(); err != nil {
+			return nil, nil, err
+		}
 
-	return s.query(query, values...)
+		filters = append(filters, dialect.Predicate(p, bind))
+	}
+
+	// Link types and actions are filtered like predicates on the meta
+	// data, which are not restricted to the paths users can query.
+	if len(filter.LinkTypes) > 0 {
+		p := &store.Predicate{Path: "meta.type", Op: store.OpIn, Value: stringValues(filter.LinkTypes)}
+		filters = append(filters, dialect.Predicate(p, bind))
+	}
+
+	if len(filter.Actions) > 0 {
+		p := &store.Predicate{Path: "meta.action", Op: store.OpIn, Value: stringValues(filter.Actions)}
+		filters = append(filters, dialect.Predicate(p, bind))
+	}
+
+	if filter.CreatedAfter != nil {
+		filters = append(filters, "l.created_at >= "+dialect.Timestamp(bind(filter.CreatedAfter.UTC())))
+	}
+
+	if filter.CreatedBefore != nil {
+		filters = append(filters, "l.created_at < "+dialect.Timestamp(bind(filter.CreatedBefore.UTC())))
+	}
+
+	if filter.EvidenceBackend != "" {
+		filters = append(filters, dialect.HasEvidenceBackend(bind(filter.EvidenceBackend)))
+	}
+
+	return filters, values, nil
+}
+
+func stringValues(values []string) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = v
+	}
+	return res
+}
+
+// placeholders returns a list of n placeholders numbered after the given
+// number of values.
+func placeholders(offset, n int) string {
+	p := make([]string, n)
+	for i := range p {
+		p[i] = fmt.Sprintf("$%d", offset+i+1)
+	}
+	return strings.Join(p, ", ")
 }
@@ -18,23 +18,28 @@ import (
 	"context"
 	"encoding/json"
 
-	"github.com/lib/pq"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/types"
 )
 
-type writer struct {
-	stmts writeStmts
+// Writer implements the write methods of a store using SQL statements.
+type Writer struct {
+	stmts WriteStmts
+}
+
+// NewWriter creates a Writer that uses the given statements.
+func NewWriter(stmts WriteStmts) *Writer {
+	return &Writer{stmts: stmts}
 }
 
 // SetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.SetValue.
-func (a *writer) SetValue(ctx context.Context, key []byte, value []byte) error {
+func (a *Writer) SetValue(ctx context.Context, key []byte, value []byte) error {
 	_, err := a.stmts.SaveValue.Exec(key, value)
 	return err
 }
 
 // DeleteValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.DeleteValue.
-func (a *writer) DeleteValue(ctx context.Context, key []byte) ([]byte, error) {
+func (a *Writer) DeleteValue(ctx context.Context, key []byte) ([]byte, error) {
 	var data []byte
 
 	if err := a.stmts.DeleteValue.QueryRow(key).Scan(&data); err != nil {
@@ -48,7 +53,7 @@ func (a *writer) DeleteValue(ctx context.Context, key []byte) ([]byte, error) {
 }
 
 // CreateLink implements github.com/stratumn/go-indigocore/store.Adapter.CreateLink.
-func (a *writer) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
+func (a *Writer) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
 	var (
 		priority     = link.Meta.Priority
 		mapID        = link.Meta.MapID
@@ -68,10 +73,19 @@ func (a *writer) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32,
 	}
 
 	if prevLinkHash == nil {
-		_, err = a.stmts.CreateLink.Exec(linkHash[:], priority, mapID, []byte{}, pq.Array(tags), string(data), process)
+		_, err = a.stmts.CreateLink.Exec(linkHash[:], priority, mapID, []byte{}, a.stmts.dialect.Tags(tags), string(data), process)
 	} else {
-		_, err = a.stmts.CreateLink.Exec(linkHash[:], priority, mapID, prevLinkHash[:], pq.Array(tags), string(data), process)
+		_, err = a.stmts.CreateLink.Exec(linkHash[:], priority, mapID, prevLinkHash[:], a.stmts.dialect.Tags(tags), string(data), process)
+	}
+	if err != nil {
+		return linkHash, err
+	}
+
+	for _, refLinkHash := range link.Meta.GetRefLinkHashes() {
+		if _, err = a.stmts.AddRef.Exec(linkHash[:], refLinkHash); err != nil {
+			return linkHash, err
+		}
 	}
 
-	return linkHash, err
+	return linkHash, nil
 }
@@ -57,10 +57,11 @@ func Initialize(config *Config, create, drop bool) *Store {
 	if err != nil {
 		log.WithField("error", err).Fatal("Failed to check RethinkDB tables and indexes")
 	}
+	// Existing tables get the indexes added by newer versions.
+	if err = a.Create(); err != nil {
+		log.WithField("error", err).Fatal("Failed to create RethinkDB tables and indexes")
+	}
 	if !exists {
-		if err = a.Create(); err != nil {
-			log.WithField("error", err).Fatal("Failed to create RethinkDB tables and indexes")
-		}
 		log.Info("Created tables and indexes")
 	}
 
@@ -0,0 +1,69 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rethinkstore
+
+import (
+	"github.com/stratumn/go-indigocore/store"
+
+	rethink "gopkg.in/dancannon/gorethink.v4"
+)
+
+// rethinkPredicate translates a predicate to a term on the content of a link
+// document. Rows where the path does not exist are filtered out by RethinkDB.
+func rethinkPredicate(link rethink.Term, p *store.Predicate) rethink.Term {
+	keys := p.Keys()
+
+	value := link
+	for _, key := range keys {
+		value = value.Field(key)
+	}
+
+	switch p.Op {
+	case store.OpExists:
+		// HasFields is false for null values.
+		var fields interface{} = true
+		for i := len(keys) - 1; i >= 0; i-- {
+			fields = map[string]interface{}{keys[i]: fields}
+		}
+		return link.HasFields(fields)
+
+	case store.OpEq:
+		return value.Eq(p.Value)
+
+	case store.OpIn:
+		return rethink.Expr(p.Value).Contains(value)
+	}
+
+	// RethinkDB orders values of different types, so the type of the value
+	// must be checked first.
+	typ := "NUMBER"
+	if _, ok := p.Value.(string); ok {
+		typ = "STRING"
+	}
+
+	var cmp rethink.Term
+	switch p.Op {
+	case store.OpGt:
+		cmp = value.Gt(p.Value)
+	case store.OpGte:
+		cmp = value.Ge(p.Value)
+	case store.OpLt:
+		cmp = value.Lt(p.Value)
+	default:
+		cmp = value.Le(p.Value)
+	}
+
+	return value.TypeOf().Eq(typ).And(cmp)
+}
@@ -104,6 +104,7 @@ type linkWrapper struct {
 	MapID        string    `json:"mapId"`
 	PrevLinkHash []byte    `json:"prevLinkHash"`
 	Tags         []string  `json:"tags,omitempty"`
+	Refs         []string  `json:"refs,omitempty"`
 	Process      string    `json:"process"`
 }
 
@@ -209,6 +210,7 @@ func (a *Store) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32,
 		UpdatedAt: time.Now().UTC(),
 		MapID:     link.Meta.MapID,
 		Tags:      link.Meta.Tags,
+		Refs:      link.Meta.GetRefLinkHashes(),
 		Process:   link.Meta.Process,
 	}
 
@@ -256,40 +258,210 @@ func (a *Store) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Se
 
 // FindSegments implements github.com/stratumn/go-indigocore/store.SegmentReader.FindSegments.
 func (a *Store) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
-	var prevLinkHash []byte
-	q := a.links
+	q, err := a.sortedSegmentsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evidences are fetched by link hash for the links of the page only.
+	q = q.Skip(filter.Offset).Limit(filter.Limit).Map(func(row rethink.Term) interface{} {
+		return map[string]interface{}{
+			"link": row.Field("content"),
+			"meta": map[string]interface{}{
+				"evidences": a.evidences.Get(row.Field("id")).Field("content").Default(cs.Evidences{}),
+			},
+		}
+	})
+
+	cur, err := q.Run(a.session)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	segments := make(cs.SegmentSlice, 0, filter.Limit)
+	if err := cur.All(&segments); err != nil {
+		return nil, err
+	}
+	for _, s := range segments {
+		err = s.SetLinkHash()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return segments, nil
+}
+
+// CountSegments implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegments.
+func (a *Store) CountSegments(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	q, err := a.segmentsQuery(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := q.Count().Run(a.session)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close()
+
+	var count int
+	if err := cur.One(&count); err != nil {
+		return 0, err
+	}
 
+	return count, nil
+}
+
+// CountSegmentsBy implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegmentsBy.
+func (a *Store) CountSegmentsBy(ctx context.Context, filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	q, err := a.segmentsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := q.Group(func(row rethink.Term) interface{} {
+		return row.Field("content").Field("meta").Field(groupBy).Default("")
+	}).Count().Ungroup().Run(a.session)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	var groups []struct {
+		Group     string `json:"group"`
+		Reduction int    `json:"reduction"`
+	}
+	if err := cur.All(&groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(groups))
+	for _, g := range groups {
+		counts[g.Group] = g.Reduction
+	}
+
+	return counts, nil
+}
+
+// sortedSegmentsQuery returns the query selecting the link documents that
+// match a filter in its sort order, starting after its pagination cursor.
+// Links are sorted in the default order using an index. Other sort orders,
+// like the links selected by hash or by reference, are sorted in memory.
+func (a *Store) sortedSegmentsQuery(filter *store.SegmentFilter) (rethink.Term, error) {
+	if filter.Sort != nil {
+		if err := filter.Sort.Validate(); err != nil {
+			return a.links, err
+		}
+	}
+
+	cursor, err := filter.SegmentCursor()
+	if err != nil {
+		return a.links, err
+	}
+
+	if !filter.Sort.IsDefault() || len(filter.LinkHashes) > 0 || filter.RefLinkHash != "" {
+		q, err := a.segmentsQuery(filter)
+		if err != nil {
+			return q, err
+		}
+
+		if cursor != nil {
+			q = q.Filter(func(row rethink.Term) interface{} {
+				return row.Field("priority").Lt(cursor.Priority).Or(
+					row.Field("priority").Eq(cursor.Priority).And(row.Field("id").Gt(cursor.LinkHash[:])),
+				)
+			})
+		}
+
+		return q.OrderBy(rethinkOrder(filter.Sort)...), nil
+	}
+
+	// Indexes are prefixed by the field the links are selected by, if
+	// any, followed by the sort key of the default order.
+	index, prefix := "priorityOrder", []interface{}{}
 	if filter.PrevLinkHash != nil {
-		if prevLinkHashBytes, err := types.NewBytes32FromString(*filter.PrevLinkHash); prevLinkHashBytes != nil && err == nil {
-			prevLinkHash = prevLinkHashBytes[:]
+		index, prefix = "prevLinkHashPriorityOrder", []interface{}{prevLinkHashBytes(filter)}
+	} else if len(filter.MapIDs) == 1 {
+		index, prefix = "mapIdPriorityOrder", []interface{}{filter.MapIDs[0]}
+	}
+
+	lower := append(append([]interface{}{}, prefix...), rethink.MinVal, rethink.MinVal)
+	upper := append(append([]interface{}{}, prefix...), rethink.MaxVal, rethink.MaxVal)
+	leftBound := "closed"
+	if cursor != nil {
+		lower = append(append([]interface{}{}, prefix...), -cursor.Priority, cursor.LinkHash[:])
+		leftBound = "open"
+	}
+
+	q := a.links.Between(lower, upper, rethink.BetweenOpts{
+		Index:      index,
+		LeftBound:  leftBound,
+		RightBound: "closed",
+	}).OrderBy(rethink.OrderByOpts{Index: index})
+
+	return a.filterLinks(q, filter)
+}
+
+// segmentsQuery returns the query selecting the link documents that match
+// a filter, ignoring its pagination and its sort order.
+func (a *Store) segmentsQuery(filter *store.SegmentFilter) (rethink.Term, error) {
+	q := a.links
+
+	if len(filter.LinkHashes) > 0 {
+		linkHashes, err := cs.NewLinkHashesFromStrings(filter.LinkHashes)
+		if err != nil {
+			return q, err
+		}
+
+		ids := make([]interface{}, len(linkHashes))
+		for i, v := range linkHashes {
+			ids[i] = v
+		}
+		q = q.GetAll(ids...)
+
+		if filter.PrevLinkHash != nil {
+			q = q.Filter(rethink.Row.Field("prevLinkHash").Eq(prevLinkHashBytes(filter)))
 		}
+	} else if filter.PrevLinkHash != nil {
+		prevLinkHash := prevLinkHashBytes(filter)
 		q = q.Between([]interface{}{
 			prevLinkHash,
 			rethink.MinVal,
+			rethink.MinVal,
 		}, []interface{}{
 			prevLinkHash,
 			rethink.MaxVal,
+			rethink.MaxVal,
 		}, rethink.BetweenOpts{
-			Index:      "prevLinkHashOrder",
+			Index:      "prevLinkHashPriorityOrder",
 			LeftBound:  "closed",
 			RightBound: "closed",
 		})
+	} else if filter.RefLinkHash != "" {
+		q = q.GetAllByIndex("refs", filter.RefLinkHash)
 	}
 
-	if len(filter.LinkHashes) > 0 {
-
-		linkHashes, err := cs.NewLinkHashesFromStrings(filter.LinkHashes)
-		if err != nil {
-			return nil, err
-		}
+	return a.filterLinks(q, filter)
+}
 
-		ids := make([]interface{}, len(linkHashes))
-		for i, v := range linkHashes {
-			ids[i] = v
-		}
-		q = q.GetAll(ids...)
+// prevLinkHashBytes returns the previous link hash of a filter as stored in
+// link documents.
+func prevLinkHashBytes(filter *store.SegmentFilter) []byte {
+	if prevLinkHash, err := types.NewBytes32FromString(*filter.PrevLinkHash); prevLinkHash != nil && err == nil {
+		return prevLinkHash[:]
 	}
+	return nil
+}
 
+// filterLinks filters link documents on all the fields of a filter except
+// their hash and their previous link hash.
+func (a *Store) filterLinks(q rethink.Term, filter *store.SegmentFilter) (rethink.Term, error) {
 	if mapIDs := filter.MapIDs; len(mapIDs) > 0 {
 		ids := make([]interface{}, len(mapIDs))
 		for i, v := range mapIDs {
@@ -298,14 +470,6 @@ func (a *Store) FindSegments(ctx context.Context, filter *store.SegmentFilter) (
 		q = q.Filter(func(row rethink.Term) interface{} {
 			return rethink.Expr(ids).Contains(row.Field("mapId"))
 		})
-	} else if prevLinkHash := filter.PrevLinkHash; prevLinkHash != nil {
-		q = q.OrderBy(rethink.OrderByOpts{Index: "prevLinkHashOrder"})
-	} else if linkHashes := filter.LinkHashes; len(linkHashes) > 0 {
-		q = q.OrderBy(rethink.Asc("id"))
-	} else if mapIDs := filter.MapIDs; len(mapIDs) > 0 {
-		q = q.OrderBy(rethink.OrderByOpts{Index: rethink.Desc("mapIdOrder")})
-	} else {
-		q = q.OrderBy(rethink.OrderByOpts{Index: rethink.Desc("order")})
 	}
 
 	if process := filter.Process; len(process) > 0 {
@@ -320,35 +484,51 @@ func (a *Store) FindSegments(ctx context.Context, filter *store.SegmentFilter) (
 		q = q.Filter(rethink.Row.Field("tags").Contains(t...))
 	}
 
-	q = q.OuterJoin(a.evidences, func(a, b rethink.Term) rethink.Term {
-		return a.Field("id").Eq(b.Field("id"))
-	}).Map(func(row rethink.Term) interface{} {
-		return map[string]interface{}{
-			"link": row.Field("left").Field("content"),
-			"meta": map[string]interface{}{
-				"evidences": rethink.Branch(row.HasFields("right"), row.Field("right").Field("content"), cs.Evidences{}),
-			},
-		}
-	})
+	if refLinkHash := filter.RefLinkHash; refLinkHash != "" {
+		q = q.Filter(rethink.Row.Field("refs").Default([]interface{}{}).Contains(refLinkHash))
+	}
 
-	cur, err := q.Skip(filter.Offset).Limit(filter.Limit).Run(a.session)
-	if err != nil {
-		return nil, err
+	if linkTypes := filter.LinkTypes; len(linkTypes) > 0 {
+		q = q.Filter(func(row rethink.Term) interface{} {
+			return rethink.Expr(linkTypes).Contains(row.Field("content").Field("meta").Field("type"))
+		})
 	}
-	defer cur.Close()
 
-	segments := make(cs.SegmentSlice, 0, filter.Limit)
-	if err := cur.All(&segments); err != nil {
-		return nil, err
+	if actions := filter.Actions; len(actions) > 0 {
+		q = q.Filter(func(row rethink.Term) interface{} {
+			return rethink.Expr(actions).Contains(row.Field("content").Field("meta").Field("action"))
+		})
 	}
-	for _, s := range segments {
-		err = s.SetLinkHash()
-		if err != nil {
-			return nil, err
+
+	// The time at which a link was saved is the last time it was written.
+	if filter.CreatedAfter != nil {
+		q = q.Filter(rethink.Row.Field("updatedAt").Ge(filter.CreatedAfter.UTC()))
+	}
+
+	if filter.CreatedBefore != nil {
+		q = q.Filter(rethink.Row.Field("updatedAt").Lt(filter.CreatedBefore.UTC()))
+	}
+
+	for i := range filter.Where {
+		p := &filter.Where[i]
+		if err := p.Validate(); err != nil {
+			return q, err
 		}
+		q = q.Filter(func(row rethink.Term) interface{} {
+			return rethinkPredicate(row.Field("content"), p)
+		})
 	}
 
-	return segments, nil
+	if backend := filter.EvidenceBackend; backend != "" {
+		q = q.Filter(func(row rethink.Term) interface{} {
+			evidences := a.evidences.Get(row.Field("id")).Field("content").Default([]interface{}{})
+			return evidences.Contains(func(e rethink.Term) interface{} {
+				return e.Field("backend").Eq(backend)
+			})
+		})
+	}
+
+	return q, nil
 }
 
 // GetMapIDs implements github.com/stratumn/go-indigocore/store.SegmentReader.GetMapIDs.
@@ -400,6 +580,15 @@ func (a *Store) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]strin
 		}
 	}
 
+	mapCursor, err := filter.MapCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	if mapCursor != "" {
+		q = q.Filter(rethink.Row.Gt(mapCursor))
+	}
+
 	cur, err := q.Skip(filter.Pagination.Offset).Limit(filter.Limit).Run(a.session)
 	if err != nil {
 		return nil, err
@@ -547,7 +736,8 @@ func (a *Store) NewBatch(ctx context.Context) (store.Batch, error) {
 	return &rethinkBufferedBatch{Batch: bbBatch}, nil
 }
 
-// Create creates the database tables and indexes.
+// Create creates the database tables and indexes. If the tables exist, it
+// only creates the missing indexes.
 func (a *Store) Create() (err error) {
 	exec := func(term rethink.Term) {
 		if err == nil {
@@ -559,7 +749,7 @@ func (a *Store) Create() (err error) {
 	if err != nil {
 		return err
 	} else if exists {
-		return nil
+		return a.updateIndexes()
 	}
 
 	tblOpts := rethink.TableCreateOpts{}
@@ -569,30 +759,6 @@ func (a *Store) Create() (err error) {
 
 	exec(a.db.TableCreate("links", tblOpts))
 	exec(a.links.Wait())
-	exec(a.links.IndexCreate("mapId"))
-	exec(a.links.IndexWait("mapId"))
-	exec(a.links.IndexCreateFunc("order", []interface{}{
-		rethink.Row.Field("priority"),
-		rethink.Row.Field("updatedAt"),
-	}))
-	exec(a.links.IndexWait("order"))
-	exec(a.links.IndexCreateFunc("mapIdOrder", []interface{}{
-		rethink.Row.Field("mapId"),
-		rethink.Row.Field("priority"),
-		rethink.Row.Field("updatedAt"),
-	}))
-	exec(a.links.IndexWait("mapIdOrder"))
-	exec(a.links.IndexCreateFunc("prevLinkHashOrder", []interface{}{
-		rethink.Row.Field("prevLinkHash"),
-		rethink.Row.Field("priority"),
-		rethink.Row.Field("updatedAt"),
-	}))
-	exec(a.links.IndexWait("prevLinkHashOrder"))
-	exec(a.links.IndexCreateFunc("processOrder", []interface{}{
-		rethink.Row.Field("process"),
-		rethink.Row.Field("mapId"),
-	}))
-	exec(a.links.IndexWait("processOrder"))
 
 	exec(a.db.TableCreate("evidences", tblOpts))
 	exec(a.evidences.Wait())
@@ -600,7 +766,121 @@ func (a *Store) Create() (err error) {
 	exec(a.db.TableCreate("values", tblOpts))
 	exec(a.values.Wait())
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	return a.updateIndexes()
+}
+
+// linkIndexes are the secondary indexes of the links table.
+// Links are sorted by descending priority, then by ascending link hash.
+var linkIndexes = map[string]interface{}{
+	"mapId": rethink.Row.Field("mapId"),
+	"priorityOrder": []interface{}{
+		rethink.Row.Field("priority").Mul(-1),
+		rethink.Row.Field("id"),
+	},
+	"mapIdPriorityOrder": []interface{}{
+		rethink.Row.Field("mapId"),
+		rethink.Row.Field("priority").Mul(-1),
+		rethink.Row.Field("id"),
+	},
+	"prevLinkHashPriorityOrder": []interface{}{
+		rethink.Row.Field("prevLinkHash"),
+		rethink.Row.Field("priority").Mul(-1),
+		rethink.Row.Field("id"),
+	},
+	"processOrder": []interface{}{
+		rethink.Row.Field("process"),
+		rethink.Row.Field("mapId"),
+	},
+}
+
+// multiLinkIndexes are the secondary indexes of the links table that index
+// each value of an array.
+var multiLinkIndexes = map[string]interface{}{
+	"refs": rethink.Row.Field("refs"),
+}
+
+// obsoleteLinkIndexes are the indexes of the links table that are no longer
+// used.
+var obsoleteLinkIndexes = []string{"order", "mapIdOrder", "prevLinkHashOrder"}
+
+// updateIndexes creates the missing indexes of the links table and drops
+// the obsolete ones, so that databases created by previous versions can be
+// used.
+func (a *Store) updateIndexes() error {
+	cur, err := a.links.IndexList().Run(a.session)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer cur.Close()
+
+	var names []string
+	if err := cur.All(&names); err != nil {
+		return errors.WithStack(err)
+	}
+
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	for _, name := range obsoleteLinkIndexes {
+		if existing[name] {
+			if err := a.links.IndexDrop(name).Exec(a.session); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	var created []interface{}
+	for name, index := range linkIndexes {
+		if !existing[name] {
+			if err := a.links.IndexCreateFunc(name, index).Exec(a.session); err != nil {
+				return errors.WithStack(err)
+			}
+			created = append(created, name)
+		}
+	}
+
+	if !existing["refs"] {
+		if err := a.addRefs(); err != nil {
+			return err
+		}
+	}
+
+	for name, index := range multiLinkIndexes {
+		if !existing[name] {
+			opts := rethink.IndexCreateOpts{Multi: true}
+			if err := a.links.IndexCreateFunc(name, index, opts).Exec(a.session); err != nil {
+				return errors.WithStack(err)
+			}
+			created = append(created, name)
+		}
+	}
+
+	if len(created) > 0 {
+		return errors.WithStack(a.links.IndexWait(created...).Exec(a.session))
+	}
+
+	return nil
+}
+
+// addRefs sets the link hashes of the references of the links saved by
+// previous versions, which did not save them alongside the link.
+func (a *Store) addRefs() error {
+	q := a.links.Filter(rethink.Row.HasFields("refs").Not()).Update(func(row rethink.Term) interface{} {
+		refs := row.Field("content").Field("meta").Field("refs").Default([]interface{}{})
+		return map[string]interface{}{
+			"refs": refs.Map(func(ref rethink.Term) interface{} {
+				return ref.Field("linkHash")
+			}),
+		}
+	})
+
+	return errors.WithStack(q.Exec(a.session))
 }
 
 // Drop drops the database tables and indexes.
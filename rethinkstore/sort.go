@@ -0,0 +1,72 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rethinkstore
+
+import (
+	"github.com/stratumn/go-indigocore/store"
+
+	rethink "gopkg.in/dancannon/gorethink.v4"
+)
+
+// rethinkOrder returns the terms ordering link documents in the given valid
+// sort order.
+func rethinkOrder(s *store.Sort) []interface{} {
+	if s.IsDefault() {
+		// Segments must be ordered the same way in all stores for
+		// continuation tokens to be usable, hence the explicit ordering
+		// on the link hash.
+		return []interface{}{rethink.Desc("priority"), rethink.Asc("id")}
+	}
+
+	direction := rethink.Asc
+	if s.Desc {
+		direction = rethink.Desc
+	}
+
+	switch s.Field {
+	case store.SortByPriority:
+		return []interface{}{direction("priority"), rethink.Asc("id")}
+	case store.SortByCreatedAt:
+		// The time at which a link was saved is the last time it was
+		// written.
+		return []interface{}{direction("updatedAt"), rethink.Asc("id")}
+	case store.SortByLinkHash:
+		return []interface{}{direction("id")}
+	}
+
+	keys := s.Keys()
+
+	value := func(row rethink.Term) rethink.Term {
+		v := row.Field("content")
+		for _, key := range keys {
+			v = v.Field(key)
+		}
+		return v.Default(nil)
+	}
+
+	// Numbers come first, then strings, then other values whatever the
+	// direction. Other values are not compared with each other.
+	rank := func(row rethink.Term) interface{} {
+		typ := value(row).TypeOf()
+		return rethink.Branch(typ.Eq("NUMBER"), 0, typ.Eq("STRING"), 1, 2)
+	}
+
+	ranked := func(row rethink.Term) interface{} {
+		typ := value(row).TypeOf()
+		return rethink.Branch(typ.Eq("NUMBER").Or(typ.Eq("STRING")), value(row), nil)
+	}
+
+	return []interface{}{rethink.Asc(rank), direction(ranked), rethink.Asc("id")}
+}
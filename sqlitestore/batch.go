@@ -0,0 +1,83 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"github.com/stratumn/go-indigocore/bufferedbatch"
+	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/postgresstore"
+	"github.com/stratumn/go-indigocore/store"
+	"go.opencensus.io/trace"
+)
+
+// Batch is the type that implements github.com/stratumn/go-indigocore/store.Batch.
+// Links are buffered and written in a single transaction, so that a batch
+// does not hold the database lock until it is written.
+type Batch struct {
+	*bufferedbatch.Batch
+
+	originalStore *Store
+}
+
+// NewBatch creates a new Batch.
+func NewBatch(ctx context.Context, a *Store) *Batch {
+	return &Batch{
+		Batch:         bufferedbatch.NewBatch(ctx, a),
+		originalStore: a,
+	}
+}
+
+// Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+func (b *Batch) Write(ctx context.Context) (err error) {
+	ctx, span := trace.StartSpan(ctx, "sqlitestore/batch/Write")
+	defer monitoring.SetSpanStatusAndEnd(span, err)
+
+	a := b.originalStore
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmts, err := postgresstore.NewTxStmts(tx, SQLite)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	w := postgresstore.NewWriter(stmts.WriteStmts)
+	for _, link := range b.Links {
+		if _, err = w.CreateLink(ctx, link); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(b.Links) > 0 {
+		linksEvent := store.NewSavedLinks(b.Links...)
+
+		for _, c := range a.eventChans {
+			c <- linksEvent
+		}
+	}
+
+	return nil
+}
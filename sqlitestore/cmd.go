@@ -0,0 +1,59 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitestore
+
+import (
+	"flag"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stratumn/go-indigocore/utils"
+)
+
+var (
+	path string
+)
+
+// Initialize initializes a SQLite store adapter.
+// The tables and indexes are created if they do not exist.
+func Initialize(config *Config) *Store {
+	a, err := New(config)
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to create SQLite store")
+	}
+
+	if err := a.Create(); err != nil {
+		log.WithField("error", err).Fatal("Failed to create SQLite tables and indexes")
+	}
+
+	if err := a.Prepare(); err != nil {
+		log.WithField("error", err).Fatal("Failed to prepare SQLite statements")
+	}
+
+	return a
+}
+
+// RegisterFlags registers the flags used by InitializeWithFlags.
+func RegisterFlags() {
+	flag.StringVar(&path, "path", utils.OrStrings(os.Getenv("SQLITESTORE_PATH"), DefaultPath), "path of the SQLite database file")
+}
+
+// InitializeWithFlags should be called after RegisterFlags and flag.Parse to initialize
+// a SQLite adapter using flag values.
+func InitializeWithFlags(version, commit string) *Store {
+	config := &Config{Path: path, Version: version, Commit: commit}
+	return Initialize(config)
+}
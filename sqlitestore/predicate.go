@@ -0,0 +1,89 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitestore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stratumn/go-indigocore/store"
+)
+
+// sqlitePredicate translates a predicate to a condition on the JSON data
+// column of the links table using the JSON functions of SQLite.
+func sqlitePredicate(p *store.Predicate, bind func(value interface{}) string) string {
+	path := bind(jsonPath(p.Keys()))
+	typ := fmt.Sprintf("json_type(l.data, %s)", path)
+	value := fmt.Sprintf("json_extract(l.data, %s)", path)
+
+	switch p.Op {
+	case store.OpExists:
+		return fmt.Sprintf("%s <> 'null'", typ)
+
+	case store.OpEq:
+		return sqliteEqual(typ, value, p.Value, bind)
+
+	case store.OpIn:
+		values := p.Value.([]interface{})
+		conditions := make([]string, len(values))
+		for i, v := range values {
+			conditions[i] = sqliteEqual(typ, value, v, bind)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(conditions, " OR "))
+	}
+
+	return fmt.Sprintf("(%s AND %s %s %s)", sqliteType(typ, p.Value), value, p.Op, bind(p.Value))
+}
+
+// sqliteEqual returns a condition that is true if the extracted value is
+// equal to the given one. JSON booleans are extracted as integers so they
+// are only checked by type.
+func sqliteEqual(typ, value string, v interface{}, bind func(value interface{}) string) string {
+	if _, ok := v.(bool); ok {
+		return sqliteType(typ, v)
+	}
+	return fmt.Sprintf("(%s AND %s = %s)", sqliteType(typ, v), value, bind(v))
+}
+
+// sqliteType returns a condition that is true if the JSON type of the
+// extracted value matches the type of the given value.
+func sqliteType(typ string, v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%s = 'text'", typ)
+	case bool:
+		return fmt.Sprintf("%s = '%t'", typ, v)
+	default:
+		return fmt.Sprintf("%s IN ('integer', 'real')", typ)
+	}
+}
+
+// sqliteSortState returns the number and the text found at the given keys
+// of the JSON data column of the links table.
+func sqliteSortState(keys []string) (number, text string) {
+	path := "'" + jsonPath(keys) + "'"
+	typ := fmt.Sprintf("json_type(l.data, %s)", path)
+	value := fmt.Sprintf("json_extract(l.data, %s)", path)
+
+	number = fmt.Sprintf("CASE WHEN %s IN ('integer', 'real') THEN %s END", typ, value)
+	text = fmt.Sprintf("CASE WHEN %s = 'text' THEN %s END", typ, value)
+
+	return number, text
+}
+
+// jsonPath returns the SQLite JSON path of the given keys.
+func jsonPath(keys []string) string {
+	return `$."` + strings.Join(keys, `"."`) + `"`
+}
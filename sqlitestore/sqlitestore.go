@@ -0,0 +1,199 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlitestore implements a store that saves all the segments in an
+// embedded SQLite database. It shares its statements with postgresstore and
+// requires SQLite >= 3.35 for "RETURNING" support.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	// Registers the sqlite3 database driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/postgresstore"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// Name is the name set in the store's information.
+	Name = "sqlite"
+
+	// Description is the description set in the store's information.
+	Description = "Indigo's SQLite Store"
+
+	// DefaultPath is the default path of the database file.
+	DefaultPath = "/var/stratumn/sqlitestore/store.db"
+
+	// busyTimeout is how long in milliseconds a connection waits for
+	// another one to release its lock on the database.
+	busyTimeout = 5000
+)
+
+// Config contains configuration options for the store.
+type Config struct {
+	// A version string that will be set in the store's information.
+	Version string
+
+	// A git commit hash that will be set in the store's information.
+	Commit string
+
+	// The path of the database file. It is created if it does not exist.
+	Path string
+}
+
+// Info is the info returned by GetInfo.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+}
+
+// Store is the type that implements github.com/stratumn/go-indigocore/store.Adapter.
+type Store struct {
+	*postgresstore.Reader
+	*postgresstore.Writer
+	config     *Config
+	eventChans []chan *store.Event
+	db         *sql.DB
+	stmts      *postgresstore.Stmts
+}
+
+// New creates an instance of a Store.
+func New(config *Config) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0700); err != nil {
+		return nil, err
+	}
+
+	// The write-ahead log lets readers run concurrently with a writer.
+	// LIKE is made case sensitive to behave like PostgreSQL.
+	dsn := fmt.Sprintf(
+		"file:%s?_busy_timeout=%d&_journal_mode=WAL&_txlock=immediate&_cslike=1",
+		(&url.URL{Path: config.Path}).EscapedPath(),
+		busyTimeout,
+	)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{config: config, db: db}, nil
+}
+
+// GetInfo implements github.com/stratumn/go-indigocore/store.Adapter.GetInfo.
+func (a *Store) GetInfo(ctx context.Context) (interface{}, error) {
+	return &Info{
+		Name:        Name,
+		Description: Description,
+		Version:     a.config.Version,
+		Commit:      a.config.Commit,
+	}, nil
+}
+
+// NewBatch implements github.com/stratumn/go-indigocore/store.Adapter.NewBatch.
+func (a *Store) NewBatch(ctx context.Context) (store.Batch, error) {
+	return NewBatch(ctx, a), nil
+}
+
+// AddStoreEventChannel implements github.com/stratumn/go-indigocore/store.Adapter.AddStoreEventChannel
+func (a *Store) AddStoreEventChannel(eventChan chan *store.Event) {
+	a.eventChans = append(a.eventChans, eventChan)
+}
+
+// CreateLink implements github.com/stratumn/go-indigocore/store.LinkWriter.CreateLink.
+func (a *Store) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
+	linkHash, err := a.Writer.CreateLink(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	linkEvent := store.NewSavedLinks(link)
+
+	for _, c := range a.eventChans {
+		c <- linkEvent
+	}
+	return linkHash, nil
+}
+
+// AddEvidence implements github.com/stratumn/go-indigocore/store.EvidenceWriter.AddEvidence.
+func (a *Store) AddEvidence(ctx context.Context, linkHash *types.Bytes32, evidence *cs.Evidence) error {
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.stmts.AddEvidence.Exec(linkHash[:], evidence.Provider, string(data))
+	if err != nil {
+		return err
+	}
+
+	evidenceEvent := store.NewSavedEvidences()
+	evidenceEvent.AddSavedEvidence(linkHash, evidence)
+
+	for _, c := range a.eventChans {
+		c <- evidenceEvent
+	}
+
+	return nil
+}
+
+// Create creates the database tables and indexes if they do not exist.
+func (a *Store) Create() error {
+	for _, query := range SQLite.Create {
+		if _, err := a.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare prepares the database stmts.
+// It should be called once before interacting with segments.
+// It assumes the tables have been created using Create().
+func (a *Store) Prepare() error {
+	stmts, err := postgresstore.NewStmts(a.db, SQLite)
+	if err != nil {
+		return err
+	}
+	a.stmts = stmts
+	a.Reader = postgresstore.NewReader(a.stmts.ReadStmts)
+	a.Writer = postgresstore.NewWriter(a.stmts.WriteStmts)
+
+	return nil
+}
+
+// Drop drops the database tables and indexes.
+func (a *Store) Drop() error {
+	for _, query := range SQLite.Drop {
+		if _, err := a.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database connection.
+func (a *Store) Close() error {
+	return a.db.Close()
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitestore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storetestcases"
+	"github.com/stratumn/go-indigocore/tmpop/tmpoptestcases"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	factory := storetestcases.Factory{
+		New:               createAdapter,
+		NewKeyValueStore:  createKeyValueStore,
+		Free:              freeAdapter,
+		FreeKeyValueStore: freeKeyValueStore,
+	}
+
+	factory.RunStoreTests(t)
+	factory.RunKeyValueStoreTests(t)
+}
+
+func TestSQLiteTMPop(t *testing.T) {
+	tmpoptestcases.Factory{
+		New:  createAdapterTMPop,
+		Free: freeAdapterTMPop,
+	}.RunTests(t)
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+
+	a, err := createStore()
+	require.NoError(t, err, "createStore()")
+	defer os.RemoveAll(filepath.Dir(a.config.Path))
+
+	link := cstesting.RandomLink()
+	linkHash, err := a.CreateLink(ctx, link)
+	require.NoError(t, err, "a.CreateLink()")
+	require.NoError(t, a.Close(), "a.Close()")
+
+	a, err = New(a.config)
+	require.NoError(t, err, "New()")
+	defer a.Close()
+	require.NoError(t, a.Create(), "a.Create()")
+	require.NoError(t, a.Prepare(), "a.Prepare()")
+
+	got, err := a.GetSegment(ctx, linkHash)
+	require.NoError(t, err, "a.GetSegment()")
+	require.NotNil(t, got, "a.GetSegment()")
+	assert.Equal(t, *link, got.Link, "got.Link")
+}
+
+func createStore() (*Store, error) {
+	dir, err := ioutil.TempDir("", "sqlitestore")
+	if err != nil {
+		return nil, err
+	}
+	a, err := New(&Config{Path: filepath.Join(dir, "store.db")})
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Create(); err != nil {
+		return nil, err
+	}
+	if err := a.Prepare(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func createAdapter() (store.Adapter, error) {
+	return createStore()
+}
+
+func createKeyValueStore() (store.KeyValueStore, error) {
+	return createStore()
+}
+
+func freeStore(s *Store) {
+	if err := s.Drop(); err != nil {
+		panic(err)
+	}
+	if err := s.Close(); err != nil {
+		panic(err)
+	}
+	os.RemoveAll(filepath.Dir(s.config.Path))
+}
+
+func freeAdapter(s store.Adapter) {
+	freeStore(s.(*Store))
+}
+
+func freeKeyValueStore(s store.KeyValueStore) {
+	freeStore(s.(*Store))
+}
+
+func createAdapterTMPop() (store.Adapter, store.KeyValueStore, error) {
+	a, err := createStore()
+	return a, a, err
+}
+
+func freeAdapterTMPop(a store.Adapter, _ store.KeyValueStore) {
+	freeAdapter(a)
+}
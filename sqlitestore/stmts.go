@@ -0,0 +1,146 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitestore
+
+import (
+	"encoding/json"
+
+	"github.com/stratumn/go-indigocore/postgresstore"
+)
+
+// The tables are the same as the PostgreSQL ones. Tags are saved as a JSON
+// array since SQLite has no array type.
+var sqlCreate = []string{
+	`
+		CREATE TABLE IF NOT EXISTS links (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			link_hash BLOB NOT NULL,
+			priority REAL NOT NULL,
+			map_id TEXT NOT NULL,
+			prev_link_hash BLOB DEFAULT NULL,
+			tags TEXT DEFAULT NULL,
+			data TEXT NOT NULL,
+			process TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	`
+		CREATE UNIQUE INDEX IF NOT EXISTS links_link_hash_idx
+		ON links (link_hash)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS links_priority_created_at_idx
+		ON links (priority DESC, created_at DESC)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS links_map_id_idx
+		ON links (map_id)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS links_map_id_priority_created_at_idx
+		ON links (map_id, priority DESC, created_at DESC)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS links_prev_link_hash_priority_created_at_idx
+		ON links (prev_link_hash, priority DESC, created_at DESC)
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS evidences (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			link_hash BLOB NOT NULL,
+			provider TEXT NOT NULL,
+			data TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	`
+		CREATE UNIQUE INDEX IF NOT EXISTS evidences_link_hash_provider_idx
+		ON evidences (link_hash, provider)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS evidences_link_hash_idx
+		ON evidences (link_hash)
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS "values" (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key BLOB NOT NULL,
+			value BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	`
+		CREATE UNIQUE INDEX IF NOT EXISTS values_key_idx
+		ON "values" (key)
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS refs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			link_hash BLOB NOT NULL,
+			ref_link_hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	`
+		CREATE UNIQUE INDEX IF NOT EXISTS refs_link_hash_ref_link_hash_idx
+		ON refs (link_hash, ref_link_hash)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS refs_ref_link_hash_idx
+		ON refs (ref_link_hash)
+	`,
+}
+
+var sqlDrop = []string{
+	"DROP TABLE IF EXISTS links",
+	"DROP TABLE IF EXISTS evidences",
+	`DROP TABLE IF EXISTS "values"`,
+	"DROP TABLE IF EXISTS refs",
+}
+
+// SQLite is the dialect used to run the postgresstore statements on a SQLite
+// database.
+var SQLite = &postgresstore.Dialect{
+	Create: sqlCreate,
+	Drop:   sqlDrop,
+	Tags: func(tags []string) interface{} {
+		if len(tags) == 0 {
+			return nil
+		}
+		// Marshaling a slice of strings cannot fail.
+		data, _ := json.Marshal(tags)
+		return string(data)
+	},
+	ContainsTags: func(placeholder string) string {
+		return "NOT EXISTS (SELECT value FROM json_each(" + placeholder + ") EXCEPT SELECT value FROM json_each(tags))"
+	},
+	Predicate: sqlitePredicate,
+	Timestamp: func(placeholder string) string {
+		// Links are saved with a millisecond precision, using the same
+		// format.
+		return "strftime('%Y-%m-%d %H:%M:%f', " + placeholder + ")"
+	},
+	HasEvidenceBackend: func(placeholder string) string {
+		return `EXISTS (
+			SELECT 1 FROM evidences ev
+			WHERE ev.link_hash = l.link_hash AND json_extract(ev.data, '$.backend') = ` + placeholder + `
+		)`
+	},
+	SortState: sqliteSortState,
+	LinkType:  "json_extract(l.data, '$.meta.type')",
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+)
+
+// Fields segments can be grouped by when they are counted.
+const (
+	// GroupByMapID groups segments by the map ID of their link.
+	GroupByMapID = "mapId"
+
+	// GroupByProcess groups segments by the process of their link.
+	GroupByProcess = "process"
+
+	// GroupByType groups segments by the type of their link.
+	GroupByType = "type"
+)
+
+// ErrInvalidGroupBy is returned when segments are grouped by an unknown
+// field.
+var ErrInvalidGroupBy = errors.New("invalid group by field")
+
+// SegmentCounter is an optional interface that can be implemented by
+// stores that are able to count segments without fetching them.
+type SegmentCounter interface {
+	// Count the segments matching the filter.
+	// The pagination and the sort order of the filter are ignored.
+	CountSegments(ctx context.Context, filter *SegmentFilter) (int, error)
+
+	// Count the segments matching the filter for each value of the given
+	// field. Values without segments are omitted.
+	// The pagination and the sort order of the filter are ignored.
+	CountSegmentsBy(ctx context.Context, filter *SegmentFilter, groupBy string) (map[string]int, error)
+}
+
+// MapCounter is an optional interface that can be implemented by stores
+// that are able to count maps without grouping all their segments.
+type MapCounter interface {
+	// Count the maps containing segments matching the filter.
+	CountMaps(ctx context.Context, filter *SegmentFilter) (int, error)
+}
+
+// ValidateGroupBy checks that segments can be grouped by the given field.
+func ValidateGroupBy(groupBy string) error {
+	switch groupBy {
+	case GroupByMapID, GroupByProcess, GroupByType:
+		return nil
+	}
+
+	return errors.Wrapf(ErrInvalidGroupBy, "field %q must be %q, %q or %q", groupBy, GroupByMapID, GroupByProcess, GroupByType)
+}
+
+// GroupValue returns the value of the field a segment is grouped by.
+func GroupValue(segment *cs.Segment, groupBy string) string {
+	switch groupBy {
+	case GroupByMapID:
+		return segment.Link.Meta.MapID
+	case GroupByProcess:
+		return segment.Link.Meta.Process
+	case GroupByType:
+		return segment.Link.Meta.Type
+	}
+
+	return ""
+}
+
+// CountSegments returns the number of segments matching the filter.
+// If the reader implements SegmentCounter it is used directly, otherwise
+// the segments are streamed and counted.
+func CountSegments(ctx context.Context, reader SegmentReader, filter *SegmentFilter) (int, error) {
+	if counter, ok := reader.(SegmentCounter); ok {
+		return counter.CountSegments(ctx, filter)
+	}
+
+	count := 0
+	err := forEachSegment(ctx, reader, filter, func(*cs.Segment) {
+		count++
+	})
+
+	return count, err
+}
+
+// CountSegmentsBy returns the number of segments matching the filter for
+// each value of the given field.
+// If the reader implements SegmentCounter it is used directly, otherwise
+// the segments are streamed and counted.
+func CountSegmentsBy(ctx context.Context, reader SegmentReader, filter *SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	if counter, ok := reader.(SegmentCounter); ok {
+		return counter.CountSegmentsBy(ctx, filter, groupBy)
+	}
+
+	counts := map[string]int{}
+	err := forEachSegment(ctx, reader, filter, func(segment *cs.Segment) {
+		counts[GroupValue(segment, groupBy)]++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CountMaps returns the number of maps containing segments matching the
+// filter.
+// If the reader implements MapCounter it is used directly, otherwise the
+// segments are counted by map ID.
+func CountMaps(ctx context.Context, reader SegmentReader, filter *SegmentFilter) (int, error) {
+	if counter, ok := reader.(MapCounter); ok {
+		return counter.CountMaps(ctx, filter)
+	}
+
+	counts, err := CountSegmentsBy(ctx, reader, filter, GroupByMapID)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(counts), nil
+}
+
+func forEachSegment(ctx context.Context, reader SegmentReader, filter *SegmentFilter, fn func(*cs.Segment)) error {
+	// Segments are counted in any order and without pagination.
+	f := *filter
+	f.Pagination = Pagination{}
+	f.Sort = nil
+
+	it, err := StreamSegments(ctx, reader, &f)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		fn(it.Segment())
+	}
+
+	return it.Err()
+}
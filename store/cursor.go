@@ -0,0 +1,149 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// cursor is the decoded content of a continuation token.
+// Tokens are opaque to clients: they are base64-encoded JSON documents
+// and should only be created by NewSegmentCursor and NewMapCursor.
+type cursor struct {
+	Priority float64 `json:"p,omitempty"`
+	LinkHash string  `json:"h,omitempty"`
+	MapID    string  `json:"m,omitempty"`
+}
+
+func (c *cursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(token string) (*cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(js, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// SegmentCursor points to a position in the ordered list of segments.
+// Segments are ordered by descending priority, then by ascending link hash
+// (see cs.SegmentSlice), so a cursor is the sort key of the last segment
+// of a page.
+type SegmentCursor struct {
+	Priority float64
+	LinkHash *types.Bytes32
+}
+
+// NewSegmentCursor creates a continuation token that resumes a search right
+// after the given segment.
+func NewSegmentCursor(segment *cs.Segment) string {
+	c := cursor{
+		Priority: segment.Link.Meta.Priority,
+		LinkHash: segment.GetLinkHashString(),
+	}
+	return c.encode()
+}
+
+// NewMapCursor creates a continuation token that resumes a search right
+// after the given map ID.
+func NewMapCursor(mapID string) string {
+	c := cursor{MapID: mapID}
+	return c.encode()
+}
+
+// Before returns true if the segment sorts before or at the cursor position,
+// which means it belongs to a previous page.
+func (c *SegmentCursor) Before(segment *cs.Segment) bool {
+	p := segment.Link.Meta.Priority
+	if p != c.Priority {
+		return p > c.Priority
+	}
+	return segment.GetLinkHashString() <= c.LinkHash.String()
+}
+
+// SegmentCursor decodes the pagination cursor of a segment search.
+// It returns nil if no cursor was given.
+func (p *Pagination) SegmentCursor() (*SegmentCursor, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	linkHash, err := types.NewBytes32FromString(c.LinkHash)
+	if err != nil || c.MapID != "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &SegmentCursor{Priority: c.Priority, LinkHash: linkHash}, nil
+}
+
+// MapCursor decodes the pagination cursor of a map search and returns the
+// map ID after which results should start.
+// It returns an empty string if no cursor was given.
+func (p *Pagination) MapCursor() (string, error) {
+	if p.Cursor == "" {
+		return "", nil
+	}
+
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return "", err
+	}
+
+	if c.MapID == "" || c.LinkHash != "" {
+		return "", ErrInvalidCursor
+	}
+
+	return c.MapID, nil
+}
+
+// NextSegmentsCursor returns the continuation token of the page following
+// the given segments, or an empty string if there are no more results.
+func (p *Pagination) NextSegmentsCursor(segments cs.SegmentSlice) string {
+	if len(segments) == 0 || len(segments) < p.Limit {
+		return ""
+	}
+	return NewSegmentCursor(segments[len(segments)-1])
+}
+
+// NextMapIDsCursor returns the continuation token of the page following
+// the given map IDs, or an empty string if there are no more results.
+func (p *Pagination) NextMapIDsCursor(mapIDs []string) string {
+	if len(mapIDs) == 0 || len(mapIDs) < p.Limit {
+		return ""
+	}
+	return NewMapCursor(mapIDs[len(mapIDs)-1])
+}
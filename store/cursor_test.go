@@ -0,0 +1,107 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagination_SegmentCursor(t *testing.T) {
+	t.Run("Empty cursor", func(t *testing.T) {
+		p := store.Pagination{}
+		c, err := p.SegmentCursor()
+		assert.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("Valid cursor", func(t *testing.T) {
+		s := defaultTestingSegment()
+		p := store.Pagination{Cursor: store.NewSegmentCursor(s)}
+		c, err := p.SegmentCursor()
+		require.NoError(t, err)
+		assert.Equal(t, s.Link.Meta.Priority, c.Priority)
+		assert.Equal(t, s.GetLinkHashString(), c.LinkHash.String())
+		assert.True(t, c.Before(s))
+	})
+
+	t.Run("Malformed cursor", func(t *testing.T) {
+		p := store.Pagination{Cursor: "not a cursor"}
+		_, err := p.SegmentCursor()
+		assert.EqualError(t, err, store.ErrInvalidCursor.Error())
+	})
+
+	t.Run("Map cursor", func(t *testing.T) {
+		p := store.Pagination{Cursor: store.NewMapCursor("map")}
+		_, err := p.SegmentCursor()
+		assert.EqualError(t, err, store.ErrInvalidCursor.Error())
+	})
+}
+
+func TestPagination_MapCursor(t *testing.T) {
+	t.Run("Valid cursor", func(t *testing.T) {
+		p := store.Pagination{Cursor: store.NewMapCursor("map")}
+		c, err := p.MapCursor()
+		assert.NoError(t, err)
+		assert.Equal(t, "map", c)
+	})
+
+	t.Run("Segment cursor", func(t *testing.T) {
+		p := store.Pagination{Cursor: store.NewSegmentCursor(defaultTestingSegment())}
+		_, err := p.MapCursor()
+		assert.EqualError(t, err, store.ErrInvalidCursor.Error())
+	})
+}
+
+func TestPagination_PaginateSegments_cursor(t *testing.T) {
+	segments := make(cs.SegmentSlice, sliceSize)
+	copy(segments, segmentSlice)
+	sort.Sort(segments)
+
+	var got cs.SegmentSlice
+	p := store.Pagination{Limit: 7}
+	for {
+		page := p.PaginateSegments(segments)
+		got = append(got, page...)
+		if p.Cursor = p.NextSegmentsCursor(page); p.Cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, segments, got)
+}
+
+func TestPagination_PaginateStrings_cursor(t *testing.T) {
+	strs := make([]string, sliceSize)
+	copy(strs, stringSlice)
+	sort.Strings(strs)
+
+	var got []string
+	p := store.Pagination{Limit: 7}
+	for {
+		page := p.PaginateStrings(strs)
+		got = append(got, page...)
+		if p.Cursor = p.NextMapIDsCursor(page); p.Cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, strs, got)
+}
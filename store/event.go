@@ -108,3 +108,41 @@ func (event *Event) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// Filter returns an event containing only the links or evidences that match
+// the filter, or nil if none of them match. Evidences match if the link
+// returned by getLink for their link hash matches. Other events are returned
+// unchanged.
+func (event *Event) Filter(filter *SegmentFilter, getLink func(linkHash string) *cs.Link) *Event {
+	switch event.EventType {
+	case SavedLinks:
+		var links []*cs.Link
+		for _, link := range event.Data.([]*cs.Link) {
+			if filter.MatchLink(link) {
+				links = append(links, link)
+			}
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return &Event{EventType: SavedLinks, Data: links}
+
+	case SavedEvidences:
+		evidences := map[string]*cs.Evidence{}
+		for linkHash, evidence := range event.Data.(map[string]*cs.Evidence) {
+			if filter.EvidenceBackend != "" && evidence.Backend != filter.EvidenceBackend {
+				continue
+			}
+			if filter.MatchLink(getLink(linkHash)) {
+				evidences[linkHash] = evidence
+			}
+		}
+		if len(evidences) == 0 {
+			return nil
+		}
+		return &Event{EventType: SavedEvidences, Data: evidences}
+
+	default:
+		return event
+	}
+}
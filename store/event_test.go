@@ -0,0 +1,62 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilter(t *testing.T) {
+	link1 := cstesting.NewLinkBuilder().WithProcess("p1").Build()
+	link2 := cstesting.NewLinkBuilder().WithProcess("p2").Build()
+	lh1, _ := link1.Hash()
+	lh2, _ := link2.Hash()
+	links := map[string]*cs.Link{lh1.String(): link1, lh2.String(): link2}
+	getLink := func(linkHash string) *cs.Link { return links[linkHash] }
+
+	filter := &store.SegmentFilter{Process: "p2"}
+
+	t.Run("links", func(t *testing.T) {
+		event := store.NewSavedLinks(link1, link2)
+		assert.Equal(t, store.NewSavedLinks(link2), event.Filter(filter, getLink))
+		assert.Nil(t, store.NewSavedLinks(link1).Filter(filter, getLink))
+	})
+
+	t.Run("evidences", func(t *testing.T) {
+		e1, e2 := cstesting.RandomEvidence(), cstesting.RandomEvidence()
+		event := store.NewSavedEvidences()
+		event.AddSavedEvidence(lh1, e1)
+		event.AddSavedEvidence(lh2, e2)
+
+		want := store.NewSavedEvidences()
+		want.AddSavedEvidence(lh2, e2)
+		assert.Equal(t, want, event.Filter(filter, getLink))
+
+		unknown := store.NewSavedEvidences()
+		unknown.AddSavedEvidence(&types.Bytes32{}, e1)
+		assert.Nil(t, unknown.Filter(filter, getLink))
+	})
+
+	t.Run("other events", func(t *testing.T) {
+		event := &store.Event{EventType: "Other", Data: "data"}
+		assert.Equal(t, event, event.Filter(filter, getLink))
+	})
+}
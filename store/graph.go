@@ -0,0 +1,238 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// GraphReader is an optional interface that can be implemented by stores
+// that are able to traverse the links of maps without fetching segments one
+// at a time.
+type GraphReader interface {
+	// Get the segments whose previous link is the given one, sorted by
+	// descending priority.
+	GetChildren(ctx context.Context, linkHash *types.Bytes32) (cs.SegmentSlice, error)
+
+	// Get the segments of the previous links of the given one, from its
+	// parent up to the root of its map. It returns nil if the link doesn't
+	// exist.
+	GetAncestors(ctx context.Context, linkHash *types.Bytes32) (cs.SegmentSlice, error)
+
+	// Get the segments of a map that have no child in this map, sorted by
+	// descending priority.
+	GetMapHeads(ctx context.Context, mapID string) (cs.SegmentSlice, error)
+}
+
+// Graph is the directed acyclic graph formed by the segments of a map.
+type Graph struct {
+	// Segments contains all the segments of the map, sorted by descending
+	// priority.
+	Segments cs.SegmentSlice `json:"segments"`
+
+	// Children maps the link hashes of the segments that have children to
+	// the link hashes of their children.
+	Children map[string][]string `json:"children"`
+
+	// Roots contains the link hashes of the segments whose previous link
+	// is not in the map.
+	Roots []string `json:"roots"`
+
+	// Heads contains the link hashes of the segments that have no child.
+	Heads []string `json:"heads"`
+}
+
+// GetChildren returns the segments whose previous link is the given one,
+// sorted by descending priority.
+// If the reader implements GraphReader it is used directly, otherwise the
+// children are streamed.
+func GetChildren(ctx context.Context, reader SegmentReader, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	if graphReader, ok := reader.(GraphReader); ok {
+		return graphReader.GetChildren(ctx, linkHash)
+	}
+
+	prevLinkHash := linkHash.String()
+	children, err := streamAll(ctx, reader, &SegmentFilter{PrevLinkHash: &prevLinkHash})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(children)
+
+	return children, nil
+}
+
+// GetAncestors returns the segments of the previous links of the given
+// one, from its parent up to the root of its map. The walk stops at the
+// first previous link that is not in the store. It returns nil if the link
+// doesn't exist.
+// If the reader implements GraphReader it is used directly, otherwise the
+// previous links are fetched one at a time.
+func GetAncestors(ctx context.Context, reader SegmentReader, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	if graphReader, ok := reader.(GraphReader); ok {
+		return graphReader.GetAncestors(ctx, linkHash)
+	}
+
+	segment, err := reader.GetSegment(ctx, linkHash)
+	if err != nil || segment == nil {
+		return nil, err
+	}
+
+	ancestors := cs.SegmentSlice{}
+	visited := map[types.Bytes32]struct{}{*linkHash: {}}
+
+	for prev := segment.Link.Meta.GetPrevLinkHash(); prev != nil; prev = segment.Link.Meta.GetPrevLinkHash() {
+		if _, ok := visited[*prev]; ok {
+			break
+		}
+		visited[*prev] = struct{}{}
+
+		if segment, err = reader.GetSegment(ctx, prev); err != nil {
+			return nil, err
+		}
+		if segment == nil {
+			break
+		}
+		ancestors = append(ancestors, segment)
+	}
+
+	return ancestors, nil
+}
+
+// GetMapHeads returns the segments of a map that have no child in this
+// map, sorted by descending priority.
+// If the reader implements GraphReader it is used directly, otherwise the
+// segments of the map are streamed.
+func GetMapHeads(ctx context.Context, reader SegmentReader, mapID string) (cs.SegmentSlice, error) {
+	if graphReader, ok := reader.(GraphReader); ok {
+		return graphReader.GetMapHeads(ctx, mapID)
+	}
+
+	graph, err := GetMapGraph(ctx, reader, mapID)
+	if err != nil {
+		return nil, err
+	}
+
+	heads := make(map[string]struct{}, len(graph.Heads))
+	for _, linkHash := range graph.Heads {
+		heads[linkHash] = struct{}{}
+	}
+
+	segments := cs.SegmentSlice{}
+	for _, segment := range graph.Segments {
+		if _, ok := heads[segment.GetLinkHashString()]; ok {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments, nil
+}
+
+// GetMapGraph returns the graph formed by the segments of a map.
+func GetMapGraph(ctx context.Context, reader SegmentReader, mapID string) (*Graph, error) {
+	segments, err := streamAll(ctx, reader, &SegmentFilter{MapIDs: []string{mapID}})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(segments)
+
+	graph := Graph{
+		Segments: segments,
+		Children: map[string][]string{},
+		Roots:    []string{},
+		Heads:    []string{},
+	}
+
+	linkHashes := make(map[string]struct{}, len(segments))
+	for _, segment := range segments {
+		linkHashes[segment.GetLinkHashString()] = struct{}{}
+	}
+
+	for _, segment := range segments {
+		linkHash := segment.GetLinkHashString()
+		prev := segment.Link.Meta.PrevLinkHash
+		if _, ok := linkHashes[prev]; ok {
+			graph.Children[prev] = append(graph.Children[prev], linkHash)
+		} else {
+			graph.Roots = append(graph.Roots, linkHash)
+		}
+	}
+
+	for _, segment := range segments {
+		linkHash := segment.GetLinkHashString()
+		if _, ok := graph.Children[linkHash]; !ok {
+			graph.Heads = append(graph.Heads, linkHash)
+		}
+	}
+
+	return &graph, nil
+}
+
+// GetReferences returns the segments referenced by a link, in the order of
+// its references. References to segments that are not in the store are
+// skipped. It returns nil if the link doesn't exist.
+func GetReferences(ctx context.Context, reader SegmentReader, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	segment, err := reader.GetSegment(ctx, linkHash)
+	if err != nil || segment == nil {
+		return nil, err
+	}
+
+	refs := cs.SegmentSlice{}
+	for _, ref := range segment.Link.Meta.Refs {
+		refLinkHash, err := types.NewBytes32FromString(ref.LinkHash)
+		if err != nil {
+			return nil, err
+		}
+
+		refSegment, err := reader.GetSegment(ctx, refLinkHash)
+		if err != nil {
+			return nil, err
+		}
+		if refSegment != nil {
+			refs = append(refs, refSegment)
+		}
+	}
+
+	return refs, nil
+}
+
+// GetReferencedBy returns the segments whose link references the given link
+// hash, sorted by descending priority. Unlike GetReferences, it doesn't
+// require the referenced segment to be in the store.
+func GetReferencedBy(ctx context.Context, reader SegmentReader, linkHash *types.Bytes32) (cs.SegmentSlice, error) {
+	segments, err := streamAll(ctx, reader, &SegmentFilter{RefLinkHash: linkHash.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(segments)
+
+	return segments, nil
+}
+
+// streamAll returns all the segments matching the filter.
+func streamAll(ctx context.Context, reader SegmentReader, filter *SegmentFilter) (cs.SegmentSlice, error) {
+	segments := cs.SegmentSlice{}
+	err := forEachSegment(ctx, reader, filter, func(segment *cs.Segment) {
+		segments = append(segments, segment)
+	})
+
+	return segments, err
+}
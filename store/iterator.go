@@ -0,0 +1,198 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+
+	"github.com/stratumn/go-indigocore/cs"
+)
+
+// SegmentIterator streams segments one at a time.
+//
+// A typical usage is:
+//
+//	it, err := store.StreamSegments(ctx, adapter, filter)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		segment := it.Segment()
+//	}
+//	return it.Err()
+type SegmentIterator interface {
+	// Next advances to the next segment.
+	// It returns false when there are no more segments or an error occurred.
+	Next() bool
+
+	// Segment returns the current segment.
+	// It must only be called after a call to Next returned true.
+	Segment() *cs.Segment
+
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+
+	// Close releases the resources held by the iterator.
+	// It is safe to call it several times.
+	Close() error
+}
+
+// SegmentStreamer is an optional interface that can be implemented by
+// stores that are able to stream segments without loading them all in
+// memory.
+type SegmentStreamer interface {
+	// Stream all the segments matching the filter.
+	// The pagination of the filter is ignored.
+	StreamSegments(ctx context.Context, filter *SegmentFilter) (SegmentIterator, error)
+}
+
+// StreamSegments returns an iterator over all the segments matching the
+// filter. The pagination of the filter is ignored.
+// If the reader implements SegmentStreamer it is used directly, otherwise
+// segments are fetched in batches using FindSegments.
+func StreamSegments(ctx context.Context, reader SegmentReader, filter *SegmentFilter) (SegmentIterator, error) {
+	if streamer, ok := reader.(SegmentStreamer); ok {
+		return streamer.StreamSegments(ctx, filter)
+	}
+
+	return NewFindSegmentsIterator(ctx, reader, filter), nil
+}
+
+// findSegmentsIterator iterates over segments by paginating FindSegments
+// with continuation tokens, or with offsets when segments are not sorted in
+// the default order.
+type findSegmentsIterator struct {
+	ctx    context.Context
+	reader SegmentReader
+	filter SegmentFilter
+
+	page    cs.SegmentSlice
+	pos     int
+	current *cs.Segment
+	done    bool
+	err     error
+}
+
+// NewFindSegmentsIterator creates an iterator that fetches segments
+// matching the filter in batches of MaxLimit segments using FindSegments.
+// The pagination of the filter is ignored.
+func NewFindSegmentsIterator(ctx context.Context, reader SegmentReader, filter *SegmentFilter) SegmentIterator {
+	f := *filter
+	f.Pagination = Pagination{Limit: MaxLimit}
+
+	return &findSegmentsIterator{
+		ctx:    ctx,
+		reader: reader,
+		filter: f,
+	}
+}
+
+// Next implements github.com/stratumn/go-indigocore/store.SegmentIterator.Next.
+func (it *findSegmentsIterator) Next() bool {
+	it.current = nil
+
+	if it.err != nil {
+		return false
+	}
+
+	if it.pos >= len(it.page) {
+		if it.done {
+			return false
+		}
+
+		page, err := it.reader.FindSegments(it.ctx, &it.filter)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page, it.pos = page, 0
+		if it.filter.Sort.IsDefault() {
+			if it.filter.Cursor = it.filter.NextSegmentsCursor(page); it.filter.Cursor == "" {
+				it.done = true
+			}
+		} else {
+			// Cursors only work with the default order, so other
+			// orders are paginated with offsets.
+			it.filter.Offset += len(page)
+			it.done = len(page) < it.filter.Limit
+		}
+
+		if len(page) == 0 {
+			return false
+		}
+	}
+
+	it.current = it.page[it.pos]
+	it.pos++
+
+	return true
+}
+
+// Segment implements github.com/stratumn/go-indigocore/store.SegmentIterator.Segment.
+func (it *findSegmentsIterator) Segment() *cs.Segment {
+	return it.current
+}
+
+// Err implements github.com/stratumn/go-indigocore/store.SegmentIterator.Err.
+func (it *findSegmentsIterator) Err() error {
+	return it.err
+}
+
+// Close implements github.com/stratumn/go-indigocore/store.SegmentIterator.Close.
+func (it *findSegmentsIterator) Close() error {
+	it.page, it.current, it.done = nil, nil, true
+	return nil
+}
+
+// sliceIterator iterates over an in-memory slice of segments.
+type sliceIterator struct {
+	segments cs.SegmentSlice
+	current  *cs.Segment
+}
+
+// NewSliceIterator creates an iterator over the given segments.
+func NewSliceIterator(segments cs.SegmentSlice) SegmentIterator {
+	return &sliceIterator{segments: segments}
+}
+
+// Next implements github.com/stratumn/go-indigocore/store.SegmentIterator.Next.
+func (it *sliceIterator) Next() bool {
+	if len(it.segments) == 0 {
+		it.current = nil
+		return false
+	}
+
+	it.current, it.segments = it.segments[0], it.segments[1:]
+
+	return true
+}
+
+// Segment implements github.com/stratumn/go-indigocore/store.SegmentIterator.Segment.
+func (it *sliceIterator) Segment() *cs.Segment {
+	return it.current
+}
+
+// Err implements github.com/stratumn/go-indigocore/store.SegmentIterator.Err.
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+// Close implements github.com/stratumn/go-indigocore/store.SegmentIterator.Close.
+func (it *sliceIterator) Close() error {
+	it.segments, it.current = nil, nil
+	return nil
+}
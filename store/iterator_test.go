@@ -0,0 +1,78 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSegmentsIterator(t *testing.T) {
+	segments := make(cs.SegmentSlice, sliceSize)
+	copy(segments, segmentSlice)
+	sort.Sort(segments)
+
+	a := &storetesting.MockAdapter{}
+	a.MockFindSegments.Fn = func(filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+		return filter.PaginateSegments(segments), nil
+	}
+
+	t.Run("Iterates over all segments", func(t *testing.T) {
+		it, err := store.StreamSegments(context.Background(), a, &store.SegmentFilter{})
+		require.NoError(t, err)
+		defer it.Close()
+
+		var got cs.SegmentSlice
+		for it.Next() {
+			got = append(got, it.Segment())
+		}
+
+		assert.NoError(t, it.Err())
+		assert.Equal(t, segments, got)
+	})
+
+	t.Run("Stops on error", func(t *testing.T) {
+		b := &storetesting.MockAdapter{}
+		b.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) {
+			return nil, errors.New("test")
+		}
+
+		it := store.NewFindSegmentsIterator(context.Background(), b, &store.SegmentFilter{})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), "test")
+		assert.NoError(t, it.Close())
+	})
+}
+
+func TestSliceIterator(t *testing.T) {
+	it := store.NewSliceIterator(segmentSlice[:2])
+
+	assert.True(t, it.Next())
+	assert.Equal(t, segmentSlice[0], it.Segment())
+	assert.True(t, it.Next())
+	assert.Equal(t, segmentSlice[1], it.Segment())
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Segment())
+	assert.NoError(t, it.Err())
+	assert.NoError(t, it.Close())
+}
@@ -0,0 +1,351 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+)
+
+// Operator is the operator of a predicate.
+type Operator string
+
+const (
+	// OpEq matches values equal to the value of the predicate.
+	OpEq Operator = "=="
+
+	// OpGt matches values greater than the value of the predicate.
+	OpGt Operator = ">"
+
+	// OpGte matches values greater than or equal to the value of the
+	// predicate.
+	OpGte Operator = ">="
+
+	// OpLt matches values less than the value of the predicate.
+	OpLt Operator = "<"
+
+	// OpLte matches values less than or equal to the value of the
+	// predicate.
+	OpLte Operator = "<="
+
+	// OpIn matches values equal to one of the values of the predicate.
+	OpIn Operator = "in"
+
+	// OpExists matches paths that have a value other than null.
+	OpExists Operator = "exists"
+)
+
+// Path prefixes of predicates.
+const (
+	StatePathPrefix    = "state."
+	MetaDataPathPrefix = "meta.data."
+)
+
+// ErrInvalidPredicate is returned when a predicate cannot be parsed or is not
+// valid.
+var ErrInvalidPredicate = errors.New("invalid predicate")
+
+// Predicate is a condition on a value of the state or of the meta data of a
+// link. Predicates are backend-neutral: stores translate them to their own
+// query language, and MatchLink evaluates them in memory.
+//
+// Path is a JSON path made of keys separated by dots. It starts with either
+// "state." or "meta.data.", for instance "state.status" or
+// "meta.data.amount". Keys may only contain letters, digits, dashes and
+// underscores.
+//
+// Value must be a string, a float64 or a bool, or a slice of those for OpIn.
+// It is ignored by OpExists. Comparisons only apply to numbers and strings
+// and never match values of a different type. Strings are compared byte by
+// byte.
+type Predicate struct {
+	Path  string      `json:"path"`
+	Op    Operator    `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ParsePredicate parses the text form of a predicate, which is a path, an
+// operator and a JSON value separated by spaces, for instance:
+//
+//	state.status == "approved"
+//	meta.data.amount > 1000
+//	state.kind in ["order", "invoice"]
+//	state.signedBy exists
+func ParsePredicate(s string) (*Predicate, error) {
+	fields := strings.SplitN(strings.TrimSpace(s), " ", 2)
+	if len(fields) < 2 {
+		return nil, errors.Wrapf(ErrInvalidPredicate, "%q", s)
+	}
+
+	rest := strings.TrimSpace(fields[1])
+	p := Predicate{Path: fields[0]}
+
+	for _, op := range []Operator{OpEq, OpGte, OpLte, OpGt, OpLt, OpIn, OpExists} {
+		if strings.HasPrefix(rest, string(op)) {
+			p.Op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+
+	if p.Op != OpExists {
+		if err := json.Unmarshal([]byte(rest), &p.Value); err != nil {
+			return nil, errors.Wrapf(ErrInvalidPredicate, "%q", s)
+		}
+	} else if rest != "" {
+		return nil, errors.Wrapf(ErrInvalidPredicate, "%q", s)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// String returns the text form of the predicate.
+func (p Predicate) String() string {
+	if p.Op == OpExists {
+		return fmt.Sprintf("%s %s", p.Path, p.Op)
+	}
+	value, _ := json.Marshal(p.Value)
+	return fmt.Sprintf("%s %s %s", p.Path, p.Op, value)
+}
+
+// Keys returns the keys of the path of the predicate in the JSON
+// representation of a link, for instance ["meta", "data", "amount"].
+func (p *Predicate) Keys() []string {
+	return strings.Split(p.Path, ".")
+}
+
+// Validate checks that the path, operator and value of the predicate are
+// valid.
+func (p *Predicate) Validate() error {
+	var keys string
+	switch {
+	case strings.HasPrefix(p.Path, StatePathPrefix):
+		keys = p.Path[len(StatePathPrefix):]
+	case strings.HasPrefix(p.Path, MetaDataPathPrefix):
+		keys = p.Path[len(MetaDataPathPrefix):]
+	default:
+		return errors.Wrapf(ErrInvalidPredicate, "path %q must start with %q or %q", p.Path, StatePathPrefix, MetaDataPathPrefix)
+	}
+
+	for _, key := range strings.Split(keys, ".") {
+		if !isValidKey(key) {
+			return errors.Wrapf(ErrInvalidPredicate, "invalid path %q", p.Path)
+		}
+	}
+
+	switch p.Op {
+	case OpExists:
+		return nil
+
+	case OpEq:
+		if !isScalar(p.Value) {
+			return errors.Wrapf(ErrInvalidPredicate, "%s: value must be a string, a number or a boolean", p)
+		}
+
+	case OpIn:
+		values, ok := p.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return errors.Wrapf(ErrInvalidPredicate, "%s: value must be a non-empty array", p)
+		}
+		for _, v := range values {
+			if !isScalar(v) {
+				return errors.Wrapf(ErrInvalidPredicate, "%s: values must be strings, numbers or booleans", p)
+			}
+		}
+
+	case OpGt, OpGte, OpLt, OpLte:
+		switch p.Value.(type) {
+		case string, float64:
+		default:
+			return errors.Wrapf(ErrInvalidPredicate, "%s: value must be a string or a number", p)
+		}
+
+	default:
+		return errors.Wrapf(ErrInvalidPredicate, "unknown operator %q", p.Op)
+	}
+
+	return nil
+}
+
+// MatchLink checks if the link matches the predicate.
+func (p *Predicate) MatchLink(link *cs.Link) bool {
+	value, ok := p.lookup(link)
+	if !ok || value == nil {
+		return false
+	}
+
+	switch p.Op {
+	case OpExists:
+		return true
+
+	case OpEq:
+		return equal(value, p.Value)
+
+	case OpIn:
+		values, _ := p.Value.([]interface{})
+		for _, v := range values {
+			if equal(value, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	c, ok := compare(value, p.Value)
+	if !ok {
+		return false
+	}
+
+	switch p.Op {
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+
+	return false
+}
+
+// lookup returns the value at the path of the predicate.
+func (p *Predicate) lookup(link *cs.Link) (interface{}, bool) {
+	var (
+		obj  map[string]interface{}
+		keys = p.Keys()
+	)
+
+	switch {
+	case strings.HasPrefix(p.Path, StatePathPrefix):
+		obj, keys = link.State, keys[1:]
+	case strings.HasPrefix(p.Path, MetaDataPathPrefix):
+		obj, keys = link.Meta.Data, keys[2:]
+	default:
+		return nil, false
+	}
+
+	for i, key := range keys {
+		value, ok := obj[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return value, true
+		}
+		if obj, ok = value.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+func isValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// toFloat converts any Go number to a float64, since links created in memory
+// may contain numbers that are not float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
+
+func equal(value, want interface{}) bool {
+	c, ok := compare(value, want)
+	if ok {
+		return c == 0
+	}
+	if b, ok := want.(bool); ok {
+		v, ok := value.(bool)
+		return ok && v == b
+	}
+	return false
+}
+
+// compare compares two numbers or two strings. It returns false if the values
+// are not comparable.
+func compare(value, want interface{}) (int, bool) {
+	if s, ok := want.(string); ok {
+		v, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, s), true
+	}
+
+	w, ok := toFloat(want)
+	if !ok {
+		return 0, false
+	}
+	v, ok := toFloat(value)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case v < w:
+		return -1, true
+	case v > w:
+		return 1, true
+	}
+	return 0, true
+}
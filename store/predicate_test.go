@@ -0,0 +1,147 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *store.Predicate
+	}{
+		{`state.status == "approved"`, &store.Predicate{Path: "state.status", Op: store.OpEq, Value: "approved"}},
+		{"meta.data.amount > 1000", &store.Predicate{Path: "meta.data.amount", Op: store.OpGt, Value: 1000.0}},
+		{"meta.data.amount >= 1.5", &store.Predicate{Path: "meta.data.amount", Op: store.OpGte, Value: 1.5}},
+		{`state.name < "m"`, &store.Predicate{Path: "state.name", Op: store.OpLt, Value: "m"}},
+		{"state.count <= 3", &store.Predicate{Path: "state.count", Op: store.OpLte, Value: 3.0}},
+		{"state.done == true", &store.Predicate{Path: "state.done", Op: store.OpEq, Value: true}},
+		{`state.kind in ["order", "invoice"]`, &store.Predicate{Path: "state.kind", Op: store.OpIn, Value: []interface{}{"order", "invoice"}}},
+		{"  state.user.signedBy exists ", &store.Predicate{Path: "state.user.signedBy", Op: store.OpExists}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := store.ParsePredicate(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			again, err := store.ParsePredicate(got.String())
+			require.NoError(t, err, "got.String()")
+			assert.Equal(t, got, again, "got.String()")
+		})
+	}
+}
+
+func TestParsePredicate_invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"state.status",
+		`state.status = "approved"`,
+		"state.status == approved",
+		`meta.priority == 1`,
+		`state == 1`,
+		`state.a..b == 1`,
+		`state.a'b == 1`,
+		`state.x > true`,
+		`state.x == null`,
+		`state.x == {"a": 1}`,
+		`state.x in []`,
+		`state.x in "a"`,
+		`state.x in [{"a": 1}]`,
+		`state.x exists 1`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := store.ParsePredicate(input)
+			assert.EqualError(t, errors.Cause(err), store.ErrInvalidPredicate.Error())
+		})
+	}
+}
+
+func TestPredicate_MatchLink(t *testing.T) {
+	link := cstesting.RandomLink()
+	link.State = map[string]interface{}{
+		"status": "approved",
+		"count":  3,
+		"done":   false,
+		"user":   map[string]interface{}{"name": "alice"},
+		"empty":  nil,
+	}
+	link.Meta.Data = map[string]interface{}{
+		"amount": 1500.5,
+		"code":   "42",
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`state.status == "approved"`, true},
+		{`state.status == "rejected"`, false},
+		{"state.count == 3", true},
+		{`state.count == "3"`, false},
+		{"state.done == false", true},
+		{"state.done == 0", false},
+		{`state.user.name == "alice"`, true},
+		{"meta.data.amount > 1000", true},
+		{"meta.data.amount < 1000", false},
+		{"meta.data.amount >= 1500.5", true},
+		{"meta.data.amount <= 1500.5", true},
+		{`meta.data.code > "4"`, true},
+		{"meta.data.code > 4", false},
+		{`state.status in ["pending", "approved"]`, true},
+		{`state.status in ["pending", "rejected"]`, false},
+		{"state.count in [1, 2, 3]", true},
+		{"state.user.name exists", true},
+		{"state.user.age exists", false},
+		{"state.empty exists", false},
+		{"state.status.name exists", false},
+		{"meta.data.missing == 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p, err := store.ParsePredicate(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p.MatchLink(link))
+		})
+	}
+}
+
+func TestSegmentFilter_MatchLink_where(t *testing.T) {
+	link := cstesting.RandomLink()
+	link.State = map[string]interface{}{"status": "approved", "count": 3.0}
+
+	filter := store.SegmentFilter{
+		Pagination: store.Pagination{Limit: store.DefaultLimit},
+		Where: []store.Predicate{
+			{Path: "state.status", Op: store.OpEq, Value: "approved"},
+			{Path: "state.count", Op: store.OpGt, Value: 2.0},
+		},
+	}
+	assert.True(t, filter.Match(link.Segmentify()))
+
+	filter.Where[1].Value = 3.0
+	assert.False(t, filter.Match(link.Segmentify()))
+}
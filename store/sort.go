@@ -0,0 +1,230 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+)
+
+// Fields segments can be sorted by, besides values of their state.
+const (
+	// SortByPriority sorts segments by the priority of their link.
+	SortByPriority = "priority"
+
+	// SortByCreatedAt sorts segments by the time at which the store saved
+	// their link.
+	SortByCreatedAt = "createdAt"
+
+	// SortByLinkHash sorts segments by their link hash.
+	SortByLinkHash = "linkHash"
+)
+
+var (
+	// ErrInvalidSort is returned when a sort order cannot be parsed or is
+	// not valid.
+	ErrInvalidSort = errors.New("invalid sort order")
+
+	// ErrCursorSort is returned when a pagination cursor is used with a
+	// sort order other than the default one.
+	ErrCursorSort = errors.New("pagination cursors can only be used with the default sort order")
+)
+
+// Sort is the order in which segments are returned.
+//
+// Field is either SortByPriority, SortByCreatedAt, SortByLinkHash or a path
+// in the state of links such as "state.amount". Segments with equal values
+// are sorted by ascending link hash.
+//
+// When sorting by a state field, numbers come first, then strings, then the
+// segments that have neither at this path, whatever the direction. Strings
+// are compared byte by byte.
+//
+// When a filter has no sort order, segments are sorted by descending
+// priority. Pagination cursors only work with this order.
+type Sort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// ParseSort parses the text form of a sort order, which is a field prefixed
+// with a minus sign for a descending order, for instance "-priority" or
+// "state.amount".
+func ParseSort(s string) (*Sort, error) {
+	sortOrder := Sort{Field: strings.TrimSpace(s)}
+	if strings.HasPrefix(sortOrder.Field, "-") {
+		sortOrder.Field, sortOrder.Desc = sortOrder.Field[1:], true
+	}
+
+	if err := sortOrder.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &sortOrder, nil
+}
+
+// String returns the text form of the sort order.
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// EncodeValues encodes the sort order in a query string using its text form.
+func (s Sort) EncodeValues(key string, v *url.Values) error {
+	v.Set(key, s.String())
+	return nil
+}
+
+// Validate checks that the field of the sort order is valid.
+func (s *Sort) Validate() error {
+	switch s.Field {
+	case SortByPriority, SortByCreatedAt, SortByLinkHash:
+		return nil
+	}
+
+	if !strings.HasPrefix(s.Field, StatePathPrefix) {
+		return errors.Wrapf(ErrInvalidSort, "field %q must be %q, %q, %q or start with %q", s.Field, SortByPriority, SortByCreatedAt, SortByLinkHash, StatePathPrefix)
+	}
+
+	for _, key := range strings.Split(s.Field[len(StatePathPrefix):], ".") {
+		if !isValidKey(key) {
+			return errors.Wrapf(ErrInvalidSort, "invalid field %q", s.Field)
+		}
+	}
+
+	return nil
+}
+
+// IsDefault returns true if segments are sorted by descending priority.
+// A nil sort order is the default one.
+func (s *Sort) IsDefault() bool {
+	return s == nil || (s.Field == SortByPriority && s.Desc)
+}
+
+// IsStateField returns true if segments are sorted by a value of their
+// state.
+func (s *Sort) IsStateField() bool {
+	return s != nil && strings.HasPrefix(s.Field, StatePathPrefix)
+}
+
+// Keys returns the keys of the field in the JSON representation of a link,
+// for instance ["state", "amount"].
+func (s *Sort) Keys() []string {
+	return strings.Split(s.Field, ".")
+}
+
+// SegmentCursor decodes the pagination cursor of the filter.
+// It returns ErrCursorSort if a cursor is used with a sort order other than
+// the default one.
+func (filter *SegmentFilter) SegmentCursor() (*SegmentCursor, error) {
+	if filter.Cursor != "" && !filter.Sort.IsDefault() {
+		return nil, ErrCursorSort
+	}
+
+	return filter.Pagination.SegmentCursor()
+}
+
+// NextSegmentsCursor returns the continuation token of the page following
+// the given segments. It returns an empty string if there are no more
+// results or if the segments are not sorted in the default order.
+func (filter *SegmentFilter) NextSegmentsCursor(segments cs.SegmentSlice) string {
+	if !filter.Sort.IsDefault() {
+		return ""
+	}
+
+	return filter.Pagination.NextSegmentsCursor(segments)
+}
+
+// SortSegments sorts segments in the order of the filter.
+// The createdAt function returns the time at which the link of a segment was
+// saved. It is only called when sorting by creation time.
+func (filter *SegmentFilter) SortSegments(segments cs.SegmentSlice, createdAt func(*cs.Segment) time.Time) {
+	s := filter.Sort
+	if s.IsDefault() {
+		sort.Sort(segments)
+		return
+	}
+
+	var less func(s1, s2 *cs.Segment) (less, ok bool)
+
+	switch {
+	case s.Field == SortByPriority:
+		less = func(s1, s2 *cs.Segment) (bool, bool) {
+			p1, p2 := s1.Link.Meta.Priority, s2.Link.Meta.Priority
+			return p1 < p2, p1 != p2
+		}
+
+	case s.Field == SortByCreatedAt:
+		times := make(map[*cs.Segment]time.Time, len(segments))
+		for _, segment := range segments {
+			times[segment] = createdAt(segment)
+		}
+		less = func(s1, s2 *cs.Segment) (bool, bool) {
+			t1, t2 := times[s1], times[s2]
+			return t1.Before(t2), !t1.Equal(t2)
+		}
+
+	case s.IsStateField():
+		p := Predicate{Path: s.Field}
+		less = func(s1, s2 *cs.Segment) (bool, bool) {
+			v1, _ := p.lookup(&s1.Link)
+			v2, _ := p.lookup(&s2.Link)
+			r1, r2 := sortRank(v1), sortRank(v2)
+			if r1 != r2 {
+				// The rank doesn't depend on the direction.
+				return (r1 < r2) != s.Desc, true
+			}
+			c, ok := compare(v1, v2)
+			return c < 0, ok && c != 0
+		}
+
+	default:
+		less = func(*cs.Segment, *cs.Segment) (bool, bool) {
+			return false, false
+		}
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		s1, s2 := segments[i], segments[j]
+		if l, ok := less(s1, s2); ok {
+			return l != s.Desc
+		}
+
+		h1, h2 := s1.GetLinkHashString(), s2.GetLinkHashString()
+		if s.Field == SortByLinkHash && s.Desc {
+			return h1 > h2
+		}
+		return h1 < h2
+	})
+}
+
+// sortRank returns the rank of the type of a state value: numbers, then
+// strings, then everything else.
+func sortRank(v interface{}) int {
+	if _, ok := v.(string); ok {
+		return 1
+	}
+	if _, ok := toFloat(v); ok {
+		return 0
+	}
+	return 2
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *store.Sort
+	}{
+		{"priority", &store.Sort{Field: store.SortByPriority}},
+		{"-priority", &store.Sort{Field: store.SortByPriority, Desc: true}},
+		{"createdAt", &store.Sort{Field: store.SortByCreatedAt}},
+		{" -linkHash ", &store.Sort{Field: store.SortByLinkHash, Desc: true}},
+		{"state.user.age", &store.Sort{Field: "state.user.age"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := store.ParseSort(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			again, err := store.ParseSort(got.String())
+			require.NoError(t, err, "got.String()")
+			assert.Equal(t, got, again, "got.String()")
+		})
+	}
+}
+
+func TestParseSort_invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"-",
+		"--priority",
+		"mapId",
+		"state",
+		"state.",
+		"state.a..b",
+		"state.a'b",
+		"meta.priority",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := store.ParseSort(input)
+			assert.EqualError(t, errors.Cause(err), store.ErrInvalidSort.Error())
+		})
+	}
+}
+
+func TestSegmentFilter_SegmentCursor_sort(t *testing.T) {
+	filter := store.SegmentFilter{
+		Pagination: store.Pagination{Cursor: store.NewSegmentCursor(cstesting.RandomSegment())},
+	}
+
+	_, err := filter.SegmentCursor()
+	assert.NoError(t, err, "default order")
+
+	filter.Sort = &store.Sort{Field: store.SortByPriority, Desc: true}
+	_, err = filter.SegmentCursor()
+	assert.NoError(t, err, "descending priority")
+
+	filter.Sort = &store.Sort{Field: store.SortByCreatedAt}
+	_, err = filter.SegmentCursor()
+	assert.EqualError(t, err, store.ErrCursorSort.Error(), "creation time")
+}
+
+func TestSegmentFilter_SortSegments(t *testing.T) {
+	newSegment := func(priority float64, amount interface{}) *cs.Segment {
+		s := cstesting.RandomSegment()
+		s.Link.Meta.Priority = priority
+		if amount != nil {
+			s.Link.State["amount"] = amount
+		} else {
+			delete(s.Link.State, "amount")
+		}
+		return s
+	}
+
+	s1 := newSegment(2, 10.0)
+	s2 := newSegment(1, "abc")
+	s3 := newSegment(3, 2.5)
+	s4 := newSegment(0, nil)
+	s5 := newSegment(5, true)
+
+	now := time.Now()
+	times := map[*cs.Segment]time.Time{
+		s1: now.Add(3 * time.Second),
+		s2: now.Add(time.Second),
+		s3: now,
+		s4: now.Add(4 * time.Second),
+		s5: now.Add(2 * time.Second),
+	}
+	createdAt := func(s *cs.Segment) time.Time { return times[s] }
+
+	tests := []struct {
+		name string
+		sort *store.Sort
+		want cs.SegmentSlice
+	}{
+		{"default", nil, cs.SegmentSlice{s5, s3, s1, s2, s4}},
+		{"priority", &store.Sort{Field: store.SortByPriority}, cs.SegmentSlice{s4, s2, s1, s3, s5}},
+		{"createdAt", &store.Sort{Field: store.SortByCreatedAt}, cs.SegmentSlice{s3, s2, s5, s1, s4}},
+		{"-createdAt", &store.Sort{Field: store.SortByCreatedAt, Desc: true}, cs.SegmentSlice{s4, s1, s5, s2, s3}},
+		{"state", &store.Sort{Field: "state.amount"}, cs.SegmentSlice{s3, s1, s2}},
+		{"-state", &store.Sort{Field: "state.amount", Desc: true}, cs.SegmentSlice{s1, s3, s2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := cs.SegmentSlice{s1, s2, s3, s4, s5}
+			filter := store.SegmentFilter{Sort: tt.sort}
+			filter.SortSegments(segments, createdAt)
+
+			if tt.sort.IsStateField() {
+				// Segments without a number or a string come last,
+				// ordered by link hash.
+				rest := segments[len(tt.want):]
+				assert.ElementsMatch(t, cs.SegmentSlice{s4, s5}, rest)
+				assert.True(t, rest[0].GetLinkHashString() < rest[1].GetLinkHashString(), "link hash order")
+				segments = segments[:len(tt.want)]
+			}
+
+			assert.Equal(t, tt.want, segments)
+		})
+	}
+
+	t.Run("linkHash", func(t *testing.T) {
+		segments := cs.SegmentSlice{s1, s2, s3, s4, s5}
+		filter := store.SegmentFilter{Sort: &store.Sort{Field: store.SortByLinkHash, Desc: true}}
+		filter.SortSegments(segments, createdAt)
+
+		for i := 1; i < len(segments); i++ {
+			assert.True(t, segments[i-1].GetLinkHashString() > segments[i].GetLinkHashString(), "segments[%d]", i)
+		}
+	})
+}
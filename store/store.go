@@ -17,7 +17,9 @@ package store
 
 import (
 	"context"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/types"
@@ -41,7 +43,8 @@ type SegmentReader interface {
 	// Will return links and evidences (if there are some).
 	FindSegments(ctx context.Context, filter *SegmentFilter) (cs.SegmentSlice, error)
 
-	// Get all the existing map IDs.
+	// Get all the existing map IDs, sorted in ascending order so that
+	// they can be paginated with cursors.
 	GetMapIDs(ctx context.Context, filter *MapFilter) ([]string, error)
 }
 
@@ -83,6 +86,14 @@ type Batch interface {
 	Write(ctx context.Context) error
 }
 
+// BatchRollbacker is implemented by batches that hold resources until they
+// are written, such as a database transaction. A batch that is not written
+// must then be rolled back.
+type BatchRollbacker interface {
+	// Rollback discards the content of the Batch.
+	Rollback(ctx context.Context) error
+}
+
 // Adapter is the minimal interface that all stores should implement.
 // Then a store may optionally implement the KeyValueStore interface.
 type Adapter interface {
@@ -125,6 +136,11 @@ type Pagination struct {
 
 	// Maximum number of entries.
 	Limit int `json:"limit" url:"limit"`
+
+	// Opaque continuation token returned by a previous search.
+	// When set, results start right after the last entry of the previous
+	// page and Offset is relative to that position.
+	Cursor string `json:"cursor,omitempty" url:"cursor,omitempty"`
 }
 
 // SegmentFilter contains filtering options for segments.
@@ -148,8 +164,36 @@ type SegmentFilter struct {
 	// This attribute is optional.
 	LinkHashes []string `json:"linkHashes" url:"linkHashes,brackets"`
 
+	// A link hash the segments must reference in their meta refs.
+	// This attribute is optional.
+	RefLinkHash string `json:"refLinkHash" url:"refLinkHash,omitempty"`
+
 	// A slice of tags the segments must all contain.
 	Tags []string `json:"tags" url:"tags,brackets"`
+
+	// Predicates on the state and meta data the segments must all match.
+	Where []Predicate `json:"where" url:"where,brackets"`
+
+	// Link types the segments must have one of.
+	LinkTypes []string `json:"linkTypes" url:"linkTypes,brackets"`
+
+	// Actions the segments must have one of.
+	Actions []string `json:"actions" url:"actions,brackets"`
+
+	// If set, only segments saved at or after this time are returned.
+	// The time is the one recorded by the store when it saved the link.
+	CreatedAfter *time.Time `json:"createdAfter" url:"createdAfter,omitempty"`
+
+	// If set, only segments saved strictly before this time are returned.
+	CreatedBefore *time.Time `json:"createdBefore" url:"createdBefore,omitempty"`
+
+	// If set, only segments with at least one evidence produced by this
+	// backend ("TMPop", "bitcoin", "dummy"...) are returned.
+	EvidenceBackend string `json:"evidenceBackend" url:"evidenceBackend,omitempty"`
+
+	// The order in which segments are returned.
+	// nil sorts segments by descending priority.
+	Sort *Sort `json:"sort" url:"sort,omitempty"`
 }
 
 // MapFilter contains filtering options for segments.
@@ -166,26 +210,38 @@ type MapFilter struct {
 	Process string `json:"process" url:"-"`
 }
 
-// PaginateStrings paginates a list of strings
+// PaginateStrings paginates a list of strings.
+// If a cursor is set, the list is expected to be sorted and the cursor valid.
 func (p *Pagination) PaginateStrings(a []string) []string {
+	start := p.Offset
+	if mapID, err := p.MapCursor(); err == nil && mapID != "" {
+		start += sort.Search(len(a), func(i int) bool { return a[i] > mapID })
+	}
+
 	l := len(a)
-	if p.Offset >= l {
+	if start >= l {
 		return []string{}
 	}
 
-	end := min(l, p.Offset+p.Limit)
-	return a[p.Offset:end]
+	end := min(l, start+p.Limit)
+	return a[start:end]
 }
 
-// PaginateSegments paginate a list of segments
+// PaginateSegments paginate a list of segments.
+// If a cursor is set, the list is expected to be sorted and the cursor valid.
 func (p *Pagination) PaginateSegments(a cs.SegmentSlice) cs.SegmentSlice {
+	start := p.Offset
+	if c, err := p.SegmentCursor(); err == nil && c != nil {
+		start += sort.Search(len(a), func(i int) bool { return !c.Before(a[i]) })
+	}
+
 	l := len(a)
-	if p.Offset >= l {
+	if start >= l {
 		return cs.SegmentSlice{}
 	}
 
-	end := min(l, p.Offset+p.Limit)
-	return a[p.Offset:end]
+	end := min(l, start+p.Limit)
+	return a[start:end]
 }
 
 // Min of two ints, duh.
@@ -202,9 +258,33 @@ func (filter SegmentFilter) Match(segment *cs.Segment) bool {
 		return false
 	}
 
+	if filter.EvidenceBackend != "" && len(segment.Meta.FindEvidences(filter.EvidenceBackend)) == 0 {
+		return false
+	}
+
 	return filter.MatchLink(&segment.Link)
 }
 
+// MatchCreatedAt checks if the time at which a store saved a link is within
+// the bounds of the filter.
+func (filter SegmentFilter) MatchCreatedAt(createdAt time.Time) bool {
+	if filter.CreatedAfter != nil && createdAt.Before(*filter.CreatedAfter) {
+		return false
+	}
+
+	if filter.CreatedBefore != nil && !createdAt.Before(*filter.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// HasCreatedAtBounds returns true if the filter has bounds on the time at
+// which links were saved.
+func (filter SegmentFilter) HasCreatedAtBounds() bool {
+	return filter.CreatedAfter != nil || filter.CreatedBefore != nil
+}
+
 // MatchLink checks if link matches with filter
 func (filter SegmentFilter) MatchLink(link *cs.Link) bool {
 	if link == nil {
@@ -239,6 +319,10 @@ func (filter SegmentFilter) MatchLink(link *cs.Link) bool {
 		}
 	}
 
+	if filter.RefLinkHash != "" && !link.Meta.HasRef(filter.RefLinkHash) {
+		return false
+	}
+
 	if filter.Process != "" && filter.Process != link.Meta.Process {
 		return false
 	}
@@ -262,9 +346,33 @@ func (filter SegmentFilter) MatchLink(link *cs.Link) bool {
 			}
 		}
 	}
+
+	if len(filter.LinkTypes) > 0 && !contains(filter.LinkTypes, link.Meta.Type) {
+		return false
+	}
+
+	if len(filter.Actions) > 0 && !contains(filter.Actions, link.Meta.Action) {
+		return false
+	}
+
+	for i := range filter.Where {
+		if !filter.Where[i].MatchLink(link) {
+			return false
+		}
+	}
+
 	return true
 }
 
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Match checks if segment matches with filter
 func (filter MapFilter) Match(segment *cs.Segment) bool {
 	if segment == nil {
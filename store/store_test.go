@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/cs/cstesting"
@@ -58,6 +59,8 @@ func defaultTestingSegment() *cs.Segment {
 			PrevLinkHash: prevLinkHashTestingValue,
 			Process:      "TheProcess",
 			MapID:        "TheMapId",
+			Type:         "TheType",
+			Action:       "TheAction",
 			Tags:         []string{"Foo", "Bar"},
 			Priority:     42.,
 		},
@@ -71,14 +74,30 @@ func emptyPrevLinkHashTestingSegment() *cs.Segment {
 	return seg
 }
 
+func evidenceTestingSegment() *cs.Segment {
+	seg := defaultTestingSegment()
+	seg.Meta.Evidences = cs.Evidences{{Backend: "dummy", Provider: "TheProvider"}}
+	return seg
+}
+
+func refTestingSegment() *cs.Segment {
+	seg := defaultTestingSegment()
+	seg.Link.Meta.Refs = []cs.SegmentReference{{Process: "AProcess", LinkHash: prevLinkHashTestingValue}}
+	return seg
+}
+
 func TestSegmentFilter_Match(t *testing.T) {
 	type fields struct {
-		Pagination   store.Pagination
-		MapIDs       []string
-		Process      string
-		PrevLinkHash *string
-		LinkHashes   []string
-		Tags         []string
+		Pagination      store.Pagination
+		MapIDs          []string
+		Process         string
+		PrevLinkHash    *string
+		LinkHashes      []string
+		RefLinkHash     string
+		Tags            []string
+		LinkTypes       []string
+		Actions         []string
+		EvidenceBackend string
 	}
 	type args struct {
 		segment *cs.Segment
@@ -169,6 +188,24 @@ func TestSegmentFilter_Match(t *testing.T) {
 			args:   args{segment: defaultTestingSegment()},
 			want:   false,
 		},
+		{
+			name:   "Good refLinkHash",
+			fields: fields{RefLinkHash: prevLinkHashTestingValue},
+			args:   args{segment: refTestingSegment()},
+			want:   true,
+		},
+		{
+			name:   "Bad refLinkHash",
+			fields: fields{RefLinkHash: testutil.RandomHash().String()},
+			args:   args{segment: refTestingSegment()},
+			want:   false,
+		},
+		{
+			name:   "No refs",
+			fields: fields{RefLinkHash: prevLinkHashTestingValue},
+			args:   args{segment: defaultTestingSegment()},
+			want:   false,
+		},
 		{
 			name:   "One tag",
 			fields: fields{Tags: []string{"Foo"}},
@@ -193,16 +230,62 @@ func TestSegmentFilter_Match(t *testing.T) {
 			args:   args{segment: defaultTestingSegment()},
 			want:   false,
 		},
+		{
+			name:   "Good link type",
+			fields: fields{LinkTypes: []string{"AType", "TheType"}},
+			args:   args{segment: defaultTestingSegment()},
+			want:   true,
+		},
+		{
+			name:   "Bad link type",
+			fields: fields{LinkTypes: []string{"AType"}},
+			args:   args{segment: defaultTestingSegment()},
+			want:   false,
+		},
+		{
+			name:   "Good action",
+			fields: fields{Actions: []string{"TheAction", "AnAction"}},
+			args:   args{segment: defaultTestingSegment()},
+			want:   true,
+		},
+		{
+			name:   "Bad action",
+			fields: fields{Actions: []string{"AnAction"}},
+			args:   args{segment: defaultTestingSegment()},
+			want:   false,
+		},
+		{
+			name:   "Good evidence backend",
+			fields: fields{EvidenceBackend: "dummy"},
+			args:   args{segment: evidenceTestingSegment()},
+			want:   true,
+		},
+		{
+			name:   "Bad evidence backend",
+			fields: fields{EvidenceBackend: "bitcoin"},
+			args:   args{segment: evidenceTestingSegment()},
+			want:   false,
+		},
+		{
+			name:   "No evidence",
+			fields: fields{EvidenceBackend: "dummy"},
+			args:   args{segment: defaultTestingSegment()},
+			want:   false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			filter := store.SegmentFilter{
-				Pagination:   tt.fields.Pagination,
-				MapIDs:       tt.fields.MapIDs,
-				Process:      tt.fields.Process,
-				LinkHashes:   tt.fields.LinkHashes,
-				PrevLinkHash: tt.fields.PrevLinkHash,
-				Tags:         tt.fields.Tags,
+				Pagination:      tt.fields.Pagination,
+				MapIDs:          tt.fields.MapIDs,
+				Process:         tt.fields.Process,
+				LinkHashes:      tt.fields.LinkHashes,
+				PrevLinkHash:    tt.fields.PrevLinkHash,
+				RefLinkHash:     tt.fields.RefLinkHash,
+				Tags:            tt.fields.Tags,
+				LinkTypes:       tt.fields.LinkTypes,
+				Actions:         tt.fields.Actions,
+				EvidenceBackend: tt.fields.EvidenceBackend,
 			}
 			if got := filter.Match(tt.args.segment); got != tt.want {
 				t.Errorf("SegmentFilter.Match() = %v, want %v", got, tt.want)
@@ -211,6 +294,38 @@ func TestSegmentFilter_Match(t *testing.T) {
 	}
 }
 
+func TestSegmentFilter_MatchCreatedAt(t *testing.T) {
+	now := time.Now()
+	before := now.Add(-time.Second)
+	after := now.Add(time.Second)
+
+	tests := []struct {
+		name          string
+		createdAfter  *time.Time
+		createdBefore *time.Time
+		want          bool
+	}{
+		{"no bounds", nil, nil, true},
+		{"after lower bound", &before, nil, true},
+		{"at lower bound", &now, nil, true},
+		{"before lower bound", &after, nil, false},
+		{"before upper bound", nil, &after, true},
+		{"at upper bound", nil, &now, false},
+		{"after upper bound", nil, &before, false},
+		{"within bounds", &before, &after, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := store.SegmentFilter{
+				CreatedAfter:  tt.createdAfter,
+				CreatedBefore: tt.createdBefore,
+			}
+			assert.Equal(t, tt.want, filter.MatchCreatedAt(now))
+			assert.Equal(t, tt.createdAfter != nil || tt.createdBefore != nil, filter.HasCreatedAtBounds())
+		})
+	}
+}
+
 func TestMapFilter_Match(t *testing.T) {
 	type fields struct {
 		Pagination store.Pagination
@@ -0,0 +1,49 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/stratumn/go-indigocore/bufferedbatch"
+)
+
+// Batch is the type that implements github.com/stratumn/go-indigocore/store.Batch.
+// Its links are sent in a single request when it is written, so either all
+// or none of them are saved.
+type Batch struct {
+	*bufferedbatch.Batch
+
+	client *Client
+}
+
+// NewBatch creates a new Batch.
+func NewBatch(ctx context.Context, c *Client) *Batch {
+	return &Batch{
+		Batch:  bufferedbatch.NewBatch(ctx, c),
+		client: c,
+	}
+}
+
+// Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+func (b *Batch) Write(ctx context.Context) error {
+	if len(b.Links) == 0 {
+		return nil
+	}
+
+	_, err := b.client.do(ctx, http.MethodPost, "/links/batch", nil, b.Links, nil)
+	return err
+}
@@ -0,0 +1,233 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storehttp"
+)
+
+// message is a message of the web socket of the server.
+type message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	Seq  uint64          `json:"seq"`
+}
+
+// AddStoreEventChannel implements github.com/stratumn/go-indigocore/store.Adapter.AddStoreEventChannel.
+// The first call connects to the web socket of the server. The channel
+// receives the events that follow the call.
+func (c *Client) AddStoreEventChannel(eventChan chan *store.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.eventChans = append(c.eventChans, eventChan)
+	if c.listenDone != nil {
+		return
+	}
+	c.listenDone = make(chan struct{})
+
+	// Getting the sequence number of the last event now ensures that
+	// the events that follow are received, whenever the connection is
+	// established.
+	since, err := c.lastSeq(c.ctx)
+	if err != nil {
+		log.WithField("error", err).Warn("Failed to get the last store event")
+	}
+
+	go c.listen(since, err == nil)
+}
+
+// lastSeq returns the sequence number of the last event of the server.
+func (c *Client) lastSeq(ctx context.Context) (uint64, error) {
+	var info storehttp.Info
+	if _, err := c.do(ctx, http.MethodGet, "/", nil, nil, &info); err != nil {
+		return 0, err
+	}
+
+	return info.Seq, nil
+}
+
+// listen receives events from the web socket of the server until the
+// client is closed. When the connection is lost, it reconnects and asks
+// for the events that follow the last one it received.
+func (c *Client) listen(since uint64, resume bool) {
+	defer close(c.listenDone)
+
+	delay := c.config.ReconnectDelay
+
+	for {
+		if !resume {
+			if seq, err := c.lastSeq(c.ctx); err == nil {
+				since, resume = seq, true
+			}
+		}
+
+		conn, err := c.dial(since, resume)
+		if err == nil {
+			delay = c.config.ReconnectDelay
+			err = c.readEvents(conn, &since, &resume)
+		}
+
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"error": err,
+			"delay": delay,
+		}).Warn("Lost connection to the store web socket, reconnecting")
+
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return
+		}
+
+		if delay *= 2; delay > c.config.MaxReconnectDelay {
+			delay = c.config.MaxReconnectDelay
+		}
+	}
+}
+
+// dial connects to the web socket of the server.
+func (c *Client) dial(since uint64, resume bool) (*websocket.Conn, error) {
+	u := *c.url
+	u.Path += "/websocket"
+	if resume {
+		u.RawQuery = "since=" + strconv.FormatUint(since, 10)
+	}
+
+	// The request is only used to authenticate the handshake.
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if c.config.Authenticate != nil {
+		if err := c.config.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: c.client.Timeout,
+	}
+	if t, ok := c.client.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+
+	conn, res, err := dialer.Dial(u.String(), req.Header)
+	if err != nil {
+		if res != nil && res.StatusCode != http.StatusSwitchingProtocols {
+			return nil, decodeErr(res)
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return conn, nil
+}
+
+// readEvents sends the events of a web socket connection to the event
+// channels until the connection is closed. It keeps track of the sequence
+// number of the last event.
+func (c *Client) readEvents(conn *websocket.Conn, since *uint64, resume *bool) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-c.ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if msg.Type == storehttp.EventsLost {
+			log.WithField("seq", msg.Seq).Warn("Some store events were lost")
+		} else if event, err := decodeEvent(&msg); err != nil {
+			log.WithFields(log.Fields{
+				"type":  msg.Type,
+				"error": err,
+			}).Warn("Failed to decode a store event")
+		} else if event != nil {
+			c.sendEvent(event)
+		}
+
+		if msg.Seq > 0 || msg.Type == storehttp.EventsLost {
+			*since, *resume = msg.Seq, true
+		}
+	}
+}
+
+// sendEvent sends an event to all the event channels.
+func (c *Client) sendEvent(event *store.Event) {
+	c.mu.Lock()
+	eventChans := c.eventChans
+	c.mu.Unlock()
+
+	for _, eventChan := range eventChans {
+		select {
+		case eventChan <- event:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// decodeEvent decodes the data of a message. It returns nil if the type of
+// the message is unknown.
+func decodeEvent(msg *message) (*store.Event, error) {
+	switch store.EventType(msg.Type) {
+	case store.SavedLinks:
+		var links []*cs.Link
+		if err := json.Unmarshal(msg.Data, &links); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return store.NewSavedLinks(links...), nil
+
+	case store.SavedEvidences:
+		var evidences map[string]*cs.Evidence
+		if err := json.Unmarshal(msg.Data, &evidences); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &store.Event{EventType: store.SavedEvidences, Data: evidences}, nil
+
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,476 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storeclient implements a store adapter that sends requests to a
+// storehttp server.
+//
+// The client implements store.KeyValueStore, but the server must be
+// configured to serve the key-value store of its adapter, otherwise
+// ErrKeyValueStoreUnavailable is returned. If the server authenticates
+// requests, the client also needs an admin policy. The server keeps these
+// values apart from the ones it stores itself.
+//
+// Store events are received from the web socket of the server. The client
+// reconnects when the connection is lost and asks the server for the events
+// it missed.
+package storeclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-querystring/query"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storehttp"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a request.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultReconnectDelay is the default delay before reconnecting to
+	// the web socket of the server.
+	DefaultReconnectDelay = time.Second
+
+	// DefaultMaxReconnectDelay is the default maximum delay before
+	// reconnecting to the web socket of the server.
+	DefaultMaxReconnectDelay = time.Minute
+)
+
+var (
+	// ErrURL is returned when the URL of the server is invalid.
+	ErrURL = errors.New("store URL must be an absolute HTTP URL")
+
+	// ErrKeyValueStoreUnavailable is returned when the server doesn't
+	// serve the key-value store of its adapter.
+	ErrKeyValueStoreUnavailable = errors.New("store server doesn't serve a key-value store")
+)
+
+// Config contains configuration options for the client.
+type Config struct {
+	// The URL of the storehttp server.
+	URL string
+
+	// Optionally, the HTTP client used to send requests, for instance to
+	// use a TLS client certificate. The default client has a timeout of
+	// DefaultTimeout.
+	HTTPClient *http.Client
+
+	// Optionally, a function called to add credentials to each request,
+	// for instance a bearer token or a signature (see
+	// jsonhttp.SignRequest).
+	Authenticate func(*http.Request) error
+
+	// The delay before reconnecting to the web socket of the server. It is
+	// doubled after each failed attempt, up to MaxReconnectDelay.
+	ReconnectDelay time.Duration
+
+	// The maximum delay before reconnecting to the web socket of the
+	// server.
+	MaxReconnectDelay time.Duration
+}
+
+// Client is a store adapter backed by a storehttp server.
+type Client struct {
+	config *Config
+	url    *url.URL
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	eventChans []chan *store.Event
+	listenDone chan struct{}
+}
+
+// New creates a client for the server at the URL of the configuration.
+func New(config *Config) (*Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || !u.IsAbs() || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Wrapf(ErrURL, "%q", config.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := *config
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if c.ReconnectDelay <= 0 {
+		c.ReconnectDelay = DefaultReconnectDelay
+	}
+	if c.MaxReconnectDelay <= 0 {
+		c.MaxReconnectDelay = DefaultMaxReconnectDelay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Client{
+		config: &c,
+		url:    u,
+		client: c.HTTPClient,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Close stops receiving store events. Event channels are not closed.
+func (c *Client) Close() {
+	c.cancel()
+
+	c.mu.Lock()
+	done := c.listenDone
+	c.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// GetInfo implements github.com/stratumn/go-indigocore/store.Adapter.GetInfo.
+// It returns the information of the adapter of the server.
+func (c *Client) GetInfo(ctx context.Context) (interface{}, error) {
+	var info storehttp.Info
+	if _, err := c.do(ctx, http.MethodGet, "/", nil, nil, &info); err != nil {
+		return nil, err
+	}
+
+	return info.Adapter, nil
+}
+
+// NewBatch implements github.com/stratumn/go-indigocore/store.Adapter.NewBatch.
+func (c *Client) NewBatch(ctx context.Context) (store.Batch, error) {
+	return NewBatch(ctx, c), nil
+}
+
+// CreateLink implements github.com/stratumn/go-indigocore/store.LinkWriter.CreateLink.
+func (c *Client) CreateLink(ctx context.Context, link *cs.Link) (*types.Bytes32, error) {
+	if _, err := c.do(ctx, http.MethodPost, "/links", nil, link, nil); err != nil {
+		return nil, err
+	}
+
+	return link.Hash()
+}
+
+// AddEvidence implements github.com/stratumn/go-indigocore/store.EvidenceWriter.AddEvidence.
+func (c *Client) AddEvidence(ctx context.Context, linkHash *types.Bytes32, evidence *cs.Evidence) error {
+	_, err := c.do(ctx, http.MethodPost, "/evidences/"+linkHash.String(), nil, evidence, nil)
+	return err
+}
+
+// GetEvidences implements github.com/stratumn/go-indigocore/store.EvidenceReader.GetEvidences.
+func (c *Client) GetEvidences(ctx context.Context, linkHash *types.Bytes32) (*cs.Evidences, error) {
+	segment, err := c.GetSegment(ctx, linkHash)
+	if err != nil {
+		return nil, err
+	}
+	if segment == nil {
+		return &cs.Evidences{}, nil
+	}
+
+	return &segment.Meta.Evidences, nil
+}
+
+// GetSegment implements github.com/stratumn/go-indigocore/store.SegmentReader.GetSegment.
+func (c *Client) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
+	var segment cs.Segment
+	if _, err := c.do(ctx, http.MethodGet, "/segments/"+linkHash.String(), nil, nil, &segment); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &segment, nil
+}
+
+// FindSegments implements github.com/stratumn/go-indigocore/store.SegmentReader.FindSegments.
+// Several requests are sent if the limit of the filter is greater than
+// store.MaxLimit.
+func (c *Client) FindSegments(ctx context.Context, filter *store.SegmentFilter) (cs.SegmentSlice, error) {
+	segments := cs.SegmentSlice{}
+	page := *filter
+
+	for {
+		page.Limit = min(filter.Limit-len(segments), store.MaxLimit)
+
+		q, err := segmentFilterQuery(&page)
+		if err != nil {
+			return nil, err
+		}
+
+		var slice cs.SegmentSlice
+		header, err := c.do(ctx, http.MethodGet, "/segments", q, nil, &slice)
+		if err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, slice...)
+		if len(segments) >= filter.Limit || len(slice) < page.Limit {
+			return segments, nil
+		}
+
+		if next := header.Get(storehttp.NextCursorHeader); next != "" {
+			page.Cursor, page.Offset = next, 0
+		} else {
+			page.Offset += len(slice)
+		}
+	}
+}
+
+// GetMapIDs implements github.com/stratumn/go-indigocore/store.SegmentReader.GetMapIDs.
+// Several requests are sent if the limit of the filter is greater than
+// store.MaxLimit.
+func (c *Client) GetMapIDs(ctx context.Context, filter *store.MapFilter) ([]string, error) {
+	mapIDs := []string{}
+	page := *filter
+
+	for {
+		page.Limit = min(filter.Limit-len(mapIDs), store.MaxLimit)
+
+		q, err := query.Values(&page)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if page.Process != "" {
+			q.Set("process", page.Process)
+		}
+
+		var slice []string
+		header, err := c.do(ctx, http.MethodGet, "/maps", q, nil, &slice)
+		if err != nil {
+			return nil, err
+		}
+
+		mapIDs = append(mapIDs, slice...)
+		if len(mapIDs) >= filter.Limit || len(slice) < page.Limit {
+			return mapIDs, nil
+		}
+
+		if next := header.Get(storehttp.NextCursorHeader); next != "" {
+			page.Cursor, page.Offset = next, 0
+		} else {
+			page.Offset += len(slice)
+		}
+	}
+}
+
+// CountSegments implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegments.
+func (c *Client) CountSegments(ctx context.Context, filter *store.SegmentFilter) (int, error) {
+	count, err := c.countSegments(ctx, filter, "")
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Count, nil
+}
+
+// CountSegmentsBy implements github.com/stratumn/go-indigocore/store.SegmentCounter.CountSegmentsBy.
+func (c *Client) CountSegmentsBy(ctx context.Context, filter *store.SegmentFilter, groupBy string) (map[string]int, error) {
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	count, err := c.countSegments(ctx, filter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	if count.Groups == nil {
+		return map[string]int{}, nil
+	}
+
+	return count.Groups, nil
+}
+
+func (c *Client) countSegments(ctx context.Context, filter *store.SegmentFilter, groupBy string) (*storehttp.Count, error) {
+	// The pagination and the sort order are ignored when counting, but
+	// the server still validates them.
+	f := *filter
+	f.Pagination = store.Pagination{}
+	f.Sort = nil
+
+	q, err := segmentFilterQuery(&f)
+	if err != nil {
+		return nil, err
+	}
+	if groupBy != "" {
+		q.Set("groupBy", groupBy)
+	}
+
+	var count storehttp.Count
+	if _, err := c.do(ctx, http.MethodGet, "/segments/count", q, nil, &count); err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+// GetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.GetValue.
+func (c *Client) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+	var value storehttp.Value
+	if _, err := c.do(ctx, http.MethodGet, valuePath(key), nil, nil, &value); err != nil {
+		return nil, keyValueErr(err)
+	}
+
+	return value.Value, nil
+}
+
+// SetValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.SetValue.
+func (c *Client) SetValue(ctx context.Context, key []byte, value []byte) error {
+	_, err := c.do(ctx, http.MethodPut, valuePath(key), nil, &storehttp.Value{Value: value}, nil)
+	return keyValueErr(err)
+}
+
+// DeleteValue implements github.com/stratumn/go-indigocore/store.KeyValueStore.DeleteValue.
+func (c *Client) DeleteValue(ctx context.Context, key []byte) ([]byte, error) {
+	var value storehttp.Value
+	if _, err := c.do(ctx, http.MethodDelete, valuePath(key), nil, nil, &value); err != nil {
+		return nil, keyValueErr(err)
+	}
+
+	return value.Value, nil
+}
+
+func valuePath(key []byte) string {
+	return "/values/" + hex.EncodeToString(key)
+}
+
+// keyValueErr converts the not found errors of the key-value routes, which
+// are only returned if they are not served.
+func keyValueErr(err error) error {
+	if isNotFound(err) {
+		return ErrKeyValueStoreUnavailable
+	}
+	return err
+}
+
+// segmentFilterQuery encodes a filter in the query string of the segment
+// routes.
+func segmentFilterQuery(filter *store.SegmentFilter) (url.Values, error) {
+	q, err := query.Values(filter)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if filter.Process != "" {
+		q.Set("process", filter.Process)
+	}
+
+	// A nil previous link hash means any, whereas an empty one means
+	// none.
+	if filter.PrevLinkHash == nil {
+		q.Del("prevLinkHash")
+	}
+
+	// Times are encoded with their nanoseconds, which the store keeps.
+	if filter.CreatedAfter != nil {
+		q.Set("createdAfter", filter.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if filter.CreatedBefore != nil {
+		q.Set("createdBefore", filter.CreatedBefore.Format(time.RFC3339Nano))
+	}
+
+	return q, nil
+}
+
+// do sends a request to the server and decodes the JSON response in out.
+// It returns the headers of the response.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, in, out interface{}) (http.Header, error) {
+	u := *c.url
+	u.Path += path
+	u.RawQuery = q.Encode()
+
+	var body io.Reader
+	if in != nil {
+		js, err := json.Marshal(in)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		body = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Authenticate != nil {
+		if err := c.config.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, decodeErr(res)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return res.Header, nil
+}
+
+// decodeErr converts an error response to an HTTP error.
+func decodeErr(res *http.Response) error {
+	var body struct {
+		Error string          `json:"error"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = http.StatusText(res.StatusCode)
+	}
+
+	err := jsonhttp.NewErrHTTP(body.Error, res.StatusCode)
+	if len(body.Data) > 0 {
+		err = err.WithData(body.Data)
+	}
+
+	return err
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(jsonhttp.ErrHTTP)
+	return ok && e.Status() == http.StatusNotFound
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
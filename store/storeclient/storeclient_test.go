@@ -0,0 +1,283 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/dummystore"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storehttp"
+	"github.com/stratumn/go-indigocore/store/storetestcases"
+	// Needed to deserialize the evidences of the test cases.
+	_ "github.com/stratumn/go-indigocore/tmpop/evidences"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listener keeps track of the connections it accepts so that tests can
+// break them.
+type listener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *listener) closeConns() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+type testServer struct {
+	*httptest.Server
+	store    *storehttp.Server
+	listener *listener
+}
+
+func startServer(config *storehttp.Config, httpConfig *jsonhttp.Config) *testServer {
+	config.StoreEventsChanSize = storehttp.DefaultStoreEventsChanSize
+	config.JournalSize = storehttp.DefaultJournalSize
+	s := storehttp.New(dummystore.New(&dummystore.Config{}), config, httpConfig, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{
+		Size:         256,
+		WriteTimeout: 10 * time.Second,
+		PongTimeout:  70 * time.Second,
+		PingInterval: time.Minute,
+		MaxMsgSize:   1024,
+	})
+	go s.Start()
+
+	ts := httptest.NewUnstartedServer(s)
+	l := &listener{Listener: ts.Listener}
+	ts.Listener = l
+	ts.Start()
+
+	return &testServer{Server: ts, store: s, listener: l}
+}
+
+func (s *testServer) stop() {
+	s.listener.closeConns()
+	s.Close()
+	s.store.Shutdown(context.Background())
+}
+
+func newClient(t *testing.T, s *testServer) *Client {
+	c, err := New(&Config{URL: s.URL, ReconnectDelay: 10 * time.Millisecond})
+	require.NoError(t, err, "New()")
+	return c
+}
+
+func TestClient(t *testing.T) {
+	servers := map[*Client]*testServer{}
+
+	newAdapter := func() (*Client, error) {
+		s := startServer(&storehttp.Config{KeyValueStore: true}, &jsonhttp.Config{})
+		c, err := New(&Config{URL: s.URL})
+		if err != nil {
+			return nil, err
+		}
+		servers[c] = s
+		return c, nil
+	}
+	free := func(c *Client) {
+		c.Close()
+		servers[c].stop()
+		delete(servers, c)
+	}
+
+	storetestcases.Factory{
+		New: func() (store.Adapter, error) {
+			return newAdapter()
+		},
+		Free: func(a store.Adapter) {
+			free(a.(*Client))
+		},
+		NewKeyValueStore: func() (store.KeyValueStore, error) {
+			return newAdapter()
+		},
+		FreeKeyValueStore: func(a store.KeyValueStore) {
+			free(a.(*Client))
+		},
+	}.RunKeyValueStoreTests(t)
+
+	storetestcases.Factory{
+		New: func() (store.Adapter, error) {
+			return newAdapter()
+		},
+		Free: func(a store.Adapter) {
+			free(a.(*Client))
+		},
+	}.RunStoreTests(t)
+}
+
+func TestNew_invalidURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:5000", "ftp://localhost", "http://%zz"} {
+		_, err := New(&Config{URL: u})
+		assert.Error(t, err, u)
+	}
+}
+
+func TestClient_keyValueStoreUnavailable(t *testing.T) {
+	s := startServer(&storehttp.Config{}, &jsonhttp.Config{})
+	defer s.stop()
+	c := newClient(t, s)
+	defer c.Close()
+
+	_, err := c.GetValue(context.Background(), []byte("key"))
+	assert.Equal(t, ErrKeyValueStoreUnavailable, err)
+}
+
+func TestClient_batchInvalidLink(t *testing.T) {
+	s := startServer(&storehttp.Config{}, &jsonhttp.Config{})
+	defer s.stop()
+	c := newClient(t, s)
+	defer c.Close()
+
+	ctx := context.Background()
+	b, err := c.NewBatch(ctx)
+	require.NoError(t, err)
+
+	linkHash, err := b.CreateLink(ctx, cstesting.RandomLink())
+	require.NoError(t, err)
+	_, err = b.CreateLink(ctx, cstesting.NewLinkBuilder().WithProcess("").Build())
+	require.NoError(t, err)
+
+	err = b.Write(ctx)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(jsonhttp.ErrHTTP).Status())
+
+	segment, err := c.GetSegment(ctx, linkHash)
+	require.NoError(t, err)
+	assert.Nil(t, segment, "no link should be saved")
+}
+
+func TestClient_reconnect(t *testing.T) {
+	s := startServer(&storehttp.Config{}, &jsonhttp.Config{})
+	defer s.stop()
+
+	// Requests don't reuse connections so that only the web socket is
+	// broken.
+	c, err := New(&Config{
+		URL:            s.URL,
+		HTTPClient:     &http.Client{Transport: &http.Transport{DisableKeepAlives: true}},
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	require.NoError(t, err, "New()")
+	defer c.Close()
+
+	ctx := context.Background()
+	events := make(chan *store.Event, 10)
+	c.AddStoreEventChannel(events)
+
+	createLink := func() *cs.Link {
+		link := cstesting.RandomLink()
+		_, err := c.CreateLink(ctx, link)
+		require.NoError(t, err, "c.CreateLink()")
+		return link
+	}
+	receive := func(link *cs.Link) {
+		select {
+		case e := <-events:
+			assert.Equal(t, store.NewSavedLinks(link), e)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no store event received")
+		}
+	}
+
+	link := createLink()
+	receive(link)
+
+	// Links saved while the client is disconnected are received when
+	// it reconnects.
+	s.listener.closeConns()
+	link = createLink()
+	receive(link)
+
+	link = createLink()
+	receive(link)
+
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %v", e)
+	default:
+	}
+}
+
+func TestClient_auth(t *testing.T) {
+	s := startServer(&storehttp.Config{}, &jsonhttp.Config{
+		Authenticator: jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+			"token": {Name: "client"},
+		}),
+	})
+	defer s.stop()
+
+	c := newClient(t, s)
+	defer c.Close()
+
+	_, err := c.GetInfo(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.(jsonhttp.ErrHTTP).Status())
+
+	c, err = New(&Config{
+		URL: s.URL,
+		Authenticate: func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.GetInfo(context.Background())
+	assert.NoError(t, err)
+
+	events := make(chan *store.Event, 1)
+	c.AddStoreEventChannel(events)
+
+	link := cstesting.RandomLink()
+	_, err = c.CreateLink(context.Background(), link)
+	require.NoError(t, err)
+
+	select {
+	case e := <-events:
+		assert.Equal(t, store.NewSavedLinks(link), e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no store event received")
+	}
+}
@@ -29,10 +29,14 @@ import (
 	"github.com/stratumn/go-indigocore/jsonws"
 	"github.com/stratumn/go-indigocore/monitoring"
 	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/webhook"
 )
 
 var (
 	storeEventsChanSize int
+	journalSize         int
+	maxBatchSize        int
+	serveKeyValueStore  bool
 	addr                string
 	wsReadBufSize       int
 	wsWriteBufSize      int
@@ -43,10 +47,16 @@ var (
 	wsMaxMsgSize        int64
 	certFile            string
 	keyFile             string
+	clientCAFile        string
+	authConfig          string
 	readTimeout         time.Duration
 	writeTimeout        time.Duration
 	maxHeaderBytes      int
 	shutdownTimeout     time.Duration
+	webhooks            bool
+	webhookMaxRetries   int
+	webhookRetryDelay   time.Duration
+	webhookTimeout      time.Duration
 )
 
 // Run launches a storehttp server.
@@ -90,6 +100,9 @@ func Run(
 // RegisterFlags register the flags used by RunWithFlags.
 func RegisterFlags() {
 	flag.IntVar(&storeEventsChanSize, "store_events_chan_size", DefaultStoreEventsChanSize, "Size of the store events channel")
+	flag.IntVar(&journalSize, "ws_journal_size", DefaultJournalSize, "Number of store events kept for clients that reconnect")
+	flag.IntVar(&maxBatchSize, "max_batch_size", DefaultMaxBatchSize, "Maximum number of links of a batch")
+	flag.BoolVar(&serveKeyValueStore, "serve_kv", false, "Serve the key-value store of the adapter to admin clients, if it has one")
 	flag.StringVar(&addr, "http", DefaultAddress, "HTTP address")
 	flag.IntVar(&wsReadBufSize, "ws_read_buf_size", jsonws.DefaultWebSocketReadBufferSize, "Web socket read buffer size")
 	flag.IntVar(&wsWriteBufSize, "ws_write_buf_size", jsonws.DefaultWebSocketWriteBufferSize, "Web socket write buffer size")
@@ -100,10 +113,16 @@ func RegisterFlags() {
 	flag.Int64Var(&wsMaxMsgSize, "max_msg_size", jsonws.DefaultWebSocketMaxMsgSize, "Maximum size of a received web socket message")
 	flag.StringVar(&certFile, "tls_cert", "", "TLS certificate file")
 	flag.StringVar(&keyFile, "tls_key", "", "TLS private key file")
+	flag.StringVar(&clientCAFile, "tls_client_ca", "", "Certificates of the authorities of TLS client certificates")
+	flag.StringVar(&authConfig, "auth_config", "", "JSON file describing the clients allowed to send requests")
 	flag.DurationVar(&readTimeout, "read_timeout", jsonhttp.DefaultReadTimeout, "Read timeout")
 	flag.DurationVar(&writeTimeout, "write_timeout", jsonhttp.DefaultWriteTimeout, "Write timeout")
 	flag.IntVar(&maxHeaderBytes, "max_header_bytes", jsonhttp.DefaultMaxHeaderBytes, "Maximum header bytes")
 	flag.DurationVar(&shutdownTimeout, "shutdown_timeout", 10*time.Second, "Shutdown timeout")
+	flag.BoolVar(&webhooks, "webhooks", false, "Post store events to webhooks, which are kept in the store if it is a key-value store")
+	flag.IntVar(&webhookMaxRetries, "webhook_max_retries", webhook.DefaultMaxRetries, "Maximum number of attempts of a webhook delivery")
+	flag.DurationVar(&webhookRetryDelay, "webhook_retry_delay", webhook.DefaultRetryDelay, "Delay before retrying a webhook delivery, doubled at each attempt")
+	flag.DurationVar(&webhookTimeout, "webhook_timeout", webhook.DefaultTimeout, "Timeout of a webhook request")
 }
 
 // RunWithFlags should be called after RegisterFlags and flag.Parse to launch
@@ -111,6 +130,20 @@ func RegisterFlags() {
 func RunWithFlags(a store.Adapter) {
 	config := &Config{
 		StoreEventsChanSize: storeEventsChanSize,
+		JournalSize:         journalSize,
+		MaxBatchSize:        maxBatchSize,
+		KeyValueStore:       serveKeyValueStore,
+	}
+	if webhooks {
+		kv, _ := a.(store.KeyValueStore)
+		config.Webhooks = webhook.New(&webhook.Config{
+			MaxRetries: webhookMaxRetries,
+			RetryDelay: webhookRetryDelay,
+			Timeout:    webhookTimeout,
+		}, kv)
+		if err := config.Webhooks.Load(context.Background()); err != nil {
+			log.WithField("error", err).Fatal("Failed to load webhooks")
+		}
 	}
 	monitoringConfig := monitoring.ConfigurationFromFlags()
 	httpConfig := &jsonhttp.Config{
@@ -120,6 +153,14 @@ func RunWithFlags(a store.Adapter) {
 		MaxHeaderBytes: maxHeaderBytes,
 		CertFile:       certFile,
 		KeyFile:        keyFile,
+		ClientCAFile:   clientCAFile,
+	}
+	if authConfig != "" {
+		authenticator, err := jsonhttp.LoadAuthenticator(authConfig)
+		if err != nil {
+			log.WithField("error", err).Fatal("Failed to load auth config")
+		}
+		httpConfig.Authenticator = authenticator
 	}
 	basicConfig := &jsonws.BasicConfig{
 		ReadBufferSize:  wsReadBufSize,
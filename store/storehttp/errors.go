@@ -48,3 +48,87 @@ func newErrLinkHashes(msg string) jsonhttp.ErrHTTP {
 	}
 	return jsonhttp.NewErrBadRequest(msg)
 }
+
+func newErrRefLinkHash(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "refLinkHash must be a 64 byte long hexadecimal string"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrCursor(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "cursor must be a token returned by a previous request"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrWhere(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "where must be an array of predicates such as 'state.status == \"approved\"'"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrCreatedAfter(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "createdAfter must be a RFC 3339 time such as 2006-01-02T15:04:05Z"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrCreatedBefore(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "createdBefore must be a RFC 3339 time such as 2006-01-02T15:04:05Z"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrGroupBy(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "groupBy must be mapId, process or type"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrSort(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "sort must be priority, createdAt, linkHash or a state field, prefixed with - for a descending order"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrSince(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "since must be a positive integer"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrBatchSize(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "batch must contain between one and the maximum batch size links"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrBatch(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "batch contains invalid links"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrKey(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "key must be a non empty hexadecimal string"
+	}
+	return jsonhttp.NewErrBadRequest(msg)
+}
+
+func newErrBatchWrite(msg string) jsonhttp.ErrHTTP {
+	if msg == "" {
+		msg = "batch could not be saved"
+	}
+	return jsonhttp.NewErrInternalServer(msg)
+}
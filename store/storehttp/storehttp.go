@@ -16,12 +16,27 @@
 //
 // It serves the following routes:
 //	GET /
-//		Renders information about the store.
+//		Renders information about the store and the sequence number of
+//		the last store event:
+//			{ "adapter": info, "seq": seq }
 //
 //	POST /links
 //		Saves then renders a link.
 //		Body should be a JSON encoded link.
 //
+//	POST /links/batch
+//		Saves links in a single batch, then renders their hashes:
+//			[ { "linkHash": linkHash } ]
+//		Body should be a JSON encoded array of links.
+//		Links are validated in order and can depend on the previous ones.
+//		If a link is invalid, nothing is saved and the error is rendered
+//		with the result of each link in its data:
+//			[ { "linkHash": linkHash }, { "error": error } ]
+//		If the store fails to save a link, the error is rendered the same
+//		way, but only the links that were saved have a hash. Stores that
+//		use transactions save none of them, while the others save the
+//		links that come before the failed one.
+//
 //	POST /evidences/:linkHash
 //		Adds evidence to a link.
 //		Body should be a JSON encoded evidence.
@@ -29,16 +44,106 @@
 //	GET /segments/:linkHash
 //		Renders a segment.
 //
-//	GET /segments?[offset=offset]&[limit=limit]&[mapIds[]=id1]&[mapIds[]=id2]&[prevLinkHash=prevLinkHash]&[tags[]=tag1]&[tags[]=tag2]
+//	GET /segments/:linkHash/children
+//		Renders the segments whose previous link is the given one.
+//
+//	GET /segments/:linkHash/ancestors
+//		Renders the segments of the previous links of the given one,
+//		from its parent up to the root of its map.
+//
+//	GET /segments/:linkHash/refs
+//		Renders the segments referenced by the link, which can belong to
+//		other processes.
+//
+//	GET /segments?[offset=offset]&[limit=limit]&[cursor=cursor]&[mapIds[]=id1]&[mapIds[]=id2]&[prevLinkHash=prevLinkHash]&[refLinkHash=linkHash]&[tags[]=tag1]&[tags[]=tag2]&[where[]=predicate]&[linkTypes[]=type]&[actions[]=action]&[createdAfter=time]&[createdBefore=time]&[evidenceBackend=backend]&[sort=[-]field]
 //		Finds and renders segments.
+//		If refLinkHash is set, only the segments that reference this link
+//		hash in their meta refs are rendered.
+//		If there are more results, the X-Next-Cursor header contains
+//		the cursor of the next page. Cursors are only returned when
+//		segments are sorted by descending priority, the default.
+//
+//	GET /segments/count?[mapIds[]=id1]&[...segment filters]&[groupBy=mapId|process|type]
+//		Counts the segments matching the same filters as /segments,
+//		ignoring pagination. If groupBy is set, the count of each value
+//		of the field is also rendered.
+//
+//	GET /maps/:mapId/graph
+//		Renders all the segments of a map along with the link hashes of
+//		their children, of its roots and of its heads.
 //
-//	GET /maps?[offset=offset]&[limit=limit]
-//		Finds and renders map IDs.
+//	GET /maps/:mapId/heads
+//		Renders the segments of a map that have no child.
 //
-//	GET /websocket
+//	GET /stats?[mapIds[]=id1]&[...segment filters]
+//		Renders the number of segments and maps, and the number of
+//		segments per process and per link type.
+//
+//	GET /maps?[offset=offset]&[limit=limit]&[cursor=cursor]&[process=process]&[prefix=prefix]&[suffix=suffix]
+//		Finds and renders map IDs in ascending order.
+//		If there are more results, the X-Next-Cursor header contains
+//		the cursor of the next page.
+//
+//	GET /websocket?[since=seq]&[process=process]&[mapIds[]=id1]&[...segment filters]
 //		A web socket that broadcasts messages from the store:
-//			{ "type": "SavedLink", "data": [link] }
-//			{ "type": "SavedEvidence", "data": [evidence] }
+//			{ "type": "SavedLinks", "data": [link], "seq": seq }
+//			{ "type": "SavedEvidences", "data": {linkHash: evidence}, "seq": seq }
+//		If segment filters are set, only the links and evidences that
+//		match them are sent. Messages carry an increasing sequence
+//		number. A client that reconnects with since set to the last
+//		sequence number it received is first sent the events it missed.
+//		If some of them are no longer available, an EventsLost message
+//		is sent first and the client should resync using /segments.
+//
+//	GET /events?[since=seq]&[process=process]&[mapIds[]=id1]&[...segment filters]
+//		A server-sent events stream of the same messages as /websocket.
+//		Event IDs are sequence numbers, so the Last-Event-ID header can
+//		be used instead of since to resume the stream.
+//
+// If the server is configured to serve the key-value store of the adapter,
+// it also serves the following routes, where key is hex encoded. Keys are
+// stored under a fixed prefix, so only the values set through these routes
+// can be accessed:
+//	GET /values/:key
+//		Renders a value, which is null if the key is not set:
+//			{ "value": base64 }
+//
+//	PUT /values/:key
+//		Sets a value.
+//		Body should be a JSON encoded value:
+//			{ "value": base64 }
+//
+//	DELETE /values/:key
+//		Deletes a value, then renders it.
+//
+// If the server has a webhook dispatcher, it also serves the following
+// routes:
+//	GET /webhooks
+//		Renders the webhook endpoints, without their secret.
+//
+//	POST /webhooks
+//		Adds or replaces a webhook endpoint.
+//		Body should be a JSON encoded endpoint:
+//			{ "id": id, "url": url, "secret": secret, "eventTypes": [type], "filter": {segment filter} }
+//
+//	DELETE /webhooks/:id
+//		Removes a webhook endpoint.
+//
+//	GET /deadletters
+//		Renders the webhook deliveries that failed.
+//
+//	DELETE /deadletters/:id
+//		Removes a failed delivery.
+//
+//	POST /deadletters/:id/redeliver
+//		Sends a failed delivery again.
+//
+// If the HTTP configuration has an authenticator, clients must be
+// authenticated. Saving a link also requires a policy that allows its
+// process, and adding an evidence a policy that allows its backend. The
+// key-value store and webhook routes require an admin policy. If that policy is limited to some
+// processes, endpoints must filter one of them, and only those endpoints and
+// their failed deliveries are visible.
 package storehttp
 
 import (
@@ -46,14 +151,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/stratumn/go-indigocore/bufferedbatch"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/jsonhttp"
 	"github.com/stratumn/go-indigocore/jsonws"
 	"github.com/stratumn/go-indigocore/monitoring"
 	"github.com/stratumn/go-indigocore/store"
 	"github.com/stratumn/go-indigocore/types"
+	"github.com/stratumn/go-indigocore/webhook"
+	"github.com/stratumn/go-indigocore/webhook/webhookhttp"
 
 	"go.opencensus.io/trace"
 )
@@ -62,27 +171,104 @@ const (
 	// DefaultStoreEventsChanSize is the default size of the store events channel.
 	DefaultStoreEventsChanSize = 256
 
+	// DefaultJournalSize is the default number of store events kept for
+	// clients that reconnect.
+	DefaultJournalSize = 1024
+
+	// DefaultMaxBatchSize is the default maximum number of links of a
+	// batch.
+	DefaultMaxBatchSize = 1000
+
 	// DefaultAddress is the default address of the server.
 	DefaultAddress = ":5000"
+
+	// NextCursorHeader is the response header containing the pagination
+	// cursor of the next page of results.
+	NextCursorHeader = "X-Next-Cursor"
+
+	// EventsLost is the type of the message sent when some of the events
+	// a client asked for are no longer available.
+	EventsLost = "EventsLost"
+
+	// streamChanSize is the number of events an event stream can lag
+	// behind, in addition to the replayed ones, before it is ended.
+	streamChanSize = 256
 )
 
 // Server is an HTTP server for stores.
 type Server struct {
+	// seq is the sequence number of the last store event. It is first so
+	// that it is aligned for atomic operations.
+	seq uint64
+
 	*jsonhttp.Server
+	kv              store.KeyValueStore
 	adapter         store.Adapter
+	webhooks        *webhook.Dispatcher
+	webhooksCtx     context.Context
+	stopWebhooks    context.CancelFunc
+	webhooksDone    chan struct{}
 	ws              *jsonws.Basic
 	storeEventsChan chan *store.Event
+	resolvedChan    chan *resolvedEvent
+	journal         *jsonhttp.Journal
+	journalSize     int
+	maxBatchSize    int
+	evidenceLinks   map[uint64]map[string]*cs.Link
+	subChan         chan *subscription
+	unsubChan       chan *subscription
+	loopDone        chan struct{}
 }
 
 // Config contains configuration options for the server.
 type Config struct {
 	// The size of the store event channel.
 	StoreEventsChanSize int
+
+	// The number of store events kept for clients that reconnect.
+	JournalSize int
+
+	// The maximum number of links of a batch, or zero for no limit.
+	MaxBatchSize int
+
+	// Whether to serve the key-value store of the adapter, if it has one.
+	// Admin clients can then read and write the values set through the
+	// server, which are stored under a fixed prefix.
+	KeyValueStore bool
+
+	// Optionally, a dispatcher that posts store events to webhooks. It is
+	// started and stopped with the server.
+	Webhooks *webhook.Dispatcher
 }
 
 // Info is the info returned by the root route.
 type Info struct {
 	Adapter interface{} `json:"adapter"`
+
+	// Seq is the sequence number of the last store event. Web socket
+	// clients can pass it as since to get all the events that follow.
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// BatchResult is the result of a link of a batch: either its hash or the
+// reason it is invalid.
+type BatchResult struct {
+	LinkHash *types.Bytes32 `json:"linkHash,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Count is the number of segments returned by the count route.
+type Count struct {
+	Count  int            `json:"count"`
+	Groups map[string]int `json:"groups,omitempty"`
+}
+
+// Stats are the statistics returned by the stats route.
+type Stats struct {
+	Segments  int            `json:"segments"`
+	Maps      int            `json:"maps"`
+	Processes map[string]int `json:"processes"`
+	Types     map[string]int `json:"types"`
 }
 
 // New create an instance of a server.
@@ -96,17 +282,49 @@ func New(
 	s := Server{
 		Server:          jsonhttp.New(httpConfig),
 		adapter:         a,
+		webhooks:        config.Webhooks,
+		webhooksDone:    make(chan struct{}),
 		ws:              jsonws.NewBasic(basicConfig, bufConnConfig),
 		storeEventsChan: make(chan *store.Event, config.StoreEventsChanSize),
+		resolvedChan:    make(chan *resolvedEvent, config.StoreEventsChanSize),
+		journal:         jsonhttp.NewJournal(config.JournalSize),
+		journalSize:     config.JournalSize,
+		maxBatchSize:    config.MaxBatchSize,
+		evidenceLinks:   map[uint64]map[string]*cs.Link{},
+		subChan:         make(chan *subscription),
+		unsubChan:       make(chan *subscription),
+		loopDone:        make(chan struct{}),
+	}
+	s.webhooksCtx, s.stopWebhooks = context.WithCancel(context.Background())
+	if config.KeyValueStore {
+		s.kv, _ = a.(store.KeyValueStore)
 	}
 
 	s.Get("/", s.root)
 	s.Post("/links", s.createLink)
+	s.Post("/links/batch", s.createLinks)
 	s.Post("/evidences/:linkHash", s.addEvidence)
 	s.Get("/segments/:linkHash", s.getSegment)
+	s.Get("/segments/:linkHash/children", s.getRelatedSegments("children", store.GetChildren))
+	s.Get("/segments/:linkHash/ancestors", s.getRelatedSegments("ancestors", store.GetAncestors))
+	s.Get("/segments/:linkHash/refs", s.getRelatedSegments("refs", store.GetReferences))
 	s.Get("/segments", s.findSegments)
 	s.Get("/maps", s.getMapIDs)
+	s.Get("/maps/:mapId/graph", s.getMapGraph)
+	s.Get("/maps/:mapId/heads", s.getMapHeads)
+	s.Get("/stats", s.getStats)
 	s.GetRaw("/websocket", s.getWebSocket)
+	s.GetRaw("/events", s.getEvents)
+
+	if s.webhooks != nil {
+		webhookhttp.Register(s.Server, s.webhooks)
+	}
+
+	if s.kv != nil {
+		s.Get("/values/:key", s.getValue)
+		s.Put("/values/:key", s.setValue)
+		s.Delete("/values/:key", s.deleteValue)
+	}
 
 	return &s
 }
@@ -135,6 +353,13 @@ func (s *Server) ListenAndServe() (err error) {
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.ws.Stop()
 	close(s.storeEventsChan)
+	if s.webhooks != nil {
+		s.stopWebhooks()
+		select {
+		case <-s.webhooksDone:
+		case <-ctx.Done():
+		}
+	}
 	return s.Server.Shutdown(ctx)
 }
 
@@ -144,13 +369,29 @@ func (s *Server) Start() {
 	s.adapter.AddStoreEventChannel(s.storeEventsChan)
 
 	wg := sync.WaitGroup{}
-	wg.Add(2)
+	wg.Add(3)
+
+	if s.webhooks != nil {
+		s.webhooks.ListenStore(s.adapter)
+		wg.Add(1)
+
+		go func() {
+			s.webhooks.Start(s.webhooksCtx)
+			close(s.webhooksDone)
+			wg.Done()
+		}()
+	}
 
 	go func() {
 		s.ws.Start()
 		wg.Done()
 	}()
 
+	go func() {
+		s.resolve()
+		wg.Done()
+	}()
+
 	go func() {
 		s.loop()
 		wg.Done()
@@ -159,16 +400,6 @@ func (s *Server) Start() {
 	wg.Wait()
 }
 
-// Web socket loop.
-func (s *Server) loop() {
-	for event := range s.storeEventsChan {
-		s.ws.Broadcast(&jsonws.Message{
-			Type: string(event.EventType),
-			Data: event.Data,
-		}, nil)
-	}
-}
-
 func (s *Server) root(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
 	ctx, span := trace.StartSpan(r.Context(), "storehttp/root")
 	defer span.End()
@@ -181,6 +412,7 @@ func (s *Server) root(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 
 	return &Info{
 		Adapter: adapterInfo,
+		Seq:     atomic.LoadUint64(&s.seq),
 	}, nil
 }
 
@@ -196,6 +428,11 @@ func (s *Server) createLink(w http.ResponseWriter, r *http.Request, _ httprouter
 		return nil, jsonhttp.NewErrBadRequest(err.Error())
 	}
 
+	if err := jsonhttp.AuthorizeProcess(ctx, link.Meta.Process); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+		return nil, err
+	}
+
 	if err := link.Validate(ctx, s.adapter.GetSegment); err != nil {
 		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
 		return nil, jsonhttp.NewErrBadRequest(err.Error())
@@ -208,6 +445,116 @@ func (s *Server) createLink(w http.ResponseWriter, r *http.Request, _ httprouter
 	return link.Segmentify(), nil
 }
 
+func (s *Server) createLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/createLinks")
+	defer span.End()
+
+	decoder := json.NewDecoder(r.Body)
+
+	var links []*cs.Link
+	if err := decoder.Decode(&links); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, jsonhttp.NewErrBadRequest(err.Error())
+	}
+	if len(links) == 0 || s.maxBatchSize > 0 && len(links) > s.maxBatchSize {
+		err := newErrBatchSize("")
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link == nil {
+			err := jsonhttp.NewErrBadRequest("link required")
+			span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+			return nil, err
+		}
+		if err := jsonhttp.AuthorizeProcess(ctx, link.Meta.Process); err != nil {
+			span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+			return nil, err
+		}
+	}
+
+	// Links are validated before the batch is created so that invalid
+	// requests don't start a transaction. They can depend on the previous
+	// links of the request.
+	results := make([]*BatchResult, len(links))
+	valid := true
+	pending := make(map[types.Bytes32]*cs.Segment, len(links))
+	getSegment := func(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
+		if segment, ok := pending[*linkHash]; ok {
+			return segment, nil
+		}
+		return s.adapter.GetSegment(ctx, linkHash)
+	}
+	for i, link := range links {
+		results[i] = &BatchResult{}
+		if err := link.Validate(ctx, getSegment); err != nil {
+			results[i].Error = err.Error()
+			valid = false
+			continue
+		}
+		linkHash, err := link.Hash()
+		if err != nil {
+			results[i].Error = err.Error()
+			valid = false
+			continue
+		}
+		results[i].LinkHash = linkHash
+		pending[*linkHash] = link.Segmentify()
+	}
+
+	if !valid {
+		err := newErrBatch("").WithData(results)
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, err
+	}
+
+	batch, err := s.adapter.NewBatch(ctx)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+	if batch == nil {
+		batch = bufferedbatch.NewBatch(ctx, s.adapter)
+	}
+
+	for i, link := range links {
+		if _, err := batch.CreateLink(ctx, link); err != nil {
+			if rollbacker, ok := batch.(store.BatchRollbacker); ok {
+				rollbacker.Rollback(ctx)
+			}
+			err := batchWriteErr(results, 0, i, err)
+			span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+			return nil, err
+		}
+	}
+
+	if err := batch.Write(ctx); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		if writeErr, ok := err.(*bufferedbatch.WriteError); ok {
+			return nil, batchWriteErr(results, writeErr.Written, writeErr.Written, writeErr.Err)
+		}
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// batchWriteErr returns the error rendered when the link at index failed
+// to be saved and only the links before written were saved. In its data,
+// only the results of the saved links have a hash.
+func batchWriteErr(results []*BatchResult, written, index int, err error) error {
+	for i, result := range results {
+		if i >= written {
+			result.LinkHash = nil
+		}
+		if i == index {
+			result.Error = err.Error()
+		}
+	}
+	return newErrBatchWrite("").WithData(results)
+}
+
 func (s *Server) addEvidence(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
 	ctx, span := trace.StartSpan(r.Context(), "storehttp/addEvidence")
 	defer span.End()
@@ -226,6 +573,11 @@ func (s *Server) addEvidence(w http.ResponseWriter, r *http.Request, p httproute
 		return nil, jsonhttp.NewErrBadRequest(err.Error())
 	}
 
+	if err := jsonhttp.AuthorizeEvidenceBackend(ctx, evidence.Backend); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+		return nil, err
+	}
+
 	if err := s.adapter.AddEvidence(ctx, linkHash, &evidence); err != nil {
 		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
 		return nil, err
@@ -235,6 +587,12 @@ func (s *Server) addEvidence(w http.ResponseWriter, r *http.Request, p httproute
 }
 
 func (s *Server) getSegment(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	// The router doesn't allow a static route next to the link hash
+	// parameter.
+	if p.ByName("linkHash") == "count" {
+		return s.countSegments(w, r, p)
+	}
+
 	ctx, span := trace.StartSpan(r.Context(), "storehttp/getSegment")
 	defer span.End()
 
@@ -273,6 +631,10 @@ func (s *Server) findSegments(w http.ResponseWriter, r *http.Request, _ httprout
 		return nil, err
 	}
 
+	if next := filter.NextSegmentsCursor(slice); next != "" {
+		w.Header().Set(NextCursorHeader, next)
+	}
+
 	return slice, nil
 }
 
@@ -292,9 +654,154 @@ func (s *Server) getMapIDs(w http.ResponseWriter, r *http.Request, _ httprouter.
 		return nil, err
 	}
 
+	if next := filter.NextMapIDsCursor(slice); next != "" {
+		w.Header().Set(NextCursorHeader, next)
+	}
+
 	return slice, nil
 }
 
-func (s *Server) getWebSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	s.ws.Handle(w, r)
+// getRelatedSegments returns a handler that renders the segments related to
+// the one of the link hash parameter.
+func (s *Server) getRelatedSegments(
+	name string,
+	get func(context.Context, store.SegmentReader, *types.Bytes32) (cs.SegmentSlice, error),
+) jsonhttp.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+		ctx, span := trace.StartSpan(r.Context(), "storehttp/segments/"+name)
+		defer span.End()
+
+		linkHash, err := types.NewBytes32FromString(p.ByName("linkHash"))
+		if err != nil {
+			span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+			return nil, jsonhttp.NewErrBadRequest(err.Error())
+		}
+
+		seg, err := s.adapter.GetSegment(ctx, linkHash)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+			return nil, err
+		}
+		if seg == nil {
+			span.SetStatus(trace.Status{Code: monitoring.NotFound})
+			return nil, jsonhttp.NewErrNotFound("")
+		}
+
+		segments, err := get(ctx, s.adapter, linkHash)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+			return nil, err
+		}
+
+		return segments, nil
+	}
+}
+
+func (s *Server) getMapGraph(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/getMapGraph")
+	defer span.End()
+
+	graph, err := store.GetMapGraph(ctx, s.adapter, p.ByName("mapId"))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+	if len(graph.Segments) == 0 {
+		span.SetStatus(trace.Status{Code: monitoring.NotFound})
+		return nil, jsonhttp.NewErrNotFound("")
+	}
+
+	return graph, nil
+}
+
+func (s *Server) getMapHeads(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/getMapHeads")
+	defer span.End()
+
+	heads, err := store.GetMapHeads(ctx, s.adapter, p.ByName("mapId"))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+	if len(heads) == 0 {
+		span.SetStatus(trace.Status{Code: monitoring.NotFound})
+		return nil, jsonhttp.NewErrNotFound("")
+	}
+
+	return heads, nil
+}
+
+func (s *Server) countSegments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/countSegments")
+	defer span.End()
+
+	filter, e := parseSegmentFilter(r)
+	if e != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: e.Error()})
+		return nil, jsonhttp.NewErrBadRequest(e.Error())
+	}
+
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		count, err := store.CountSegments(ctx, s.adapter, filter)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+			return nil, err
+		}
+
+		return &Count{Count: count}, nil
+	}
+
+	if err := store.ValidateGroupBy(groupBy); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, newErrGroupBy("")
+	}
+
+	groups, err := store.CountSegmentsBy(ctx, s.adapter, filter, groupBy)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	res := Count{Groups: groups}
+	for _, count := range groups {
+		res.Count += count
+	}
+
+	return &res, nil
+}
+
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/getStats")
+	defer span.End()
+
+	filter, e := parseSegmentFilter(r)
+	if e != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: e.Error()})
+		return nil, jsonhttp.NewErrBadRequest(e.Error())
+	}
+
+	segments, err := store.CountSegments(ctx, s.adapter, filter)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	maps, err := store.CountMaps(ctx, s.adapter, filter)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	stats := Stats{Segments: segments, Maps: maps}
+	if stats.Processes, err = store.CountSegmentsBy(ctx, s.adapter, filter, store.GroupByProcess); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+	if stats.Types, err = store.CountSegmentsBy(ctx, s.adapter, filter, store.GroupByType); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	return &stats, nil
 }
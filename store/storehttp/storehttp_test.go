@@ -15,13 +15,18 @@
 package storehttp
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,6 +40,7 @@ import (
 	"github.com/stratumn/go-indigocore/testutil"
 	"github.com/stratumn/go-indigocore/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const zeros = "0000000000000000000000000000000000000000000000000000000000000000"
@@ -153,6 +159,166 @@ func TestCreateLink_invalidLink(t *testing.T) {
 	}
 }
 
+func TestCreateLink_auth(t *testing.T) {
+	s, a := createServerWithAuth(jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+		"writer": {Name: "writer", Policy: jsonhttp.Policy{Processes: []string{"allowed"}}},
+		"reader": {Name: "reader", Policy: jsonhttp.Policy{ReadOnly: true}},
+	}))
+	a.MockCreateLink.Fn = func(l *cs.Link) (*types.Bytes32, error) { return l.Hash() }
+
+	post := func(token, process string) int {
+		l := cstesting.RandomLink()
+		l.Meta.Process = process
+		body, err := json.Marshal(l)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/links", bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("", "allowed"), "anonymous")
+	assert.Equal(t, http.StatusForbidden, post("reader", "allowed"), "reader")
+	assert.Equal(t, http.StatusForbidden, post("writer", "other"), "other process")
+	assert.Equal(t, 0, a.MockCreateLink.CalledCount)
+
+	assert.Equal(t, http.StatusOK, post("writer", "allowed"), "allowed process")
+	assert.Equal(t, 1, a.MockCreateLink.CalledCount)
+}
+
+func TestCreateLinks(t *testing.T) {
+	s, a := createServer()
+	batch := &storetesting.MockBatch{}
+	batch.MockCreateLink.Fn = func(l *cs.Link) (*types.Bytes32, error) { return l.Hash() }
+	a.MockNewBatch.Fn = func() store.Batch { return batch }
+
+	l1, l2 := cstesting.RandomLink(), cstesting.RandomLink()
+	lh1, _ := l1.Hash()
+	lh2, _ := l2.Hash()
+
+	var results []*BatchResult
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/links/batch", []*cs.Link{l1, l2}, &results)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []*BatchResult{{LinkHash: lh1}, {LinkHash: lh2}}, results)
+	assert.Equal(t, []*cs.Link{l1, l2}, batch.MockCreateLink.CalledWith)
+	assert.Equal(t, 1, batch.MockWrite.CalledCount)
+	assert.Equal(t, 0, a.MockCreateLink.CalledCount)
+}
+
+func TestCreateLinks_invalidLink(t *testing.T) {
+	s, a := createServer()
+	batch := &storetesting.MockBatch{}
+	batch.MockCreateLink.Fn = func(l *cs.Link) (*types.Bytes32, error) { return l.Hash() }
+	a.MockNewBatch.Fn = func() store.Batch { return batch }
+
+	l1, l2 := cstesting.RandomLink(), cstesting.RandomLink()
+	l2.Meta.Process = ""
+	lh1, _ := l1.Hash()
+
+	var body struct {
+		Error  string         `json:"error"`
+		Status int            `json:"status"`
+		Data   []*BatchResult `json:"data"`
+	}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/links/batch", []*cs.Link{l1, l2}, &body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, newErrBatch("").Error(), body.Error)
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, lh1, body.Data[0].LinkHash)
+	assert.Empty(t, body.Data[0].Error)
+	assert.Nil(t, body.Data[1].LinkHash)
+	assert.NotEmpty(t, body.Data[1].Error)
+	assert.Equal(t, 0, a.MockNewBatch.CalledCount, "no batch should be created")
+}
+
+func TestCreateLinks_createError(t *testing.T) {
+	s, a := createServer()
+	batch := &storetesting.MockBatch{}
+	batch.MockCreateLink.Fn = func(l *cs.Link) (*types.Bytes32, error) {
+		if batch.MockCreateLink.CalledCount == 2 {
+			return nil, errors.New("duplicate key")
+		}
+		return l.Hash()
+	}
+	a.MockNewBatch.Fn = func() store.Batch { return batch }
+
+	var body struct {
+		Data []*BatchResult `json:"data"`
+	}
+	links := []*cs.Link{cstesting.RandomLink(), cstesting.RandomLink(), cstesting.RandomLink()}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/links/batch", links, &body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 2, batch.MockCreateLink.CalledCount, "links should not be created after an error")
+	assert.Equal(t, 1, batch.MockRollback.CalledCount)
+	assert.Equal(t, 0, batch.MockWrite.CalledCount)
+	require.Len(t, body.Data, 3)
+	assert.Equal(t, []*BatchResult{{}, {Error: "duplicate key"}, {}}, body.Data)
+}
+
+func TestCreateLinks_partialWrite(t *testing.T) {
+	s, a := createServer()
+	a.MockNewBatch.Fn = func() store.Batch { return nil }
+
+	l1, l2, l3 := cstesting.RandomLink(), cstesting.RandomLink(), cstesting.RandomLink()
+	lh1, _ := l1.Hash()
+	lh2, _ := l2.Hash()
+	a.MockCreateLink.Fn = func(l *cs.Link) (*types.Bytes32, error) {
+		if lh, _ := l.Hash(); *lh == *lh2 {
+			return nil, errors.New("unavailable")
+		}
+		return l.Hash()
+	}
+
+	var body struct {
+		Data []*BatchResult `json:"data"`
+	}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/links/batch", []*cs.Link{l1, l2, l3}, &body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, []*BatchResult{{LinkHash: lh1}, {Error: "unavailable"}, {}}, body.Data)
+}
+
+func TestCreateLinks_size(t *testing.T) {
+	a := &storetesting.MockAdapter{}
+	s := New(a, &Config{MaxBatchSize: 1}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	for _, links := range [][]*cs.Link{{}, {cstesting.RandomLink(), cstesting.RandomLink()}} {
+		w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/links/batch", links, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, 0, a.MockNewBatch.CalledCount)
+}
+
+func TestCreateLinks_auth(t *testing.T) {
+	s, a := createServerWithAuth(jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+		"writer": {Name: "writer", Policy: jsonhttp.Policy{Processes: []string{"allowed"}}},
+	}))
+
+	l1, l2 := cstesting.RandomLink(), cstesting.RandomLink()
+	l1.Meta.Process, l2.Meta.Process = "allowed", "other"
+	body, _ := json.Marshal([]*cs.Link{l1, l2})
+
+	req := httptest.NewRequest("POST", "/links/batch", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer writer")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 0, a.MockNewBatch.CalledCount)
+}
+
 func TestCreateLink_invalidJSON(t *testing.T) {
 	s, a := createServer()
 
@@ -198,6 +364,32 @@ func TestAddEvidence(t *testing.T) {
 	}
 }
 
+func TestAddEvidence_auth(t *testing.T) {
+	s, a := createServerWithAuth(jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+		"generic": {Name: "generic", Policy: jsonhttp.Policy{EvidenceBackends: []string{"generic"}}},
+		"bitcoin": {Name: "bitcoin", Policy: jsonhttp.Policy{EvidenceBackends: []string{"bitcoin"}}},
+	}))
+	a.MockAddEvidence.Fn = func(*types.Bytes32, *cs.Evidence) error { return nil }
+
+	linkHash := testutil.RandomHash().String()
+	post := func(token string) int {
+		body, err := json.Marshal(cstesting.RandomEvidence())
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/evidences/"+linkHash, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, post("bitcoin"), "other backend")
+	assert.Equal(t, 0, a.MockAddEvidence.CalledCount)
+
+	assert.Equal(t, http.StatusOK, post("generic"), "allowed backend")
+	assert.Equal(t, 1, a.MockAddEvidence.CalledCount)
+}
+
 func TestAddEvidence_err(t *testing.T) {
 	s, a := createServer()
 	a.MockAddEvidence.Fn = func(*types.Bytes32, *cs.Evidence) error { return errors.New("test") }
@@ -535,13 +727,344 @@ func TestFindSegments_invalidLinkHashes(t *testing.T) {
 	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
 }
 
+func TestFindSegments_refLinkHash(t *testing.T) {
+	s, a := createServer()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return nil, nil }
+
+	refLinkHash := testutil.RandomHash().String()
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?refLinkHash="+refLinkHash, nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+	assert.Equal(t, refLinkHash, a.MockFindSegments.LastCalledWith.RefLinkHash)
+}
+
+func TestFindSegments_invalidRefLinkHash(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?refLinkHash=3", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrRefLinkHash("").Status(), w.Code)
+	assert.Equal(t, newErrRefLinkHash("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_where(t *testing.T) {
+	s, a := createServer()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return nil, nil }
+
+	q := url.Values{}
+	q.Add("where[]", `state.status == "approved"`)
+	q.Add("where[]", "meta.data.amount >= 1000")
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?"+q.Encode(), nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+
+	f := a.MockFindSegments.LastCalledWith
+	assert.Equal(t, []store.Predicate{
+		{Path: "state.status", Op: store.OpEq, Value: "approved"},
+		{Path: "meta.data.amount", Op: store.OpGte, Value: 1000.0},
+	}, f.Where)
+}
+
+func TestFindSegments_invalidWhere(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?where[]="+url.QueryEscape("meta.priority == 1"), nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrWhere("").Status(), w.Code)
+	assert.Contains(t, body["error"].(string), store.ErrInvalidPredicate.Error())
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_typesActionsTimesAndBackend(t *testing.T) {
+	s, a := createServer()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return nil, nil }
+
+	after := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	before := time.Date(2018, 3, 2, 10, 0, 0, 500, time.UTC)
+
+	q := url.Values{}
+	q.Add("linkTypes[]", "order")
+	q.Add("linkTypes[]", "invoice")
+	q.Add("actions[]", "create")
+	q.Set("createdAfter", after.Format(time.RFC3339))
+	q.Set("createdBefore", before.Format(time.RFC3339Nano))
+	q.Set("evidenceBackend", "bitcoin")
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?"+q.Encode(), nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+
+	f := a.MockFindSegments.LastCalledWith
+	assert.Equal(t, []string{"order", "invoice"}, f.LinkTypes)
+	assert.Equal(t, []string{"create"}, f.Actions)
+	if assert.NotNil(t, f.CreatedAfter) {
+		assert.True(t, after.Equal(*f.CreatedAfter), "f.CreatedAfter")
+	}
+	if assert.NotNil(t, f.CreatedBefore) {
+		assert.True(t, before.Equal(*f.CreatedBefore), "f.CreatedBefore")
+	}
+	assert.Equal(t, "bitcoin", f.EvidenceBackend)
+}
+
+func TestFindSegments_invalidCreatedAfter(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?createdAfter=yesterday", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrCreatedAfter("").Status(), w.Code)
+	assert.Equal(t, newErrCreatedAfter("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_invalidCreatedBefore(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?createdBefore=1520000000", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrCreatedBefore("").Status(), w.Code)
+	assert.Equal(t, newErrCreatedBefore("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_sort(t *testing.T) {
+	s, a := createServer()
+	var s1 cs.SegmentSlice
+	for i := 0; i < 2; i++ {
+		s1 = append(s1, cstesting.RandomSegment())
+	}
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return s1, nil }
+
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?limit=2&sort=-state.amount", nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+	assert.Equal(t, &store.Sort{Field: "state.amount", Desc: true}, a.MockFindSegments.LastCalledWith.Sort)
+	assert.Empty(t, w.Header().Get(NextCursorHeader))
+}
+
+func TestFindSegments_invalidSort(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?sort=-amount", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrSort("").Status(), w.Code)
+	assert.Equal(t, newErrSort("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_cursorWithSort(t *testing.T) {
+	s, a := createServer()
+
+	cursor := store.NewSegmentCursor(cstesting.RandomSegment())
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?sort=createdAt&cursor="+cursor, nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, store.ErrCursorSort.Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestFindSegments_cursor(t *testing.T) {
+	s, a := createServer()
+	var s1 cs.SegmentSlice
+	for i := 0; i < 2; i++ {
+		s1 = append(s1, cstesting.RandomSegment())
+	}
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return s1, nil }
+
+	cursor := store.NewSegmentCursor(cstesting.RandomSegment())
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?limit=2&cursor="+cursor, nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+	assert.Equal(t, cursor, a.MockFindSegments.LastCalledWith.Cursor)
+	assert.Equal(t, store.NewSegmentCursor(s1[1]), w.Header().Get(NextCursorHeader))
+}
+
+func TestFindSegments_lastPage(t *testing.T) {
+	s, a := createServer()
+	s1 := cs.SegmentSlice{cstesting.RandomSegment()}
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return s1, nil }
+
+	var s2 cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?limit=2", nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(NextCursorHeader))
+}
+
+func TestFindSegments_invalidCursor(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments?cursor="+store.NewMapCursor("map"), nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrCursor("").Status(), w.Code)
+	assert.Equal(t, newErrCursor("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestGetAncestors(t *testing.T) {
+	s, a := createServer()
+
+	root := cstesting.NewLinkBuilder().WithoutParent().Build()
+	parent := cstesting.NewLinkBuilder().Branch(root).Build()
+	link := cstesting.NewLinkBuilder().Branch(parent).Build()
+	segments := map[string]*cs.Segment{}
+	for _, l := range []*cs.Link{root, parent, link} {
+		segment := l.Segmentify()
+		segments[segment.GetLinkHashString()] = segment
+	}
+	a.MockGetSegment.Fn = func(linkHash *types.Bytes32) (*cs.Segment, error) {
+		return segments[linkHash.String()], nil
+	}
+
+	linkHash, _ := link.HashString()
+	var got cs.SegmentSlice
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments/"+linkHash+"/ancestors", nil, &got)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, parent.Meta.MapID, got[0].Link.Meta.MapID)
+		assert.Equal(t, link.Meta.PrevLinkHash, got[0].GetLinkHashString())
+		assert.Equal(t, parent.Meta.PrevLinkHash, got[1].GetLinkHashString())
+	}
+}
+
+func TestGetChildren_notFound(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments/"+zeros+"/children", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonhttp.NewErrNotFound("").Status(), w.Code)
+	assert.Equal(t, 1, a.MockGetSegment.CalledCount)
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestGetMapGraph(t *testing.T) {
+	s, a := createServer()
+
+	root := cstesting.NewLinkBuilder().WithoutParent().WithMapID("map").WithPriority(2).Build()
+	child := cstesting.NewLinkBuilder().Branch(root).WithPriority(1).Build()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) {
+		return cs.SegmentSlice{root.Segmentify(), child.Segmentify()}, nil
+	}
+
+	rootHash, _ := root.HashString()
+	childHash, _ := child.HashString()
+
+	var graph struct {
+		Children map[string][]string `json:"children"`
+		Roots    []string            `json:"roots"`
+		Heads    []string            `json:"heads"`
+	}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps/map/graph", nil, &graph)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"map"}, a.MockFindSegments.LastCalledWith.MapIDs)
+	assert.Equal(t, map[string][]string{rootHash: {childHash}}, graph.Children)
+	assert.Equal(t, []string{rootHash}, graph.Roots)
+	assert.Equal(t, []string{childHash}, graph.Heads)
+}
+
+func TestGetMapHeads_notFound(t *testing.T) {
+	s, _ := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps/map/heads", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonhttp.NewErrNotFound("").Status(), w.Code)
+}
+
+func countSegments() cs.SegmentSlice {
+	var segments cs.SegmentSlice
+	for i, mapID := range []string{"one", "one", "two"} {
+		segment := cstesting.RandomSegment()
+		segment.Link.Meta.MapID = mapID
+		segment.Link.Meta.Process = "proc"
+		segment.Link.Meta.Type = []string{"order", "invoice", "order"}[i]
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+func TestCountSegments(t *testing.T) {
+	s, a := createServer()
+	segments := countSegments()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return segments, nil }
+
+	var count Count
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments/count?process=proc&limit=1", nil, &count)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Count{Count: 3}, count)
+	assert.Equal(t, 1, a.MockFindSegments.CalledCount)
+	assert.Equal(t, "proc", a.MockFindSegments.LastCalledWith.Process)
+	assert.Equal(t, store.MaxLimit, a.MockFindSegments.LastCalledWith.Limit)
+}
+
+func TestCountSegments_groupBy(t *testing.T) {
+	s, a := createServer()
+	segments := countSegments()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return segments, nil }
+
+	var count Count
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments/count?groupBy=type", nil, &count)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Count{Count: 3, Groups: map[string]int{"order": 2, "invoice": 1}}, count)
+}
+
+func TestCountSegments_invalidGroupBy(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/segments/count?groupBy=priority", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrGroupBy("").Status(), w.Code)
+	assert.Equal(t, newErrGroupBy("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockFindSegments.CalledCount)
+}
+
+func TestGetStats(t *testing.T) {
+	s, a := createServer()
+	segments := countSegments()
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) { return segments, nil }
+
+	var stats Stats
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/stats?tags[]=tag", nil, &stats)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Stats{
+		Segments:  3,
+		Maps:      2,
+		Processes: map[string]int{"proc": 3},
+		Types:     map[string]int{"order": 2, "invoice": 1},
+	}, stats)
+	assert.Equal(t, []string{"tag"}, a.MockFindSegments.LastCalledWith.Tags)
+}
+
 func TestGetMapIDs(t *testing.T) {
 	s, a := createServer()
 	s1 := []string{"one", "two", "three"}
 	a.MockGetMapIDs.Fn = func(*store.MapFilter) ([]string, error) { return s1, nil }
 
 	var s2 []string
-	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps?offset=20&limit=10", nil, &s2)
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps?offset=20&limit=10&prefix=o&suffix=e", nil, &s2)
 	if err != nil {
 		t.Fatalf("testutil.RequestJSON(): err: %s", err)
 	}
@@ -563,6 +1086,12 @@ func TestGetMapIDs(t *testing.T) {
 	if got, want := p.Limit, 10; got != want {
 		t.Errorf("a.MockGetMapIDs.LastCalledWith.Limit = %d want %d", got, want)
 	}
+	if got, want := p.Prefix, "o"; got != want {
+		t.Errorf("a.MockGetMapIDs.LastCalledWith.Prefix = %q want %q", got, want)
+	}
+	if got, want := p.Suffix, "e"; got != want {
+		t.Errorf("a.MockGetMapIDs.LastCalledWith.Suffix = %q want %q", got, want)
+	}
 }
 
 func TestGetMapIDs_err(t *testing.T) {
@@ -627,6 +1156,32 @@ func TestGetMapIDs_limitTooLarge(t *testing.T) {
 	}
 }
 
+func TestGetMapIDs_cursor(t *testing.T) {
+	s, a := createServer()
+	s1 := []string{"one", "two"}
+	a.MockGetMapIDs.Fn = func(*store.MapFilter) ([]string, error) { return s1, nil }
+
+	cursor := store.NewMapCursor("abc")
+	var s2 []string
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps?limit=2&cursor="+cursor, nil, &s2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, s1, s2)
+	assert.Equal(t, cursor, a.MockGetMapIDs.LastCalledWith.Cursor)
+	assert.Equal(t, store.NewMapCursor("two"), w.Header().Get(NextCursorHeader))
+}
+
+func TestGetMapIDs_invalidCursor(t *testing.T) {
+	s, a := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/maps?cursor=%21", nil, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, newErrCursor("").Status(), w.Code)
+	assert.Equal(t, newErrCursor("").Error(), body["error"].(string))
+	assert.Equal(t, 0, a.MockGetMapIDs.CalledCount)
+}
+
 func TestNotFound(t *testing.T) {
 	s, _ := createServer()
 
@@ -727,3 +1282,253 @@ func TestGetSocket(t *testing.T) {
 		t.Fatalf("saved segment not broadcasted")
 	}
 }
+
+func TestGetSocket_filter(t *testing.T) {
+	link1 := cstesting.NewLinkBuilder().WithProcess("p1").Build()
+	link2 := cstesting.NewLinkBuilder().WithProcess("p2").Build()
+	event := store.NewSavedLinks(link1, link2)
+
+	sendChan := make(chan chan *store.Event)
+	readyChan := make(chan struct{})
+	doneChan := make(chan struct{})
+
+	conn := jsonwstesting.MockConn{}
+	conn.MockReadJSON.Fn = func(interface{}) error {
+		readyChan <- struct{}{}
+		return nil
+	}
+	conn.MockWriteJSON.Fn = func(interface{}) error {
+		doneChan <- struct{}{}
+		return nil
+	}
+
+	upgradeHandle := func(w http.ResponseWriter, r *http.Request, h http.Header) (jsonws.PingableConn, error) {
+		return &conn, nil
+	}
+
+	a := &storetesting.MockAdapter{}
+	a.MockAddStoreEventChannel.Fn = func(c chan *store.Event) {
+		sendChan <- c
+	}
+
+	s := New(a, &Config{}, &jsonhttp.Config{}, &jsonws.BasicConfig{
+		UpgradeHandle: upgradeHandle,
+	}, &jsonws.BufferedConnConfig{
+		Size:         256,
+		WriteTimeout: 10 * time.Second,
+		PongTimeout:  70 * time.Second,
+		PingInterval: time.Minute,
+		MaxMsgSize:   1024,
+	})
+
+	go s.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer s.Shutdown(ctx)
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/websocket?process=p2", nil)
+	go s.getWebSocket(w, r, nil)
+
+	select {
+	case c := <-sendChan:
+		select {
+		case <-readyChan:
+		case <-time.After(time.Second):
+			t.Fatalf("connection ready timeout")
+		}
+		c <- event
+	case <-time.After(time.Second):
+		t.Fatalf("save channel not added")
+	}
+
+	select {
+	case <-doneChan:
+		msg := conn.MockWriteJSON.LastCalledWith.(*jsonws.Message)
+		assert.Equal(t, string(store.SavedLinks), msg.Type)
+		assert.Equal(t, uint64(1), msg.Seq)
+		assert.Equal(t, []*cs.Link{link2}, msg.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("saved segment not broadcasted")
+	}
+}
+
+func TestGetSocket_invalidSince(t *testing.T) {
+	s, _ := createServer()
+
+	var body map[string]interface{}
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/websocket?since=-1", nil, &body)
+	if err != nil {
+		t.Fatalf("testutil.RequestJSON(): err: %s", err)
+	}
+
+	if got, want := w.Code, newErrSince("").Status(); got != want {
+		t.Errorf("w.Code = %d want %d", got, want)
+	}
+	if got, want := body["error"].(string), newErrSince("").Error(); got != want {
+		t.Errorf(`body["error"] = %q want %q`, got, want)
+	}
+}
+
+func readStreamEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	e := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "r.ReadString()")
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(e) > 0 {
+				return e
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		require.Len(t, parts, 2, line)
+		e[parts[0]] = parts[1]
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	link1 := cstesting.NewLinkBuilder().WithProcess("p1").Build()
+	link2 := cstesting.NewLinkBuilder().WithProcess("p2").Build()
+
+	sendChan := make(chan chan *store.Event)
+	a := &storetesting.MockAdapter{}
+	a.MockAddStoreEventChannel.Fn = func(c chan *store.Event) {
+		sendChan <- c
+	}
+
+	s := New(a, &Config{JournalSize: 8}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	go s.Start()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var c chan *store.Event
+	select {
+	case c = <-sendChan:
+	case <-time.After(time.Second):
+		t.Fatalf("save channel not added")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	openStream := func(path, lastEventID string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set(jsonhttp.LastEventIDHeader, lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		return res
+	}
+
+	res := openStream("/events", "")
+	defer res.Body.Close()
+	body := bufio.NewReader(res.Body)
+
+	c <- store.NewSavedLinks(link1)
+	c <- store.NewSavedLinks(link2)
+
+	e := readStreamEvent(t, body)
+	assert.Equal(t, "1", e["id"])
+	assert.Equal(t, string(store.SavedLinks), e["event"])
+	e = readStreamEvent(t, body)
+	assert.Equal(t, "2", e["id"])
+
+	t.Run("Resume with filter", func(t *testing.T) {
+		res := openStream("/events?process=p2", "0")
+		defer res.Body.Close()
+
+		e := readStreamEvent(t, bufio.NewReader(res.Body))
+		assert.Equal(t, "2", e["id"])
+
+		var links []*cs.Link
+		require.NoError(t, json.Unmarshal([]byte(e["data"]), &links))
+		require.Len(t, links, 1)
+		assert.Equal(t, "p2", links[0].Meta.Process)
+	})
+
+	t.Run("Events lost", func(t *testing.T) {
+		res := openStream("/events", "42")
+		defer res.Body.Close()
+
+		e := readStreamEvent(t, bufio.NewReader(res.Body))
+		assert.Equal(t, EventsLost, e["event"])
+		assert.Equal(t, "2", e["id"])
+	})
+}
+
+func TestGetEvents_defaultConfig(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the default write timeout")
+	}
+
+	sendChan := make(chan chan *store.Event)
+	a := &storetesting.MockAdapter{}
+	a.MockAddStoreEventChannel.Fn = func(c chan *store.Event) {
+		sendChan <- c
+	}
+
+	s := New(a, &Config{
+		StoreEventsChanSize: DefaultStoreEventsChanSize,
+		JournalSize:         DefaultJournalSize,
+		MaxBatchSize:        DefaultMaxBatchSize,
+	}, &jsonhttp.Config{
+		ReadTimeout:    jsonhttp.DefaultReadTimeout,
+		WriteTimeout:   jsonhttp.DefaultWriteTimeout,
+		MaxHeaderBytes: jsonhttp.DefaultMaxHeaderBytes,
+	}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	go s.Start()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(l)
+
+	var c chan *store.Event
+	select {
+	case c = <-sendChan:
+	case <-time.After(time.Second):
+		t.Fatalf("save channel not added")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body := bufio.NewReader(res.Body)
+
+	c <- store.NewSavedLinks(cstesting.RandomLink())
+	e := readStreamEvent(t, body)
+	assert.Equal(t, "1", e["id"])
+
+	// The stream must outlive the write timeout of the server.
+	time.Sleep(jsonhttp.DefaultWriteTimeout + time.Second)
+
+	c <- store.NewSavedLinks(cstesting.RandomLink())
+	e = readStreamEvent(t, body)
+	assert.Equal(t, "2", e["id"])
+}
+
+func TestGetEvents_invalidLastEventID(t *testing.T) {
+	s, _ := createServer()
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set(jsonhttp.LastEventIDHeader, "abc")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
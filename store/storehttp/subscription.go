@@ -0,0 +1,313 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storehttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// subscription is the state of a web socket connection or of an event
+// stream. Web socket connections are tagged with their subscription, so
+// messages can be broadcasted to each subscription with its own filtered
+// data.
+type subscription struct {
+	// filter is nil if the client wants all the events.
+	filter *store.SegmentFilter
+	// since is the sequence number of the last event received by the
+	// client, if resume is true.
+	since  uint64
+	resume bool
+	// events receives the events of an event stream. It is nil for web
+	// socket connections.
+	events chan *jsonhttp.StreamEvent
+}
+
+// resolvedEvent is a store event along with the links of its evidences, so
+// that they can be filtered without querying the store in the loop.
+type resolvedEvent struct {
+	event *store.Event
+	links map[string]*cs.Link
+}
+
+// parseSubscription parses the filter and the sequence number of a web
+// socket or event stream request. Filters use the same query parameters as /segments.
+func parseSubscription(r *http.Request) (*subscription, error) {
+	var sub subscription
+
+	q := r.URL.Query()
+	if since := q.Get("since"); since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return nil, newErrSince("")
+		}
+		sub.since, sub.resume = seq, true
+		q.Del("since")
+	}
+
+	if len(q) == 0 {
+		return &sub, nil
+	}
+
+	filter, err := parseSegmentFilter(r)
+	if err != nil {
+		return nil, err
+	}
+	sub.filter = filter
+
+	return &sub, nil
+}
+
+// resolve gets the links of the evidences of store events before passing
+// them to the loop.
+func (s *Server) resolve() {
+	defer close(s.resolvedChan)
+
+	for event := range s.storeEventsChan {
+		r := &resolvedEvent{event: event}
+		if event.EventType == store.SavedEvidences {
+			r.links = s.getEvidenceLinks(event)
+		}
+		s.resolvedChan <- r
+	}
+}
+
+// Web socket and event stream loop.
+func (s *Server) loop() {
+	defer close(s.loopDone)
+
+	subs := map[*subscription]struct{}{}
+
+	for {
+		select {
+		case r, ok := <-s.resolvedChan:
+			if !ok {
+				for sub := range subs {
+					s.unsubscribe(subs, sub)
+				}
+				return
+			}
+			e := &jsonhttp.StreamEvent{
+				Type: string(r.event.EventType),
+				Data: r.event.Data,
+			}
+			s.journal.Add(e)
+			atomic.StoreUint64(&s.seq, e.ID)
+			if r.links != nil {
+				s.evidenceLinks[e.ID] = r.links
+			}
+			s.pruneEvidenceLinks()
+			for sub := range subs {
+				s.send(subs, sub, e)
+			}
+		case sub := <-s.subChan:
+			subs[sub] = struct{}{}
+			if sub.resume {
+				events, ok := s.journal.Since(sub.since)
+				if !ok {
+					s.send(subs, sub, &jsonhttp.StreamEvent{
+						ID:   s.journal.LastID(),
+						Type: EventsLost,
+					})
+				}
+				for _, e := range events {
+					s.send(subs, sub, e)
+				}
+			}
+		case sub := <-s.unsubChan:
+			delete(subs, sub)
+		}
+	}
+}
+
+// send sends the part of an event that matches a subscription.
+func (s *Server) send(subs map[*subscription]struct{}, sub *subscription, e *jsonhttp.StreamEvent) {
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	data := e.Data
+	if e.Type != EventsLost {
+		if data = s.filterEvent(sub.filter, e); data == nil {
+			return
+		}
+	}
+
+	if sub.events == nil {
+		s.ws.Broadcast(&jsonws.Message{
+			Type: e.Type,
+			Data: data,
+			Seq:  e.ID,
+		}, sub)
+		return
+	}
+
+	select {
+	case sub.events <- &jsonhttp.StreamEvent{ID: e.ID, Type: e.Type, Data: data}:
+	default:
+		// The client is too slow, so end its stream. It can resume it
+		// using the ID of the last event it received.
+		s.unsubscribe(subs, sub)
+	}
+}
+
+// unsubscribe removes a subscription and ends its event stream.
+func (s *Server) unsubscribe(subs map[*subscription]struct{}, sub *subscription) {
+	delete(subs, sub)
+	if sub.events != nil {
+		close(sub.events)
+	}
+}
+
+// filterEvent returns the data of an event that matches a filter, or nil if
+// nothing matches.
+func (s *Server) filterEvent(filter *store.SegmentFilter, e *jsonhttp.StreamEvent) interface{} {
+	if filter == nil {
+		return e.Data
+	}
+
+	event := &store.Event{EventType: store.EventType(e.Type), Data: e.Data}
+	event = event.Filter(filter, func(linkHash string) *cs.Link {
+		return s.evidenceLink(e.ID, linkHash)
+	})
+	if event == nil {
+		return nil
+	}
+
+	return event.Data
+}
+
+// evidenceLink returns the link an evidence event refers to. Links are
+// kept while the event is in the journal.
+func (s *Server) evidenceLink(id uint64, linkHash string) *cs.Link {
+	return s.evidenceLinks[id][linkHash]
+}
+
+// getEvidenceLinks returns the links of the evidences of a SavedEvidences
+// event. Links that cannot be found are nil.
+func (s *Server) getEvidenceLinks(event *store.Event) map[string]*cs.Link {
+	evidences, _ := event.Data.(map[string]*cs.Evidence)
+	links := make(map[string]*cs.Link, len(evidences))
+
+	for linkHash := range evidences {
+		var link *cs.Link
+		if lh, err := types.NewBytes32FromString(linkHash); err == nil {
+			segment, err := s.adapter.GetSegment(context.Background(), lh)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"linkHash": linkHash,
+					"error":    err,
+				}).Warn("Failed to get the segment of an evidence event")
+			} else if segment != nil {
+				link = &segment.Link
+			}
+		}
+		links[linkHash] = link
+	}
+
+	return links
+}
+
+// pruneEvidenceLinks drops the cached links of the event that left the
+// journal. Without a journal, it drops those of the previous event.
+func (s *Server) pruneEvidenceLinks() {
+	keep := uint64(s.journalSize)
+	if keep == 0 {
+		keep = 1
+	}
+	if lastID := s.journal.LastID(); lastID > keep {
+		delete(s.evidenceLinks, lastID-keep)
+	}
+}
+
+// subscribe registers a subscription until the given function returns.
+func (s *Server) subscribe(sub *subscription, serve func()) {
+	defer func() {
+		select {
+		case s.unsubChan <- sub:
+		case <-s.loopDone:
+		}
+	}()
+
+	serve()
+}
+
+func (s *Server) getWebSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sub, err := parseSubscription(r)
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+
+	s.subscribe(sub, func() {
+		s.ws.HandleRegistered(w, r, func(conn *jsonws.BufferedConn) {
+			s.ws.Tag(conn, sub)
+			select {
+			case s.subChan <- sub:
+			case <-s.loopDone:
+			}
+		})
+	})
+}
+
+func (s *Server) getEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sub, err := parseSubscription(r)
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+
+	lastEventID, ok, err := jsonhttp.LastEventID(r)
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+	if ok {
+		sub.since, sub.resume = lastEventID, true
+	}
+
+	sub.events = make(chan *jsonhttp.StreamEvent, s.journalSize+streamChanSize)
+
+	select {
+	case s.subChan <- sub:
+	case <-s.loopDone:
+		renderErr(w, jsonhttp.NewErrHTTP("server is shutting down", http.StatusServiceUnavailable))
+		return
+	}
+
+	s.subscribe(sub, func() {
+		jsonhttp.ServeEventStream(w, r, sub.events, jsonhttp.DefaultStreamHeartbeatInterval)
+	})
+}
+
+// renderErr renders an error from a raw handler.
+func renderErr(w http.ResponseWriter, err error) {
+	e, ok := err.(jsonhttp.ErrHTTP)
+	if !ok {
+		e = jsonhttp.NewErrInternalServer("")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.Error(w, string(e.JSONMarshal()), e.Status())
+}
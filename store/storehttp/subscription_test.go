@@ -0,0 +1,92 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storehttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterEvent_evidences(t *testing.T) {
+	s, a := createServer()
+
+	link1 := cstesting.NewLinkBuilder().WithProcess("p1").Build()
+	link2 := cstesting.NewLinkBuilder().WithProcess("p2").Build()
+	lh1, _ := link1.Hash()
+	lh2, _ := link2.Hash()
+	segments := map[types.Bytes32]*cs.Segment{
+		*lh1: link1.Segmentify(),
+		*lh2: link2.Segmentify(),
+	}
+	a.MockGetSegment.Fn = func(linkHash *types.Bytes32) (*cs.Segment, error) {
+		return segments[*linkHash], nil
+	}
+
+	e1 := cstesting.RandomEvidence()
+	e2 := cstesting.RandomEvidence()
+	event := store.NewSavedEvidences()
+	event.AddSavedEvidence(lh1, e1)
+	event.AddSavedEvidence(lh2, e2)
+	e := &jsonhttp.StreamEvent{Type: string(event.EventType), Data: event.Data}
+	s.journal.Add(e)
+	s.evidenceLinks[e.ID] = s.getEvidenceLinks(event)
+	assert.Equal(t, 2, a.MockGetSegment.CalledCount, "a.MockGetSegment.CalledCount")
+
+	data := s.filterEvent(&store.SegmentFilter{Process: "p2"}, e)
+	assert.Equal(t, map[string]*cs.Evidence{lh2.String(): e2}, data)
+
+	data = s.filterEvent(&store.SegmentFilter{Process: "p3"}, e)
+	assert.Nil(t, data)
+	assert.Equal(t, 2, a.MockGetSegment.CalledCount, "links should be resolved once")
+
+	data = s.filterEvent(nil, e)
+	assert.Equal(t, event.Data, data)
+}
+
+func TestLoop_resolvesLinksOutsideLoop(t *testing.T) {
+	s, a := createServer()
+
+	release := make(chan struct{})
+	a.MockGetSegment.Fn = func(*types.Bytes32) (*cs.Segment, error) {
+		<-release
+		return nil, nil
+	}
+
+	go s.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer s.Shutdown(ctx)
+	defer cancel()
+	defer close(release)
+
+	event := store.NewSavedEvidences()
+	event.AddSavedEvidence(testutil.RandomHash(), cstesting.RandomEvidence())
+	s.storeEventsChan <- event
+
+	// Subscribing must not wait for the link of the evidence.
+	select {
+	case s.subChan <- &subscription{}:
+	case <-time.After(time.Second):
+		t.Fatal("subscription blocked by a store query")
+	}
+}
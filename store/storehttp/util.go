@@ -17,6 +17,7 @@ package storehttp
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/stratumn/go-indigocore/store"
 	"github.com/stratumn/go-indigocore/types"
@@ -37,8 +38,17 @@ func parseSegmentFilter(r *http.Request) (*store.SegmentFilter, error) {
 		process         = q.Get("process")
 		prevLinkHashStr = q.Get(prevLinkHashKey)
 		tags            = append(q["tags[]"], q["tags%5B%5D"]...)
+		whereStr        = append(q["where[]"], q["where%5B%5D"]...)
+		linkTypes       = append(q["linkTypes[]"], q["linkTypes%5B%5D"]...)
+		actions         = append(q["actions[]"], q["actions%5B%5D"]...)
+		evidenceBackend = q.Get("evidenceBackend")
+		refLinkHash     = q.Get("refLinkHash")
 		prevLinkHash    *string
 		linkHashes      []string
+		where           []store.Predicate
+		createdAfter    *time.Time
+		createdBefore   *time.Time
+		sortOrder       *store.Sort
 	)
 
 	if _, exists := q[prevLinkHashKey]; exists {
@@ -51,6 +61,10 @@ func parseSegmentFilter(r *http.Request) (*store.SegmentFilter, error) {
 		}
 	}
 
+	if _, err := pagination.SegmentCursor(); err != nil {
+		return nil, newErrCursor("")
+	}
+
 	if len(linkHashesStr) > 0 {
 		for _, lh := range linkHashesStr {
 			_, err := types.NewBytes32FromString(lh)
@@ -61,13 +75,60 @@ func parseSegmentFilter(r *http.Request) (*store.SegmentFilter, error) {
 		}
 	}
 
+	if refLinkHash != "" {
+		if _, err := types.NewBytes32FromString(refLinkHash); err != nil {
+			return nil, newErrRefLinkHash("")
+		}
+	}
+
+	if s := q.Get("createdAfter"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, newErrCreatedAfter("")
+		}
+		createdAfter = &t
+	}
+
+	if s := q.Get("createdBefore"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, newErrCreatedBefore("")
+		}
+		createdBefore = &t
+	}
+
+	if s := q.Get("sort"); s != "" {
+		if sortOrder, err = store.ParseSort(s); err != nil {
+			return nil, newErrSort("")
+		}
+		if pagination.Cursor != "" && !sortOrder.IsDefault() {
+			return nil, newErrCursor(store.ErrCursorSort.Error())
+		}
+	}
+
+	for _, w := range whereStr {
+		p, err := store.ParsePredicate(w)
+		if err != nil {
+			return nil, newErrWhere(err.Error())
+		}
+		where = append(where, *p)
+	}
+
 	return &store.SegmentFilter{
-		Pagination:   *pagination,
-		MapIDs:       mapIDs,
-		Process:      process,
-		PrevLinkHash: prevLinkHash,
-		LinkHashes:   linkHashes,
-		Tags:         tags,
+		Pagination:      *pagination,
+		MapIDs:          mapIDs,
+		Process:         process,
+		PrevLinkHash:    prevLinkHash,
+		LinkHashes:      linkHashes,
+		RefLinkHash:     refLinkHash,
+		Tags:            tags,
+		Where:           where,
+		LinkTypes:       linkTypes,
+		Actions:         actions,
+		CreatedAfter:    createdAfter,
+		CreatedBefore:   createdBefore,
+		EvidenceBackend: evidenceBackend,
+		Sort:            sortOrder,
 	}, nil
 }
 
@@ -77,11 +138,17 @@ func parseMapFilter(r *http.Request) (*store.MapFilter, error) {
 		return nil, err
 	}
 
-	var process = r.URL.Query().Get("process")
+	if _, err := pagination.MapCursor(); err != nil {
+		return nil, newErrCursor("")
+	}
+
+	q := r.URL.Query()
 
 	return &store.MapFilter{
 		Pagination: *pagination,
-		Process:    process,
+		Process:    q.Get("process"),
+		Prefix:     q.Get("prefix"),
+		Suffix:     q.Get("suffix"),
 	}, nil
 }
 
@@ -112,5 +179,6 @@ func parsePagination(r *http.Request) (*store.Pagination, error) {
 	return &store.Pagination{
 		Offset: offset,
 		Limit:  limit,
+		Cursor: q.Get("cursor"),
 	}, nil
 }
@@ -23,8 +23,12 @@ import (
 )
 
 func createServer() (*Server, *storetesting.MockAdapter) {
+	return createServerWithAuth(nil)
+}
+
+func createServerWithAuth(authenticator jsonhttp.Authenticator) (*Server, *storetesting.MockAdapter) {
 	a := &storetesting.MockAdapter{}
-	s := New(a, &Config{}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{
+	s := New(a, &Config{}, &jsonhttp.Config{Authenticator: authenticator}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{
 		Size:         256,
 		WriteTimeout: 10 * time.Second,
 		PongTimeout:  70 * time.Second,
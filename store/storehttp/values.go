@@ -0,0 +1,131 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storehttp
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/monitoring"
+
+	"go.opencensus.io/trace"
+)
+
+// Value is the body of the key-value store routes. Value is nil if the key
+// is not set.
+type Value struct {
+	Value []byte `json:"value"`
+}
+
+// valuePrefix is prepended to the keys of the key-value store routes. The
+// values stored by the server itself, such as the webhook endpoints and
+// their secrets or the state of TMPop, are therefore out of reach.
+const valuePrefix = "storehttp:value:"
+
+func parseKey(p httprouter.Params) ([]byte, error) {
+	key, err := hex.DecodeString(p.ByName("key"))
+	if err != nil || len(key) == 0 {
+		return nil, newErrKey("")
+	}
+	return append([]byte(valuePrefix), key...), nil
+}
+
+// authorizeAdmin returns a forbidden error if the client that sent a request
+// doesn't have an admin policy.
+func authorizeAdmin(ctx context.Context, span *trace.Span) error {
+	err := jsonhttp.AuthorizeAdmin(ctx)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+	}
+	return err
+}
+
+func (s *Server) getValue(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/getValue")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	key, err := parseKey(p)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, err
+	}
+
+	value, err := s.kv.GetValue(ctx, key)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	return &Value{Value: value}, nil
+}
+
+func (s *Server) setValue(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/setValue")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	key, err := parseKey(p)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, err
+	}
+
+	var value Value
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, jsonhttp.NewErrBadRequest(err.Error())
+	}
+
+	if err := s.kv.SetValue(ctx, key, value.Value); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	return "ok", nil
+}
+
+func (s *Server) deleteValue(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "storehttp/deleteValue")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	key, err := parseKey(p)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, err
+	}
+
+	value, err := s.kv.DeleteValue(ctx, key)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return nil, err
+	}
+
+	return &Value{Value: value}, nil
+}
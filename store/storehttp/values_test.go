@@ -0,0 +1,111 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/dummystore"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValues(t *testing.T) {
+	a := dummystore.New(&dummystore.Config{})
+	s := New(a, &Config{KeyValueStore: true}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	var value Value
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/values/6b6579", nil, &value)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, value.Value)
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "PUT", "/values/6b6579", &Value{Value: []byte("value")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored, err := a.GetValue(context.Background(), []byte(valuePrefix+"key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), stored)
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "GET", "/values/6b6579", nil, &value)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []byte("value"), value.Value)
+
+	value = Value{}
+	w, err = testutil.RequestJSON(s.ServeHTTP, "DELETE", "/values/6b6579", nil, &value)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []byte("value"), value.Value)
+
+	stored, err = a.GetValue(context.Background(), []byte(valuePrefix+"key"))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestValues_reservedKeys(t *testing.T) {
+	a := dummystore.New(&dummystore.Config{})
+	s := New(a, &Config{KeyValueStore: true}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	require.NoError(t, a.SetValue(context.Background(), []byte("key"), []byte("secret")))
+
+	var value Value
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/values/6b6579", nil, &value)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, value.Value)
+}
+
+func TestValues_auth(t *testing.T) {
+	s := New(dummystore.New(&dummystore.Config{}), &Config{KeyValueStore: true}, &jsonhttp.Config{
+		Authenticator: jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+			"writer": {Name: "writer"},
+			"admin":  {Name: "admin", Policy: jsonhttp.Policy{Admin: true}},
+		}),
+	}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	get := func(token string) int {
+		req := httptest.NewRequest("GET", "/values/6b6579", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("writer"))
+	assert.Equal(t, http.StatusOK, get("admin"))
+}
+
+func TestValues_invalidKey(t *testing.T) {
+	s := New(dummystore.New(&dummystore.Config{}), &Config{KeyValueStore: true}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/values/key", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValues_disabled(t *testing.T) {
+	s := New(dummystore.New(&dummystore.Config{}), &Config{}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/values/6b6579", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storehttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/jsonws"
+	"github.com/stratumn/go-indigocore/store/storetesting"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createServerWithWebhooks() (*Server, *webhook.Dispatcher) {
+	d := webhook.New(&webhook.Config{}, nil)
+	s := New(&storetesting.MockAdapter{}, &Config{Webhooks: d}, &jsonhttp.Config{}, &jsonws.BasicConfig{}, &jsonws.BufferedConnConfig{})
+	return s, d
+}
+
+func TestWebhooks(t *testing.T) {
+	s, d := createServerWithWebhooks()
+
+	var endpoint webhook.Endpoint
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/webhooks", &webhook.Endpoint{URL: "http://localhost/hook"}, &endpoint)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, d.GetEndpoints(), 1)
+	assert.Equal(t, endpoint.ID, d.GetEndpoints()[0].ID)
+}
+
+func TestWebhooks_disabled(t *testing.T) {
+	s, _ := createServer()
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/webhooks", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShutdown_webhooks(t *testing.T) {
+	s, _ := createServerWithWebhooks()
+	go s.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	select {
+	case <-s.webhooksDone:
+	default:
+		t.Error("webhooks dispatcher not stopped")
+	}
+}
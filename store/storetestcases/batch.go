@@ -76,6 +76,25 @@ func (f Factory) TestBatch(t *testing.T) {
 		assert.EqualValues(t, *link, found.Link, "Link should be found in adapter after a Write")
 	})
 
+	t.Run("Rollback should discard the batch", func(t *testing.T) {
+		ctx = context.Background()
+		b := initBatch(t, a)
+		rollbacker, ok := b.(store.BatchRollbacker)
+		if !ok {
+			t.Skip("batch cannot be rolled back")
+		}
+
+		linkHash, err := b.CreateLink(ctx, cstesting.RandomLink())
+		assert.NoError(t, err, "b.CreateLink()")
+
+		err = rollbacker.Rollback(ctx)
+		assert.NoError(t, err, "b.Rollback()")
+
+		found, err := a.GetSegment(ctx, linkHash)
+		assert.NoError(t, err, "a.GetSegment()")
+		assert.Nil(t, found, "Link should not be found in adapter after a Rollback")
+	})
+
 	t.Run("Finding segments should find in both batch and underlying store", func(t *testing.T) {
 		ctx = context.Background()
 		b := initBatch(t, a)
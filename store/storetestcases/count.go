@@ -0,0 +1,97 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCountSegments tests counting segments, natively if the store
+// implements store.SegmentCounter.
+func (f Factory) TestCountSegments(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	for i := 0; i < 5; i++ {
+		createRandomLink(a, func(l *cs.Link) {
+			l.Meta.MapID = []string{"map1", "map2"}[i%2]
+			l.Meta.Process = "count"
+			l.Meta.Type = []string{"order", "invoice", ""}[i%3]
+			l.Meta.Tags = []string{"all"}
+		})
+	}
+	createRandomLink(a, func(l *cs.Link) {
+		l.Meta.MapID = "map3"
+		l.Meta.Process = "other"
+		l.Meta.Type = "order"
+	})
+
+	ctx := context.Background()
+
+	t.Run("all", func(t *testing.T) {
+		count, err := store.CountSegments(ctx, a, &store.SegmentFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 6, count)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		count, err := store.CountSegments(ctx, a, &store.SegmentFilter{
+			Pagination: store.Pagination{Limit: 1},
+			Tags:       []string{"all"},
+			LinkTypes:  []string{"order"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	tests := []struct {
+		groupBy string
+		filter  *store.SegmentFilter
+		want    map[string]int
+	}{
+		{store.GroupByMapID, &store.SegmentFilter{}, map[string]int{"map1": 3, "map2": 2, "map3": 1}},
+		{store.GroupByProcess, &store.SegmentFilter{}, map[string]int{"count": 5, "other": 1}},
+		{store.GroupByType, &store.SegmentFilter{}, map[string]int{"order": 3, "invoice": 2, "": 1}},
+		{store.GroupByType, &store.SegmentFilter{Process: "count"}, map[string]int{"order": 2, "invoice": 2, "": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run("by "+tt.groupBy, func(t *testing.T) {
+			counts, err := store.CountSegmentsBy(ctx, a, tt.filter, tt.groupBy)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, counts)
+		})
+	}
+
+	t.Run("invalid group", func(t *testing.T) {
+		_, err := store.CountSegmentsBy(ctx, a, &store.SegmentFilter{}, "priority")
+		assert.Error(t, err)
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		count, err := store.CountMaps(ctx, a, &store.SegmentFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		count, err = store.CountMaps(ctx, a, &store.SegmentFilter{Process: "other"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createdAtDelay is the time waited between links in tests of the bounds on
+// creation times. It must be larger than the precision of the stores.
+const createdAtDelay = 50 * time.Millisecond
+
+func verifyLinkHashes(t *testing.T, err error, slice cs.SegmentSlice, links ...*cs.Link) {
+	verifyResultsCount(t, err, slice, len(links))
+
+	var want []string
+	for _, l := range links {
+		linkHash, err := l.HashString()
+		require.NoError(t, err, "l.HashString()")
+		want = append(want, linkHash)
+	}
+
+	var got []string
+	for _, s := range slice {
+		got = append(got, s.GetLinkHashString())
+	}
+
+	assert.ElementsMatch(t, want, got, "Invalid link hashes")
+}
+
+// TestFindSegmentsLinkTypesAndActions tests finding segments by link types and
+// actions.
+func (f Factory) TestFindSegmentsLinkTypesAndActions(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	order := createRandomLink(a, func(l *cs.Link) {
+		l.Meta.Type = "order"
+		l.Meta.Action = "create"
+	})
+	invoice := createRandomLink(a, func(l *cs.Link) {
+		l.Meta.Type = "invoice"
+		l.Meta.Action = "create"
+	})
+	payment := createRandomLink(a, func(l *cs.Link) {
+		l.Meta.Type = "invoice"
+		l.Meta.Action = "pay"
+	})
+
+	tests := []struct {
+		name      string
+		linkTypes []string
+		actions   []string
+		want      []*cs.Link
+	}{
+		{"one type", []string{"order"}, nil, []*cs.Link{order}},
+		{"several types", []string{"order", "invoice"}, nil, []*cs.Link{order, invoice, payment}},
+		{"unknown type", []string{"receipt"}, nil, nil},
+		{"one action", nil, []string{"pay"}, []*cs.Link{payment}},
+		{"several actions", nil, []string{"create", "pay"}, []*cs.Link{order, invoice, payment}},
+		{"type and action", []string{"invoice"}, []string{"create"}, []*cs.Link{invoice}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := a.FindSegments(context.Background(), &store.SegmentFilter{
+				Pagination: store.Pagination{Limit: store.DefaultLimit},
+				LinkTypes:  tt.linkTypes,
+				Actions:    tt.actions,
+			})
+			verifyLinkHashes(t, err, slice, tt.want...)
+		})
+	}
+}
+
+// TestFindSegmentsCreatedAt tests finding segments by the time at which they
+// were saved.
+func (f Factory) TestFindSegmentsCreatedAt(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	start := time.Now()
+	time.Sleep(createdAtDelay)
+	first := createRandomLink(a, nil)
+	time.Sleep(createdAtDelay)
+	middle := time.Now()
+	time.Sleep(createdAtDelay)
+	second := createRandomLink(a, nil)
+	time.Sleep(createdAtDelay)
+	end := time.Now()
+
+	tests := []struct {
+		name   string
+		after  *time.Time
+		before *time.Time
+		want   []*cs.Link
+	}{
+		{"after start", &start, nil, []*cs.Link{first, second}},
+		{"after middle", &middle, nil, []*cs.Link{second}},
+		{"after end", &end, nil, nil},
+		{"before start", nil, &start, nil},
+		{"before middle", nil, &middle, []*cs.Link{first}},
+		{"before end", nil, &end, []*cs.Link{first, second}},
+		{"between start and middle", &start, &middle, []*cs.Link{first}},
+		{"between middle and end", &middle, &end, []*cs.Link{second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := a.FindSegments(context.Background(), &store.SegmentFilter{
+				Pagination:    store.Pagination{Limit: store.DefaultLimit},
+				CreatedAfter:  tt.after,
+				CreatedBefore: tt.before,
+			})
+			verifyLinkHashes(t, err, slice, tt.want...)
+		})
+	}
+}
+
+// TestFindSegmentsEvidenceBackend tests finding segments by the backend of
+// their evidences.
+func (f Factory) TestFindSegmentsEvidenceBackend(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	ctx := context.Background()
+	addEvidences := func(l *cs.Link, evidences ...cs.Evidence) {
+		linkHash, err := l.Hash()
+		require.NoError(t, err, "l.Hash()")
+		for i := range evidences {
+			require.NoError(t, a.AddEvidence(ctx, linkHash, &evidences[i]), "a.AddEvidence()")
+		}
+	}
+
+	dummy := createRandomLink(a, nil)
+	addEvidences(dummy, cs.Evidence{Backend: "dummy", Provider: "1"})
+
+	both := createRandomLink(a, nil)
+	addEvidences(both, cs.Evidence{Backend: "dummy", Provider: "1"}, cs.Evidence{Backend: "batch", Provider: "2"})
+
+	createRandomLink(a, nil)
+
+	tests := []struct {
+		backend string
+		want    []*cs.Link
+	}{
+		{"dummy", []*cs.Link{dummy, both}},
+		{"batch", []*cs.Link{both}},
+		{"bcbatch", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			slice, err := a.FindSegments(ctx, &store.SegmentFilter{
+				Pagination:      store.Pagination{Limit: store.DefaultLimit},
+				EvidenceBackend: tt.backend,
+			})
+			verifyLinkHashes(t, err, slice, tt.want...)
+			for _, s := range slice {
+				assert.NotEmpty(t, s.Meta.FindEvidences(tt.backend), "s.Meta.FindEvidences()")
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"io/ioutil"
 	"log"
+	"sort"
 	"sync/atomic"
 	"testing"
 
@@ -137,6 +138,46 @@ func (f Factory) TestFindSegments(t *testing.T) {
 		verifyPriorityOrdering(t, slice)
 	})
 
+	t.Run("Should support cursor pagination", func(t *testing.T) {
+		ctx := context.Background()
+		filter := &store.SegmentFilter{
+			Pagination: store.Pagination{
+				Limit: testPageSize,
+			},
+		}
+
+		var all cs.SegmentSlice
+		for {
+			slice, err := a.FindSegments(ctx, filter)
+			require.NoError(t, err)
+			all = append(all, slice...)
+
+			if filter.Cursor = filter.NextSegmentsCursor(slice); filter.Cursor == "" {
+				break
+			}
+		}
+
+		verifyResultsCount(t, nil, all, segmentsTotalCount)
+		assert.True(t, sort.IsSorted(all), "Invalid ordering")
+
+		seen := map[string]bool{}
+		for _, s := range all {
+			assert.False(t, seen[s.GetLinkHashString()], "Duplicate segment")
+			seen[s.GetLinkHashString()] = true
+		}
+	})
+
+	t.Run("Should reject an invalid cursor", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := a.FindSegments(ctx, &store.SegmentFilter{
+			Pagination: store.Pagination{
+				Limit:  testPageSize,
+				Cursor: store.NewMapCursor("map1"),
+			},
+		})
+		assert.Error(t, err)
+	})
+
 	t.Run("Should return no results for invalid tag filter", func(t *testing.T) {
 		ctx := context.Background()
 		slice, err := a.FindSegments(ctx, &store.SegmentFilter{
@@ -97,6 +97,34 @@ func (f Factory) TestGetMapIDs(t *testing.T) {
 		assert.Equal(t, 0, len(slice), "Invalid number of map IDs found")
 	})
 
+	t.Run("Map ID cursor pagination should work", func(t *testing.T) {
+		ctx := context.Background()
+		filter := &store.MapFilter{
+			Pagination: store.Pagination{Limit: 2},
+		}
+
+		var all []string
+		for {
+			slice, err := a.GetMapIDs(ctx, filter)
+			require.NoError(t, err)
+			all = append(all, slice...)
+
+			if filter.Cursor = filter.NextMapIDsCursor(slice); filter.Cursor == "" {
+				break
+			}
+		}
+
+		assert.Equal(t, []string{"map0", "map1", "map2", "other-map2"}, all)
+	})
+
+	t.Run("Map ID pagination should reject an invalid cursor", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := a.GetMapIDs(ctx, &store.MapFilter{
+			Pagination: store.Pagination{Limit: 2, Cursor: "invalid"},
+		})
+		assert.Error(t, err)
+	})
+
 	t.Run("Filtering by process should work", func(t *testing.T) {
 		ctx := context.Background()
 		processName := processNames[0]
@@ -0,0 +1,121 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGraph tests traversing the links of maps, natively if the store
+// implements store.GraphReader.
+func (f Factory) TestGraph(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	mapID := testutil.RandomString(12)
+	other := createRandomLink(a, nil)
+	missing := cstesting.RandomLink()
+
+	root := createLink(a, cstesting.NewLinkBuilder().WithoutParent().WithMapID(mapID).WithPriority(1).Build(), nil)
+	child := createLinkBranch(a, root, func(l *cs.Link) { l.Meta.Priority = 2 })
+	branch := createLinkBranch(a, root, func(l *cs.Link) { l.Meta.Priority = 3 })
+	grandchild := createLink(a, cstesting.NewLinkBuilder().Branch(child).WithPriority(4).WithRef(other).WithRef(missing).Build(), nil)
+
+	ctx := context.Background()
+
+	linkHash := func(link *cs.Link) string {
+		linkHash, err := link.HashString()
+		require.NoError(t, err, "link.HashString()")
+		return linkHash
+	}
+
+	t.Run("children", func(t *testing.T) {
+		h, _ := root.Hash()
+		children, err := store.GetChildren(ctx, a, h)
+		verifyLinkOrder(t, err, children, branch, child)
+
+		h, _ = grandchild.Hash()
+		children, err = store.GetChildren(ctx, a, h)
+		verifyLinkOrder(t, err, children)
+	})
+
+	t.Run("ancestors", func(t *testing.T) {
+		h, _ := grandchild.Hash()
+		ancestors, err := store.GetAncestors(ctx, a, h)
+		verifyLinkOrder(t, err, ancestors, child, root)
+
+		h, _ = root.Hash()
+		ancestors, err = store.GetAncestors(ctx, a, h)
+		verifyLinkOrder(t, err, ancestors)
+
+		h, _ = missing.Hash()
+		ancestors, err = store.GetAncestors(ctx, a, h)
+		assert.NoError(t, err)
+		assert.Nil(t, ancestors)
+	})
+
+	t.Run("heads", func(t *testing.T) {
+		heads, err := store.GetMapHeads(ctx, a, mapID)
+		verifyLinkOrder(t, err, heads, grandchild, branch)
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		graph, err := store.GetMapGraph(ctx, a, mapID)
+		verifyLinkOrder(t, err, graph.Segments, grandchild, branch, child, root)
+		assert.Equal(t, []string{linkHash(root)}, graph.Roots, "graph.Roots")
+		assert.Equal(t, []string{linkHash(grandchild), linkHash(branch)}, graph.Heads, "graph.Heads")
+		assert.Equal(t, map[string][]string{
+			linkHash(root):  {linkHash(branch), linkHash(child)},
+			linkHash(child): {linkHash(grandchild)},
+		}, graph.Children, "graph.Children")
+	})
+
+	t.Run("references", func(t *testing.T) {
+		h, _ := grandchild.Hash()
+		refs, err := store.GetReferences(ctx, a, h)
+		verifyLinkOrder(t, err, refs, other)
+	})
+
+	t.Run("referenced by", func(t *testing.T) {
+		referrer := createLink(a, cstesting.NewLinkBuilder().WithPriority(5).WithRef(other).Build(), nil)
+
+		h, _ := other.Hash()
+		segments, err := store.GetReferencedBy(ctx, a, h)
+		verifyLinkOrder(t, err, segments, referrer, grandchild)
+
+		segments, err = a.FindSegments(ctx, &store.SegmentFilter{
+			Pagination:  store.Pagination{Limit: store.DefaultLimit},
+			MapIDs:      []string{mapID},
+			RefLinkHash: h.String(),
+		})
+		verifyLinkOrder(t, err, segments, grandchild)
+
+		h, _ = missing.Hash()
+		segments, err = store.GetReferencedBy(ctx, a, h)
+		verifyLinkOrder(t, err, segments, grandchild)
+
+		h, _ = root.Hash()
+		segments, err = store.GetReferencedBy(ctx, a, h)
+		verifyLinkOrder(t, err, segments)
+	})
+}
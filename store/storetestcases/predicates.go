@@ -0,0 +1,104 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFindSegmentsWhere tests finding segments with predicates on their state
+// and meta data.
+func (f Factory) TestFindSegmentsWhere(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	createRandomLink(a, func(l *cs.Link) {
+		l.State = map[string]interface{}{
+			"status": "approved",
+			"count":  3,
+			"signed": true,
+			"nested": map[string]interface{}{"kind": "order"},
+		}
+		l.Meta.Data = map[string]interface{}{"amount": 1500}
+	})
+
+	createRandomLink(a, func(l *cs.Link) {
+		l.State = map[string]interface{}{
+			"status": "pending",
+			"count":  10,
+			"signed": false,
+		}
+		l.Meta.Data = map[string]interface{}{"amount": 500.5}
+	})
+
+	createRandomLink(a, func(l *cs.Link) {
+		l.State = map[string]interface{}{
+			"status": "approved",
+			"count":  "3",
+		}
+		l.Meta.Data = map[string]interface{}{"amount": 1000, "note": nil}
+	})
+
+	createRandomLink(a, nil)
+
+	tests := []struct {
+		name  string
+		where []string
+		want  int
+	}{
+		{"equal string", []string{`state.status == "approved"`}, 2},
+		{"equal number", []string{`state.count == 3`}, 1},
+		{"equal boolean", []string{`state.signed == false`}, 1},
+		{"nested path", []string{`state.nested.kind == "order"`}, 1},
+		{"greater than", []string{`meta.data.amount > 1000`}, 1},
+		{"greater than or equal", []string{`meta.data.amount >= 1000`}, 2},
+		{"less than", []string{`meta.data.amount < 1000`}, 1},
+		{"less than or equal", []string{`meta.data.amount <= 1000`}, 2},
+		{"compare numbers only", []string{`state.count > 2`}, 2},
+		{"compare strings", []string{`state.status > "b"`}, 1},
+		{"in", []string{`state.status in ["pending", "rejected"]`}, 1},
+		{"in mixed types", []string{`state.count in [10, "3"]`}, 2},
+		{"exists", []string{`state.nested exists`}, 1},
+		{"exists ignores null", []string{`meta.data.note exists`}, 0},
+		{"several predicates", []string{`state.status == "approved"`, `meta.data.amount > 1000`}, 1},
+		{"unknown path", []string{`state.unknown == "approved"`}, 0},
+		{"resists to SQL injections", []string{`state.status == "approved' OR 'bar' = 'bar'--"`}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &store.SegmentFilter{
+				Pagination: store.Pagination{Limit: store.DefaultLimit},
+			}
+			for _, w := range tt.where {
+				p, err := store.ParsePredicate(w)
+				require.NoError(t, err, "store.ParsePredicate()")
+				filter.Where = append(filter.Where, *p)
+			}
+
+			slice, err := a.FindSegments(context.Background(), filter)
+			verifyResultsCount(t, err, slice, tt.want)
+			for _, s := range slice {
+				assert.True(t, filter.Match(s), "filter.Match()")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verifyLinkOrder(t *testing.T, err error, slice cs.SegmentSlice, links ...*cs.Link) {
+	verifyResultsCount(t, err, slice, len(links))
+
+	var want []string
+	for _, l := range links {
+		linkHash, err := l.HashString()
+		require.NoError(t, err, "l.HashString()")
+		want = append(want, linkHash)
+	}
+
+	var got []string
+	for _, s := range slice {
+		got = append(got, s.GetLinkHashString())
+	}
+
+	assert.Equal(t, want, got, "Invalid order")
+}
+
+// TestFindSegmentsSort tests sorting segments.
+func (f Factory) TestFindSegmentsSort(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	newLink := func(priority float64, amount interface{}) *cs.Link {
+		time.Sleep(createdAtDelay)
+		return createRandomLink(a, func(l *cs.Link) {
+			l.Meta.Priority = priority
+			if amount != nil {
+				l.State["amount"] = amount
+			}
+		})
+	}
+
+	l1 := newLink(2, 10.0)
+	l2 := newLink(1, "abc")
+	l3 := newLink(3, 2.5)
+	l4 := newLink(0, nil)
+	l5 := newLink(5, "b")
+
+	// h contains the links sorted by link hash.
+	h := []*cs.Link{l1, l2, l3, l4, l5}
+	sort.Slice(h, func(i, j int) bool {
+		h1, _ := h[i].HashString()
+		h2, _ := h[j].HashString()
+		return h1 < h2
+	})
+
+	tests := []struct {
+		name   string
+		sort   *store.Sort
+		offset int
+		limit  int
+		want   []*cs.Link
+	}{
+		{"default", nil, 0, store.DefaultLimit, []*cs.Link{l5, l3, l1, l2, l4}},
+		{"priority", &store.Sort{Field: store.SortByPriority}, 0, store.DefaultLimit, []*cs.Link{l4, l2, l1, l3, l5}},
+		{"-priority", &store.Sort{Field: store.SortByPriority, Desc: true}, 0, store.DefaultLimit, []*cs.Link{l5, l3, l1, l2, l4}},
+		{"createdAt", &store.Sort{Field: store.SortByCreatedAt}, 0, store.DefaultLimit, []*cs.Link{l1, l2, l3, l4, l5}},
+		{"-createdAt", &store.Sort{Field: store.SortByCreatedAt, Desc: true}, 0, store.DefaultLimit, []*cs.Link{l5, l4, l3, l2, l1}},
+		{"linkHash", &store.Sort{Field: store.SortByLinkHash}, 0, store.DefaultLimit, []*cs.Link{h[0], h[1], h[2], h[3], h[4]}},
+		{"-linkHash", &store.Sort{Field: store.SortByLinkHash, Desc: true}, 0, store.DefaultLimit, []*cs.Link{h[4], h[3], h[2], h[1], h[0]}},
+		{"state", &store.Sort{Field: "state.amount"}, 0, store.DefaultLimit, []*cs.Link{l3, l1, l2, l5, l4}},
+		{"-state", &store.Sort{Field: "state.amount", Desc: true}, 0, store.DefaultLimit, []*cs.Link{l1, l3, l5, l2, l4}},
+		{"paginated", &store.Sort{Field: "state.amount"}, 1, 2, []*cs.Link{l1, l2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := a.FindSegments(context.Background(), &store.SegmentFilter{
+				Pagination: store.Pagination{Offset: tt.offset, Limit: tt.limit},
+				Sort:       tt.sort,
+			})
+			verifyLinkOrder(t, err, slice, tt.want...)
+		})
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		_, err := a.FindSegments(context.Background(), &store.SegmentFilter{
+			Pagination: store.Pagination{
+				Limit:  store.DefaultLimit,
+				Cursor: store.NewSegmentCursor(l1.Segmentify()),
+			},
+			Sort: &store.Sort{Field: store.SortByCreatedAt},
+		})
+		assert.EqualError(t, errors.Cause(err), store.ErrCursorSort.Error())
+	})
+}
@@ -56,7 +56,15 @@ func (f Factory) RunStoreTests(t *testing.T) {
 	t.Run("Test store events", f.TestStoreEvents)
 	t.Run("Test store info", f.TestGetInfo)
 	t.Run("Test finding segments", f.TestFindSegments)
+	t.Run("Test finding segments with predicates", f.TestFindSegmentsWhere)
+	t.Run("Test finding segments by link types and actions", f.TestFindSegmentsLinkTypesAndActions)
+	t.Run("Test finding segments by creation time", f.TestFindSegmentsCreatedAt)
+	t.Run("Test finding segments by evidence backend", f.TestFindSegmentsEvidenceBackend)
+	t.Run("Test sorting segments", f.TestFindSegmentsSort)
+	t.Run("Test counting segments", f.TestCountSegments)
+	t.Run("Test traversing maps", f.TestGraph)
 	t.Run("Test getting map IDs", f.TestGetMapIDs)
+	t.Run("Test streaming segments", f.TestStreamSegments)
 	t.Run("Test getting segments", f.TestGetSegment)
 	t.Run("Test creating links", f.TestCreateLink)
 	t.Run("Test batch implementation", f.TestBatch)
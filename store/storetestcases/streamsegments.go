@@ -0,0 +1,109 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetestcases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamSegments tests what happens when you stream segments.
+// Stores that do not implement store.SegmentStreamer are tested with the
+// fallback iterator.
+func (f Factory) TestStreamSegments(t *testing.T) {
+	a := f.initAdapter(t)
+	defer f.freeAdapter(a)
+
+	// Use more links than the batch sizes of the iterators.
+	linksCount := store.MaxLimit + 50
+	linkHashes := make(map[string]bool, linksCount)
+	wantEvidences := 0
+
+	for i := 0; i < linksCount; i++ {
+		l := cstesting.NewLinkBuilder().WithMapID("stream").Build()
+		linkHash, err := a.CreateLink(context.Background(), l)
+		require.NoError(t, err, "a.CreateLink()")
+		linkHashes[linkHash.String()] = true
+
+		if i%50 == 0 {
+			for j := 0; j < 3; j++ {
+				e := &cs.Evidence{Backend: "dummy", Provider: fmt.Sprintf("%d", j)}
+				require.NoError(t, a.AddEvidence(context.Background(), linkHash, e), "a.AddEvidence()")
+				wantEvidences++
+			}
+		}
+	}
+
+	createRandomLink(a, nil)
+
+	t.Run("Streams all matching segments", func(t *testing.T) {
+		ctx := context.Background()
+		it, err := store.StreamSegments(ctx, a, &store.SegmentFilter{
+			Pagination: store.Pagination{Limit: 1},
+			MapIDs:     []string{"stream"},
+		})
+		require.NoError(t, err, "store.StreamSegments()")
+		defer it.Close()
+
+		seen := make(map[string]bool, linksCount)
+		evidencesCount := 0
+		for it.Next() {
+			s := it.Segment()
+			require.NotNil(t, s)
+
+			linkHash := s.GetLinkHashString()
+			assert.True(t, linkHashes[linkHash], "Unexpected segment")
+			assert.False(t, seen[linkHash], "Duplicate segment")
+			seen[linkHash] = true
+			evidencesCount += len(s.Meta.Evidences)
+		}
+
+		assert.NoError(t, it.Err(), "it.Err()")
+		assert.Len(t, seen, linksCount, "Invalid number of segments")
+		assert.Equal(t, wantEvidences, evidencesCount, "Invalid number of evidences")
+	})
+
+	t.Run("Returns no segments when nothing matches", func(t *testing.T) {
+		ctx := context.Background()
+		it, err := store.StreamSegments(ctx, a, &store.SegmentFilter{
+			MapIDs: []string{"not-found"},
+		})
+		require.NoError(t, err, "store.StreamSegments()")
+
+		assert.False(t, it.Next(), "it.Next()")
+		assert.NoError(t, it.Err(), "it.Err()")
+		assert.NoError(t, it.Close(), "it.Close()")
+		assert.NoError(t, it.Close(), "it.Close()")
+	})
+
+	t.Run("Stops after being closed", func(t *testing.T) {
+		ctx := context.Background()
+		it, err := store.StreamSegments(ctx, a, &store.SegmentFilter{
+			MapIDs: []string{"stream"},
+		})
+		require.NoError(t, err, "store.StreamSegments()")
+
+		assert.True(t, it.Next(), "it.Next()")
+		assert.NoError(t, it.Close(), "it.Close()")
+		assert.False(t, it.Next(), "it.Next()")
+	})
+}
@@ -23,7 +23,8 @@ import (
 )
 
 // MockBatch is used to mock a batch.
-// It implements github.com/stratumn/go-indigocore/store.Batch
+// It implements github.com/stratumn/go-indigocore/store.Batch and
+// github.com/stratumn/go-indigocore/store.BatchRollbacker.
 type MockBatch struct {
 	// The mock for the CreateLink function.
 	MockCreateLink MockBatchCreateLink
@@ -31,6 +32,9 @@ type MockBatch struct {
 	// The mock for the Write function.
 	MockWrite MockBatchWrite
 
+	// The mock for the Rollback function.
+	MockRollback MockBatchRollback
+
 	// The mock for the GetSegment function.
 	MockGetSegment MockBatchGetSegment
 
@@ -65,6 +69,15 @@ type MockBatchWrite struct {
 	Fn func() error
 }
 
+// MockBatchRollback mocks the Rollback function.
+type MockBatchRollback struct {
+	// The number of times the function was called.
+	CalledCount int
+
+	// An optional implementation of the function.
+	Fn func() error
+}
+
 // MockBatchGetSegment mocks the GetSegment function.
 type MockBatchGetSegment struct {
 	// The number of times the function was called.
@@ -115,6 +128,16 @@ func (a *MockBatch) Write(ctx context.Context) error {
 	return nil
 }
 
+// Rollback implements github.com/stratumn/go-indigocore/store.BatchRollbacker.Rollback.
+func (a *MockBatch) Rollback(ctx context.Context) error {
+	a.MockRollback.CalledCount++
+
+	if a.MockRollback.Fn != nil {
+		return a.MockRollback.Fn()
+	}
+	return nil
+}
+
 // GetSegment delegates the call to a underlying store
 func (a *MockBatch) GetSegment(ctx context.Context, linkHash *types.Bytes32) (*cs.Segment, error) {
 	a.MockGetSegment.CalledCount++
@@ -0,0 +1,228 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+// Types of problems found while verifying segments.
+const (
+	// VerifyHashMismatch is used when the link hash stored in a segment
+	// is not the hash of its link.
+	VerifyHashMismatch = "hash_mismatch"
+
+	// VerifyInvalidLink is used when a link does not pass validation
+	// (format, signatures or references).
+	VerifyInvalidLink = "invalid_link"
+
+	// VerifyMissingParent is used when the previous link hash of a segment
+	// cannot be found in the store.
+	VerifyMissingParent = "missing_parent"
+
+	// VerifyInvalidEvidence is used when the proof of an evidence is
+	// missing or cannot be verified.
+	VerifyInvalidEvidence = "invalid_evidence"
+)
+
+// VerifyError describes a problem found in a segment.
+type VerifyError struct {
+	LinkHash string `json:"linkHash"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+}
+
+// Fork describes a segment that has more than one child.
+type Fork struct {
+	LinkHash string   `json:"linkHash"`
+	Children []string `json:"children"`
+}
+
+// VerifyReport is the result of the verification of the segments matching
+// a filter. It is meant to be serialized to JSON.
+type VerifyReport struct {
+	// Valid is true if no error and no orphan was found.
+	Valid bool `json:"valid"`
+
+	// Number of segments and evidences that were checked.
+	SegmentsCount  int `json:"segmentsCount"`
+	EvidencesCount int `json:"evidencesCount"`
+
+	// Link hashes of segments without a previous link.
+	Roots []string `json:"roots"`
+
+	// Link hashes of segments without children.
+	Heads []string `json:"heads"`
+
+	// Link hashes of segments whose previous link could not be found.
+	Orphans []string `json:"orphans"`
+
+	// Segments that have more than one child. Forks are allowed by the
+	// protocol so they do not invalidate a report.
+	Forks []Fork `json:"forks"`
+
+	// Problems found in segments.
+	Errors []VerifyError `json:"errors"`
+}
+
+// ProofVerifier verifies proofs that cannot be trusted on their own, for
+// instance because they must be checked against trusted certificates or a
+// blockchain.
+type ProofVerifier interface {
+	// VerifyProof returns an error if the proof of the given link hash is
+	// not valid.
+	VerifyProof(proof cs.Proof, linkHash *types.Bytes32) error
+}
+
+// VerifySegments checks all the segments matching the filter and returns a report.
+// The pagination of the filter is ignored.
+//
+// For every segment it re-hashes the link, validates it (including
+// signatures and references), checks that its previous link exists and
+// verifies the proof of each evidence. It also detects orphans and forks.
+//
+// The proofs of the evidences whose backend has a verifier are verified by
+// it, other proofs are verified on their own.
+//
+// An error is only returned if the store cannot be read.
+func VerifySegments(ctx context.Context, reader SegmentReader, filter *SegmentFilter, verifiers map[string]ProofVerifier) (*VerifyReport, error) {
+	it, err := StreamSegments(ctx, reader, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	report := &VerifyReport{
+		Roots:   []string{},
+		Heads:   []string{},
+		Orphans: []string{},
+		Forks:   []Fork{},
+		Errors:  []VerifyError{},
+	}
+
+	parents := map[string]string{}
+	children := map[string][]string{}
+
+	addError := func(linkHash, errType, format string, args ...interface{}) {
+		report.Errors = append(report.Errors, VerifyError{
+			LinkHash: linkHash,
+			Type:     errType,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for it.Next() {
+		segment := it.Segment()
+		report.SegmentsCount++
+
+		linkHash, err := segment.Link.Hash()
+		if err != nil {
+			addError(segment.GetLinkHashString(), VerifyInvalidLink, "could not hash link: %s", err)
+			continue
+		}
+
+		linkHashStr := linkHash.String()
+		if segment.GetLinkHashString() != linkHashStr {
+			addError(segment.GetLinkHashString(), VerifyHashMismatch, "link hash should be %s", linkHashStr)
+		}
+
+		if err := segment.Link.Validate(ctx, reader.GetSegment); err != nil {
+			addError(linkHashStr, VerifyInvalidLink, "%s", err)
+		}
+
+		for _, evidence := range segment.Meta.Evidences {
+			report.EvidencesCount++
+			if evidence.Proof == nil {
+				addError(linkHashStr, VerifyInvalidEvidence, "%s evidence from %s has no proof", evidence.Backend, evidence.Provider)
+			} else if verifier, ok := verifiers[evidence.Backend]; ok {
+				if err := verifier.VerifyProof(evidence.Proof, linkHash); err != nil {
+					addError(linkHashStr, VerifyInvalidEvidence, "%s evidence from %s could not be verified: %s", evidence.Backend, evidence.Provider, err)
+				}
+			} else if !evidence.Proof.Verify(linkHash) {
+				addError(linkHashStr, VerifyInvalidEvidence, "%s evidence from %s could not be verified", evidence.Backend, evidence.Provider)
+			}
+		}
+
+		prevLinkHash := segment.Link.Meta.PrevLinkHash
+		parents[linkHashStr] = prevLinkHash
+		if prevLinkHash != "" {
+			children[prevLinkHash] = append(children[prevLinkHash], linkHashStr)
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	for linkHash, prevLinkHash := range parents {
+		if prevLinkHash == "" {
+			report.Roots = append(report.Roots, linkHash)
+		} else if _, ok := parents[prevLinkHash]; !ok {
+			// The parent may not match the filter, so look it up.
+			found, err := hasSegment(ctx, reader, prevLinkHash)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				report.Orphans = append(report.Orphans, linkHash)
+				addError(linkHash, VerifyMissingParent, "previous link %s not found", prevLinkHash)
+			}
+		}
+
+		if len(children[linkHash]) == 0 {
+			report.Heads = append(report.Heads, linkHash)
+		}
+	}
+
+	for linkHash, c := range children {
+		if _, ok := parents[linkHash]; ok && len(c) > 1 {
+			sort.Strings(c)
+			report.Forks = append(report.Forks, Fork{LinkHash: linkHash, Children: c})
+		}
+	}
+
+	sort.Strings(report.Roots)
+	sort.Strings(report.Heads)
+	sort.Strings(report.Orphans)
+	sort.Slice(report.Forks, func(i, j int) bool {
+		return report.Forks[i].LinkHash < report.Forks[j].LinkHash
+	})
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].LinkHash < report.Errors[j].LinkHash
+	})
+
+	report.Valid = len(report.Errors) == 0
+
+	return report, nil
+}
+
+func hasSegment(ctx context.Context, reader SegmentReader, linkHashStr string) (bool, error) {
+	linkHash, err := types.NewBytes32FromString(linkHashStr)
+	if err != nil {
+		return false, nil
+	}
+
+	segment, err := reader.GetSegment(ctx, linkHash)
+	if err != nil {
+		return false, err
+	}
+
+	return segment != nil, nil
+}
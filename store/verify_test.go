@@ -0,0 +1,166 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/store/storetesting"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invalidProof struct{}
+
+func (p *invalidProof) Time() uint64            { return 0 }
+func (p *invalidProof) FullProof() []byte       { return nil }
+func (p *invalidProof) Verify(interface{}) bool { return false }
+
+type proofVerifier func(cs.Proof, *types.Bytes32) error
+
+func (v proofVerifier) VerifyProof(proof cs.Proof, linkHash *types.Bytes32) error {
+	return v(proof, linkHash)
+}
+
+func verifySegments(t *testing.T, segments ...*cs.Segment) *store.VerifyReport {
+	return verifySegmentsWith(t, nil, segments...)
+}
+
+func verifySegmentsWith(t *testing.T, verifiers map[string]store.ProofVerifier, segments ...*cs.Segment) *store.VerifyReport {
+	a := &storetesting.MockAdapter{}
+	a.MockFindSegments.Fn = func(*store.SegmentFilter) (cs.SegmentSlice, error) {
+		return segments, nil
+	}
+	a.MockGetSegment.Fn = func(linkHash *types.Bytes32) (*cs.Segment, error) {
+		for _, s := range segments {
+			if s.GetLinkHashString() == linkHash.String() {
+				return s, nil
+			}
+		}
+		return nil, nil
+	}
+
+	report, err := store.VerifySegments(context.Background(), a, &store.SegmentFilter{}, verifiers)
+	require.NoError(t, err, "store.VerifySegments()")
+
+	return report
+}
+
+func TestVerifySegments(t *testing.T) {
+	t.Run("Valid chain", func(t *testing.T) {
+		root := cstesting.NewLinkBuilder().WithoutParent().Build()
+		child := cstesting.NewLinkBuilder().Branch(root).Build()
+
+		report := verifySegments(t, root.Segmentify(), child.Segmentify())
+		assert.True(t, report.Valid)
+		assert.Equal(t, 2, report.SegmentsCount)
+		assert.Equal(t, []string{root.Segmentify().GetLinkHashString()}, report.Roots)
+		assert.Equal(t, []string{child.Segmentify().GetLinkHashString()}, report.Heads)
+		assert.Empty(t, report.Errors)
+	})
+
+	t.Run("Detects forks", func(t *testing.T) {
+		root := cstesting.NewLinkBuilder().WithoutParent().Build()
+		child1 := cstesting.NewLinkBuilder().Branch(root).Build()
+		child2 := cstesting.NewLinkBuilder().Branch(root).Build()
+
+		report := verifySegments(t, root.Segmentify(), child1.Segmentify(), child2.Segmentify())
+		assert.True(t, report.Valid)
+		require.Len(t, report.Forks, 1)
+		assert.Equal(t, root.Segmentify().GetLinkHashString(), report.Forks[0].LinkHash)
+		assert.Len(t, report.Forks[0].Children, 2)
+		assert.Len(t, report.Heads, 2)
+	})
+
+	t.Run("Detects orphans", func(t *testing.T) {
+		orphan := cstesting.NewLinkBuilder().WithPrevLinkHash(testutil.RandomHash().String()).Build()
+
+		report := verifySegments(t, orphan.Segmentify())
+		assert.False(t, report.Valid)
+		assert.Equal(t, []string{orphan.Segmentify().GetLinkHashString()}, report.Orphans)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, store.VerifyMissingParent, report.Errors[0].Type)
+	})
+
+	t.Run("Detects hash mismatches", func(t *testing.T) {
+		s := cstesting.NewLinkBuilder().WithoutParent().Build().Segmentify()
+		s.Meta.LinkHash = testutil.RandomHash().String()
+
+		report := verifySegments(t, s)
+		assert.False(t, report.Valid)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, store.VerifyHashMismatch, report.Errors[0].Type)
+	})
+
+	t.Run("Detects invalid links", func(t *testing.T) {
+		s := cstesting.NewLinkBuilder().WithoutParent().Invalid().Build().Segmentify()
+
+		report := verifySegments(t, s)
+		assert.False(t, report.Valid)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, store.VerifyInvalidLink, report.Errors[0].Type)
+	})
+
+	t.Run("Detects invalid evidences", func(t *testing.T) {
+		s := cstesting.NewLinkBuilder().WithoutParent().Build().Segmentify()
+		s.Meta.Evidences = cs.Evidences{
+			&cs.Evidence{Backend: "dummy", Provider: "1", Proof: &invalidProof{}},
+			&cs.Evidence{Backend: "dummy", Provider: "2"},
+		}
+
+		report := verifySegments(t, s)
+		assert.False(t, report.Valid)
+		assert.Equal(t, 2, report.EvidencesCount)
+		require.Len(t, report.Errors, 2)
+		assert.Equal(t, store.VerifyInvalidEvidence, report.Errors[0].Type)
+		assert.Equal(t, store.VerifyInvalidEvidence, report.Errors[1].Type)
+	})
+
+	t.Run("Uses proof verifiers", func(t *testing.T) {
+		s := cstesting.NewLinkBuilder().WithoutParent().Build().Segmentify()
+		s.Meta.Evidences = cs.Evidences{
+			&cs.Evidence{Backend: "trusted", Provider: "1", Proof: &invalidProof{}},
+			&cs.Evidence{Backend: "untrusted", Provider: "2", Proof: &invalidProof{}},
+		}
+
+		var verified []string
+		verifier := func(valid bool) store.ProofVerifier {
+			return proofVerifier(func(proof cs.Proof, linkHash *types.Bytes32) error {
+				verified = append(verified, linkHash.String())
+				if !valid {
+					return errors.New("untrusted")
+				}
+				return nil
+			})
+		}
+
+		report := verifySegmentsWith(t, map[string]store.ProofVerifier{
+			"trusted":   verifier(true),
+			"untrusted": verifier(false),
+		}, s)
+		assert.False(t, report.Valid)
+		assert.Equal(t, []string{s.GetLinkHashString(), s.GetLinkHashString()}, verified)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, store.VerifyInvalidEvidence, report.Errors[0].Type)
+		assert.Contains(t, report.Errors[0].Message, "untrusted evidence from 2")
+	})
+}
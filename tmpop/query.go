@@ -18,13 +18,14 @@ import "encoding/json"
 
 // Query types.
 const (
-	AddEvidence   = "AddEvidence"
-	FindSegments  = "FindSegments"
-	GetEvidences  = "GetEvidences"
-	GetInfo       = "GetInfo"
-	GetMapIDs     = "GetMapIDs"
-	GetSegment    = "GetSegment"
-	PendingEvents = "PendingEvents"
+	AddEvidence     = "AddEvidence"
+	FindSegments    = "FindSegments"
+	GetEvidences    = "GetEvidences"
+	GetInfo         = "GetInfo"
+	GetMapIDs       = "GetMapIDs"
+	GetReferencedBy = "GetReferencedBy"
+	GetSegment      = "GetSegment"
+	PendingEvents   = "PendingEvents"
 )
 
 // BuildQueryBinary outputs the marshalled Query.
@@ -87,20 +87,41 @@ func (s *State) UpdateValidators(ctx context.Context) {
 	}
 }
 
-// Check checks if creating this link is a valid operation
-func (s *State) Check(ctx context.Context, link *cs.Link) *ABCIError {
-	return s.checkLinkAndAddToBatch(ctx, link, s.checkedLinks)
+// Check checks if creating these links is a valid operation
+func (s *State) Check(ctx context.Context, links ...*cs.Link) *ABCIError {
+	return s.checkLinksAndAddToBatch(ctx, links, s.checkedLinks)
 }
 
-// Deliver adds a link to the list of links to be committed
-func (s *State) Deliver(ctx context.Context, link *cs.Link) *ABCIError {
-	res := s.checkLinkAndAddToBatch(ctx, link, s.deliveredLinks)
+// Deliver adds links to the list of links to be committed
+func (s *State) Deliver(ctx context.Context, links ...*cs.Link) *ABCIError {
+	res := s.checkLinksAndAddToBatch(ctx, links, s.deliveredLinks)
 	if res.IsOK() {
-		s.deliveredLinksList = append(s.deliveredLinksList, link)
+		s.deliveredLinksList = append(s.deliveredLinksList, links...)
 	}
 	return res
 }
 
+// checkLinksAndAddToBatch checks links in order and adds them to the batch
+// only if they are all valid. They are staged in the meantime so that a link
+// can reference the ones before it.
+func (s *State) checkLinksAndAddToBatch(ctx context.Context, links []*cs.Link, batch store.Batch) *ABCIError {
+	staged := bufferedbatch.NewBatch(ctx, batch)
+	for _, link := range links {
+		if res := s.checkLinkAndAddToBatch(ctx, link, staged); !res.IsOK() {
+			return res
+		}
+	}
+
+	if err := staged.Write(ctx); err != nil {
+		return &ABCIError{
+			Code: CodeTypeInternalError,
+			Log:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
 // checkLinkAndAddToBatch validates the link's format and runs the validations (signatures, schema)
 func (s *State) checkLinkAndAddToBatch(ctx context.Context, link *cs.Link, batch store.Batch) *ABCIError {
 	err := link.Validate(ctx, batch.GetSegment)
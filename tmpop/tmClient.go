@@ -45,7 +45,8 @@ type Block struct {
 	Votes []*evidences.TendermintVote
 	// A block at height N contains the validator set for block N-1.
 	Validators *tmtypes.ValidatorSet
-	// The block's transactions.
+	// The block's transactions. The links of a CreateLinks transaction are
+	// split into CreateLink transactions.
 	Txs []*Tx
 }
 
@@ -107,12 +108,19 @@ func (c *TendermintClientWrapper) Block(ctx context.Context, height int64) (*Blo
 
 	for _, tx := range tmBlock.Block.Txs {
 		tmTx, err := unmarshallTx(tx)
-		if !err.IsOK() || tmTx.TxType != CreateLink {
+		if !err.IsOK() || (tmTx.TxType != CreateLink && tmTx.TxType != CreateLinks) {
 			log.Warnf("Could not unmarshall block Tx %+v. Evidence will not be created.", tx)
 			span.Annotatef(nil, "Could not unmarshall block Tx %+v.", tx)
 			continue
 		}
 
+		if tmTx.TxType == CreateLinks {
+			for _, link := range tmTx.Links {
+				block.Txs = append(block.Txs, &Tx{TxType: CreateLink, Link: link})
+			}
+			continue
+		}
+
 		block.Txs = append(block.Txs, tmTx)
 	}
 
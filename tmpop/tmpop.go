@@ -340,6 +340,14 @@ func (t *TMPop) Query(reqQuery abci.RequestQuery) (resQuery abci.ResponseQuery)
 
 		result, err = t.adapter.FindSegments(ctx, filter)
 
+	case GetReferencedBy:
+		linkHash := &types.Bytes32{}
+		if err = linkHash.UnmarshalJSON(reqQuery.Data); err != nil {
+			break
+		}
+
+		result, err = store.GetReferencedBy(ctx, t.adapter, linkHash)
+
 	case GetMapIDs:
 		filter := &store.MapFilter{}
 		if err = json.Unmarshal(reqQuery.Data, filter); err != nil {
@@ -377,7 +385,7 @@ func (t *TMPop) Query(reqQuery abci.RequestQuery) (resQuery abci.ResponseQuery)
 	return
 }
 
-func (t *TMPop) doTx(ctx context.Context, createLink func(context.Context, *cs.Link) *ABCIError, txBytes []byte) *ABCIError {
+func (t *TMPop) doTx(ctx context.Context, createLinks func(context.Context, ...*cs.Link) *ABCIError, txBytes []byte) *ABCIError {
 	if len(txBytes) == 0 {
 		return &ABCIError{
 			Code: CodeTypeValidation,
@@ -392,7 +400,15 @@ func (t *TMPop) doTx(ctx context.Context, createLink func(context.Context, *cs.L
 
 	switch tx.TxType {
 	case CreateLink:
-		return createLink(ctx, tx.Link)
+		return createLinks(ctx, tx.Link)
+	case CreateLinks:
+		if len(tx.Links) == 0 {
+			return &ABCIError{
+				Code: CodeTypeValidation,
+				Log:  "Tx must contain at least one link",
+			}
+		}
+		return createLinks(ctx, tx.Links...)
 	default:
 		return &ABCIError{
 			Code: CodeTypeNotImplemented,
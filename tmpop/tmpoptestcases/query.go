@@ -44,7 +44,7 @@ func (f Factory) TestQuery(t *testing.T) {
 
 	link2 := cstesting.NewLinkBuilder().WithProcess(link1.Meta.Process).Build()
 	linkHash2, _ := link2.Hash()
-	commitLink(t, h, link2, req)
+	req = commitLink(t, h, link2, req)
 
 	t.Run("Info() returns correct last seen height and app hash", func(t *testing.T) {
 		abciInfo := h.Info(abci.RequestInfo{})
@@ -154,6 +154,23 @@ func (f Factory) TestQuery(t *testing.T) {
 		assert.Len(t, events, 0, "Events should not be delivered twice")
 	})
 
+	t.Run("GetReferencedBy()", func(t *testing.T) {
+		refLink := cstesting.NewLinkBuilder().WithRef(link2).Build()
+		refLinkHash, _ := refLink.Hash()
+		commitLink(t, h, refLink, req)
+
+		gots := cs.SegmentSlice{}
+		err := makeQuery(h, tmpop.GetReferencedBy, linkHash2, &gots)
+		assert.NoError(t, err)
+		require.Len(t, gots, 1, "Unexpected number of segments")
+		assert.Equal(t, *refLinkHash, *gots[0].GetLinkHash())
+
+		gots = cs.SegmentSlice{}
+		err = makeQuery(h, tmpop.GetReferencedBy, refLinkHash, &gots)
+		assert.NoError(t, err)
+		assert.Len(t, gots, 0, "Unexpected number of segments")
+	})
+
 	t.Run("Unsupported Query", func(t *testing.T) {
 		q := h.Query(abci.RequestQuery{
 			Path: "Unsupported",
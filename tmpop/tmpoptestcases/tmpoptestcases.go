@@ -117,6 +117,18 @@ func makeCreateLinkTx(t *testing.T, l *cs.Link) []byte {
 	return res
 }
 
+func makeCreateLinksTx(t *testing.T, links ...*cs.Link) []byte {
+	tx := tmpop.Tx{
+		TxType: tmpop.CreateLinks,
+		Links:  links,
+	}
+	res, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
 func makeBeginBlock(appHash []byte, height int64) abci.RequestBeginBlock {
 	return abci.RequestBeginBlock{
 		Hash: []byte{},
@@ -68,6 +68,27 @@ func (f Factory) TestCheckTx(t *testing.T) {
 
 		assert.True(t, res.IsOK(), "Expected CheckTx to return an OK result, got %v", res)
 	})
+
+	t.Run("Check links referencing each other in a single tx returns ok", func(t *testing.T) {
+		link := cstesting.RandomLink()
+		linkHash, _ := link.Hash()
+
+		linkWithRef := cstesting.NewLinkBuilder().WithProcess(link.Meta.Process).Build()
+		linkWithRef.Meta.Refs = []cs.SegmentReference{cs.SegmentReference{
+			Process:  link.Meta.Process,
+			LinkHash: linkHash.String(),
+		}}
+
+		res := h.CheckTx(makeCreateLinksTx(t, link, linkWithRef))
+
+		assert.True(t, res.IsOK(), "Expected CheckTx to return an OK result, got %v", res)
+	})
+
+	t.Run("Check tx without links returns not-ok", func(t *testing.T) {
+		res := h.CheckTx(makeCreateLinksTx(t))
+
+		assert.EqualValues(t, tmpop.CodeTypeValidation, res.Code)
+	})
 }
 
 // TestDeliverTx tests what happens when the ABCI method DeliverTx() is called
@@ -99,6 +120,29 @@ func (f Factory) TestDeliverTx(t *testing.T) {
 
 		assert.EqualValues(t, tmpop.CodeTypeValidation, res.Code)
 	})
+
+	t.Run("Deliver links with an invalid one delivers none of them", func(t *testing.T) {
+		link := cstesting.RandomLink()
+		linkHash, _ := link.Hash()
+
+		invalidLink := cstesting.RandomLink()
+		invalidLink.Meta.Refs = []cs.SegmentReference{cs.SegmentReference{
+			Process:  "proc",
+			LinkHash: "invalidLinkHash",
+		}}
+
+		res := h.DeliverTx(makeCreateLinksTx(t, link, invalidLink))
+		assert.EqualValues(t, tmpop.CodeTypeValidation, res.Code)
+
+		linkWithRef := cstesting.NewLinkBuilder().WithProcess(link.Meta.Process).Build()
+		linkWithRef.Meta.Refs = []cs.SegmentReference{cs.SegmentReference{
+			Process:  link.Meta.Process,
+			LinkHash: linkHash.String(),
+		}}
+		res = h.DeliverTx(makeCreateLinkTx(t, linkWithRef))
+
+		assert.EqualValues(t, tmpop.CodeTypeValidation, res.Code, "the first link should not be delivered")
+	})
 }
 
 // TestCommitTx tests what happens when the ABCI method CommitTx() is called
@@ -120,6 +164,9 @@ func (f Factory) TestCommitTx(t *testing.T) {
 	link2, tx := makeCreateRandomLinkTx(t)
 	h.DeliverTx(tx)
 
+	link3, link4 := cstesting.RandomLink(), cstesting.RandomLink()
+	h.DeliverTx(makeCreateLinksTx(t, link3, link4))
+
 	res := h.Commit()
 	if len(res.GetData()) == 0 {
 		t.Fatalf("Commit failed")
@@ -128,6 +175,8 @@ func (f Factory) TestCommitTx(t *testing.T) {
 	t.Run("Commit correctly saves links and updates app hash", func(t *testing.T) {
 		verifyLinkStored(t, h, link1)
 		verifyLinkStored(t, h, link2)
+		verifyLinkStored(t, h, link3)
+		verifyLinkStored(t, h, link4)
 
 		if bytes.Equal(previousAppHash, res.Data) {
 			t.Errorf("Committed app hash is the same as the previous app hash")
@@ -144,8 +193,10 @@ func (f Factory) TestCommitTx(t *testing.T) {
 		assert.EqualValues(t, store.SavedLinks, savedEvent.EventType)
 
 		savedLinks := savedEvent.Data.([]*cs.Link)
-		require.Len(t, savedLinks, 2, "Invalid number of links")
+		require.Len(t, savedLinks, 4, "Invalid number of links")
 		assert.EqualValues(t, link1, savedLinks[0])
 		assert.EqualValues(t, link2, savedLinks[1])
+		assert.EqualValues(t, link3, savedLinks[2])
+		assert.EqualValues(t, link4, savedLinks[3])
 	})
 }
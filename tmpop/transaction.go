@@ -27,6 +27,10 @@ type TxType byte
 const (
 	// CreateLink characterizes a transaction that creates a new link
 	CreateLink TxType = iota
+
+	// CreateLinks characterizes a transaction that creates several links.
+	// Either all or none of them are created.
+	CreateLinks
 )
 
 // Tx represents a TMPoP transaction
@@ -34,6 +38,10 @@ type Tx struct {
 	TxType   TxType         `json:"type"`
 	Link     *cs.Link       `json:"link"`
 	LinkHash *types.Bytes32 `json:"linkhash"`
+
+	// Links are the links of a CreateLinks transaction, in order. A link
+	// can reference the ones before it.
+	Links []*cs.Link `json:"links,omitempty"`
 }
 
 func unmarshallTx(txBytes []byte) (*Tx, *ABCIError) {
@@ -0,0 +1,53 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmstore
+
+import (
+	"context"
+
+	"github.com/stratumn/go-indigocore/bufferedbatch"
+	"github.com/stratumn/go-indigocore/tmpop"
+)
+
+// Batch is the type that implements github.com/stratumn/go-indigocore/store.Batch.
+// Its links are broadcast in a single TMPop transaction when it is written,
+// so either all or none of them are saved. The transaction must fit in a
+// Tendermint block.
+type Batch struct {
+	*bufferedbatch.Batch
+
+	store *TMStore
+}
+
+// NewBatch creates a new Batch.
+func NewBatch(ctx context.Context, t *TMStore) *Batch {
+	return &Batch{
+		Batch: bufferedbatch.NewBatch(ctx, t),
+		store: t,
+	}
+}
+
+// Write implements github.com/stratumn/go-indigocore/store.Batch.Write.
+func (b *Batch) Write(ctx context.Context) error {
+	if len(b.Links) == 0 {
+		return nil
+	}
+
+	_, err := b.store.broadcastTx(ctx, &tmpop.Tx{
+		TxType: tmpop.CreateLinks,
+		Links:  b.Links,
+	})
+	return err
+}
@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/stratumn/go-indigocore/bufferedbatch"
 	"github.com/stratumn/go-indigocore/cs"
 	"github.com/stratumn/go-indigocore/monitoring"
 	"github.com/stratumn/go-indigocore/store"
@@ -336,7 +335,7 @@ func (t *TMStore) GetMapIDs(ctx context.Context, filter *store.MapFilter) (ids [
 
 // NewBatch implements github.com/stratumn/go-indigocore/store.Adapter.NewBatch.
 func (t *TMStore) NewBatch(ctx context.Context) (store.Batch, error) {
-	return bufferedbatch.NewBatch(ctx, t), nil
+	return NewBatch(ctx, t), nil
 }
 
 func (t *TMStore) broadcastTx(ctx context.Context, tx *tmpop.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
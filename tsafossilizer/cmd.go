@@ -0,0 +1,125 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tsafossilizer
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/batchfossilizer"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	url       string
+	policy    string
+	roots     string
+	interval  time.Duration
+	maxLeaves int
+	path      string
+	archive   bool
+	exitBatch bool
+	fsync     bool
+)
+
+// RegisterFlags registers the flags used by RunWithFlags.
+func RegisterFlags() {
+	flag.StringVar(&url, "tsa", os.Getenv("TSAFOSSILIZER_URL"), "URL of the RFC 3161 timestamp authority")
+	flag.StringVar(&policy, "policy", "", "an optional OID of the policy under which timestamps must be issued")
+	flag.StringVar(&roots, "roots", "", "an optional PEM file of root certificates trusted to issue timestamps")
+	flag.DurationVar(&interval, "interval", batchfossilizer.DefaultInterval, "batch interval")
+	flag.IntVar(&maxLeaves, "maxleaves", batchfossilizer.DefaultMaxLeaves, "maximum number of leaves in a Merkle tree")
+	flag.StringVar(&path, "path", "", "an optional path to store files")
+	flag.BoolVar(&archive, "archive", batchfossilizer.DefaultArchive, "whether to archive completed batches (requires path)")
+	flag.BoolVar(&exitBatch, "exitbatch", batchfossilizer.DefaultStopBatch, "whether to do a batch on exit")
+	flag.BoolVar(&fsync, "fsync", batchfossilizer.DefaultFSync, "whether to fsync after saving a pending hash (requires path)")
+}
+
+// RunWithFlags should be called after RegisterFlags and flag.Parse to initialize
+// a tsafossilizer using flag values.
+func RunWithFlags(ctx context.Context, version, commit string) *Fossilizer {
+	log.Infof("%s v%s@%s", Description, version, commit[:7])
+
+	config := &Config{URL: url}
+
+	if policy != "" {
+		oid, err := parseOID(policy)
+		if err != nil {
+			log.WithField("error", err).Fatal("Invalid policy")
+		}
+		config.Policy = oid
+	}
+
+	if roots != "" {
+		pem, err := ioutil.ReadFile(roots)
+		if err != nil {
+			log.WithField("error", err).Fatal("Failed to read root certificates")
+		}
+		config.Roots = x509.NewCertPool()
+		if !config.Roots.AppendCertsFromPEM(pem) {
+			log.WithField("roots", roots).Fatal("No root certificate found")
+		}
+	}
+
+	a, err := New(config, &batchfossilizer.Config{
+		Version:   version,
+		Commit:    commit,
+		Interval:  interval,
+		MaxLeaves: maxLeaves,
+		Path:      path,
+		Archive:   archive,
+		StopBatch: exitBatch,
+		FSync:     fsync,
+	})
+	if err != nil {
+		log.WithField("error", err).Fatal("Failed to create timestamping batch fossilizer")
+	}
+
+	go func() {
+		if err := a.Start(ctx); err != nil {
+			log.WithField("error", err)
+		}
+	}()
+
+	return a
+}
+
+// parseOID parses a dotted object identifier such as 1.2.3.4.1.
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, errors.Errorf("invalid OID %q", s)
+	}
+
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid OID %q", s)
+		}
+		oid[i] = n
+	}
+
+	return oid, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evidences defines tsafossilizer evidence types.
+package evidences
+
+import (
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+var (
+	// TSAFossilizerName is the name used as the TSAProof backend
+	TSAFossilizerName = "tsa"
+)
+
+var (
+	// ErrInvalidProof is returned when the Merkle path of a proof is not
+	// valid.
+	ErrInvalidProof = errors.New("invalid batch proof")
+
+	// ErrNoRoots is returned when a proof is verified without trusted root
+	// certificates.
+	ErrNoRoots = errors.New("trusted root certificates are required to verify the timestamp token")
+)
+
+// TSAProof implements the Proof interface.
+// It contains a RFC 3161 timestamp token of the Merkle root of a batch.
+type TSAProof struct {
+	Batch batchevidences.BatchProof `json:"batch"`
+	Token []byte                    `json:"token"` // DER encoded TimeStampToken
+}
+
+// Time returns the time at which the timestamp token was issued
+func (p *TSAProof) Time() uint64 {
+	token, err := rfc3161.ParseToken(p.Token)
+	if err != nil {
+		return 0
+	}
+	return uint64(token.Time().Unix())
+}
+
+// FullProof returns a JSON formatted proof
+func (p *TSAProof) FullProof() []byte {
+	bytes, err := json.MarshalIndent(p, "", "   ")
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
+// Verify implements github.com/stratumn/go-indigocore/cs.Proof.Verify.
+// The timestamp token cannot be checked to be issued by a trusted authority
+// without root certificates, so it always returns false. Use VerifyWithRoots
+// or a Verifier instead.
+func (p *TSAProof) Verify(linkHash interface{}) bool {
+	return p.VerifyWithRoots(linkHash, nil) == nil
+}
+
+// VerifyWithRoots checks that the proof of a given linkHash is correct.
+// The Merkle path is verified, then the timestamp token is checked to be
+// correctly signed by an authority trusted by the given roots and to contain
+// the imprint of the Merkle root.
+func (p *TSAProof) VerifyWithRoots(linkHash interface{}, roots *x509.CertPool) error {
+	if !p.Batch.Verify(linkHash) || p.Batch.Root == nil {
+		return ErrInvalidProof
+	}
+
+	if roots == nil {
+		return ErrNoRoots
+	}
+
+	token, err := rfc3161.ParseToken(p.Token)
+	if err != nil {
+		return err
+	}
+
+	if err := token.Verify(p.Batch.Root[:]); err != nil {
+		return err
+	}
+
+	return token.VerifyCertificate(roots)
+}
+
+// Verifier verifies TSA proofs using trusted root certificates.
+// It implements github.com/stratumn/go-indigocore/store.ProofVerifier.
+type Verifier struct {
+	roots *x509.CertPool
+}
+
+// NewVerifier creates a verifier that trusts timestamp tokens issued by
+// the given roots.
+func NewVerifier(roots *x509.CertPool) *Verifier {
+	return &Verifier{roots: roots}
+}
+
+// VerifyProof checks that a TSA proof of a given linkHash is correct.
+func (v *Verifier) VerifyProof(proof cs.Proof, linkHash *types.Bytes32) error {
+	p, ok := proof.(*TSAProof)
+	if !ok {
+		return errors.Errorf("unexpected proof type %T", proof)
+	}
+
+	return p.VerifyWithRoots(linkHash, v.roots)
+}
+
+func init() {
+	cs.DeserializeMethods[TSAFossilizerName] = func(rawProof json.RawMessage) (cs.Proof, error) {
+		p := TSAProof{}
+		if err := json.Unmarshal(rawProof, &p); err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evidences_test
+
+import (
+	"crypto"
+	"encoding/json"
+	"testing"
+	"time"
+
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/tsafossilizer/evidences"
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+	"github.com/stratumn/go-indigocore/tsafossilizer/tsatesting"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stratumn/merkle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createProof(t *testing.T, tsa *tsatesting.TSA) (*types.Bytes32, *evidences.TSAProof) {
+	linkHash := testutil.RandomHash()
+	tree, err := merkle.NewStaticTree([][]byte{linkHash[:], testutil.RandomHash()[:]})
+	require.NoError(t, err, "merkle.NewStaticTree()")
+
+	root := types.NewBytes32FromBytes(tree.Root())
+	req, err := rfc3161.NewRequest(crypto.SHA256, root[:])
+	require.NoError(t, err, "rfc3161.NewRequest()")
+
+	token, err := tsa.Timestamp(req)
+	require.NoError(t, err, "tsa.Timestamp()")
+
+	return linkHash, &evidences.TSAProof{
+		Batch: batchevidences.BatchProof{
+			Timestamp: time.Now().Unix(),
+			Root:      root,
+			Path:      tree.Path(0),
+		},
+		Token: token,
+	}
+}
+
+func TestTSAProof_Time(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	_, p := createProof(t, tsa)
+	assert.InDelta(t, time.Now().Unix(), p.Time(), 2, "p.Time()")
+
+	p.Token = []byte("token")
+	assert.Equal(t, uint64(0), p.Time(), "p.Time()")
+}
+
+func TestTSAProof_FullProof(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	_, p := createProof(t, tsa)
+	e := cs.Evidence{Backend: evidences.TSAFossilizerName, Provider: "tsa", Proof: p}
+
+	js, err := json.Marshal(&e)
+	require.NoError(t, err, "json.Marshal()")
+
+	var got cs.Evidence
+	require.NoError(t, json.Unmarshal(js, &got), "json.Unmarshal()")
+	assert.Equal(t, p, got.Proof, "got.Proof")
+}
+
+func TestTSAProof_Verify(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	linkHash, p := createProof(t, tsa)
+	assert.False(t, p.Verify(linkHash), "p.Verify()")
+}
+
+func TestTSAProof_VerifyWithRoots(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	t.Run("Valid proof", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		assert.NoError(t, p.VerifyWithRoots(linkHash, tsa.Roots()), "p.VerifyWithRoots()")
+	})
+
+	t.Run("Other link hash", func(t *testing.T) {
+		_, p := createProof(t, tsa)
+		err := p.VerifyWithRoots(testutil.RandomHash(), tsa.Roots())
+		assert.EqualError(t, err, evidences.ErrInvalidProof.Error(), "p.VerifyWithRoots()")
+	})
+
+	t.Run("Token of another root", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		_, other := createProof(t, tsa)
+		p.Token = other.Token
+		assert.Error(t, p.VerifyWithRoots(linkHash, tsa.Roots()), "p.VerifyWithRoots()")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		p.Token = []byte("token")
+		assert.Error(t, p.VerifyWithRoots(linkHash, tsa.Roots()), "p.VerifyWithRoots()")
+	})
+
+	t.Run("No roots", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		err := p.VerifyWithRoots(linkHash, nil)
+		assert.EqualError(t, err, evidences.ErrNoRoots.Error(), "p.VerifyWithRoots()")
+	})
+
+	t.Run("Untrusted authority", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+
+		other, err := tsatesting.New()
+		require.NoError(t, err, "tsatesting.New()")
+
+		assert.Error(t, p.VerifyWithRoots(linkHash, other.Roots()), "p.VerifyWithRoots()")
+	})
+}
+
+func TestVerifier_VerifyProof(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	v := evidences.NewVerifier(tsa.Roots())
+
+	t.Run("Valid proof", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		assert.NoError(t, v.VerifyProof(p, linkHash), "v.VerifyProof()")
+	})
+
+	t.Run("Other proof type", func(t *testing.T) {
+		linkHash, p := createProof(t, tsa)
+		assert.Error(t, v.VerifyProof(&p.Batch, linkHash), "v.VerifyProof()")
+	})
+}
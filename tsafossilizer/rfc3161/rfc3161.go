@@ -0,0 +1,343 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rfc3161 implements the parts of the Time-Stamp Protocol (RFC 3161)
+// needed to request, issue and verify timestamp tokens.
+//
+// Only DER encoded messages are supported.
+package rfc3161
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	// Register the hash functions supported by timestamp tokens.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RequestContentType is the MIME type of a timestamp request.
+	RequestContentType = "application/timestamp-query"
+
+	// ResponseContentType is the MIME type of a timestamp response.
+	ResponseContentType = "application/timestamp-reply"
+)
+
+// PKI statuses of a timestamp response.
+const (
+	StatusGranted = iota
+	StatusGrantedWithMods
+	StatusRejection
+	StatusWaiting
+	StatusRevocationWarning
+	StatusRevocationNotification
+)
+
+// Failure reasons of a rejected timestamp request, as bit positions in
+// PKIStatusInfo.FailInfo.
+const (
+	FailureBadAlg              = 0
+	FailureBadRequest          = 2
+	FailureBadDataFormat       = 5
+	FailureTimeNotAvailable    = 14
+	FailureUnacceptedPolicy    = 15
+	FailureUnacceptedExtension = 16
+	FailureAddInfoNotAvailable = 17
+	FailureSystemFailure       = 25
+)
+
+var (
+	// ErrUnsupportedHash is returned when a message uses a hash algorithm
+	// that is not supported.
+	ErrUnsupportedHash = errors.New("unsupported hash algorithm")
+
+	// ErrInvalidRequest is returned when a timestamp request is malformed.
+	ErrInvalidRequest = errors.New("invalid timestamp request")
+
+	// ErrInvalidResponse is returned when a timestamp response is malformed.
+	ErrInvalidResponse = errors.New("invalid timestamp response")
+
+	// ErrInvalidToken is returned when a timestamp token is malformed.
+	ErrInvalidToken = errors.New("invalid timestamp token")
+)
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   oidSHA1,
+	crypto.SHA256: oidSHA256,
+	crypto.SHA384: oidSHA384,
+	crypto.SHA512: oidSHA512,
+}
+
+// hashAlgorithm returns the algorithm identifier of a hash function.
+func hashAlgorithm(h crypto.Hash) (pkix.AlgorithmIdentifier, error) {
+	oid, ok := hashOIDs[h]
+	if !ok || !h.Available() {
+		return pkix.AlgorithmIdentifier{}, ErrUnsupportedHash
+	}
+
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oid,
+		Parameters: asn1.NullRawValue,
+	}, nil
+}
+
+// hashFromAlgorithm returns the hash function of an algorithm identifier.
+func hashFromAlgorithm(algorithm pkix.AlgorithmIdentifier) (crypto.Hash, error) {
+	for h, oid := range hashOIDs {
+		if oid.Equal(algorithm.Algorithm) && h.Available() {
+			return h, nil
+		}
+	}
+
+	return 0, ErrUnsupportedHash
+}
+
+// MessageImprint contains the hash of the timestamped message.
+type MessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// NewMessageImprint hashes a message with the given hash function.
+func NewMessageImprint(h crypto.Hash, message []byte) (MessageImprint, error) {
+	algorithm, err := hashAlgorithm(h)
+	if err != nil {
+		return MessageImprint{}, err
+	}
+
+	hash := h.New()
+	hash.Write(message)
+
+	return MessageImprint{
+		HashAlgorithm: algorithm,
+		HashedMessage: hash.Sum(nil),
+	}, nil
+}
+
+// Hash returns the hash function of the message imprint.
+func (m MessageImprint) Hash() (crypto.Hash, error) {
+	return hashFromAlgorithm(m.HashAlgorithm)
+}
+
+// Matches returns true if the imprint is the hash of the given message.
+func (m MessageImprint) Matches(message []byte) bool {
+	h, err := m.Hash()
+	if err != nil {
+		return false
+	}
+
+	imprint, err := NewMessageImprint(h, message)
+	if err != nil {
+		return false
+	}
+
+	return imprint.Equal(m)
+}
+
+// Equal returns true if both imprints use the same hash algorithm and have
+// the same hashed message.
+func (m MessageImprint) Equal(other MessageImprint) bool {
+	return m.HashAlgorithm.Algorithm.Equal(other.HashAlgorithm.Algorithm) &&
+		string(m.HashedMessage) == string(other.HashedMessage)
+}
+
+// timeStampReq is the ASN.1 structure of a timestamp request.
+type timeStampReq struct {
+	Version        int
+	MessageImprint MessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+// Request is a timestamp request.
+type Request struct {
+	MessageImprint MessageImprint
+	Policy         asn1.ObjectIdentifier
+	Nonce          *big.Int
+	CertReq        bool
+}
+
+// NewRequest creates a request to timestamp a message.
+// The request has a random nonce and asks the authority to include its
+// certificate in the token.
+func NewRequest(h crypto.Hash, message []byte) (*Request, error) {
+	imprint, err := NewMessageImprint(h, message)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &Request{
+		MessageImprint: imprint,
+		Nonce:          nonce,
+		CertReq:        true,
+	}, nil
+}
+
+// ParseRequest parses a DER encoded timestamp request.
+func ParseRequest(der []byte) (*Request, error) {
+	var req timeStampReq
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrInvalidRequest.Error())
+	}
+	if len(rest) > 0 || req.Version != 1 {
+		return nil, ErrInvalidRequest
+	}
+
+	h, err := req.MessageImprint.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.MessageImprint.HashedMessage) != h.Size() {
+		return nil, ErrInvalidRequest
+	}
+
+	return &Request{
+		MessageImprint: req.MessageImprint,
+		Policy:         req.ReqPolicy,
+		Nonce:          req.Nonce,
+		CertReq:        req.CertReq,
+	}, nil
+}
+
+// Marshal returns the DER encoding of the request.
+func (r *Request) Marshal() ([]byte, error) {
+	der, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: r.MessageImprint,
+		ReqPolicy:      r.Policy,
+		Nonce:          r.Nonce,
+		CertReq:        r.CertReq,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return der, nil
+}
+
+// Check returns an error if the token was not issued for this request.
+func (r *Request) Check(token *Token) error {
+	if !r.MessageImprint.Equal(token.Info.MessageImprint) {
+		return ErrImprintMismatch
+	}
+
+	if r.Nonce != nil && (token.Info.Nonce == nil || r.Nonce.Cmp(token.Info.Nonce) != 0) {
+		return ErrNonceMismatch
+	}
+
+	if r.Policy != nil && !r.Policy.Equal(token.Info.Policy) {
+		return ErrPolicyMismatch
+	}
+
+	return nil
+}
+
+// PKIStatusInfo is the status of a timestamp response.
+type PKIStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// StatusError is returned when a timestamp authority did not grant a
+// request.
+type StatusError struct {
+	PKIStatusInfo
+}
+
+// Error implements error.Error.
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("timestamp request rejected with status %d", e.Status)
+
+	for i := 0; i < e.FailInfo.BitLength; i++ {
+		if e.FailInfo.At(i) != 0 {
+			msg += fmt.Sprintf(" (failure %d)", i)
+		}
+	}
+
+	for _, s := range e.StatusString {
+		msg += ": " + s
+	}
+
+	return msg
+}
+
+// timeStampResp is the ASN.1 structure of a timestamp response.
+type timeStampResp struct {
+	Status         PKIStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// ParseResponse parses a DER encoded timestamp response and returns its
+// token.
+// It returns a StatusError if the request was not granted.
+func ParseResponse(der []byte) (*Token, error) {
+	var resp timeStampResp
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrInvalidResponse.Error())
+	}
+	if len(rest) > 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	if resp.Status.Status != StatusGranted && resp.Status.Status != StatusGrantedWithMods {
+		return nil, &StatusError{resp.Status}
+	}
+
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	return ParseToken(resp.TimeStampToken.FullBytes)
+}
+
+// CreateResponse returns the DER encoding of a timestamp response.
+// The token must be nil if the request was not granted.
+func CreateResponse(status PKIStatusInfo, token []byte) ([]byte, error) {
+	resp := timeStampResp{Status: status}
+	if token != nil {
+		resp.TimeStampToken = asn1.RawValue{FullBytes: token}
+	}
+
+	der, err := asn1.Marshal(resp)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return der, nil
+}
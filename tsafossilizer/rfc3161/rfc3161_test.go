@@ -0,0 +1,226 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rfc3161_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+	"github.com/stratumn/go-indigocore/tsafossilizer/tsatesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest(t *testing.T) {
+	req, err := rfc3161.NewRequest(crypto.SHA256, []byte("message"))
+	require.NoError(t, err)
+	assert.True(t, req.MessageImprint.Matches([]byte("message")))
+	assert.False(t, req.MessageImprint.Matches([]byte("other")))
+	assert.True(t, req.CertReq)
+	assert.NotNil(t, req.Nonce)
+
+	der, err := req.Marshal()
+	require.NoError(t, err)
+
+	got, err := rfc3161.ParseRequest(der)
+	require.NoError(t, err)
+	assert.True(t, req.MessageImprint.Equal(got.MessageImprint))
+	assert.Equal(t, 0, req.Nonce.Cmp(got.Nonce))
+	assert.True(t, got.CertReq)
+	assert.Nil(t, got.Policy)
+}
+
+func TestRequest_UnsupportedHash(t *testing.T) {
+	_, err := rfc3161.NewRequest(crypto.MD5, []byte("message"))
+	assert.EqualError(t, err, rfc3161.ErrUnsupportedHash.Error())
+}
+
+func TestParseRequest_Invalid(t *testing.T) {
+	_, err := rfc3161.ParseRequest([]byte("request"))
+	assert.Error(t, err)
+}
+
+func TestToken(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err)
+
+	req, err := rfc3161.NewRequest(crypto.SHA256, []byte("message"))
+	require.NoError(t, err)
+
+	der, err := tsa.Timestamp(req)
+	require.NoError(t, err)
+
+	token, err := rfc3161.ParseToken(der)
+	require.NoError(t, err)
+
+	t.Run("Info", func(t *testing.T) {
+		assert.Equal(t, der, token.Raw)
+		assert.Equal(t, tsa.Certificate.Raw, token.Signer.Raw)
+		assert.True(t, tsatesting.Policy.Equal(token.Info.Policy))
+		assert.WithinDuration(t, time.Now(), token.Time(), 2*time.Second)
+		assert.NoError(t, req.Check(token))
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		assert.NoError(t, token.Verify([]byte("message")))
+	})
+
+	t.Run("Verify other message", func(t *testing.T) {
+		assert.EqualError(t, token.Verify([]byte("other")), rfc3161.ErrImprintMismatch.Error())
+	})
+
+	t.Run("VerifyCertificate", func(t *testing.T) {
+		assert.NoError(t, token.VerifyCertificate(tsa.Roots()))
+	})
+
+	t.Run("VerifyCertificate untrusted", func(t *testing.T) {
+		other, err := tsatesting.New()
+		require.NoError(t, err)
+		assert.Error(t, token.VerifyCertificate(other.Roots()))
+	})
+
+	t.Run("Check other request", func(t *testing.T) {
+		other, err := rfc3161.NewRequest(crypto.SHA256, []byte("message"))
+		require.NoError(t, err)
+		assert.EqualError(t, other.Check(token), rfc3161.ErrNonceMismatch.Error())
+	})
+
+	t.Run("Forged signer", func(t *testing.T) {
+		other, err := tsatesting.New()
+		require.NoError(t, err)
+
+		forged, err := rfc3161.ParseToken(der)
+		require.NoError(t, err)
+		forged.Signer = other.Certificate
+		assert.Error(t, forged.Verify([]byte("message")))
+	})
+}
+
+func TestToken_Tampered(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err)
+
+	req, err := rfc3161.NewRequest(crypto.SHA256, []byte("message"))
+	require.NoError(t, err)
+
+	der, err := tsa.Timestamp(req)
+	require.NoError(t, err)
+
+	token, err := rfc3161.ParseToken(der)
+	require.NoError(t, err)
+
+	info, err := asn1.Marshal(token.Info)
+	require.NoError(t, err)
+	start := bytes.Index(der, info)
+	require.True(t, start > 0, "signed content not found")
+
+	// Flip a bit in every byte of the signed content and of the signature,
+	// one at a time. The token must either fail to parse or fail to verify.
+	tamper := func(i int) {
+		tampered := append([]byte{}, der...)
+		tampered[i] ^= 0x01
+
+		token, err := rfc3161.ParseToken(tampered)
+		if err != nil {
+			return
+		}
+		if token.Verify([]byte("message")) == nil {
+			t.Fatalf("tampered token at byte %d is valid", i)
+		}
+	}
+
+	for i := start; i < start+len(info); i++ {
+		tamper(i)
+	}
+	for i := len(der) - 64; i < len(der); i++ {
+		tamper(i)
+	}
+}
+
+func TestToken_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "RSA TSA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	imprint, err := rfc3161.NewMessageImprint(crypto.SHA512, []byte("message"))
+	require.NoError(t, err)
+
+	der, err := rfc3161.CreateToken(&rfc3161.TSTInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3},
+		MessageImprint: imprint,
+		SerialNumber:   big.NewInt(1),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+	}, cert, key)
+	require.NoError(t, err)
+
+	token, err := rfc3161.ParseToken(der)
+	require.NoError(t, err)
+	assert.NoError(t, token.Verify([]byte("message")))
+}
+
+func TestResponse(t *testing.T) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err)
+
+	req, err := rfc3161.NewRequest(crypto.SHA256, []byte("message"))
+	require.NoError(t, err)
+
+	der, err := tsa.Timestamp(req)
+	require.NoError(t, err)
+
+	resp, err := rfc3161.CreateResponse(rfc3161.PKIStatusInfo{Status: rfc3161.StatusGranted}, der)
+	require.NoError(t, err)
+
+	token, err := rfc3161.ParseResponse(resp)
+	require.NoError(t, err)
+	assert.Equal(t, der, token.Raw)
+}
+
+func TestResponse_Rejected(t *testing.T) {
+	status := tsatesting.Rejection(rfc3161.FailureBadAlg)
+	status.StatusString = []string{"unsupported algorithm"}
+
+	resp, err := rfc3161.CreateResponse(status, nil)
+	require.NoError(t, err)
+
+	_, err = rfc3161.ParseResponse(resp)
+	require.Error(t, err)
+	assert.EqualError(t, err, "timestamp request rejected with status 2 (failure 0): unsupported algorithm")
+
+	statusErr, ok := err.(*rfc3161.StatusError)
+	require.True(t, ok)
+	assert.Equal(t, rfc3161.StatusRejection, statusErr.Status)
+}
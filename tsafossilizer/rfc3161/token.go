@@ -0,0 +1,529 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rfc3161
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrImprintMismatch is returned when a token was not issued for the
+	// expected message.
+	ErrImprintMismatch = errors.New("message imprint mismatch")
+
+	// ErrNonceMismatch is returned when a token does not contain the nonce
+	// of the request.
+	ErrNonceMismatch = errors.New("nonce mismatch")
+
+	// ErrPolicyMismatch is returned when a token was not issued under the
+	// requested policy.
+	ErrPolicyMismatch = errors.New("policy mismatch")
+
+	// ErrSignerNotFound is returned when the certificate of the authority
+	// that signed a token is not included in the token.
+	ErrSignerNotFound = errors.New("signer certificate not found")
+
+	// ErrInvalidSignature is returned when the signature of a token is not
+	// valid.
+	ErrInvalidSignature = errors.New("invalid token signature")
+
+	// ErrUnsupportedSignature is returned when a token is signed with an
+	// algorithm that is not supported.
+	ErrUnsupportedSignature = errors.New("unsupported signature algorithm")
+)
+
+var (
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+	oidAttributeContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidSignatureRSA                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSignatureSHA1WithRSA          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSignatureSHA256WithRSA        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureSHA384WithRSA        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSignatureSHA512WithRSA        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidSignatureECDSA                = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSignatureECDSAWithSHA1        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidSignatureECDSAWithSHA256      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// signatureAlgorithms maps CMS signature algorithms and digest algorithms
+// to x509 signature algorithms.
+var signatureAlgorithms = []struct {
+	oid       asn1.ObjectIdentifier
+	hash      crypto.Hash
+	algorithm x509.SignatureAlgorithm
+}{
+	{oidSignatureRSA, crypto.SHA1, x509.SHA1WithRSA},
+	{oidSignatureRSA, crypto.SHA256, x509.SHA256WithRSA},
+	{oidSignatureRSA, crypto.SHA384, x509.SHA384WithRSA},
+	{oidSignatureRSA, crypto.SHA512, x509.SHA512WithRSA},
+	{oidSignatureSHA1WithRSA, crypto.SHA1, x509.SHA1WithRSA},
+	{oidSignatureSHA256WithRSA, crypto.SHA256, x509.SHA256WithRSA},
+	{oidSignatureSHA384WithRSA, crypto.SHA384, x509.SHA384WithRSA},
+	{oidSignatureSHA512WithRSA, crypto.SHA512, x509.SHA512WithRSA},
+	{oidSignatureECDSA, crypto.SHA1, x509.ECDSAWithSHA1},
+	{oidSignatureECDSA, crypto.SHA256, x509.ECDSAWithSHA256},
+	{oidSignatureECDSA, crypto.SHA384, x509.ECDSAWithSHA384},
+	{oidSignatureECDSA, crypto.SHA512, x509.ECDSAWithSHA512},
+	{oidSignatureECDSAWithSHA1, crypto.SHA1, x509.ECDSAWithSHA1},
+	{oidSignatureECDSAWithSHA256, crypto.SHA256, x509.ECDSAWithSHA256},
+	{oidSignatureECDSAWithSHA384, crypto.SHA384, x509.ECDSAWithSHA384},
+	{oidSignatureECDSAWithSHA512, crypto.SHA512, x509.ECDSAWithSHA512},
+}
+
+func signatureAlgorithm(algorithm pkix.AlgorithmIdentifier, h crypto.Hash) (x509.SignatureAlgorithm, error) {
+	for _, a := range signatureAlgorithms {
+		if a.oid.Equal(algorithm.Algorithm) && a.hash == h {
+			return a.algorithm, nil
+		}
+	}
+
+	return x509.UnknownSignatureAlgorithm, ErrUnsupportedSignature
+}
+
+// Accuracy is the accuracy of the time of a token.
+type Accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// TSTInfo is the content signed by a timestamp authority.
+type TSTInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint MessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       Accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+// contentInfo is the ASN.1 structure of a CMS content.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// signedData is the ASN.1 structure of CMS signed data.
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type essCertIDv2 struct {
+	// The hash algorithm is omitted because it defaults to SHA256.
+	CertHash []byte
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// Token is a parsed timestamp token.
+type Token struct {
+	// Raw contains the DER encoded token.
+	Raw []byte
+
+	// Info is the signed content of the token.
+	Info TSTInfo
+
+	// Certificates contains the certificates included in the token.
+	Certificates []*x509.Certificate
+
+	// Signer is the certificate of the authority that signed the token.
+	// It is nil if it wasn't included in the token.
+	Signer *x509.Certificate
+
+	content    []byte
+	signerInfo signerInfo
+}
+
+// ParseToken parses a DER encoded timestamp token.
+func ParseToken(der []byte) (*Token, error) {
+	var ci contentInfo
+	rest, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrInvalidToken.Error())
+	}
+	if len(rest) > 0 || !ci.ContentType.Equal(oidSignedData) {
+		return nil, ErrInvalidToken
+	}
+
+	var sd signedData
+	if rest, err = asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, errors.Wrap(err, ErrInvalidToken.Error())
+	}
+	if len(rest) > 0 || len(sd.SignerInfos) != 1 || !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, ErrInvalidToken
+	}
+
+	t := Token{
+		Raw:        der,
+		content:    sd.EncapContentInfo.EContent,
+		signerInfo: sd.SignerInfos[0],
+	}
+
+	if rest, err = asn1.Unmarshal(t.content, &t.Info); err != nil {
+		return nil, errors.Wrap(err, ErrInvalidToken.Error())
+	}
+	if len(rest) > 0 || t.Info.Version != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	if len(sd.Certificates.Bytes) > 0 {
+		if t.Certificates, err = x509.ParseCertificates(sd.Certificates.Bytes); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidToken.Error())
+		}
+	}
+
+	t.Signer = t.findSigner()
+
+	return &t, nil
+}
+
+// findSigner returns the certificate identified by the signer info.
+func (t *Token) findSigner() *x509.Certificate {
+	sid := t.signerInfo.SID
+
+	// The signer is identified by its subject key identifier.
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, cert := range t.Certificates {
+			if bytes.Equal(cert.SubjectKeyId, sid.Bytes) {
+				return cert
+			}
+		}
+		return nil
+	}
+
+	// The signer is identified by its issuer and serial number.
+	var ias issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, cert := range t.Certificates {
+		if bytes.Equal(cert.RawIssuer, ias.Issuer.FullBytes) && cert.SerialNumber.Cmp(ias.SerialNumber) == 0 {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// Time returns the time at which the token was issued.
+func (t *Token) Time() time.Time {
+	return t.Info.GenTime
+}
+
+// Verify checks that the token is a timestamp of the given message and that
+// it is correctly signed by the certificate included in the token.
+// It does not check that the certificate is trusted, see VerifyCertificate.
+func (t *Token) Verify(message []byte) error {
+	if !t.Info.MessageImprint.Matches(message) {
+		return ErrImprintMismatch
+	}
+
+	return t.verifySignature()
+}
+
+// verifySignature checks the CMS signature of the token.
+func (t *Token) verifySignature() error {
+	if t.Signer == nil {
+		return ErrSignerNotFound
+	}
+
+	// Signed attributes are mandatory in timestamp tokens.
+	si := t.signerInfo
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return ErrInvalidSignature
+	}
+
+	h, err := hashFromAlgorithm(si.DigestAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	// The signature covers the DER encoding of the attributes with
+	// an explicit SET OF tag instead of the implicit [0] tag.
+	signed := append([]byte{}, si.SignedAttrs.FullBytes...)
+	signed[0] = 0x31
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return errors.Wrap(err, ErrInvalidSignature.Error())
+	}
+
+	var contentType asn1.ObjectIdentifier
+	if err := unmarshalAttribute(attrs, oidAttributeContentType, &contentType); err != nil {
+		return err
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return ErrInvalidSignature
+	}
+
+	var digest []byte
+	if err := unmarshalAttribute(attrs, oidAttributeMessageDigest, &digest); err != nil {
+		return err
+	}
+	hash := h.New()
+	hash.Write(t.content)
+	if !bytes.Equal(digest, hash.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	algorithm, err := signatureAlgorithm(si.SignatureAlgorithm, h)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Signer.CheckSignature(algorithm, signed, si.Signature); err != nil {
+		return errors.Wrap(err, ErrInvalidSignature.Error())
+	}
+
+	return nil
+}
+
+// unmarshalAttribute unmarshals the single value of a signed attribute.
+func unmarshalAttribute(attrs []attribute, oid asn1.ObjectIdentifier, val interface{}) error {
+	for _, attr := range attrs {
+		if attr.Type.Equal(oid) {
+			if len(attr.Values) != 1 {
+				return ErrInvalidSignature
+			}
+			if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, val); err != nil {
+				return errors.Wrap(err, ErrInvalidSignature.Error())
+			}
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// VerifyCertificate checks that the certificate that signed the token chains
+// up to one of the given roots and is allowed to issue timestamps.
+func (t *Token) VerifyCertificate(roots *x509.CertPool) error {
+	if t.Signer == nil {
+		return ErrSignerNotFound
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range t.Certificates {
+		if cert != t.Signer {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	_, err := t.Signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   t.Info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+
+	return errors.WithStack(err)
+}
+
+// CreateToken issues a DER encoded timestamp token signed with the given
+// key, which must be the key of the certificate.
+// Only RSA and ECDSA keys are supported. The content is signed using SHA256.
+func CreateToken(info *TSTInfo, cert *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	var sigAlgorithm asn1.ObjectIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlgorithm = oidSignatureSHA256WithRSA
+	case *ecdsa.PublicKey:
+		sigAlgorithm = oidSignatureECDSAWithSHA256
+	default:
+		return nil, ErrUnsupportedSignature
+	}
+
+	digestAlgorithm, err := hashAlgorithm(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := asn1.Marshal(*info)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	contentDigest := sha256.Sum256(content)
+	certHash := sha256.Sum256(cert.Raw)
+
+	signedAttrs, err := marshalAttributes(
+		attributeValue{oidAttributeContentType, oidTSTInfo},
+		attributeValue{oidAttributeMessageDigest, contentDigest[:]},
+		attributeValue{oidAttributeSigningCertificateV2, signingCertificateV2{
+			Certs: []essCertIDv2{{CertHash: certHash[:]}},
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      signedAttrs,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	signedDigest := sha256.Sum256(signed)
+	signature, err := key.Sign(rand.Reader, signedDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		EncapContentInfo: encapsulatedContentInfo{
+			EContentType: oidTSTInfo,
+			EContent:     content,
+		},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      cert.Raw,
+		},
+		SignerInfos: []signerInfo{{
+			Version:         1,
+			SID:             asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm: digestAlgorithm,
+			SignedAttrs: asn1.RawValue{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        0,
+				IsCompound: true,
+				Bytes:      signedAttrs,
+			},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: sigAlgorithm},
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	token, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      sd,
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return token, nil
+}
+
+type attributeValue struct {
+	oid   asn1.ObjectIdentifier
+	value interface{}
+}
+
+// marshalAttributes returns the content of a DER encoded SET OF attributes.
+func marshalAttributes(values ...attributeValue) ([]byte, error) {
+	var encoded [][]byte
+	for _, v := range values {
+		value, err := asn1.Marshal(v.value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		attr, err := asn1.Marshal(attribute{
+			Type:   v.oid,
+			Values: []asn1.RawValue{{FullBytes: value}},
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		encoded = append(encoded, attr)
+	}
+
+	// DER requires the elements of a SET OF to be sorted.
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+
+	return bytes.Join(encoded, nil), nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tsafossilizer implements a fossilizer that timestamps batches of
+// hashes with a RFC 3161 timestamp authority.
+package tsafossilizer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stratumn/go-indigocore/batchfossilizer"
+	batchevidences "github.com/stratumn/go-indigocore/batchfossilizer/evidences"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/tsafossilizer/evidences"
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+	"github.com/stratumn/go-indigocore/types"
+)
+
+const (
+	// Name is the name set in the fossilizer's information.
+	Name = "tsa"
+
+	// Description is the description set in the fossilizer's information.
+	Description = "Indigo's RFC 3161 Timestamping Batch Fossilizer"
+
+	// DefaultTimeout is the default timeout of requests to the timestamp
+	// authority.
+	DefaultTimeout = 30 * time.Second
+
+	// maxResponseSize is the maximum size of a timestamp response.
+	maxResponseSize = 1 << 20
+)
+
+// Config contains configuration options for the fossilizer.
+type Config struct {
+	// The URL of the timestamp authority.
+	URL string
+
+	// An optional HTTP client used to send requests.
+	Client *http.Client
+
+	// An optional policy under which tokens must be issued.
+	Policy asn1.ObjectIdentifier
+
+	// Optional trusted roots used to verify the certificate of the
+	// timestamp authority.
+	Roots *x509.CertPool
+}
+
+// Info is the info returned by GetInfo.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	URL         string `json:"url"`
+}
+
+// Fossilizer is the type that
+// implements github.com/stratumn/go-indigocore/batchfossilizer.Adapter.
+type Fossilizer struct {
+	batchfossilizer.Adapter
+	config    *Config
+	client    *http.Client
+	lastRoot  *types.Bytes32
+	lastToken []byte
+}
+
+// New creates an instance of a Fossilizer.
+func New(config *Config, batchConfig *batchfossilizer.Config) (*Fossilizer, error) {
+	if config.URL == "" {
+		return nil, errors.New("a timestamp authority URL is required")
+	}
+
+	if batchConfig.MaxSimBatches > 1 {
+		return nil, fmt.Errorf("MaxSimBatches is %d want less than 2", batchConfig.MaxSimBatches)
+	}
+
+	b, err := batchfossilizer.New(batchConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	f := Fossilizer{
+		Adapter: b,
+		config:  config,
+		client:  client,
+	}
+
+	f.SetTransformer(f.transform)
+
+	return &f, nil
+}
+
+// GetInfo implements github.com/stratumn/go-indigocore/fossilizer.Adapter.GetInfo.
+func (a *Fossilizer) GetInfo(ctx context.Context) (interface{}, error) {
+	batchInfo, err := a.Adapter.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := batchInfo.(*batchfossilizer.Info)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected batchfossilizer info %#v", batchInfo)
+	}
+
+	return &Info{
+		Name:        Name,
+		Description: Description,
+		Version:     info.Version,
+		Commit:      info.Commit,
+		URL:         a.config.URL,
+	}, nil
+}
+
+func (a *Fossilizer) transform(evidence *cs.Evidence, data, meta []byte) (*fossilizer.Result, error) {
+	root := evidence.Proof.(*batchevidences.BatchProof).Root
+
+	if a.lastRoot == nil || *root != *a.lastRoot {
+		token, err := a.timestamp(root)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"serial": token.Info.SerialNumber,
+			"time":   token.Time(),
+			"root":   root,
+		}).Info("Received timestamp token")
+
+		a.lastRoot = root
+		a.lastToken = token.Raw
+	}
+
+	evidence.Provider = a.config.URL
+	evidence.Backend = Name
+	evidence.Proof = &evidences.TSAProof{
+		Batch: *evidence.Proof.(*batchevidences.BatchProof),
+		Token: a.lastToken,
+	}
+
+	r := fossilizer.Result{
+		Evidence: *evidence,
+		Data:     data,
+		Meta:     meta,
+	}
+
+	return &r, nil
+}
+
+// timestamp requests a timestamp token of a Merkle root and makes sure the
+// token is valid.
+func (a *Fossilizer) timestamp(root *types.Bytes32) (*rfc3161.Token, error) {
+	req, err := rfc3161.NewRequest(crypto.SHA256, root[:])
+	if err != nil {
+		return nil, err
+	}
+	req.Policy = a.config.Policy
+
+	der, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Post(a.config.URL, rfc3161.RequestContentType, bytes.NewReader(der))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("timestamp authority returned status %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	token, err := rfc3161.ParseResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Check(token); err != nil {
+		return nil, err
+	}
+
+	if err := token.Verify(root[:]); err != nil {
+		return nil, err
+	}
+
+	if a.config.Roots != nil {
+		if err := token.VerifyCertificate(a.config.Roots); err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tsafossilizer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/batchfossilizer"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/tsafossilizer/evidences"
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+	"github.com/stratumn/go-indigocore/tsafossilizer/tsatesting"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInterval = 100 * time.Millisecond
+
+func createTSA(t *testing.T) (*tsatesting.TSA, *httptest.Server) {
+	tsa, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+	return tsa, httptest.NewServer(tsa)
+}
+
+// fossilize fossilizes random link hashes and waits for their results.
+func fossilize(t *testing.T, a *Fossilizer, count int) []*fossilizer.Result {
+	ec := make(chan *fossilizer.Event, count)
+	a.AddFossilizerEventChan(ec)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := a.Start(ctx); err != nil && errors.Cause(err) != context.Canceled {
+			t.Errorf("a.Start(): err: %s", err)
+		}
+	}()
+
+	<-a.Started()
+
+	for i := 0; i < count; i++ {
+		linkHash := testutil.RandomHash()
+		require.NoError(t, a.Fossilize(context.Background(), linkHash[:], linkHash[:]), "a.Fossilize()")
+	}
+
+	var results []*fossilizer.Result
+	for i := 0; i < count; i++ {
+		select {
+		case e := <-ec:
+			results = append(results, e.Data.(*fossilizer.Result))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for fossilizer results")
+		}
+	}
+
+	return results
+}
+
+func TestNew_NoURL(t *testing.T) {
+	_, err := New(&Config{}, &batchfossilizer.Config{})
+	assert.Error(t, err)
+}
+
+func TestGetInfo(t *testing.T) {
+	a, err := New(&Config{URL: "http://localhost"}, &batchfossilizer.Config{})
+	require.NoError(t, err, "New()")
+
+	got, err := a.GetInfo(context.Background())
+	require.NoError(t, err, "a.GetInfo()")
+
+	info, ok := got.(*Info)
+	require.True(t, ok, "a.GetInfo(): info = %#v want *Info", got)
+	assert.Equal(t, Name, info.Name)
+	assert.Equal(t, Description, info.Description)
+	assert.Equal(t, "http://localhost", info.URL)
+}
+
+func TestFossilize(t *testing.T) {
+	tsa, server := createTSA(t)
+	defer server.Close()
+
+	a, err := New(&Config{
+		URL:    server.URL,
+		Policy: tsatesting.Policy,
+		Roots:  tsa.Roots(),
+	}, &batchfossilizer.Config{
+		Interval: testInterval,
+	})
+	require.NoError(t, err, "New()")
+
+	results := fossilize(t, a, 5)
+
+	// A single token is requested per batch.
+	roots := map[types.Bytes32]struct{}{}
+	for _, r := range results {
+		assert.Equal(t, Name, r.Evidence.Backend, "r.Evidence.Backend")
+		assert.Equal(t, server.URL, r.Evidence.Provider, "r.Evidence.Provider")
+
+		p, ok := r.Evidence.Proof.(*evidences.TSAProof)
+		require.True(t, ok, "r.Evidence.Proof = %#v want *evidences.TSAProof", r.Evidence.Proof)
+		assert.InDelta(t, time.Now().Unix(), p.Time(), 2, "p.Time()")
+		assert.NoError(t, p.VerifyWithRoots(types.NewBytes32FromBytes(r.Data), tsa.Roots()), "p.VerifyWithRoots()")
+		assert.Error(t, p.VerifyWithRoots(testutil.RandomHash(), tsa.Roots()), "p.VerifyWithRoots()")
+		roots[*p.Batch.Root] = struct{}{}
+	}
+	assert.Equal(t, len(roots), tsa.GrantedCount(), "tsa.GrantedCount()")
+}
+
+func TestFossilize_Rejected(t *testing.T) {
+	tsa, server := createTSA(t)
+	defer server.Close()
+	tsa.Status = tsatesting.Rejection(rfc3161.FailureSystemFailure)
+
+	a, err := New(&Config{URL: server.URL}, &batchfossilizer.Config{})
+	require.NoError(t, err, "New()")
+
+	_, err = a.timestamp(testutil.RandomHash())
+	require.Error(t, err, "a.timestamp()")
+	_, ok := err.(*rfc3161.StatusError)
+	assert.True(t, ok, "a.timestamp(): err = %#v want *rfc3161.StatusError", err)
+}
+
+func TestFossilize_UnexpectedPolicy(t *testing.T) {
+	_, server := createTSA(t)
+	defer server.Close()
+
+	a, err := New(&Config{
+		URL:    server.URL,
+		Policy: []int{1, 2, 3},
+	}, &batchfossilizer.Config{})
+	require.NoError(t, err, "New()")
+
+	_, err = a.timestamp(testutil.RandomHash())
+	assert.EqualError(t, err, rfc3161.ErrPolicyMismatch.Error(), "a.timestamp()")
+}
+
+func TestFossilize_UntrustedAuthority(t *testing.T) {
+	_, server := createTSA(t)
+	defer server.Close()
+
+	other, err := tsatesting.New()
+	require.NoError(t, err, "tsatesting.New()")
+
+	a, err := New(&Config{
+		URL:   server.URL,
+		Roots: other.Roots(),
+	}, &batchfossilizer.Config{})
+	require.NoError(t, err, "New()")
+
+	_, err = a.timestamp(testutil.RandomHash())
+	assert.Error(t, err, "a.timestamp()")
+}
+
+func TestFossilize_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	a, err := New(&Config{URL: server.URL}, &batchfossilizer.Config{})
+	require.NoError(t, err, "New()")
+
+	_, err = a.timestamp(testutil.RandomHash())
+	assert.EqualError(t, err, "timestamp authority returned status 404", "a.timestamp()")
+}
+
+func TestParseOID(t *testing.T) {
+	oid, err := parseOID("1.2.3.4.1")
+	require.NoError(t, err, "parseOID()")
+	assert.True(t, tsatesting.Policy.Equal(oid), "parseOID()")
+
+	_, err = parseOID("1")
+	assert.Error(t, err, "parseOID()")
+
+	_, err = parseOID("1.a")
+	assert.Error(t, err, "parseOID()")
+}
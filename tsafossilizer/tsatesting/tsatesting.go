@@ -0,0 +1,184 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tsatesting defines helpers to test timestamping.
+package tsatesting
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/tsafossilizer/rfc3161"
+)
+
+// Policy is the policy under which the test authority issues tokens.
+var Policy = asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+
+var (
+	oidExtensionExtKeyUsage    = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtKeyUsageTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// TSA is an in-process timestamp authority.
+// It signs tokens with a self-signed ECDSA certificate.
+//
+// It implements net/http.Handler so it can be used with httptest.NewServer.
+type TSA struct {
+	// Certificate is the certificate of the authority.
+	Certificate *x509.Certificate
+
+	// Key is the private key of the authority.
+	Key crypto.Signer
+
+	// Status is the status returned by the authority.
+	// Requests are granted if it is the zero value.
+	Status rfc3161.PKIStatusInfo
+
+	mutex   sync.Mutex
+	serial  int64
+	granted int
+}
+
+// New creates a timestamp authority with a new key and certificate.
+func New() (*TSA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// RFC 3161 requires the extended key usage extension to be critical.
+	extKeyUsage, err := asn1.Marshal([]asn1.ObjectIdentifier{oidExtKeyUsageTimeStamping})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Indigo Test TSA"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{
+			Id:       oidExtensionExtKeyUsage,
+			Critical: true,
+			Value:    extKeyUsage,
+		}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &TSA{Certificate: cert, Key: key}, nil
+}
+
+// Roots returns a pool containing the certificate of the authority.
+func (t *TSA) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(t.Certificate)
+	return roots
+}
+
+// GrantedCount returns the number of tokens issued by the authority.
+func (t *TSA) GrantedCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.granted
+}
+
+// Timestamp issues a DER encoded token for a request.
+func (t *TSA) Timestamp(req *rfc3161.Request) ([]byte, error) {
+	t.mutex.Lock()
+	t.serial++
+	t.granted++
+	serial := t.serial
+	t.mutex.Unlock()
+
+	return rfc3161.CreateToken(&rfc3161.TSTInfo{
+		Version:        1,
+		Policy:         Policy,
+		MessageImprint: req.MessageImprint,
+		SerialNumber:   big.NewInt(serial),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+		Accuracy:       rfc3161.Accuracy{Seconds: 1},
+		Nonce:          req.Nonce,
+	}, t.Certificate, t.Key)
+}
+
+// ServeHTTP implements net/http.Handler.ServeHTTP.
+func (t *TSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		status = t.Status
+		token  []byte
+	)
+
+	if status.Status == rfc3161.StatusGranted {
+		req, err := rfc3161.ParseRequest(body)
+		if err != nil {
+			status = Rejection(rfc3161.FailureBadDataFormat)
+		} else if token, err = t.Timestamp(req); err != nil {
+			status = Rejection(rfc3161.FailureSystemFailure)
+		}
+	}
+
+	resp, err := rfc3161.CreateResponse(status, token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rfc3161.ResponseContentType)
+	w.Write(resp)
+}
+
+// Rejection returns the status of a request rejected for the given failure
+// reason.
+func Rejection(failure int) rfc3161.PKIStatusInfo {
+	bits := make([]byte, failure/8+1)
+	bits[failure/8] = 0x80 >> uint(failure%8)
+
+	return rfc3161.PKIStatusInfo{
+		Status:   rfc3161.StatusRejection,
+		FailInfo: asn1.BitString{Bytes: bits, BitLength: failure + 1},
+	}
+}
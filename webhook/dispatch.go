@@ -0,0 +1,291 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/types"
+	"github.com/stratumn/go-indigocore/utils"
+)
+
+const (
+	// EventTypeHeader is the request header containing the type of the
+	// event.
+	EventTypeHeader = "X-Webhook-Event"
+
+	// DeliveryHeader is the request header containing the ID of the
+	// delivery.
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// ListenStore makes the dispatcher send the events of a store. The store is
+// also used to filter evidence events.
+func (d *Dispatcher) ListenStore(a store.Adapter) {
+	d.reader = a
+	a.AddStoreEventChannel(d.storeEventChan)
+}
+
+// ListenFossilizer makes the dispatcher send the events of a fossilizer.
+func (d *Dispatcher) ListenFossilizer(a fossilizer.Adapter) {
+	a.AddFossilizerEventChan(d.fossilizerEventChan)
+}
+
+// Start dispatches events until the context is done. Deliveries that are
+// still pending then are added to the dead-letter queue.
+func (d *Dispatcher) Start(ctx context.Context) {
+	defer d.wg.Wait()
+
+	for {
+		select {
+		case event := <-d.storeEventChan:
+			d.dispatchStoreEvent(ctx, event)
+		case event := <-d.fossilizerEventChan:
+			d.dispatch(ctx, string(event.EventType), func(e *Endpoint) interface{} {
+				if e.Filter != nil {
+					return nil
+				}
+				return event.Data
+			})
+		case delivery := <-d.redeliverChan:
+			d.deliver(ctx, delivery)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchStoreEvent(ctx context.Context, event *store.Event) {
+	links := map[string]*cs.Link{}
+	getLink := func(linkHash string) *cs.Link {
+		if link, ok := links[linkHash]; ok {
+			return link
+		}
+		links[linkHash] = d.getLink(ctx, linkHash)
+		return links[linkHash]
+	}
+
+	d.dispatch(ctx, string(event.EventType), func(e *Endpoint) interface{} {
+		if e.Filter == nil {
+			return event.Data
+		}
+		if filtered := event.Filter(e.Filter, getLink); filtered != nil {
+			return filtered.Data
+		}
+		return nil
+	})
+}
+
+func (d *Dispatcher) getLink(ctx context.Context, linkHash string) *cs.Link {
+	if d.reader == nil {
+		return nil
+	}
+
+	lh, err := types.NewBytes32FromString(linkHash)
+	if err != nil {
+		return nil
+	}
+
+	segment, err := d.reader.GetSegment(ctx, lh)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"linkHash": linkHash,
+			"error":    err,
+		}).Warn("Failed to get the segment of an evidence event")
+		return nil
+	}
+	if segment == nil {
+		return nil
+	}
+
+	return &segment.Link
+}
+
+// dispatch delivers an event to the endpoints that accept its type. The
+// data function returns the data sent to an endpoint, or nil if the
+// endpoint should not receive the event.
+func (d *Dispatcher) dispatch(ctx context.Context, eventType string, data func(*Endpoint) interface{}) {
+	for _, e := range d.GetEndpoints() {
+		if !e.matchType(eventType) {
+			continue
+		}
+		endpointData := data(e)
+		if endpointData == nil {
+			continue
+		}
+
+		body, err := json.Marshal(&Message{Type: eventType, Data: endpointData})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"type":  eventType,
+				"error": err,
+			}).Error("Failed to marshal webhook event")
+			continue
+		}
+
+		d.deliver(ctx, &Delivery{
+			ID:         newID(),
+			EndpointID: e.ID,
+			EventType:  eventType,
+			Body:       body,
+			CreatedAt:  time.Now().UTC(),
+		})
+	}
+}
+
+// Redeliver removes a delivery from the dead-letter queue and sends it
+// again. The dispatcher must be started.
+func (d *Dispatcher) Redeliver(ctx context.Context, id string) error {
+	delivery, err := d.RemoveDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts, delivery.Error = 0, ""
+
+	select {
+	case d.redeliverChan <- delivery:
+		return nil
+	case <-ctx.Done():
+		if err := d.addDeadLetter(context.Background(), delivery); err != nil {
+			return err
+		}
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// deliver sends a delivery in the background, retrying with an exponential
+// delay. It is added to the dead-letter queue if it still fails.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) {
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+
+		var lastErr error
+		err := utils.Retry(func(attempt int) (bool, error) {
+			delivery.Attempts++
+			lastErr = d.post(ctx, delivery)
+			if lastErr == nil {
+				return false, nil
+			}
+			if _, ok := errors.Cause(lastErr).(permanentError); ok || lastErr == ErrEndpointNotFound {
+				return false, lastErr
+			}
+			if attempt >= d.config.MaxRetries {
+				return true, lastErr
+			}
+
+			select {
+			case <-time.After(d.retryDelay(attempt)):
+				return true, lastErr
+			case <-ctx.Done():
+				return false, lastErr
+			}
+		}, d.config.MaxRetries)
+		if err == nil {
+			return
+		}
+		if lastErr == ErrEndpointNotFound {
+			// The endpoint was removed, so the delivery is dropped.
+			return
+		}
+
+		delivery.Error = lastErr.Error()
+		log.WithFields(log.Fields{
+			"endpoint": delivery.EndpointID,
+			"delivery": delivery.ID,
+			"attempts": delivery.Attempts,
+			"error":    lastErr,
+		}).Warn("Failed to deliver webhook event")
+
+		// The context might be done, but the delivery must not be lost.
+		if err := d.addDeadLetter(context.Background(), delivery); err != nil {
+			log.WithFields(log.Fields{
+				"delivery": delivery.ID,
+				"error":    err,
+			}).Error("Failed to add webhook delivery to the dead-letter queue")
+		}
+	}()
+}
+
+// retryDelay returns the delay after an attempt.
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.config.RetryDelay
+	for i := 1; i < attempt && delay < d.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxRetryDelay {
+		delay = d.config.MaxRetryDelay
+	}
+	return delay
+}
+
+// permanentError is returned when retrying a delivery would not help.
+type permanentError struct {
+	status int
+}
+
+func (e permanentError) Error() string {
+	return fmt.Sprintf("endpoint responded with status %d", e.status)
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *Delivery) error {
+	endpoint := d.getEndpoint(delivery.EndpointID)
+	if endpoint == nil {
+		return ErrEndpointNotFound
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if endpoint.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, Sign([]byte(endpoint.Secret), timestamp, delivery.Body))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode >= 400 && res.StatusCode < 500 &&
+		res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests:
+		return permanentError{status: res.StatusCode}
+	default:
+		return errors.Errorf("endpoint responded with status %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TimestampHeader is the request header containing the Unix time at
+	// which the request was signed.
+	TimestampHeader = "X-Webhook-Timestamp"
+
+	// SignatureHeader is the request header containing the signature of
+	// the request.
+	SignatureHeader = "X-Webhook-Signature"
+
+	// signaturePrefix is the prefix of signatures.
+	signaturePrefix = "sha256="
+
+	// DefaultMaxSkew is the default maximum age of a signed request.
+	DefaultMaxSkew = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidSignature is returned when the signature of a request
+	// is missing or invalid.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrExpiredSignature is returned when a request was signed too long
+	// ago.
+	ErrExpiredSignature = errors.New("expired webhook signature")
+)
+
+// Sign returns the signature of a request body. It is the hex encoded
+// HMAC-SHA256 of the timestamp and the body separated by a dot.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a request received by an endpoint. The
+// body of the request can still be read afterwards.
+func Verify(r *http.Request, secret []byte, maxSkew time.Duration) error {
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	signature := r.Header.Get(SignatureHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxSkew {
+		return ErrExpiredSignature
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
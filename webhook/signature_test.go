@@ -0,0 +1,76 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"type":"SavedLinks"}`
+
+	sign := func(timestamp int64, body string) (string, string) {
+		return strconv.FormatInt(timestamp, 10), Sign(secret, timestamp, []byte(body))
+	}
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		body      string
+		secret    string
+		err       error
+	}{
+		{"valid", time.Now(), body, "secret", nil},
+		{"wrong secret", time.Now(), body, "other", ErrInvalidSignature},
+		{"expired", time.Now().Add(-time.Hour), body, "secret", ErrExpiredSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/hook", strings.NewReader(tt.body))
+			timestamp, signature := sign(tt.timestamp.Unix(), tt.body)
+			r.Header.Set(TimestampHeader, timestamp)
+			r.Header.Set(SignatureHeader, signature)
+
+			assert.Equal(t, tt.err, Verify(r, []byte(tt.secret), DefaultMaxSkew))
+
+			got, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, tt.body, string(got), "body should still be readable")
+		})
+	}
+
+	t.Run("tampered body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/hook", strings.NewReader(`{"type":"other"}`))
+		timestamp, signature := sign(time.Now().Unix(), body)
+		r.Header.Set(TimestampHeader, timestamp)
+		r.Header.Set(SignatureHeader, signature)
+
+		assert.Equal(t, ErrInvalidSignature, Verify(r, secret, DefaultMaxSkew))
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+
+		assert.Equal(t, ErrInvalidSignature, Verify(r, secret, DefaultMaxSkew))
+	})
+}
@@ -0,0 +1,379 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook defines a dispatcher that posts store and fossilizer
+// events to HTTP endpoints.
+//
+// Requests are signed with the secret of the endpoint, and failed deliveries
+// are retried with an exponential delay. Deliveries that still fail are kept
+// in a dead-letter queue so they can be redelivered later. Endpoints and the
+// dead-letter queue are persisted in a key-value store if one is given.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/store"
+)
+
+const (
+	// DefaultMaxRetries is the default number of attempts of a delivery.
+	DefaultMaxRetries = 5
+
+	// DefaultRetryDelay is the default delay before the first retry of a
+	// delivery. It doubles at each attempt.
+	DefaultRetryDelay = time.Second
+
+	// DefaultMaxRetryDelay is the default maximum delay between two
+	// attempts of a delivery.
+	DefaultMaxRetryDelay = time.Minute
+
+	// DefaultTimeout is the default timeout of a request to an endpoint.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultDeadLetterSize is the default maximum number of deliveries
+	// kept in the dead-letter queue.
+	DefaultDeadLetterSize = 1000
+
+	// DefaultEventChanSize is the default size of the event channels.
+	DefaultEventChanSize = 256
+)
+
+var (
+	// ErrEndpointNotFound is returned when an endpoint does not exist.
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+
+	// ErrDeliveryNotFound is returned when a delivery is not in the
+	// dead-letter queue.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// ErrInvalidEndpoint is returned when an endpoint has no URL.
+	ErrInvalidEndpoint = errors.New("webhook endpoint must have a URL")
+)
+
+var (
+	endpointsKey   = []byte("webhook:endpoints")
+	deadLettersKey = []byte("webhook:deadLetters")
+)
+
+// Endpoint is an HTTP endpoint that receives events.
+type Endpoint struct {
+	// ID is set when the endpoint is added if it is empty.
+	ID  string `json:"id"`
+	URL string `json:"url"`
+
+	// Secret is the key used to sign requests. Requests are not signed if
+	// it is empty.
+	Secret string `json:"secret,omitempty"`
+
+	// EventTypes are the types of events sent to the endpoint. All events
+	// are sent if it is empty.
+	EventTypes []string `json:"eventTypes,omitempty"`
+
+	// Filter selects the links and evidences of the store events sent to
+	// the endpoint. Pagination and sorting are ignored. Fossilizer events
+	// are not sent to endpoints that have a filter.
+	Filter *store.SegmentFilter `json:"filter,omitempty"`
+}
+
+// matchType returns whether an event type is sent to the endpoint.
+func (e *Endpoint) matchType(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an event sent to an endpoint.
+type Delivery struct {
+	ID         string          `json:"id"`
+	EndpointID string          `json:"endpointId"`
+	EventType  string          `json:"eventType"`
+	Body       json.RawMessage `json:"body"`
+	CreatedAt  time.Time       `json:"createdAt"`
+
+	// Attempts and Error describe the last attempt of a failed delivery.
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Message is the body of the requests sent to endpoints.
+type Message struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Config contains configuration options for a dispatcher.
+type Config struct {
+	// The maximum number of attempts of a delivery.
+	MaxRetries int
+
+	// The delay before the first retry of a delivery.
+	RetryDelay time.Duration
+
+	// The maximum delay between two attempts of a delivery.
+	MaxRetryDelay time.Duration
+
+	// The timeout of a request to an endpoint.
+	Timeout time.Duration
+
+	// The maximum number of deliveries kept in the dead-letter queue.
+	DeadLetterSize int
+
+	// The size of the event channels.
+	EventChanSize int
+}
+
+// Dispatcher posts events to endpoints.
+type Dispatcher struct {
+	config *Config
+	kv     store.KeyValueStore
+	client *http.Client
+
+	storeEventChan      chan *store.Event
+	fossilizerEventChan chan *fossilizer.Event
+	redeliverChan       chan *Delivery
+	reader              store.SegmentReader
+
+	mu          sync.RWMutex
+	endpoints   []*Endpoint
+	deadLetters []*Delivery
+
+	wg sync.WaitGroup
+}
+
+// New creates a dispatcher. Endpoints and failed deliveries are persisted
+// in the key-value store if it is not nil.
+func New(config *Config, kv store.KeyValueStore) *Dispatcher {
+	c := *config
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = DefaultRetryDelay
+	}
+	if c.MaxRetryDelay <= 0 {
+		c.MaxRetryDelay = DefaultMaxRetryDelay
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.DeadLetterSize <= 0 {
+		c.DeadLetterSize = DefaultDeadLetterSize
+	}
+	if c.EventChanSize < 0 {
+		c.EventChanSize = 0
+	}
+
+	return &Dispatcher{
+		config:              &c,
+		kv:                  kv,
+		client:              &http.Client{Timeout: c.Timeout},
+		storeEventChan:      make(chan *store.Event, c.EventChanSize),
+		fossilizerEventChan: make(chan *fossilizer.Event, c.EventChanSize),
+		redeliverChan:       make(chan *Delivery),
+	}
+}
+
+// Load loads the endpoints and the dead-letter queue from the key-value
+// store. It should be called before the dispatcher is used.
+func (d *Dispatcher) Load(ctx context.Context) error {
+	if d.kv == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.load(ctx, endpointsKey, &d.endpoints); err != nil {
+		return err
+	}
+
+	return d.load(ctx, deadLettersKey, &d.deadLetters)
+}
+
+func (d *Dispatcher) load(ctx context.Context, key []byte, v interface{}) error {
+	value, err := d.kv.GetValue(ctx, key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if value == nil {
+		return nil
+	}
+
+	return errors.Wrapf(json.Unmarshal(value, v), "could not load %s", key)
+}
+
+// save persists a value in the key-value store. The caller must hold the
+// lock.
+func (d *Dispatcher) save(ctx context.Context, key []byte, v interface{}) error {
+	if d.kv == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(d.kv.SetValue(ctx, key, value))
+}
+
+// GetEndpoints returns the endpoints.
+func (d *Dispatcher) GetEndpoints() []*Endpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return append([]*Endpoint(nil), d.endpoints...)
+}
+
+// AddEndpoint adds an endpoint, or replaces the one with the same ID.
+func (d *Dispatcher) AddEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	if endpoint.URL == "" {
+		return ErrInvalidEndpoint
+	}
+	if endpoint.ID == "" {
+		endpoint.ID = newID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]*Endpoint, 0, len(d.endpoints)+1)
+	for _, e := range d.endpoints {
+		if e.ID != endpoint.ID {
+			endpoints = append(endpoints, e)
+		}
+	}
+	endpoints = append(endpoints, endpoint)
+
+	if err := d.save(ctx, endpointsKey, endpoints); err != nil {
+		return err
+	}
+	d.endpoints = endpoints
+
+	return nil
+}
+
+// RemoveEndpoint removes an endpoint.
+func (d *Dispatcher) RemoveEndpoint(ctx context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]*Endpoint, 0, len(d.endpoints))
+	for _, e := range d.endpoints {
+		if e.ID != id {
+			endpoints = append(endpoints, e)
+		}
+	}
+	if len(endpoints) == len(d.endpoints) {
+		return ErrEndpointNotFound
+	}
+
+	if err := d.save(ctx, endpointsKey, endpoints); err != nil {
+		return err
+	}
+	d.endpoints = endpoints
+
+	return nil
+}
+
+func (d *Dispatcher) getEndpoint(id string) *Endpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, e := range d.endpoints {
+		if e.ID == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// GetDeadLetters returns the deliveries that failed, oldest first.
+func (d *Dispatcher) GetDeadLetters() []*Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return append([]*Delivery(nil), d.deadLetters...)
+}
+
+// addDeadLetter adds a failed delivery to the dead-letter queue, dropping
+// the oldest one if the queue is full.
+func (d *Dispatcher) addDeadLetter(ctx context.Context, delivery *Delivery) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deadLetters := append([]*Delivery(nil), d.deadLetters...)
+	if len(deadLetters) >= d.config.DeadLetterSize {
+		deadLetters = deadLetters[len(deadLetters)-d.config.DeadLetterSize+1:]
+	}
+	deadLetters = append(deadLetters, delivery)
+
+	if err := d.save(ctx, deadLettersKey, deadLetters); err != nil {
+		return err
+	}
+	d.deadLetters = deadLetters
+
+	return nil
+}
+
+// RemoveDeadLetter removes a delivery from the dead-letter queue and
+// returns it.
+func (d *Dispatcher) RemoveDeadLetter(ctx context.Context, id string) (*Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var delivery *Delivery
+	deadLetters := make([]*Delivery, 0, len(d.deadLetters))
+	for _, dl := range d.deadLetters {
+		if dl.ID == id {
+			delivery = dl
+		} else {
+			deadLetters = append(deadLetters, dl)
+		}
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	if err := d.save(ctx, deadLettersKey, deadLetters); err != nil {
+		return nil, err
+	}
+	d.deadLetters = deadLetters
+
+	return delivery, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
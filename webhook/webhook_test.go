@@ -0,0 +1,250 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-indigocore/cs"
+	"github.com/stratumn/go-indigocore/cs/cstesting"
+	"github.com/stratumn/go-indigocore/dummystore"
+	"github.com/stratumn/go-indigocore/fossilizer"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = &Config{
+	MaxRetries: 3,
+	RetryDelay: time.Millisecond,
+	Timeout:    time.Second,
+}
+
+// startDispatcher starts a dispatcher and returns a function that stops it.
+func startDispatcher(d *Dispatcher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		d.Start(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestDispatcher_endpoints(t *testing.T) {
+	ctx := context.Background()
+	kv := dummystore.New(&dummystore.Config{})
+	d := New(testConfig, kv)
+
+	e := &Endpoint{URL: "http://localhost/hook", Secret: "secret"}
+	require.NoError(t, d.AddEndpoint(ctx, e))
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, ErrInvalidEndpoint, d.AddEndpoint(ctx, &Endpoint{}))
+
+	loaded := New(testConfig, kv)
+	require.NoError(t, loaded.Load(ctx))
+	assert.Equal(t, []*Endpoint{e}, loaded.GetEndpoints())
+
+	require.NoError(t, d.RemoveEndpoint(ctx, e.ID))
+	assert.Empty(t, d.GetEndpoints())
+	assert.Equal(t, ErrEndpointNotFound, d.RemoveEndpoint(ctx, e.ID))
+}
+
+func TestDispatcher_storeEvents(t *testing.T) {
+	link1 := cstesting.NewLinkBuilder().WithProcess("p1").Build()
+	link2 := cstesting.NewLinkBuilder().WithProcess("p2").Build()
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, Verify(r, []byte("secret"), DefaultMaxSkew))
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	a := dummystore.New(&dummystore.Config{})
+	d := New(testConfig, a)
+	d.ListenStore(a)
+	require.NoError(t, d.AddEndpoint(context.Background(), &Endpoint{
+		URL:        server.URL,
+		Secret:     "secret",
+		EventTypes: []string{string(store.SavedLinks)},
+		Filter:     &store.SegmentFilter{Process: "p2"},
+	}))
+	stop := startDispatcher(d)
+	defer stop()
+
+	_, err := a.CreateLink(context.Background(), link1)
+	require.NoError(t, err)
+	_, err = a.CreateLink(context.Background(), link2)
+	require.NoError(t, err)
+
+	select {
+	case r := <-received:
+		assert.Equal(t, string(store.SavedLinks), r.Header.Get(EventTypeHeader))
+		assert.NotEmpty(t, r.Header.Get(DeliveryHeader))
+
+		var msg struct {
+			Type string
+			Data []*cs.Link
+		}
+		require.NoError(t, json.Unmarshal(<-bodies, &msg))
+		require.Len(t, msg.Data, 1)
+		assert.Equal(t, "p2", msg.Data[0].Meta.Process)
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	select {
+	case <-received:
+		t.Fatal("filtered event delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_filteredFossilizerEvents(t *testing.T) {
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	a := &fossilizerAdapter{}
+	d := New(testConfig, nil)
+	d.ListenFossilizer(a)
+	require.NoError(t, d.AddEndpoint(context.Background(), &Endpoint{
+		URL:    server.URL,
+		Filter: &store.SegmentFilter{Process: "p1"},
+	}))
+	stop := startDispatcher(d)
+	defer stop()
+
+	a.eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: "result"}
+
+	select {
+	case <-received:
+		t.Fatal("fossilizer event delivered to a filtered endpoint")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_retry(t *testing.T) {
+	var attempts int32
+	delivered := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		close(delivered)
+	}))
+	defer server.Close()
+
+	a := &fossilizerAdapter{}
+	d := New(testConfig, nil)
+	d.ListenFossilizer(a)
+	require.NoError(t, d.AddEndpoint(context.Background(), &Endpoint{URL: server.URL}))
+	stop := startDispatcher(d)
+	defer stop()
+
+	a.eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: "result"}
+
+	select {
+	case <-delivered:
+		assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+}
+
+func TestDispatcher_deadLetters(t *testing.T) {
+	var fail int32 = 1
+	delivered := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delivered <- struct{}{}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	kv := dummystore.New(&dummystore.Config{})
+	a := &fossilizerAdapter{}
+	d := New(testConfig, kv)
+	d.ListenFossilizer(a)
+	require.NoError(t, d.AddEndpoint(ctx, &Endpoint{URL: server.URL}))
+	stop := startDispatcher(d)
+	defer stop()
+
+	a.eventChan <- &fossilizer.Event{EventType: fossilizer.DidFossilizeLink, Data: "result"}
+
+	var deadLetters []*Delivery
+	for i := 0; i < 100 && len(deadLetters) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		deadLetters = d.GetDeadLetters()
+	}
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, testConfig.MaxRetries, deadLetters[0].Attempts)
+	assert.Equal(t, string(fossilizer.DidFossilizeLink), deadLetters[0].EventType)
+
+	loaded := New(testConfig, kv)
+	require.NoError(t, loaded.Load(ctx))
+	assert.Len(t, loaded.GetDeadLetters(), 1)
+
+	atomic.StoreInt32(&fail, 0)
+	require.NoError(t, d.Redeliver(ctx, deadLetters[0].ID))
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("event not redelivered")
+	}
+	assert.Empty(t, d.GetDeadLetters())
+	assert.Equal(t, ErrDeliveryNotFound, d.Redeliver(ctx, deadLetters[0].ID))
+}
+
+func TestDispatcher_retryDelay(t *testing.T) {
+	d := New(&Config{RetryDelay: time.Second, MaxRetryDelay: 5 * time.Second}, nil)
+
+	assert.Equal(t, time.Second, d.retryDelay(1))
+	assert.Equal(t, 2*time.Second, d.retryDelay(2))
+	assert.Equal(t, 4*time.Second, d.retryDelay(3))
+	assert.Equal(t, 5*time.Second, d.retryDelay(4))
+}
+
+// fossilizerAdapter is a fossilizer that only sends events.
+type fossilizerAdapter struct {
+	fossilizer.Adapter
+	eventChan chan *fossilizer.Event
+}
+
+func (a *fossilizerAdapter) AddFossilizerEventChan(c chan *fossilizer.Event) {
+	a.eventChan = c
+}
@@ -0,0 +1,263 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhookhttp serves the routes that manage the endpoints and the
+// dead-letter queue of a webhook dispatcher.
+//
+// The routes are:
+//	GET /webhooks
+//		Renders the webhook endpoints, without their secret.
+//
+//	POST /webhooks
+//		Adds or replaces a webhook endpoint.
+//		Body should be a JSON encoded endpoint:
+//			{ "id": id, "url": url, "secret": secret, "eventTypes": [type], "filter": {segment filter} }
+//
+//	DELETE /webhooks/:id
+//		Removes a webhook endpoint.
+//
+//	GET /deadletters
+//		Renders the webhook deliveries that failed.
+//
+//	DELETE /deadletters/:id
+//		Removes a failed delivery.
+//
+//	POST /deadletters/:id/redeliver
+//		Sends a failed delivery again.
+//
+// They require an admin policy. If that policy is limited to some processes,
+// endpoints must filter one of them, and only those endpoints and their failed
+// deliveries are visible.
+package webhookhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/monitoring"
+	"github.com/stratumn/go-indigocore/webhook"
+
+	"go.opencensus.io/trace"
+)
+
+// handlers serves the routes of a dispatcher.
+type handlers struct {
+	webhooks *webhook.Dispatcher
+}
+
+// Register adds the routes of a webhook dispatcher to a server.
+func Register(s *jsonhttp.Server, d *webhook.Dispatcher) {
+	h := &handlers{webhooks: d}
+
+	s.Get("/webhooks", h.getWebhooks)
+	s.Post("/webhooks", h.addWebhook)
+	s.Delete("/webhooks/:id", h.removeWebhook)
+	s.Get("/deadletters", h.getDeadLetters)
+	s.Delete("/deadletters/:id", h.removeDeadLetter)
+	s.Post("/deadletters/:id/redeliver", h.redeliver)
+}
+
+// redactEndpoint returns a copy of an endpoint without its secret.
+func redactEndpoint(e *webhook.Endpoint) *webhook.Endpoint {
+	redacted := *e
+	redacted.Secret = ""
+	return &redacted
+}
+
+// webhookErr converts a webhook error to an HTTP error.
+func webhookErr(span *trace.Span, err error) error {
+	switch errors.Cause(err) {
+	case webhook.ErrEndpointNotFound, webhook.ErrDeliveryNotFound:
+		span.SetStatus(trace.Status{Code: monitoring.NotFound, Message: err.Error()})
+		return jsonhttp.NewErrNotFound("")
+	case webhook.ErrInvalidEndpoint:
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return jsonhttp.NewErrBadRequest(err.Error())
+	default:
+		span.SetStatus(trace.Status{Code: monitoring.Unknown, Message: err.Error()})
+		return err
+	}
+}
+
+// authorizeAdmin returns a forbidden error if the client that sent a request
+// doesn't have an admin policy.
+func authorizeAdmin(ctx context.Context, span *trace.Span) error {
+	err := jsonhttp.AuthorizeAdmin(ctx)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+	}
+	return err
+}
+
+// allowsEndpoint returns whether the client that sent a request can manage
+// an endpoint. Clients limited to some processes can only manage endpoints
+// that filter one of them.
+func allowsEndpoint(ctx context.Context, e *webhook.Endpoint) bool {
+	identity := jsonhttp.IdentityFromContext(ctx)
+	if identity == nil || len(identity.Policy.Processes) == 0 {
+		return true
+	}
+	return e != nil && e.Filter != nil && e.Filter.Process != "" && identity.Policy.HasProcess(e.Filter.Process)
+}
+
+// findEndpoint returns the endpoint with the given ID, or nil.
+func (h *handlers) findEndpoint(id string) *webhook.Endpoint {
+	for _, e := range h.webhooks.GetEndpoints() {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// authorizeDeadLetter returns a not found error if the delivery is not in
+// the dead-letter queue or if the client cannot manage its endpoint.
+func (h *handlers) authorizeDeadLetter(ctx context.Context, span *trace.Span, id string) error {
+	for _, d := range h.webhooks.GetDeadLetters() {
+		if d.ID == id && allowsEndpoint(ctx, h.findEndpoint(d.EndpointID)) {
+			return nil
+		}
+	}
+	return webhookErr(span, webhook.ErrDeliveryNotFound)
+}
+
+func (h *handlers) getWebhooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/getWebhooks")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	endpoints := []*webhook.Endpoint{}
+	for _, e := range h.webhooks.GetEndpoints() {
+		if allowsEndpoint(ctx, e) {
+			endpoints = append(endpoints, redactEndpoint(e))
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (h *handlers) addWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/addWebhook")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	var endpoint webhook.Endpoint
+	if err := json.NewDecoder(r.Body).Decode(&endpoint); err != nil {
+		span.SetStatus(trace.Status{Code: monitoring.InvalidArgument, Message: err.Error()})
+		return nil, jsonhttp.NewErrBadRequest(err.Error())
+	}
+
+	if !allowsEndpoint(ctx, &endpoint) {
+		err := jsonhttp.NewErrForbidden("endpoint filter must have one of the processes of the client")
+		span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+		return nil, err
+	}
+	if endpoint.ID != "" {
+		if e := h.findEndpoint(endpoint.ID); e != nil && !allowsEndpoint(ctx, e) {
+			err := jsonhttp.NewErrForbidden("not allowed to replace endpoint " + endpoint.ID)
+			span.SetStatus(trace.Status{Code: monitoring.PermissionDenied, Message: err.Error()})
+			return nil, err
+		}
+	}
+
+	if err := h.webhooks.AddEndpoint(ctx, &endpoint); err != nil {
+		return nil, webhookErr(span, err)
+	}
+
+	return redactEndpoint(&endpoint), nil
+}
+
+func (h *handlers) removeWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/removeWebhook")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	id := p.ByName("id")
+	if e := h.findEndpoint(id); e != nil && !allowsEndpoint(ctx, e) {
+		return nil, webhookErr(span, webhook.ErrEndpointNotFound)
+	}
+
+	if err := h.webhooks.RemoveEndpoint(ctx, id); err != nil {
+		return nil, webhookErr(span, err)
+	}
+
+	return "ok", nil
+}
+
+func (h *handlers) getDeadLetters(w http.ResponseWriter, r *http.Request, _ httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/getDeadLetters")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+
+	deliveries := []*webhook.Delivery{}
+	for _, d := range h.webhooks.GetDeadLetters() {
+		if allowsEndpoint(ctx, h.findEndpoint(d.EndpointID)) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (h *handlers) removeDeadLetter(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/removeDeadLetter")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+	if err := h.authorizeDeadLetter(ctx, span, p.ByName("id")); err != nil {
+		return nil, err
+	}
+
+	if _, err := h.webhooks.RemoveDeadLetter(ctx, p.ByName("id")); err != nil {
+		return nil, webhookErr(span, err)
+	}
+
+	return "ok", nil
+}
+
+func (h *handlers) redeliver(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	ctx, span := trace.StartSpan(r.Context(), "webhookhttp/redeliver")
+	defer span.End()
+
+	if err := authorizeAdmin(ctx, span); err != nil {
+		return nil, err
+	}
+	if err := h.authorizeDeadLetter(ctx, span, p.ByName("id")); err != nil {
+		return nil, err
+	}
+
+	if err := h.webhooks.Redeliver(ctx, p.ByName("id")); err != nil {
+		return nil, webhookErr(span, err)
+	}
+
+	return "ok", nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stratumn/go-indigocore/jsonhttp"
+	"github.com/stratumn/go-indigocore/store"
+	"github.com/stratumn/go-indigocore/testutil"
+	"github.com/stratumn/go-indigocore/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createServer(config *jsonhttp.Config) (*jsonhttp.Server, *webhook.Dispatcher) {
+	d := webhook.New(&webhook.Config{}, nil)
+	s := jsonhttp.New(config)
+	Register(s, d)
+	return s, d
+}
+
+func TestWebhooks(t *testing.T) {
+	s, d := createServer(&jsonhttp.Config{})
+
+	var endpoint webhook.Endpoint
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/webhooks", &webhook.Endpoint{
+		URL:    "http://localhost/hook",
+		Secret: "secret",
+	}, &endpoint)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, endpoint.ID)
+	assert.Empty(t, endpoint.Secret, "secret should not be rendered")
+	require.Len(t, d.GetEndpoints(), 1)
+	assert.Equal(t, "secret", d.GetEndpoints()[0].Secret)
+
+	var endpoints []*webhook.Endpoint
+	w, err = testutil.RequestJSON(s.ServeHTTP, "GET", "/webhooks", nil, &endpoints)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []*webhook.Endpoint{&endpoint}, endpoints)
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "DELETE", "/webhooks/"+endpoint.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, d.GetEndpoints())
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "DELETE", "/webhooks/"+endpoint.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWebhooks_auth(t *testing.T) {
+	s, d := createServer(&jsonhttp.Config{
+		Authenticator: jsonhttp.NewTokenAuthenticator(map[string]*jsonhttp.Identity{
+			"writer": {Name: "writer"},
+			"admin":  {Name: "admin", Policy: jsonhttp.Policy{Admin: true}},
+			"scoped": {Name: "scoped", Policy: jsonhttp.Policy{Admin: true, Processes: []string{"p1"}}},
+		}),
+	})
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var r io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			r = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, path, r)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	ctx := context.Background()
+	other := &webhook.Endpoint{URL: "http://localhost/other"}
+	require.NoError(t, d.AddEndpoint(ctx, other))
+
+	for _, path := range []string{"/webhooks", "/deadletters"} {
+		assert.Equal(t, http.StatusForbidden, request("writer", "GET", path, nil).Code, path)
+	}
+	assert.Equal(t, http.StatusForbidden, request("writer", "POST", "/webhooks", &webhook.Endpoint{URL: "http://localhost/hook"}).Code)
+	assert.Equal(t, http.StatusForbidden, request("writer", "DELETE", "/webhooks/"+other.ID, nil).Code)
+
+	assert.Equal(t, http.StatusForbidden, request("scoped", "POST", "/webhooks", &webhook.Endpoint{URL: "http://localhost/hook"}).Code, "no filter")
+	assert.Equal(t, http.StatusForbidden, request("scoped", "POST", "/webhooks", &webhook.Endpoint{
+		URL:    "http://localhost/hook",
+		Filter: &store.SegmentFilter{Process: "p2"},
+	}).Code, "other process")
+	assert.Equal(t, http.StatusForbidden, request("scoped", "POST", "/webhooks", &webhook.Endpoint{
+		ID:     other.ID,
+		URL:    "http://localhost/hook",
+		Filter: &store.SegmentFilter{Process: "p1"},
+	}).Code, "replace other endpoint")
+	assert.Equal(t, http.StatusNotFound, request("scoped", "DELETE", "/webhooks/"+other.ID, nil).Code)
+
+	w := request("scoped", "POST", "/webhooks", &webhook.Endpoint{
+		URL:    "http://localhost/hook",
+		Filter: &store.SegmentFilter{Process: "p1"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var scoped webhook.Endpoint
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &scoped))
+
+	var endpoints []*webhook.Endpoint
+	w = request("scoped", "GET", "/webhooks", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &endpoints))
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, scoped.ID, endpoints[0].ID)
+
+	w = request("admin", "GET", "/webhooks", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &endpoints))
+	assert.Len(t, endpoints, 2)
+
+	assert.Equal(t, http.StatusOK, request("admin", "DELETE", "/webhooks/"+other.ID, nil).Code)
+}
+
+func TestWebhooks_invalid(t *testing.T) {
+	s, _ := createServer(&jsonhttp.Config{})
+
+	w, err := testutil.RequestJSON(s.ServeHTTP, "POST", "/webhooks", &webhook.Endpoint{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeadLetters(t *testing.T) {
+	s, _ := createServer(&jsonhttp.Config{})
+
+	var deliveries []*webhook.Delivery
+	w, err := testutil.RequestJSON(s.ServeHTTP, "GET", "/deadletters", nil, &deliveries)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, deliveries)
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "POST", "/deadletters/unknown/redeliver", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, err = testutil.RequestJSON(s.ServeHTTP, "DELETE", "/deadletters/unknown", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}